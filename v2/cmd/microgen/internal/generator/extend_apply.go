@@ -214,6 +214,7 @@ func optionsFromExistingProject(existing *ExistingProject) Options {
 		WithConfig:           existing.Features.WithConfig,
 		ConfigMode:           existing.Features.ConfigMode,
 		RemoteProvider:       existing.Features.RemoteProvider,
+		ErrorFormat:          existing.Features.ErrorFormat,
 		WithDocs:             existing.Features.WithDocs,
 		WithTests:            existing.Features.WithTests,
 		WithModel:            existing.Features.WithModel,
@@ -71,6 +71,7 @@ type ExistingProjectFeatures struct {
 	WithInteraction      bool
 	ConfigMode           string
 	RemoteProvider       string
+	ErrorFormat          string
 	DBDriver             string
 	RoutePrefix          string
 	GeneratedMiddlewares []string
@@ -458,6 +459,7 @@ func projectFeaturesFromManifest(manifest ProjectManifest) ExistingProjectFeatur
 		WithInteraction:      capabilities.Interaction,
 		ConfigMode:           capabilities.ConfigMode,
 		RemoteProvider:       capabilities.RemoteProvider,
+		ErrorFormat:          capabilities.ErrorFormat,
 		DBDriver:             capabilities.DatabaseDriver,
 		RoutePrefix:          manifest.RoutePrefix,
 		GeneratedMiddlewares: append([]string(nil), manifest.GeneratedMiddlewares...),
@@ -543,9 +545,21 @@ func detectProjectFeatures(root string, project *ExistingProject) ExistingProjec
 	features.WithDB = fileContains(filepath.Join(root, "cmd", "main.go"), "repository.NewDB(")
 	features.RoutePrefix = detectRoutePrefix(project.AggregationPoints.GeneratedRoutes)
 	features.GeneratedMiddlewares = detectGeneratedMiddlewares(project.Services)
+	features.ErrorFormat = detectErrorFormat(project.Services)
 	return features
 }
 
+// detectErrorFormat reports the problem format when any generated HTTP
+// transport already uses ProblemErrorEncoder.
+func detectErrorFormat(services []ExistingService) string {
+	for _, svc := range services {
+		if fileContains(svc.HTTPTransportFile, "server.ProblemErrorEncoder") {
+			return errorFormatProblem
+		}
+	}
+	return errorFormatJSON
+}
+
 func detectGeneratedMiddlewares(services []ExistingService) []string {
 	seen := map[string]bool{}
 	for _, svc := range services {
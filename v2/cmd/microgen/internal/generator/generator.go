@@ -24,6 +24,7 @@ type Options struct {
 	WithConfig           bool
 	ConfigMode           string
 	RemoteProvider       string
+	ErrorFormat          string
 	WithDocs             bool
 	WithTests            bool
 	WithModel            bool
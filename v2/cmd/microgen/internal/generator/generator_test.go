@@ -1717,6 +1717,37 @@ func TestGenerateFull_OpenAPI_IsNotDuplicatedInTransport(t *testing.T) {
 	mustContain(t, openAPIPath, `"$ref": "#/components/schemas/ErrorResponse"`)
 }
 
+// -error-format=problem switches the generated error encoder and the
+// documented error schema together.
+func TestGenerateFull_ProblemErrorFormat(t *testing.T) {
+	outDir := newTmpDir(t)
+	project := parseIDLProject(t, "basic.go")
+
+	gen := mustNewGenerator(t, generator.Options{
+		OutputDir:   outDir,
+		ImportPath:  "example.com/basic",
+		DBDriver:    "sqlite",
+		WithOpenAPI: true,
+		ErrorFormat: "problem",
+	})
+	if err := gen.GenerateIR(project); err != nil {
+		t.Fatalf("GenerateIR: %v", err)
+	}
+
+	httpPath := filepath.Join(outDir, "transport", "userservice", "transport_http.go")
+	mustContain(t, httpPath, "server.ServerErrorEncoder(server.ProblemErrorEncoder)")
+	// instance is read from the request path PopulateRequestContext stores.
+	mustContain(t, httpPath, "server.ServerBefore(transporthttp.PopulateRequestContext)")
+	mustNotContain(t, httpPath, "JSONErrorEncoder")
+
+	openAPIPath := filepath.Join(outDir, "docs", "openapi.json")
+	mustContain(t, openAPIPath, `"application/problem+json"`)
+	mustContain(t, openAPIPath, `"$ref": "#/components/schemas/ProblemDetails"`)
+	mustNotContain(t, openAPIPath, `ErrorResponse`)
+	mustContain(t, filepath.Join(outDir, "docs", "schema.json"), `"ProblemDetails"`)
+	mustContain(t, filepath.Join(outDir, ".microgen", "manifest.json"), `"errorFormat": "problem"`)
+}
+
 // Operations are generated from IR methods, including their HTTP verbs.
 func TestGenerateFull_OpenAPI_ContainsMethodOperations(t *testing.T) {
 	outDir := newTmpDir(t)
@@ -1779,6 +1810,9 @@ func TestGenerateFull_OpenAPI_MainRoutes(t *testing.T) {
 	mustContain(t, typeScriptPath, "export class UserServiceClient")
 	mustContain(t, typeScriptPath, `let path = "/getuser"`)
 	mustContain(t, typeScriptPath, "appendQueryValue")
+	mustContain(t, typeScriptPath, "async getUser(request: GetUserRequest, options: ReadOptions = {})")
+	mustContain(t, typeScriptPath, `query.set("fields", options.fields.join(","))`)
+	mustContain(t, filepath.Join(outDir, "transport", "userservice", "transport_http.go"), "server.ServerFieldMask(server.FieldMaskOptions{})")
 }
 
 // main.go does not carry a second annotation-based API contract.
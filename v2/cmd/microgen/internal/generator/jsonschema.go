@@ -14,7 +14,7 @@ type jsonSchemaDocument struct {
 	Defs        map[string]*openAPISchema `json:"$defs"`
 }
 
-func buildJSONSchemaDocument(project *ir.Project) jsonSchemaDocument {
+func buildJSONSchemaDocument(project *ir.Project, errorFormat string) jsonSchemaDocument {
 	if project == nil {
 		project = &ir.Project{}
 	}
@@ -24,6 +24,6 @@ func buildJSONSchemaDocument(project *ir.Project) jsonSchemaDocument {
 		Schema:      jsonSchemaVersion,
 		Title:       openAPITitle(project) + " Schemas",
 		Description: openAPIDescription(project),
-		Defs:        buildContractSchemas(messages, messageNames, jsonSchemaRefPrefix),
+		Defs:        buildContractSchemas(messages, messageNames, jsonSchemaRefPrefix, errorFormat),
 	}
 }
@@ -25,7 +25,7 @@ func TestBuildJSONSchemaDocument(t *testing.T) {
 		}},
 	}
 
-	doc := buildJSONSchemaDocument(project)
+	doc := buildJSONSchemaDocument(project, errorFormatJSON)
 	if doc.Schema != jsonSchemaVersion {
 		t.Fatalf("schema = %q", doc.Schema)
 	}
@@ -41,6 +41,7 @@ type ProjectManifestCapabilities struct {
 	ConfigMode     string `json:"configMode,omitempty"`
 	RemoteProvider string `json:"remoteProvider,omitempty"`
 	DatabaseDriver string `json:"databaseDriver,omitempty"`
+	ErrorFormat    string `json:"errorFormat,omitempty"`
 }
 
 func (g *Generator) generateProjectManifest(ctx generationContext) error {
@@ -91,6 +92,7 @@ func (g *Generator) buildProjectManifest(ctx generationContext) ProjectManifest
 			ConfigMode:     g.config.ConfigMode,
 			RemoteProvider: g.config.RemoteProvider,
 			DatabaseDriver: databaseDriver,
+			ErrorFormat:    g.config.ErrorFormat,
 		},
 		Services:             services,
 		Models:               models,
@@ -220,6 +222,11 @@ func validateProjectManifest(manifest ProjectManifest, modulePath string) error
 	if !manifest.Capabilities.Config && (manifest.Capabilities.ConfigMode != "" || manifest.Capabilities.RemoteProvider != "") {
 		return fmt.Errorf("configMode and remoteProvider require capabilities.config")
 	}
+	switch manifest.Capabilities.ErrorFormat {
+	case "", errorFormatJSON, errorFormatProblem:
+	default:
+		return fmt.Errorf("unsupported capabilities.errorFormat %q", manifest.Capabilities.ErrorFormat)
+	}
 	if manifest.Capabilities.Database {
 		if _, ok := supportedDrivers[manifest.Capabilities.DatabaseDriver]; !ok {
 			return fmt.Errorf("unsupported capabilities.databaseDriver %q", manifest.Capabilities.DatabaseDriver)
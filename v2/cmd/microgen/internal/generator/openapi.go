@@ -93,11 +93,11 @@ func (g *Generator) generateContracts(ctx generationContext) error {
 	if err := g.executeTemplate("docs.tmpl", g.layout.docsEmbed(), struct{}{}); err != nil {
 		return err
 	}
-	doc := buildOpenAPIDocument(ctx.project, g.config.RoutePrefix)
+	doc := buildOpenAPIDocument(ctx.project, g.config.RoutePrefix, g.config.ErrorFormat)
 	if err := writeJSONDocument(g.layout.openAPIFile(), doc); err != nil {
 		return fmt.Errorf("write OpenAPI document: %w", err)
 	}
-	if err := writeJSONDocument(g.layout.jsonSchemaFile(), buildJSONSchemaDocument(ctx.project)); err != nil {
+	if err := writeJSONDocument(g.layout.jsonSchemaFile(), buildJSONSchemaDocument(ctx.project, g.config.ErrorFormat)); err != nil {
 		return fmt.Errorf("write JSON Schema document: %w", err)
 	}
 	return nil
@@ -112,13 +112,13 @@ func writeJSONDocument(path string, document any) error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-func buildOpenAPIDocument(project *ir.Project, basePrefix string) openAPIDocument {
+func buildOpenAPIDocument(project *ir.Project, basePrefix, errorFormat string) openAPIDocument {
 	if project == nil {
 		project = &ir.Project{}
 	}
 	messages := collectOpenAPIMessages(project)
 	messageNames := contractMessageNames(messages)
-	schemas := buildContractSchemas(messages, messageNames, openAPIRefPrefix)
+	schemas := buildContractSchemas(messages, messageNames, openAPIRefPrefix, errorFormat)
 
 	doc := openAPIDocument{
 		OpenAPI: openAPIVersion,
@@ -147,7 +147,7 @@ func buildOpenAPIDocument(project *ir.Project, basePrefix string) openAPIDocumen
 			if item == nil {
 				item = openAPIPath{}
 			}
-			item[verb] = openAPIOperationFor(service, method, path, messages, messageNames)
+			item[verb] = openAPIOperationFor(service, method, path, messages, messageNames, errorFormat)
 			doc.Paths[path] = item
 		}
 	}
@@ -155,7 +155,7 @@ func buildOpenAPIDocument(project *ir.Project, basePrefix string) openAPIDocumen
 	return doc
 }
 
-func openAPIOperationFor(service *ir.Service, method *ir.Method, path string, messages map[string]*ir.Message, messageNames map[string]struct{}) openAPIOperation {
+func openAPIOperationFor(service *ir.Service, method *ir.Method, path string, messages map[string]*ir.Message, messageNames map[string]struct{}, errorFormat string) openAPIOperation {
 	inputName := openAPIMessageName(method.InputName, method.Input)
 	outputName := openAPIMessageName(method.OutputName, method.Output)
 	input := messages[inputName]
@@ -173,12 +173,15 @@ func openAPIOperationFor(service *ir.Service, method *ir.Method, path string, me
 		Tags:        tags,
 		Responses: map[string]openAPIResponse{
 			"200": successOpenAPIResponse(outputName),
-			"400": errorOpenAPIResponse("Invalid request"),
-			"500": errorOpenAPIResponse("Internal server error"),
+			"400": errorOpenAPIResponse("Invalid request", errorFormat),
+			"500": errorOpenAPIResponse("Internal server error", errorFormat),
 		},
 	}
 	if strings.EqualFold(method.HTTPMethod, "GET") {
 		operation.Parameters = openAPIQueryParameters(input, path, messageNames)
+		if fieldMaskSupported(method.HTTPMethod, input) {
+			operation.Parameters = append(operation.Parameters, fieldMaskOpenAPIParameter())
+		}
 	} else if inputName != "" {
 		operation.Parameters = openAPIPathParameters(input, path, messageNames)
 		operation.RequestBody = &openAPIRequestBody{
@@ -191,6 +194,36 @@ func openAPIOperationFor(service *ir.Service, method *ir.Method, path string, me
 	return operation
 }
 
+// fieldMaskParam is the query parameter generated GET routes read through
+// server.ServerFieldMask.
+const fieldMaskParam = "fields"
+
+func fieldMaskOpenAPIParameter() openAPIParameter {
+	return openAPIParameter{
+		Name:        fieldMaskParam,
+		In:          "query",
+		Description: "Comma-separated response fields to return; nested fields use dots, such as address.city.",
+		Schema:      &openAPISchema{Type: "string"},
+	}
+}
+
+// fieldMaskSupported reports whether a generated route prunes responses to
+// the fields parameter: GET routes whose request has no field of that name.
+func fieldMaskSupported(httpMethod string, input *ir.Message) bool {
+	if !strings.EqualFold(strings.TrimSpace(httpMethod), "GET") {
+		return false
+	}
+	if input == nil {
+		return true
+	}
+	for _, field := range input.Fields {
+		if field != nil && (field.JSONName == fieldMaskParam || strings.EqualFold(field.Name, fieldMaskParam)) {
+			return false
+		}
+	}
+	return true
+}
+
 func openAPIMessageName(explicit string, message *ir.Message) string {
 	if explicit != "" {
 		return explicit
@@ -259,7 +292,18 @@ func successOpenAPIResponse(outputName string) openAPIResponse {
 	return response
 }
 
-func errorOpenAPIResponse(description string) openAPIResponse {
+// errorOpenAPIResponse describes the body written by the generated error
+// encoder: ErrorResponse for JSONErrorEncoder, ProblemDetails as
+// application/problem+json for ProblemErrorEncoder.
+func errorOpenAPIResponse(description, errorFormat string) openAPIResponse {
+	if errorFormat == errorFormatProblem {
+		return openAPIResponse{
+			Description: description,
+			Content: map[string]openAPIMedia{
+				"application/problem+json": {Schema: openAPIRef("ProblemDetails")},
+			},
+		}
+	}
 	return openAPIResponse{
 		Description: description,
 		Content: map[string]openAPIMedia{
@@ -374,12 +418,16 @@ func contractMessageNames(messages map[string]*ir.Message) map[string]struct{} {
 	return names
 }
 
-func buildContractSchemas(messages map[string]*ir.Message, messageNames map[string]struct{}, refPrefix string) map[string]*openAPISchema {
+func buildContractSchemas(messages map[string]*ir.Message, messageNames map[string]struct{}, refPrefix, errorFormat string) map[string]*openAPISchema {
 	schemas := make(map[string]*openAPISchema, len(messages)+1)
 	for name, message := range messages {
 		schemas[name] = contractMessageSchema(message, messageNames, refPrefix)
 	}
-	schemas["ErrorResponse"] = errorResponseSchema()
+	if errorFormat == errorFormatProblem {
+		schemas["ProblemDetails"] = problemDetailsSchema()
+	} else {
+		schemas["ErrorResponse"] = errorResponseSchema()
+	}
 	return schemas
 }
 
@@ -395,6 +443,36 @@ func errorResponseSchema() *openAPISchema {
 	}
 }
 
+// problemDetailsSchema mirrors server.ProblemDetails: the RFC 9457 members
+// plus the code, request_id, and errors extensions.
+func problemDetailsSchema() *openAPISchema {
+	return &openAPISchema{
+		Type:        "object",
+		Description: "RFC 9457 problem details",
+		Properties: map[string]*openAPISchema{
+			"type":       {Type: "string", Format: "uri-reference"},
+			"title":      {Type: "string"},
+			"status":     {Type: "integer", Format: "int32"},
+			"detail":     {Type: "string"},
+			"instance":   {Type: "string", Format: "uri-reference"},
+			"code":       {Type: "string"},
+			"request_id": {Type: "string"},
+			"errors": {
+				Type: "array",
+				Items: &openAPISchema{
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"field":  {Type: "string"},
+						"reason": {Type: "string"},
+					},
+					Required: []string{"field", "reason"},
+				},
+			},
+		},
+		Required: []string{"type", "title", "status", "code"},
+	}
+}
+
 func collectOpenAPIMessages(project *ir.Project) map[string]*ir.Message {
 	messages := map[string]*ir.Message{}
 	for _, message := range project.Messages {
@@ -37,12 +37,12 @@ func TestBuildOpenAPIDocument(t *testing.T) {
 		}},
 	}
 
-	doc := buildOpenAPIDocument(project, "/api/v1")
+	doc := buildOpenAPIDocument(project, "/api/v1", errorFormatJSON)
 	if doc.OpenAPI != "3.1.0" {
 		t.Fatalf("openapi = %q", doc.OpenAPI)
 	}
 	get := doc.Paths["/api/v1/userservice/users/{id}"]["get"]
-	if get.OperationID != "UserService_GetUser" || len(get.Parameters) != 3 {
+	if get.OperationID != "UserService_GetUser" || len(get.Parameters) != 4 {
 		t.Fatalf("GET operation = %#v", get)
 	}
 	if get.Parameters[0].In != "path" || !get.Parameters[0].Required {
@@ -54,6 +54,9 @@ func TestBuildOpenAPIDocument(t *testing.T) {
 	if get.Parameters[2].Schema.Type != "string" {
 		t.Fatalf("duration query parameter = %#v", get.Parameters[2])
 	}
+	if get.Parameters[3].Name != "fields" || get.Parameters[3].In != "query" || get.Parameters[3].Required {
+		t.Fatalf("field mask parameter = %#v", get.Parameters[3])
+	}
 	post := doc.Paths["/api/v1/userservice/users"]["post"]
 	if post.RequestBody == nil || post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/CreateUserRequest" {
 		t.Fatalf("POST request body = %#v", post.RequestBody)
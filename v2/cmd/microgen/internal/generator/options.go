@@ -7,6 +7,12 @@ import (
 
 const defaultGoKitVersion = "v2.5.2"
 
+// Generated HTTP error formats.
+const (
+	errorFormatJSON    = "json"
+	errorFormatProblem = "problem"
+)
+
 // Normalize returns options with derived defaults filled in.
 func (opt Options) Normalize() Options {
 	if opt.OutputDir == "" {
@@ -18,6 +24,10 @@ func (opt Options) Normalize() Options {
 	opt.ConfigMode = strings.TrimSpace(opt.ConfigMode)
 	opt.RemoteProvider = strings.TrimSpace(opt.RemoteProvider)
 	opt.DBDriver = strings.TrimSpace(opt.DBDriver)
+	opt.ErrorFormat = strings.ToLower(strings.TrimSpace(opt.ErrorFormat))
+	if opt.ErrorFormat == "" {
+		opt.ErrorFormat = errorFormatJSON
+	}
 
 	if opt.WithConfig && opt.ConfigMode == "" {
 		opt.ConfigMode = "file"
@@ -42,6 +52,12 @@ func (opt Options) Validate() error {
 		}
 	}
 
+	switch opt.ErrorFormat {
+	case errorFormatJSON, errorFormatProblem:
+	default:
+		return fmt.Errorf("unsupported -error-format %q (want json or problem)", opt.ErrorFormat)
+	}
+
 	if !opt.WithConfig {
 		if opt.ConfigMode != "" {
 			return fmt.Errorf("-config-mode requires -config=true")
@@ -73,6 +73,10 @@ func (g *Generator) generateHTTPTransportFile(service *serviceView, irService *i
 		ImportPath:   g.config.ImportPath,
 		RoutePrefix:  routePrefix(g.config.RoutePrefix, service.ServiceName),
 		Source:       source,
+		ErrorEncoder: "JSONErrorEncoder",
+	}
+	if g.config.ErrorFormat == errorFormatProblem {
+		data.ErrorEncoder = "ProblemErrorEncoder"
 	}
 	return g.executeTemplate("transport.tmpl", g.layout.httpTransportFile(service.ServiceName), data)
 }
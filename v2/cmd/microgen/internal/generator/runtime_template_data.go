@@ -46,6 +46,7 @@ type httpTransportTemplateData struct {
 	ImportPath   string
 	RoutePrefix  string
 	Source       string
+	ErrorEncoder string
 }
 
 type grpcTransportTemplateData struct {
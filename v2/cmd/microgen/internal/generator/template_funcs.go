@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"text/template"
+
+	"github.com/dreamsxin/go-kit/v2/cmd/microgen/internal/ir"
 )
 
 func newTemplateSet() *template.Template {
@@ -21,5 +23,8 @@ func newTemplateSet() *template.Template {
 		"escape": func(s string) string {
 			return strings.ReplaceAll(s, "\"", "\\\"")
 		},
+		"fieldMask": func(m *ir.Method) bool {
+			return m != nil && fieldMaskSupported(m.HTTPMethod, m.Input)
+		},
 	})
 }
@@ -50,6 +50,7 @@ type typeScriptMethod struct {
 	Route         string
 	HasRequest    bool
 	HasBody       bool
+	FieldMask     bool
 	PathFields    []typeScriptRouteField
 	QueryFields   []typeScriptRouteField
 	ExampleFields []typeScriptExampleField
@@ -157,6 +158,7 @@ func buildTypeScriptMethod(method *ir.Method, messages map[string]*ir.Message, m
 	if input == nil {
 		input = method.Input
 	}
+	view.FieldMask = fieldMaskSupported(httpMethod, input)
 	if input == nil {
 		return view
 	}
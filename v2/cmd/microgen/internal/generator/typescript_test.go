@@ -63,8 +63,11 @@ func TestBuildTypeScriptSDKData(t *testing.T) {
 	if len(method.QueryFields) != 2 || !method.QueryFields[1].Duration {
 		t.Fatalf("query fields = %#v", method.QueryFields)
 	}
+	if !method.FieldMask {
+		t.Fatal("GET method should accept a field mask")
+	}
 	update := data.Services[0].Methods[1]
-	if !update.HasBody || len(update.PathFields) != 1 || len(update.QueryFields) != 0 {
+	if !update.HasBody || len(update.PathFields) != 1 || len(update.QueryFields) != 0 || update.FieldMask {
 		t.Fatalf("update method = %#v", update)
 	}
 
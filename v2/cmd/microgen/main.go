@@ -37,6 +37,7 @@ type config struct {
 	withConfig      bool
 	configMode      string
 	remoteProvider  string
+	errorFormat     string
 	withDocs        bool
 	withTests       bool
 	withModel       bool
@@ -87,6 +88,7 @@ func parseConfig(fs *flag.FlagSet, args []string) config {
 	withConfig := fs.Bool("config", false, "Generate config")
 	configMode := fs.String("config-mode", "", "Generated config mode: file, hybrid, remote")
 	remoteProvider := fs.String("remote-provider", "", "Generated remote config provider: consul")
+	errorFormat := fs.String("error-format", "json", "Generated HTTP error format: json, problem (RFC 9457)")
 	withDocs := fs.Bool("docs", true, "Generate docs")
 	withTests := fs.Bool("tests", false, "Generate tests")
 	withModel := fs.Bool("model", false, "Generate model")
@@ -123,6 +125,7 @@ func parseConfig(fs *flag.FlagSet, args []string) config {
 		withConfig:      *withConfig,
 		configMode:      strings.TrimSpace(*configMode),
 		remoteProvider:  strings.TrimSpace(*remoteProvider),
+		errorFormat:     strings.TrimSpace(*errorFormat),
 		withDocs:        *withDocs,
 		withTests:       *withTests,
 		withModel:       *withModel || *fromDB,
@@ -214,6 +217,7 @@ func (c config) generatorOptions(idlPath, serviceName string) generator.Options
 		WithConfig:      c.withConfig,
 		ConfigMode:      c.configMode,
 		RemoteProvider:  c.remoteProvider,
+		ErrorFormat:     c.errorFormat,
 		WithDocs:        c.withDocs,
 		WithTests:       c.withTests,
 		WithModel:       c.withModel,
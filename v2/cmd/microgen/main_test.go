@@ -62,6 +62,8 @@ func TestConfigValidate(t *testing.T) {
 		{config{fromDB: false, idlPath: "test.go", withConfig: true, configMode: "file", remoteProvider: "consul"}, false},
 		{config{fromDB: false, idlPath: "test.go", withConfig: false, configMode: "hybrid"}, false},
 		{config{fromDB: false, idlPath: "test.go", dbDriver: "oracle"}, false},
+		{config{fromDB: false, idlPath: "test.go", errorFormat: "problem"}, true},
+		{config{fromDB: false, idlPath: "test.go", errorFormat: "xml"}, false},
 	}
 	for i, c := range cases {
 		err := c.cfg.validate()
@@ -86,6 +88,7 @@ func TestParseConfig_ConfigModeAndRemoteProvider(t *testing.T) {
 		"-config-mode", "hybrid",
 		"-remote-provider", "consul",
 		"-openapi",
+		"-error-format", "problem",
 	})
 	if cfg.configMode != "hybrid" {
 		t.Fatalf("configMode = %q, want hybrid", cfg.configMode)
@@ -96,6 +99,9 @@ func TestParseConfig_ConfigModeAndRemoteProvider(t *testing.T) {
 	if !cfg.withOpenAPI {
 		t.Fatal("withOpenAPI = false, want true")
 	}
+	if cfg.errorFormat != "problem" {
+		t.Fatalf("errorFormat = %q, want problem", cfg.errorFormat)
+	}
 }
 
 func TestConfigValidateExtend(t *testing.T) {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	kitconsul "github.com/dreamsxin/go-kit/v2/integrations/consul"
+	"github.com/dreamsxin/go-kit/v2/kit"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// Example_consulRegistrar mirrors the TTL-check snippet in the Consul
+// integration README. It needs a Consul agent, so it is compiled but not run.
+func Example_consulRegistrar() {
+	raw, err := consulapi.NewClient(consulapi.DefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	client := kitconsul.NewClient(raw)
+	logger := slog.Default()
+
+	var svc *kit.Service
+	registrar := kitconsul.NewRegistrar(client, logger, "users", "10.0.0.1", 8080,
+		kitconsul.TTLCheckRegistrarOptions(kitconsul.TTLCheck{
+			TTL: 15 * time.Second,
+			// svc is assigned below; the check first runs when svc starts
+			// the registrar.
+			Critical: func(ctx context.Context) error {
+				return svc.ReadinessCheck()(ctx)
+			},
+			DeregisterCriticalServiceAfter: time.Minute,
+		}),
+		kitconsul.DrainRegistrarOptions(5*time.Second),
+	)
+	svc, err = kit.New(":8080",
+		kit.WithLifecycle(registrar),
+		kit.WithShutdownTimeout(10*time.Second),
+	)
+	if err != nil {
+		panic(err)
+	}
+	_ = svc.Run(context.Background())
+}
@@ -16,6 +16,49 @@ type Client interface {
 	Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error)
 }
 
+// TTLClient is a Client that can report TTL check results. The Client returned
+// by NewClient implements it; TTL-check registrars require it.
+type TTLClient interface {
+	Client
+
+	// UpdateTTL reports status ("pass", "warn", or "fail") for a TTL check.
+	UpdateTTL(checkID, output, status string) error
+}
+
+// KVClient is a Client that can read the Consul KV store. The Client returned
+// by NewClient implements it; Watcher requires it.
+type KVClient interface {
+	Client
+
+	// KVGet reads one key. A missing key returns a nil pair and no error.
+	KVGet(key string, queryOpts *consul.QueryOptions) (*consul.KVPair, *consul.QueryMeta, error)
+
+	// KVList reads every key under prefix.
+	KVList(prefix string, queryOpts *consul.QueryOptions) (consul.KVPairs, *consul.QueryMeta, error)
+}
+
+// LockClient is a KVClient that can manage sessions and KV locks. The Client
+// returned by NewClient implements it; Election requires it.
+type LockClient interface {
+	KVClient
+
+	// SessionCreate creates a session and returns its ID.
+	SessionCreate(entry *consul.SessionEntry, writeOpts *consul.WriteOptions) (string, error)
+
+	// SessionRenew renews a TTL session. A nil entry means the session no
+	// longer exists.
+	SessionRenew(id string, writeOpts *consul.WriteOptions) (*consul.SessionEntry, error)
+
+	// SessionDestroy destroys a session, releasing the locks it holds.
+	SessionDestroy(id string, writeOpts *consul.WriteOptions) error
+
+	// KVAcquire takes the lock on pair.Key for pair.Session.
+	KVAcquire(pair *consul.KVPair, writeOpts *consul.WriteOptions) (bool, error)
+
+	// KVRelease releases the lock on pair.Key held by pair.Session.
+	KVRelease(pair *consul.KVPair, writeOpts *consul.WriteOptions) (bool, error)
+}
+
 type client struct {
 	consul *consul.Client
 }
@@ -34,6 +77,43 @@ func (c *client) Deregister(r *consul.AgentServiceRegistration) error {
 	return c.consul.Agent().ServiceDeregister(r.ID)
 }
 
+func (c *client) UpdateTTL(checkID, output, status string) error {
+	return c.consul.Agent().UpdateTTL(checkID, output, status)
+}
+
+func (c *client) KVGet(key string, queryOpts *consul.QueryOptions) (*consul.KVPair, *consul.QueryMeta, error) {
+	return c.consul.KV().Get(key, queryOpts)
+}
+
+func (c *client) KVList(prefix string, queryOpts *consul.QueryOptions) (consul.KVPairs, *consul.QueryMeta, error) {
+	return c.consul.KV().List(prefix, queryOpts)
+}
+
+func (c *client) SessionCreate(entry *consul.SessionEntry, writeOpts *consul.WriteOptions) (string, error) {
+	id, _, err := c.consul.Session().Create(entry, writeOpts)
+	return id, err
+}
+
+func (c *client) SessionRenew(id string, writeOpts *consul.WriteOptions) (*consul.SessionEntry, error) {
+	entry, _, err := c.consul.Session().Renew(id, writeOpts)
+	return entry, err
+}
+
+func (c *client) SessionDestroy(id string, writeOpts *consul.WriteOptions) error {
+	_, err := c.consul.Session().Destroy(id, writeOpts)
+	return err
+}
+
+func (c *client) KVAcquire(pair *consul.KVPair, writeOpts *consul.WriteOptions) (bool, error) {
+	acquired, _, err := c.consul.KV().Acquire(pair, writeOpts)
+	return acquired, err
+}
+
+func (c *client) KVRelease(pair *consul.KVPair, writeOpts *consul.WriteOptions) (bool, error) {
+	released, _, err := c.consul.KV().Release(pair, writeOpts)
+	return released, err
+}
+
 func (c *client) Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
 	return c.consul.Health().Service(service, tag, passingOnly, queryOpts)
 }
@@ -1,9 +1,18 @@
 package consul
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -116,3 +125,614 @@ func TestRegistrarReturnsClientErrors(t *testing.T) {
 		t.Fatalf("Deregister error = %v, want %v", err, wantDeregister)
 	}
 }
+
+// fakeAgent is a minimal Consul agent HTTP API for registrar tests.
+type fakeAgent struct {
+	mu            sync.Mutex
+	registrations map[string]stdconsul.AgentServiceRegistration
+	registers     int
+	updates       []string
+	deregistered  []string
+}
+
+func newFakeAgent(t *testing.T) (*fakeAgent, Client) {
+	t.Helper()
+	agent := &fakeAgent{registrations: map[string]stdconsul.AgentServiceRegistration{}}
+	server := httptest.NewServer(agent)
+	t.Cleanup(server.Close)
+	raw, err := stdconsul.NewClient(&stdconsul.Config{Address: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatalf("consul client: %v", err)
+	}
+	return agent, NewClient(raw)
+}
+
+func (a *fakeAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case r.URL.Path == "/v1/agent/service/register":
+		var registration stdconsul.AgentServiceRegistration
+		if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a.registers++
+		a.registrations[registration.ID] = registration
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/check/update/"):
+		checkID := strings.TrimPrefix(r.URL.Path, "/v1/agent/check/update/")
+		serviceID := strings.TrimPrefix(checkID, "service:")
+		if _, ok := a.registrations[serviceID]; !ok {
+			http.Error(w, "Unknown check ID "+checkID, http.StatusNotFound)
+			return
+		}
+		var update struct{ Status, Output string }
+		_ = json.NewDecoder(r.Body).Decode(&update)
+		a.updates = append(a.updates, update.Status)
+	case strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/")
+		delete(a.registrations, id)
+		a.deregistered = append(a.deregistered, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *fakeAgent) restart() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registrations = map[string]stdconsul.AgentServiceRegistration{}
+}
+
+func (a *fakeAgent) snapshot() (registers int, updates []string, deregistered []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.registers, append([]string(nil), a.updates...), append([]string(nil), a.deregistered...)
+}
+
+func TestRegistrarTTLCheckReportsStatusAndReRegisters(t *testing.T) {
+	agent, client := newFakeAgent(t)
+	var warn atomic.Bool
+	registrar := NewRegistrar(client, nil, "users", "10.0.0.1", 8080,
+		IDRegistrarOptions("users-1"),
+		TTLCheckRegistrarOptions(TTLCheck{
+			TTL:      300 * time.Millisecond,
+			Interval: 10 * time.Millisecond,
+			Warning: func(context.Context) error {
+				if warn.Load() {
+					return errors.New("degraded")
+				}
+				return nil
+			},
+		}),
+	)
+	if err := registrar.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	agent.mu.Lock()
+	check := agent.registrations["users-1"].Check
+	agent.mu.Unlock()
+	if check == nil || check.TTL != "300ms" || check.CheckID != "service:users-1" {
+		t.Fatalf("registered check = %+v, want TTL check", check)
+	}
+
+	warn.Store(true)
+	waitUntil(t, func() bool {
+		_, updates, _ := agent.snapshot()
+		return len(updates) > 0 && updates[len(updates)-1] == stdconsul.HealthWarning
+	})
+
+	agent.restart()
+	waitUntil(t, func() bool {
+		registers, _, _ := agent.snapshot()
+		return registers >= 2
+	})
+
+	if err := registrar.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if _, _, deregistered := agent.snapshot(); !reflect.DeepEqual(deregistered, []string{"users-1"}) {
+		t.Fatalf("deregistered = %v", deregistered)
+	}
+}
+
+func TestRegistrarDrainMarksCriticalBeforeDeregistering(t *testing.T) {
+	agent, client := newFakeAgent(t)
+	registrar := NewRegistrar(client, nil, "users", "10.0.0.1", 8080,
+		TTLCheckRegistrarOptions(TTLCheck{
+			TTL:      time.Minute,
+			Critical: func(context.Context) error { return nil },
+		}),
+		DrainRegistrarOptions(30*time.Millisecond),
+	)
+	if err := registrar.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	start := time.Now()
+	if err := registrar.Deregister(); err != nil {
+		t.Fatalf("Deregister: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("Deregister returned after %v, want drain wait", elapsed)
+	}
+	_, updates, deregistered := agent.snapshot()
+	if !reflect.DeepEqual(updates, []string{stdconsul.HealthPassing, stdconsul.HealthCritical}) {
+		t.Fatalf("updates = %v, want passing then critical", updates)
+	}
+	if len(deregistered) != 1 {
+		t.Fatalf("deregistered = %v", deregistered)
+	}
+}
+
+func TestRegistrarDrainKeepsRegistrationUntilShutdown(t *testing.T) {
+	agent, client := newFakeAgent(t)
+	registrar := NewRegistrar(client, nil, "users", "10.0.0.1", 8080,
+		TTLCheckRegistrarOptions(TTLCheck{
+			TTL:      time.Minute,
+			Critical: func(context.Context) error { return nil },
+		}),
+		DrainRegistrarOptions(30*time.Millisecond),
+	)
+	if err := registrar.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	start := time.Now()
+	if err := registrar.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("Drain returned after %v, want drain wait", elapsed)
+	}
+	if _, updates, deregistered := agent.snapshot(); len(deregistered) != 0 ||
+		!reflect.DeepEqual(updates, []string{stdconsul.HealthPassing, stdconsul.HealthCritical}) {
+		t.Fatalf("after Drain: updates = %v, deregistered = %v", updates, deregistered)
+	}
+
+	start = time.Now()
+	if err := registrar.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 30*time.Millisecond {
+		t.Fatalf("Shutdown drained again for %v", elapsed)
+	}
+	if _, updates, deregistered := agent.snapshot(); len(deregistered) != 1 || len(updates) != 2 {
+		t.Fatalf("after Shutdown: updates = %v, deregistered = %v", updates, deregistered)
+	}
+}
+
+func TestRegistrarTTLCheckRequiresTTLClient(t *testing.T) {
+	registrar := NewRegistrar(&fakeClient{}, nil, "users", "10.0.0.1", 8080,
+		TTLCheckRegistrarOptions(TTLCheck{TTL: time.Second}))
+	if err := registrar.Register(); err == nil {
+		t.Fatal("Register accepted a client without UpdateTTL")
+	}
+	registrar = NewRegistrar(&fakeClient{}, nil, "users", "10.0.0.1", 8080,
+		TTLCheckRegistrarOptions(TTLCheck{}))
+	if err := registrar.Register(); err == nil {
+		t.Fatal("Register accepted a zero TTL")
+	}
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type fakeKV struct {
+	mu       sync.Mutex
+	values   map[string][]byte
+	locks    map[string]string
+	sessions map[string]bool
+	deny     bool
+	index    uint64
+	changed  chan struct{}
+}
+
+func newFakeKV(t *testing.T) (*fakeKV, Client) {
+	t.Helper()
+	kv := &fakeKV{
+		values:   map[string][]byte{},
+		locks:    map[string]string{},
+		sessions: map[string]bool{},
+		index:    1,
+		changed:  make(chan struct{}),
+	}
+	server := httptest.NewServer(kv)
+	t.Cleanup(server.Close)
+	raw, err := stdconsul.NewClient(&stdconsul.Config{Address: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatalf("consul client: %v", err)
+	}
+	return kv, NewClient(raw)
+}
+
+func (kv *fakeKV) put(key, value string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.values[key] = []byte(value)
+	kv.bump()
+}
+
+// bump advances the index and wakes blocking queries; kv.mu must be held.
+func (kv *fakeKV) bump() {
+	kv.index++
+	close(kv.changed)
+	kv.changed = make(chan struct{})
+}
+
+// destroySession invalidates a session and releases its locks, as Consul does
+// when a session expires.
+func (kv *fakeKV) destroySession(id string) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.sessions, id)
+	for key, holder := range kv.locks {
+		if holder == id {
+			delete(kv.locks, key)
+		}
+	}
+	kv.bump()
+}
+
+func (kv *fakeKV) holder(key string) string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.locks[key]
+}
+
+func (kv *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		kv.serveWrite(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	var waitIndex uint64
+	_, _ = fmt.Sscan(r.URL.Query().Get("index"), &waitIndex)
+	kv.mu.Lock()
+	if waitIndex > 0 && waitIndex >= kv.index {
+		changed := kv.changed
+		kv.mu.Unlock()
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		kv.mu.Lock()
+	}
+	defer kv.mu.Unlock()
+
+	var pairs []stdconsul.KVPair
+	for k, v := range kv.values {
+		if k == key || (r.URL.Query().Has("recurse") && strings.HasPrefix(k, key)) {
+			pairs = append(pairs, stdconsul.KVPair{Key: k, Value: v, Session: kv.locks[k], ModifyIndex: kv.index})
+		}
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(kv.index, 10))
+	if len(pairs) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func (kv *fakeKV) serveWrite(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/session/create":
+		kv.mu.Lock()
+		if kv.deny {
+			kv.mu.Unlock()
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		id := fmt.Sprintf("session-%d", len(kv.sessions)+int(kv.index))
+		kv.sessions[id] = true
+		kv.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")
+		kv.mu.Lock()
+		ok := kv.sessions[id]
+		kv.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]stdconsul.SessionEntry{{ID: id}})
+	case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+		kv.destroySession(strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+		_, _ = w.Write([]byte("true"))
+	case strings.HasPrefix(r.URL.Path, "/v1/kv/"):
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		body, _ := io.ReadAll(r.Body)
+		kv.mu.Lock()
+		defer kv.mu.Unlock()
+		result := false
+		if id := r.URL.Query().Get("acquire"); id != "" {
+			if !kv.sessions[id] {
+				http.Error(w, "invalid session "+id, http.StatusInternalServerError)
+				return
+			}
+			if holder := kv.locks[key]; holder == "" || holder == id {
+				kv.locks[key] = id
+				kv.values[key] = body
+				kv.bump()
+				result = true
+			}
+		} else if id := r.URL.Query().Get("release"); id != "" && kv.locks[key] == id {
+			delete(kv.locks, key)
+			kv.bump()
+			result = true
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type limits struct {
+	RPS  int  `json:"rps" yaml:"rps"`
+	Beta bool `json:"beta" yaml:"beta"`
+}
+
+func TestWatcherKeepsLastGoodValueAndNotifies(t *testing.T) {
+	kv, client := newFakeKV(t)
+	kv.put("app/limits", `{"rps":100}`)
+
+	watcher, err := NewWatcher[limits](client, nil, "app/limits")
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Stop()
+	changes := make(chan Change[limits], 1)
+	if current := watcher.Register(changes); current.RPS != 100 {
+		t.Fatalf("initial value = %+v", current)
+	}
+	var callbacks atomic.Int32
+	remove := watcher.OnChange(func(Change[limits]) { callbacks.Add(1) })
+	defer remove()
+
+	kv.put("app/limits", `{"rps":250,"beta":true}`)
+	select {
+	case change := <-changes:
+		if change.Old.RPS != 100 || change.New != (limits{RPS: 250, Beta: true}) {
+			t.Fatalf("change = %+v", change)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change delivered")
+	}
+	waitUntil(t, func() bool { return callbacks.Load() == 1 })
+
+	kv.put("app/limits", `{"rps":`)
+	waitUntil(t, func() bool { return watcher.Err() != nil })
+	if got := watcher.Value(); got.RPS != 250 {
+		t.Fatalf("value after bad update = %+v, want last good", got)
+	}
+
+	kv.put("app/limits", `{"rps":300}`)
+	waitUntil(t, func() bool { return watcher.Err() == nil && watcher.Value().RPS == 300 })
+}
+
+func TestWatcherDecodesYAMLPrefixTree(t *testing.T) {
+	kv, client := newFakeKV(t)
+	kv.put("app/config/rps", "100")
+	kv.put("app/config/beta", "true")
+
+	watcher, err := NewWatcher[limits](client, nil, "app/config/",
+		PrefixWatcherOptions(), DecoderWatcherOptions(DecodeYAML))
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Stop()
+	if got := watcher.Value(); got != (limits{RPS: 100, Beta: true}) {
+		t.Fatalf("value = %+v", got)
+	}
+	kv.put("app/config/rps", "5")
+	waitUntil(t, func() bool { return watcher.Value().RPS == 5 })
+}
+
+func TestWatcherDecodesPrefixLeavesByTheirOwnKey(t *testing.T) {
+	type settings struct {
+		Level  string `json:"level" yaml:"level"`
+		RPS    int    `json:"rps" yaml:"rps"`
+		Limits struct {
+			Burst int `json:"burst" yaml:"burst"`
+		} `json:"limits.yaml" yaml:"limits.yaml"`
+	}
+	kv, client := newFakeKV(t)
+	kv.put("app/level", "debug")
+	kv.put("app/rps", "100")
+	kv.put("app/limits.yaml", "burst: 20")
+
+	watcher, err := NewWatcher[settings](client, nil, "app/", PrefixWatcherOptions())
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Stop()
+	if got := watcher.Value(); got.Level != "debug" || got.RPS != 100 || got.Limits.Burst != 20 {
+		t.Fatalf("value = %+v", got)
+	}
+	kv.put("app/level", "info")
+	waitUntil(t, func() bool { return watcher.Value().Level == "info" })
+}
+
+func TestWatcherRejectsInvalidConfiguration(t *testing.T) {
+	if _, err := NewWatcher[limits](&fakeClient{}, nil, "app"); err == nil {
+		t.Fatal("NewWatcher accepted a client without KV access")
+	}
+	kv, client := newFakeKV(t)
+	if _, err := NewWatcher[limits](client, nil, " "); err == nil {
+		t.Fatal("NewWatcher accepted an empty key")
+	}
+	kv.put("app.yaml", "rps: [")
+	if _, err := NewWatcher[limits](client, nil, "app.yaml"); err == nil {
+		t.Fatal("NewWatcher accepted an undecodable initial value")
+	}
+}
+
+func TestElectionRunsOneLeaderAndFailsOver(t *testing.T) {
+	kv, client := newFakeKV(t)
+	var active, maxActive atomic.Int32
+	started := make(chan string, 2)
+	newElection := func(name string) *Election {
+		t.Helper()
+		election, err := NewElection(client, nil, "service/cron/leader", func(ctx context.Context) error {
+			if n := active.Add(1); n > maxActive.Load() {
+				maxActive.Store(n)
+			}
+			started <- name
+			<-ctx.Done()
+			active.Add(-1)
+			return ctx.Err()
+		}, RetryElectionOptions(10*time.Millisecond), ValueElectionOptions([]byte(name)))
+		if err != nil {
+			t.Fatalf("NewElection: %v", err)
+		}
+		if err := election.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		return election
+	}
+	elections := map[string]*Election{"a": newElection("a"), "b": newElection("b")}
+	defer func() {
+		for _, election := range elections {
+			_ = election.Shutdown(context.Background())
+		}
+	}()
+
+	var leader string
+	select {
+	case leader = <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no leader elected")
+	}
+	if !elections[leader].IsLeader() {
+		t.Fatalf("%s runs the callback but IsLeader is false", leader)
+	}
+	if err := elections[leader].Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case next := <-started:
+		if next == leader {
+			t.Fatalf("%s led again after Shutdown", next)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no failover after leader shutdown")
+	}
+	if maxActive.Load() != 1 {
+		t.Fatalf("max concurrent leaders = %d, want 1", maxActive.Load())
+	}
+	if err := elections[leader].Start(); err == nil {
+		t.Fatal("Start accepted a restart after Shutdown")
+	}
+	if kv.holder("service/cron/leader") == "" {
+		t.Fatal("lock not held by the new leader")
+	}
+}
+
+func TestElectionCancelsCallbackWhenSessionIsLost(t *testing.T) {
+	kv, client := newFakeKV(t)
+	runs := make(chan context.Context, 2)
+	election, err := NewElection(client, nil, "service/cron/leader", func(ctx context.Context) error {
+		runs <- ctx
+		<-ctx.Done()
+		return nil
+	}, RetryElectionOptions(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewElection: %v", err)
+	}
+	if err := election.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer election.Shutdown(context.Background()) //nolint:errcheck
+
+	first := <-runs
+	kv.destroySession(kv.holder("service/cron/leader"))
+	select {
+	case <-first.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback context not cancelled after losing the lock")
+	}
+	select {
+	case <-runs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("leadership not re-acquired with a new session")
+	}
+}
+
+func TestElectionHandlesCallbackErrorsWithoutStopping(t *testing.T) {
+	_, client := newFakeKV(t)
+	handled := make(chan error, 4)
+	var runs atomic.Int32
+	election, err := NewElection(client, nil, "job", func(context.Context) error {
+		runs.Add(1)
+		return errors.New("job failed")
+	}, RetryElectionOptions(10*time.Millisecond), ErrorHandlerElectionOptions(func(err error) {
+		select {
+		case handled <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatalf("NewElection: %v", err)
+	}
+	if err := election.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer election.Shutdown(context.Background()) //nolint:errcheck
+	select {
+	case err := <-handled:
+		if err == nil || err.Error() != "job failed" {
+			t.Fatalf("handled error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback error not handled")
+	}
+	// The callback failing is not fatal: the replica keeps campaigning.
+	waitUntil(t, func() bool { return runs.Load() >= 2 })
+	select {
+	case err := <-election.Errors():
+		t.Fatalf("Errors() reported callback error %v", err)
+	default:
+	}
+}
+
+func TestElectionReportsDeniedSessionAsFatal(t *testing.T) {
+	kv, client := newFakeKV(t)
+	kv.deny = true
+	election, err := NewElection(client, nil, "job", func(context.Context) error { return nil },
+		RetryElectionOptions(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewElection: %v", err)
+	}
+	if err := election.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer election.Shutdown(context.Background()) //nolint:errcheck
+	select {
+	case err := <-election.Errors():
+		var status stdconsul.StatusError
+		if !errors.As(err, &status) || status.Code != http.StatusForbidden {
+			t.Fatalf("Errors() = %v, want 403 status error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("denied session not reported")
+	}
+}
+
+func TestElectionRejectsInvalidConfiguration(t *testing.T) {
+	_, client := newFakeKV(t)
+	if _, err := NewElection(&fakeClient{}, nil, "job", func(context.Context) error { return nil }); err == nil {
+		t.Fatal("NewElection accepted a client without lock access")
+	}
+	if _, err := NewElection(client, nil, "job", nil); err == nil {
+		t.Fatal("NewElection accepted a nil callback")
+	}
+}
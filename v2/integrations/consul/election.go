@@ -0,0 +1,453 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+)
+
+const (
+	defaultSessionTTL    = 15 * time.Second
+	defaultElectionRetry = 5 * time.Second
+)
+
+// Election runs a callback on at most one replica at a time. Replicas
+// campaign for a KV lock held by a TTL session; the holder runs the callback
+// with a context that is cancelled as soon as the lock or session is lost.
+// Election implements kit.Lifecycle, so it can be attached with
+// kit.WithLifecycle.
+type Election struct {
+	client    LockClient
+	logger    *slog.Logger
+	key       string
+	run       func(context.Context) error
+	ttl       time.Duration
+	lockDelay time.Duration
+	retry     time.Duration
+	value     []byte
+	onError   func(error)
+
+	leader atomic.Bool
+	errc   chan error
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	session string
+}
+
+type ElectionOption func(*Election)
+
+// SessionTTLElectionOptions sets the session TTL. A replica that stops
+// renewing loses leadership once Consul expires the session. The default is
+// 15 seconds; Consul accepts 10 seconds to 24 hours.
+func SessionTTLElectionOptions(ttl time.Duration) ElectionOption {
+	return func(e *Election) {
+		e.ttl = ttl
+	}
+}
+
+// LockDelayElectionOptions sets how long Consul blocks re-acquiring the lock
+// after the session holding it is invalidated. Zero keeps Consul's default
+// of 15 seconds.
+func LockDelayElectionOptions(delay time.Duration) ElectionOption {
+	return func(e *Election) {
+		e.lockDelay = delay
+	}
+}
+
+// RetryElectionOptions sets the wait between campaign attempts after errors,
+// a lock-delay rejection, or the callback returning. The default is 5
+// seconds.
+func RetryElectionOptions(retry time.Duration) ElectionOption {
+	return func(e *Election) {
+		e.retry = retry
+	}
+}
+
+// ValueElectionOptions stores value in the lock key while leading, for
+// example the replica's address, so others can see who leads.
+func ValueElectionOptions(value []byte) ElectionOption {
+	return func(e *Election) {
+		e.value = value
+	}
+}
+
+// ErrorHandlerElectionOptions sets a function that receives errors returned
+// by the callback while leading, for example to count failed runs. They are
+// logged either way and never stop the Election.
+func ErrorHandlerElectionOptions(handler func(error)) ElectionOption {
+	return func(e *Election) {
+		e.onError = handler
+	}
+}
+
+// NewElection returns an Election for key that calls run while leading. run
+// must return promptly once its context is cancelled. When run returns,
+// leadership is released and the replica campaigns again after the retry
+// interval; an error returned while still leading is logged and passed to
+// the ErrorHandlerElectionOptions handler. The client must implement
+// LockClient.
+func NewElection(client Client, logger *slog.Logger, key string, run func(context.Context) error, options ...ElectionOption) (*Election, error) {
+	lockClient, ok := client.(LockClient)
+	if !ok {
+		return nil, fmt.Errorf("consul: election requires a client with session and lock access")
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil, fmt.Errorf("consul: election key cannot be empty")
+	}
+	if run == nil {
+		return nil, fmt.Errorf("consul: election callback is nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	e := &Election{
+		client: lockClient,
+		logger: logger,
+		key:    key,
+		run:    run,
+		ttl:    defaultSessionTTL,
+		retry:  defaultElectionRetry,
+		errc:   make(chan error, 1),
+	}
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("consul: election option %d is nil", i)
+		}
+		option(e)
+	}
+	switch {
+	case e.ttl <= 0:
+		return nil, fmt.Errorf("consul: session TTL must be greater than zero")
+	case e.retry <= 0:
+		return nil, fmt.Errorf("consul: election retry interval must be greater than zero")
+	case e.lockDelay < 0:
+		return nil, fmt.Errorf("consul: lock delay cannot be negative")
+	}
+	return e, nil
+}
+
+// IsLeader reports whether this replica currently holds leadership.
+func (e *Election) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start begins campaigning in the background. An Election cannot be
+// restarted after Shutdown.
+func (e *Election) Start() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started || e.stopped {
+		return fmt.Errorf("consul: election already started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.started = true
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	go e.campaign(ctx, e.done)
+	return nil
+}
+
+// Errors reports failures the Election cannot recover from, which stop it
+// campaigning: Consul refusing the session or the lock with 401 or 403, for
+// example because of a missing ACL. Transient Consul errors are retried and
+// logged, and callback errors go to the error handler, so neither stops a kit
+// service.
+func (e *Election) Errors() <-chan error {
+	return e.errc
+}
+
+// Shutdown cancels the callback, waits for it to return, and destroys the
+// session so another replica can take over without waiting for the TTL. When
+// ctx ends first the session is left to expire.
+func (e *Election) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("consul: nil shutdown context")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.started || e.stopped {
+		e.stopped = true
+		return nil
+	}
+	e.stopped = true
+	e.cancel()
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if e.session == "" {
+		return nil
+	}
+	if err := e.client.SessionDestroy(e.session, (&consul.WriteOptions{}).WithContext(ctx)); err != nil {
+		return fmt.Errorf("consul: destroy election session: %w", err)
+	}
+	e.logger.Debug("consul election session destroyed", "key", e.key)
+	return nil
+}
+
+func (e *Election) campaign(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	var session *electionSession
+	defer func() {
+		if session != nil {
+			session.stop()
+			e.session = session.id
+		}
+	}()
+	for ctx.Err() == nil {
+		if session == nil || session.isLost() {
+			if session != nil {
+				session.stop()
+				_ = e.client.SessionDestroy(session.id, (&consul.WriteOptions{}).WithContext(ctx))
+				session = nil
+			}
+			var err error
+			if session, err = e.newSession(ctx); err != nil {
+				if ctx.Err() == nil && e.fatal(fmt.Errorf("consul: create election session: %w", err)) {
+					return
+				}
+				if ctx.Err() == nil {
+					e.logger.Warn("consul election session create failed", "key", e.key, "err", err)
+				}
+				if !waitForRetry(e.retry, ctx.Done()) {
+					return
+				}
+				continue
+			}
+		}
+
+		pair := &consul.KVPair{Key: e.key, Value: e.value, Session: session.id}
+		acquired, err := e.client.KVAcquire(pair, (&consul.WriteOptions{}).WithContext(ctx))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			if e.fatal(fmt.Errorf("consul: acquire election lock: %w", err)) {
+				return
+			}
+			// Consul rejects acquires from invalidated sessions; check now
+			// rather than waiting for the next renewal.
+			e.logger.Warn("consul election lock acquire failed", "key", e.key, "err", err)
+			if entry, renewErr := e.client.SessionRenew(session.id, (&consul.WriteOptions{}).WithContext(ctx)); renewErr == nil && entry == nil {
+				session.markLost()
+			}
+			if !waitForRetry(e.retry, ctx.Done()) {
+				return
+			}
+		case acquired:
+			e.lead(ctx, session)
+			if !waitForRetry(e.retry, ctx.Done()) {
+				return
+			}
+		default:
+			if !e.waitForRelease(ctx, session) {
+				return
+			}
+		}
+	}
+}
+
+// lead runs the callback until it returns or leadership is lost.
+func (e *Election) lead(ctx context.Context, session *electionSession) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-session.lost:
+			cancel()
+		case <-leaderCtx.Done():
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer cancel()
+		e.watchLock(leaderCtx, session)
+	}()
+
+	e.leader.Store(true)
+	e.logger.Info("consul leadership acquired", "key", e.key)
+	err := e.run(leaderCtx)
+	lost := leaderCtx.Err() != nil
+	e.leader.Store(false)
+	cancel()
+	wg.Wait()
+
+	switch {
+	case err != nil && !lost:
+		e.logger.Error("consul election callback failed", "key", e.key, "err", err)
+		if e.onError != nil {
+			e.onError(err)
+		}
+	case lost && ctx.Err() == nil:
+		e.logger.Warn("consul leadership lost", "key", e.key)
+	}
+	if ctx.Err() != nil || session.isLost() {
+		return
+	}
+	pair := &consul.KVPair{Key: e.key, Session: session.id}
+	if _, err := e.client.KVRelease(pair, (&consul.WriteOptions{}).WithContext(ctx)); err != nil && ctx.Err() == nil {
+		e.logger.Warn("consul election lock release failed", "key", e.key, "err", err)
+	}
+	e.logger.Info("consul leadership released", "key", e.key)
+}
+
+// fatal reports err on Errors and returns true when Consul denied the
+// request, which retrying cannot fix.
+func (e *Election) fatal(err error) bool {
+	var status consul.StatusError
+	if !errors.As(err, &status) || (status.Code != http.StatusUnauthorized && status.Code != http.StatusForbidden) {
+		return false
+	}
+	e.logger.Error("consul election stopped", "key", e.key, "err", err)
+	select {
+	case e.errc <- err:
+	default:
+	}
+	return true
+}
+
+// watchLock returns when the lock key is no longer held by session.
+func (e *Election) watchLock(ctx context.Context, session *electionSession) {
+	var index uint64
+	for {
+		query := (&consul.QueryOptions{WaitIndex: index, WaitTime: e.ttl}).WithContext(ctx)
+		pair, meta, err := e.client.KVGet(e.key, query)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil || meta == nil:
+			e.logger.Debug("consul election lock watch failed", "key", e.key, "err", err)
+			if !waitForRetry(e.retry, ctx.Done()) {
+				return
+			}
+			continue
+		case pair == nil || pair.Session != session.id:
+			return
+		}
+		index = meta.LastIndex
+	}
+}
+
+// waitForRelease blocks until the lock looks free or the session is lost. It
+// reports false when ctx ends.
+func (e *Election) waitForRelease(ctx context.Context, session *electionSession) bool {
+	var index uint64
+	for {
+		query := (&consul.QueryOptions{WaitIndex: index, WaitTime: e.ttl}).WithContext(ctx)
+		pair, meta, err := e.client.KVGet(e.key, query)
+		switch {
+		case ctx.Err() != nil:
+			return false
+		case err != nil || meta == nil:
+			e.logger.Debug("consul election lock watch failed", "key", e.key, "err", err)
+			return waitForRetry(e.retry, ctx.Done())
+		case pair == nil || pair.Session == "" || pair.Session == session.id:
+			if index == defaultIndex {
+				// The lock was free when acquiring failed, so Consul's lock
+				// delay is still running.
+				return waitForRetry(e.retry, ctx.Done())
+			}
+			return true
+		case session.isLost():
+			return true
+		}
+		index = meta.LastIndex
+	}
+}
+
+type electionSession struct {
+	id       string
+	lost     chan struct{}
+	lostOnce sync.Once
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func (e *Election) newSession(ctx context.Context) (*electionSession, error) {
+	entry := &consul.SessionEntry{
+		Name:      "go-kit election " + e.key,
+		TTL:       e.ttl.String(),
+		LockDelay: e.lockDelay,
+		Behavior:  consul.SessionBehaviorRelease,
+	}
+	id, err := e.client.SessionCreate(entry, (&consul.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	sessionCtx, cancel := context.WithCancel(ctx)
+	session := &electionSession{
+		id:     id,
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go e.renew(sessionCtx, session)
+	e.logger.Debug("consul election session created", "key", e.key, "session", id)
+	return session, nil
+}
+
+// renew keeps the session alive every TTL/2 and marks it lost when Consul no
+// longer knows it or renewals have failed for a whole TTL.
+func (e *Election) renew(ctx context.Context, session *electionSession) {
+	defer close(session.done)
+	ticker := time.NewTicker(e.ttl / 2)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		entry, err := e.client.SessionRenew(session.id, (&consul.WriteOptions{}).WithContext(ctx))
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err == nil && entry != nil:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			e.logger.Warn("consul election session expired", "key", e.key, "session", session.id)
+		case time.Since(renewed) < e.ttl:
+			e.logger.Debug("consul election session renew failed", "key", e.key, "err", err)
+			continue
+		default:
+			e.logger.Warn("consul election session renew failed for a full TTL", "key", e.key, "err", err)
+		}
+		session.markLost()
+		return
+	}
+}
+
+func (s *electionSession) isLost() bool {
+	select {
+	case <-s.lost:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *electionSession) markLost() {
+	s.lostOnce.Do(func() { close(s.lost) })
+}
+
+func (s *electionSession) stop() {
+	s.cancel()
+	<-s.done
+}
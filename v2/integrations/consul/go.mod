@@ -2,7 +2,10 @@ module github.com/dreamsxin/go-kit/v2/integrations/consul
 
 go 1.25.8
 
-require github.com/hashicorp/consul/api v1.33.7
+require (
+	github.com/hashicorp/consul/api v1.33.7
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/armon/go-metrics v0.4.1 // indirect
@@ -1,17 +1,54 @@
 package consul
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"strconv"
+	"sync"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 )
 
+// TTL check statuses accepted by TTLClient.UpdateTTL.
+const (
+	TTLPass = "pass"
+	TTLWarn = "warn"
+	TTLFail = "fail"
+)
+
+// TTLCheck configures TTL-check mode. The registrar owns the service's health
+// check and reports it from the background instead of letting the agent poll.
+type TTLCheck struct {
+	// TTL is the check lease; the agent marks the check critical when no
+	// update arrives within it. Required.
+	TTL time.Duration
+	// Interval between updates. Zero uses TTL/3.
+	Interval time.Duration
+	// Critical reports "fail" when it returns an error. kit readiness checks
+	// (kit.HealthCheck values or Service.ReadinessCheck) fit directly.
+	Critical func(context.Context) error
+	// Warning reports "warn" when it returns an error and Critical passes.
+	Warning func(context.Context) error
+	// DeregisterCriticalServiceAfter lets the agent reap an instance whose
+	// process died without deregistering. Zero disables it.
+	DeregisterCriticalServiceAfter time.Duration
+}
+
 // 服务注册类
 type Registrar struct {
 	client       Client
 	registration *stdconsul.AgentServiceRegistration
 	logger       *slog.Logger
+	ttl          *TTLCheck
+	drain        time.Duration
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	started bool
 }
 
 type RegistrarOption func(*Registrar)
@@ -40,6 +77,26 @@ func CheckRegistrarOptions(check *stdconsul.AgentServiceCheck) RegistrarOption {
 	}
 }
 
+// TTLCheckRegistrarOptions switches the registrar to TTL-check mode. Register
+// then starts background updates that report pass, warn, or fail from the
+// configured checks and re-register after the agent loses the service, for
+// example after an agent restart. It replaces CheckRegistrarOptions and
+// requires a TTLClient.
+func TTLCheckRegistrarOptions(check TTLCheck) RegistrarOption {
+	return func(r *Registrar) {
+		r.ttl = &check
+	}
+}
+
+// DrainRegistrarOptions makes Drain, Deregister and Shutdown mark a TTL-check
+// instance critical and wait for drain before deregistering, so consumers stop
+// routing to it while in-flight requests finish.
+func DrainRegistrarOptions(drain time.Duration) RegistrarOption {
+	return func(r *Registrar) {
+		r.drain = drain
+	}
+}
+
 func NewRegistrar(client Client, logger *slog.Logger, name string, address string, port int, options ...RegistrarOption) *Registrar {
 	if logger == nil {
 		logger = slog.New(slog.DiscardHandler)
@@ -59,10 +116,56 @@ func NewRegistrar(client Client, logger *slog.Logger, name string, address strin
 	if r.registration.ID == "" {
 		r.registration.ID = r.registration.Name + "-" + r.registration.Address + "-" + strconv.Itoa(r.registration.Port)
 	}
+	if r.ttl != nil {
+		r.registration.Check = &stdconsul.AgentServiceCheck{
+			CheckID: r.checkID(),
+			Name:    "go-kit TTL check",
+			TTL:     r.ttl.TTL.String(),
+		}
+		if r.ttl.DeregisterCriticalServiceAfter > 0 {
+			r.registration.Check.DeregisterCriticalServiceAfter = r.ttl.DeregisterCriticalServiceAfter.String()
+		}
+	}
 	return r
 }
 
+func (p *Registrar) checkID() string {
+	return "service:" + p.registration.ID
+}
+
+// Register registers the service. In TTL-check mode it also reports the first
+// check result synchronously and starts background updates; calling Register
+// again while updates run is a no-op.
 func (p *Registrar) Register() error {
+	if p.ttl == nil {
+		return p.register()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return nil
+	}
+	ttlClient, interval, err := p.ttlSetup()
+	if err != nil {
+		return err
+	}
+	if err := p.register(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := p.report(ctx, ttlClient, interval); err != nil {
+		cancel()
+		return err
+	}
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	p.started = true
+	go p.heartbeat(ctx, ttlClient, interval, p.done)
+	return nil
+}
+
+func (p *Registrar) register() error {
 	if err := p.client.Register(p.registration); err != nil {
 		return err
 	}
@@ -70,10 +173,145 @@ func (p *Registrar) Register() error {
 	return nil
 }
 
+func (p *Registrar) ttlSetup() (TTLClient, time.Duration, error) {
+	ttlClient, ok := p.client.(TTLClient)
+	if !ok {
+		return nil, 0, fmt.Errorf("consul: TTL check mode requires a client with UpdateTTL")
+	}
+	if p.ttl.TTL <= 0 {
+		return nil, 0, fmt.Errorf("consul: TTL must be greater than zero")
+	}
+	interval := p.ttl.Interval
+	if interval <= 0 {
+		interval = p.ttl.TTL / 3
+	}
+	if interval <= 0 || interval >= p.ttl.TTL {
+		return nil, 0, fmt.Errorf("consul: TTL update interval must be shorter than the TTL")
+	}
+	return ttlClient, interval, nil
+}
+
+// Deregister removes the service. In TTL-check mode it first stops the
+// background updates and, when a drain period is configured, marks the
+// instance critical and waits for it.
 func (p *Registrar) Deregister() error {
+	return p.Shutdown(context.Background())
+}
+
+// Start registers the service so the Registrar can run as a kit.Lifecycle.
+func (p *Registrar) Start() error {
+	return p.Register()
+}
+
+// Errors implements kit.Lifecycle. Update failures are retried and logged
+// rather than reported, so it returns nil.
+func (p *Registrar) Errors() <-chan error {
+	return nil
+}
+
+// Drain stops TTL updates and, when a drain period is configured, marks the
+// instance critical and waits for it. It implements kit.Drainer, so a kit
+// service drains the registrar while it still serves requests and shuts the
+// HTTP server down afterwards. The wait ends early when ctx is done.
+func (p *Registrar) Drain(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("consul: nil drain context")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.drainLocked(ctx)
+}
+
+func (p *Registrar) drainLocked(ctx context.Context) error {
+	if !p.started {
+		return nil
+	}
+	p.cancel()
+	<-p.done
+	p.started = false
+	if p.drain > 0 {
+		return p.drainInstance(ctx)
+	}
+	return nil
+}
+
+// Shutdown drains the registrar unless Drain already ran, then deregisters
+// the service. The drain wait ends early when ctx is done; deregistration is
+// still attempted.
+func (p *Registrar) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("consul: nil shutdown context")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	drainErr := p.drainLocked(ctx)
 	if err := p.client.Deregister(p.registration); err != nil {
-		return err
+		return errors.Join(drainErr, err)
 	}
 	p.logger.Debug("consul service deregistered", "id", p.registration.ID)
-	return nil
+	return drainErr
+}
+
+func (p *Registrar) drainInstance(ctx context.Context) error {
+	ttlClient := p.client.(TTLClient)
+	if err := ttlClient.UpdateTTL(p.checkID(), "draining", TTLFail); err != nil {
+		p.logger.Warn("consul drain update failed", "id", p.registration.ID, "err", err)
+	}
+	p.logger.Debug("consul service draining", "id", p.registration.ID, "drain", p.drain)
+	timer := time.NewTimer(p.drain)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Registrar) heartbeat(ctx context.Context, ttlClient TTLClient, interval time.Duration, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.report(ctx, ttlClient, interval); err != nil && ctx.Err() == nil {
+				p.logger.Warn("consul TTL update failed", "id", p.registration.ID, "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// report runs the configured checks and sends the result. When the update
+// fails, for example because an agent restart dropped the registration, it
+// re-registers and sends the result again.
+func (p *Registrar) report(ctx context.Context, ttlClient TTLClient, timeout time.Duration) error {
+	status, output := p.evaluate(ctx, timeout)
+	err := ttlClient.UpdateTTL(p.checkID(), output, status)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	p.logger.Debug("consul TTL update failed; re-registering", "id", p.registration.ID, "err", err)
+	if regErr := p.register(); regErr != nil {
+		return errors.Join(err, regErr)
+	}
+	return ttlClient.UpdateTTL(p.checkID(), output, status)
+}
+
+func (p *Registrar) evaluate(ctx context.Context, timeout time.Duration) (string, string) {
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if p.ttl.Critical != nil {
+		if err := p.ttl.Critical(checkCtx); err != nil {
+			return TTLFail, err.Error()
+		}
+	}
+	if p.ttl.Warning != nil {
+		if err := p.ttl.Warning(checkCtx); err != nil {
+			return TTLWarn, err.Error()
+		}
+	}
+	return TTLPass, "ok"
 }
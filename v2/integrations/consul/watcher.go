@@ -0,0 +1,447 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	consul "github.com/hashicorp/consul/api"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder decodes a KV value into v.
+type Decoder func(data []byte, v any) error
+
+// DecodeJSON decodes JSON values with encoding/json.
+func DecodeJSON(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// DecodeYAML decodes YAML values with gopkg.in/yaml.v3, the decoder used by
+// generated config loaders, so the same yaml struct tags apply.
+func DecodeYAML(data []byte, v any) error {
+	return yaml.Unmarshal(data, v)
+}
+
+// Change is delivered to Watcher subscribers when the decoded value changes.
+type Change[T any] struct {
+	Old   T
+	New   T
+	Index uint64
+}
+
+type watcherOptions struct {
+	prefix  bool
+	decoder Decoder
+	// leafDecoder is the decoder set by DecoderWatcherOptions, if any; prefix
+	// leaves otherwise pick theirs from their own key.
+	leafDecoder Decoder
+}
+
+type WatcherOption func(*watcherOptions)
+
+// PrefixWatcherOptions watches every key under the prefix instead of a single
+// key. Keys are split on "/" into a tree whose leaves are decoded one by one,
+// and the tree is then decoded into the typed value, so "app/limits/rps" maps
+// to the field path limits.rps. Without DecoderWatcherOptions, leaves ending
+// in ".yaml" or ".yml" are YAML, other leaves are JSON, and a leaf that is not
+// valid JSON, such as a bare debug, is taken as a string.
+func PrefixWatcherOptions() WatcherOption {
+	return func(o *watcherOptions) {
+		o.prefix = true
+	}
+}
+
+// DecoderWatcherOptions sets the value decoder. By default keys ending in
+// ".yaml" or ".yml" use DecodeYAML and all others use DecodeJSON.
+func DecoderWatcherOptions(decoder Decoder) WatcherOption {
+	return func(o *watcherOptions) {
+		o.decoder = decoder
+	}
+}
+
+// Watcher keeps a typed value in sync with a Consul KV key or prefix through
+// blocking queries. When a new value fails to decode, or the key is deleted,
+// the last good value is kept and the failure is reported by Err. Values are
+// shared with every subscriber and must be treated as read-only.
+type Watcher[T any] struct {
+	client  KVClient
+	logger  *slog.Logger
+	key     string
+	options watcherOptions
+
+	mu          sync.RWMutex
+	value       T
+	err         error
+	fingerprint []byte
+	channels    map[chan Change[T]]struct{}
+	callbacks   map[uint64]func(Change[T])
+	nextID      uint64
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// NewWatcher reads key synchronously, decodes it into T, and keeps watching
+// it in the background. It fails when the first read or decode fails; a
+// missing key starts from the zero value. The client must implement KVClient.
+func NewWatcher[T any](client Client, logger *slog.Logger, key string, options ...WatcherOption) (*Watcher[T], error) {
+	kv, ok := client.(KVClient)
+	if !ok {
+		return nil, fmt.Errorf("consul: watcher requires a client with KV access")
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil, fmt.Errorf("consul: watch key cannot be empty")
+	}
+	var opts watcherOptions
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("consul: watcher option %d is nil", i)
+		}
+		option(&opts)
+	}
+	opts.leafDecoder = opts.decoder
+	if opts.decoder == nil {
+		opts.decoder = decoderFor(key)
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher[T]{
+		client:    kv,
+		logger:    logger,
+		key:       key,
+		options:   opts,
+		channels:  make(map[chan Change[T]]struct{}),
+		callbacks: make(map[uint64]func(Change[T])),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	data, found, index, err := w.read(ctx, defaultIndex)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("consul: read %q: %w", key, err)
+	}
+	if found {
+		value, err := w.decode(data)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("consul: decode %q: %w", key, err)
+		}
+		w.value = value
+		w.fingerprint = data
+	}
+	w.logger.Debug("consul config loaded", "key", key, "found", found, "index", index)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.loop(index)
+	}()
+	return w, nil
+}
+
+// Value returns the last successfully decoded value.
+func (w *Watcher[T]) Value() T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.value
+}
+
+// Err returns the error from the latest query or decode, or nil when Value
+// reflects the store.
+func (w *Watcher[T]) Err() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.err
+}
+
+// Register subscribes ch to changes and returns the current value. Delivery
+// never blocks the watcher: when ch is full the buffered change is replaced,
+// so a slow receiver only sees the latest change.
+func (w *Watcher[T]) Register(ch chan Change[T]) T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if ch != nil {
+		w.channels[ch] = struct{}{}
+	}
+	return w.value
+}
+
+// Deregister unsubscribes ch.
+func (w *Watcher[T]) Deregister(ch chan Change[T]) {
+	w.mu.Lock()
+	delete(w.channels, ch)
+	w.mu.Unlock()
+}
+
+// OnChange calls fn for every change, in order, from the watch goroutine. The
+// returned function removes the callback.
+func (w *Watcher[T]) OnChange(fn func(Change[T])) (remove func()) {
+	w.mu.Lock()
+	id := w.nextID
+	w.nextID++
+	w.callbacks[id] = fn
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.callbacks, id)
+		w.mu.Unlock()
+	}
+}
+
+// Stop terminates the watcher.
+func (w *Watcher[T]) Stop() {
+	w.stopOnce.Do(w.cancel)
+	w.wg.Wait()
+}
+
+func (w *Watcher[T]) loop(lastIndex uint64) {
+	d := 10 * time.Millisecond
+	for {
+		data, found, index, err := w.read(w.ctx, lastIndex)
+		switch {
+		case errors.Is(err, errStopped):
+			w.logger.Debug("consul config watch stopped", "key", w.key)
+			return
+		case err != nil:
+			w.logger.Debug("consul config watch failed", "key", w.key, "err", err, "retry_after", d)
+			w.setErr(err)
+			if !waitForRetry(d, w.ctx.Done()) {
+				return
+			}
+			d = nextDelay(d)
+		case index == defaultIndex:
+			w.logger.Debug("consul config watch returned zero index", "key", w.key, "retry_after", d)
+			if !waitForRetry(d, w.ctx.Done()) {
+				return
+			}
+			d = nextDelay(d)
+		case index < lastIndex:
+			w.logger.Debug("consul config watch index regressed", "key", w.key, "index", index, "previous", lastIndex)
+			lastIndex = defaultIndex
+		default:
+			lastIndex = index
+			d = 10 * time.Millisecond
+			w.apply(data, found, index)
+		}
+	}
+}
+
+// apply decodes changed data and notifies subscribers. A deleted key or a
+// value that fails to decode keeps the last good value.
+func (w *Watcher[T]) apply(data []byte, found bool, index uint64) {
+	if !found {
+		w.logger.Warn("consul config key deleted; keeping last value", "key", w.key)
+		w.setErr(fmt.Errorf("consul: key %q not found", w.key))
+		return
+	}
+	w.mu.RLock()
+	unchanged := w.fingerprint != nil && bytes.Equal(w.fingerprint, data)
+	w.mu.RUnlock()
+	if unchanged {
+		w.setErr(nil)
+		return
+	}
+	value, err := w.decode(data)
+	if err != nil {
+		w.logger.Warn("consul config decode failed; keeping last value", "key", w.key, "index", index, "err", err)
+		w.setErr(fmt.Errorf("consul: decode %q: %w", w.key, err))
+		return
+	}
+
+	w.mu.Lock()
+	change := Change[T]{Old: w.value, New: value, Index: index}
+	w.value = value
+	w.err = nil
+	w.fingerprint = data
+	channels := make([]chan Change[T], 0, len(w.channels))
+	for ch := range w.channels {
+		channels = append(channels, ch)
+	}
+	callbacks := make([]func(Change[T]), 0, len(w.callbacks))
+	for id := uint64(0); id < w.nextID; id++ {
+		if fn, ok := w.callbacks[id]; ok {
+			callbacks = append(callbacks, fn)
+		}
+	}
+	w.mu.Unlock()
+
+	w.logger.Debug("consul config updated", "key", w.key, "index", index)
+	for _, ch := range channels {
+		sendLatestChange(ch, change)
+	}
+	for _, fn := range callbacks {
+		fn(change)
+	}
+}
+
+func (w *Watcher[T]) setErr(err error) {
+	w.mu.Lock()
+	w.err = err
+	w.mu.Unlock()
+}
+
+// read runs one blocking query and returns the raw value, or for prefixes the
+// raw values keyed by full key and encoded as JSON.
+func (w *Watcher[T]) read(ctx context.Context, lastIndex uint64) ([]byte, bool, uint64, error) {
+	query := (&consul.QueryOptions{WaitIndex: lastIndex}).WithContext(ctx)
+	var (
+		data  []byte
+		found bool
+		meta  *consul.QueryMeta
+		err   error
+	)
+	if w.options.prefix {
+		var pairs consul.KVPairs
+		pairs, meta, err = w.client.KVList(w.key, query)
+		if err == nil {
+			data, found, err = encodePairs(pairs)
+		}
+	} else {
+		var pair *consul.KVPair
+		pair, meta, err = w.client.KVGet(w.key, query)
+		if err == nil && pair != nil {
+			data, found = pair.Value, true
+		}
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, false, 0, errStopped
+		}
+		return nil, false, 0, err
+	}
+	if meta == nil {
+		return nil, false, 0, fmt.Errorf("consul: KV query returned nil metadata")
+	}
+	return data, found, meta.LastIndex, nil
+}
+
+func (w *Watcher[T]) decode(data []byte) (T, error) {
+	var value T
+	if w.options.prefix {
+		tree, err := w.decodeTree(data)
+		if err != nil {
+			return value, err
+		}
+		data = tree
+	}
+	err := w.options.decoder(data, &value)
+	return value, err
+}
+
+// decoderFor picks DecodeYAML for keys ending in ".yaml" or ".yml" and
+// DecodeJSON otherwise.
+func decoderFor(key string) Decoder {
+	switch path.Ext(key) {
+	case ".yaml", ".yml":
+		return DecodeYAML
+	default:
+		return DecodeJSON
+	}
+}
+
+// decodeLeaf decodes one prefix entry with the configured decoder or, without
+// one, with the decoder for its key, keeping plain values that are not JSON as
+// strings.
+func (w *Watcher[T]) decodeLeaf(key string, raw []byte) (any, error) {
+	var leaf any
+	if w.options.leafDecoder != nil {
+		err := w.options.leafDecoder(raw, &leaf)
+		return leaf, err
+	}
+	switch path.Ext(key) {
+	case ".yaml", ".yml":
+		err := DecodeYAML(raw, &leaf)
+		return leaf, err
+	}
+	if !json.Valid(raw) {
+		return string(raw), nil
+	}
+	err := DecodeJSON(raw, &leaf)
+	return leaf, err
+}
+
+// encodePairs keeps the leaf keys of a prefix listing; folder keys carry no
+// value.
+func encodePairs(pairs consul.KVPairs) ([]byte, bool, error) {
+	leaves := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		if !strings.HasSuffix(pair.Key, "/") {
+			leaves[pair.Key] = pair.Value
+		}
+	}
+	if len(leaves) == 0 {
+		return nil, false, nil
+	}
+	data, err := json.Marshal(leaves)
+	return data, err == nil, err
+}
+
+// decodeTree nests prefix entries by path segment and decodes every leaf, so
+// the JSON result can be decoded into T. JSON is valid YAML, so either decoder
+// reads it back with its own struct tags.
+func (w *Watcher[T]) decodeTree(data []byte) ([]byte, error) {
+	var leaves map[string][]byte
+	if err := json.Unmarshal(data, &leaves); err != nil {
+		return nil, err
+	}
+	tree := map[string]any{}
+	for key, raw := range leaves {
+		rel := strings.Trim(strings.TrimPrefix(key, w.key), "/")
+		if rel == "" {
+			continue
+		}
+		leaf, err := w.decodeLeaf(key, raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+		node := tree
+		segments := strings.Split(rel, "/")
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]any)
+			if !ok {
+				if _, exists := node[segment]; exists {
+					return nil, fmt.Errorf("key %q is both a value and a prefix", key)
+				}
+				child = map[string]any{}
+				node[segment] = child
+			}
+			node = child
+		}
+		last := segments[len(segments)-1]
+		if _, exists := node[last]; exists {
+			return nil, fmt.Errorf("key %q is both a value and a prefix", key)
+		}
+		node[last] = leaf
+	}
+	return json.Marshal(tree)
+}
+
+func sendLatestChange[T any](ch chan Change[T], change Change[T]) {
+	select {
+	case ch <- change:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- change:
+	default:
+	}
+}
@@ -0,0 +1,37 @@
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Exponential returns base doubled for every attempt after the first, capped
+// at maximum, with a uniform jitter of +/- jitter (0 to 1) of the delay. The
+// jittered result never exceeds maximum. A maximum of 0 or less means no cap;
+// the delay then stops doubling only before it would overflow.
+func Exponential(base, maximum time.Duration, jitter float64, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempt && (maximum <= 0 || delay < maximum) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+	if maximum > 0 && delay > maximum {
+		delay = maximum
+	}
+	if jitter > 0 {
+		jitter = min(jitter, 1)
+		jittered := float64(delay) * (1 - jitter + 2*jitter*rand.Float64())
+		if jittered >= math.MaxInt64 {
+			delay = math.MaxInt64
+		} else {
+			delay = time.Duration(jittered)
+		}
+	}
+	if maximum > 0 && delay > maximum {
+		return maximum
+	}
+	return delay
+}
@@ -0,0 +1,28 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBoundsAndCap(t *testing.T) {
+	next := Exponential(10*time.Millisecond, time.Minute, 0.5, 2)
+	if next < 10*time.Millisecond || next > 30*time.Millisecond {
+		t.Fatalf("Exponential returned %v, want [10ms, 30ms]", next)
+	}
+	if capped := Exponential(10*time.Millisecond, time.Minute, 0.5, 40); capped > time.Minute {
+		t.Fatalf("Exponential cap = %v, want <= 1m", capped)
+	}
+	if exact := Exponential(10*time.Millisecond, time.Minute, 0, 3); exact != 40*time.Millisecond {
+		t.Fatalf("Exponential without jitter = %v, want 40ms", exact)
+	}
+	if uncapped := Exponential(10*time.Millisecond, 0, 0, 4); uncapped != 80*time.Millisecond {
+		t.Fatalf("Exponential without maximum = %v, want 80ms", uncapped)
+	}
+	if huge := Exponential(time.Second, 0, 1, 200); huge <= 0 {
+		t.Fatalf("Exponential without maximum overflowed to %v", huge)
+	}
+	if zero := Exponential(0, time.Minute, 0.5, 3); zero != 0 {
+		t.Fatalf("Exponential with zero base = %v, want 0", zero)
+	}
+}
@@ -0,0 +1,29 @@
+package kit
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DiscoveryPath serves the snapshots registered with WithDiscoverySnapshot.
+const DiscoveryPath = "/debug/discovery"
+
+type namedSnapshot struct {
+	name     string
+	snapshot func() any
+}
+
+func (s *Service) registerDiscoveryEndpoint() {
+	if len(s.discoverySnapshots) == 0 {
+		return
+	}
+	snapshots := s.discoverySnapshots
+	s.mux.HandleFunc("GET "+DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		resp := make(map[string]any, len(snapshots))
+		for _, snapshot := range snapshots {
+			resp[snapshot.name] = snapshot.snapshot()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
 
@@ -15,9 +16,10 @@ import (
 // Component owns a gRPC server and implements kit.Lifecycle. Register services
 // through Server before attaching the component to a kit.Service.
 type Component struct {
-	addr   string
-	server *googlegrpc.Server
-	errors chan error
+	addr    string
+	server  *googlegrpc.Server
+	errors  chan error
+	mounted bool
 
 	mu       sync.Mutex
 	listener net.Listener
@@ -42,6 +44,28 @@ func New(addr string, options ...googlegrpc.ServerOption) (*Component, error) {
 	}, nil
 }
 
+// NewMounted creates a component without a listener whose services are
+// served on the kit.Service port:
+//
+//	component, err := kitgrpc.NewMounted()
+//	pb.RegisterGreeterServer(component.Server(), greeter)
+//	svc, err := kit.New(":8080", kit.WithGRPCHandler(component.Handler()))
+//
+// The Service's graceful shutdown drains its calls, so the component is not
+// attached through kit.WithLifecycle; Start reports an error.
+func NewMounted(options ...googlegrpc.ServerOption) (*Component, error) {
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("kit/grpc: server option %d is nil", i)
+		}
+	}
+	return &Component{
+		server:  googlegrpc.NewServer(options...),
+		errors:  make(chan error, 1),
+		mounted: true,
+	}, nil
+}
+
 // MustNew creates a Component and panics if its configuration is invalid.
 func MustNew(addr string, options ...googlegrpc.ServerOption) *Component {
 	component, err := New(addr, options...)
@@ -59,6 +83,16 @@ func (c *Component) Server() *googlegrpc.Server {
 	return c.server
 }
 
+// Handler returns the gRPC server as an http.Handler for kit.WithGRPCHandler.
+// It relies on grpc-go's ServeHTTP, which needs HTTP/2 and does not support
+// every server option of a dedicated listener; see grpc.Server.ServeHTTP.
+func (c *Component) Handler() http.Handler {
+	if c == nil {
+		return nil
+	}
+	return c.server
+}
+
 // Addr returns the bound listener address after Start, or nil before Start.
 func (c *Component) Addr() net.Addr {
 	if c == nil {
@@ -85,6 +119,9 @@ func (c *Component) Start() error {
 	if c.stopped {
 		return fmt.Errorf("kit/grpc: component cannot be restarted after shutdown")
 	}
+	if c.mounted {
+		return fmt.Errorf("kit/grpc: mounted component is served by kit.WithGRPCHandler")
+	}
 
 	listener, err := net.Listen("tcp", c.addr)
 	if err != nil {
@@ -3,8 +3,14 @@ package grpc
 import (
 	"context"
 	"net"
+	"net/http"
 	"testing"
 	"time"
+
+	googlegrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestComponentLifecycle(t *testing.T) {
@@ -56,3 +62,40 @@ func TestNewRejectsNilServerOption(t *testing.T) {
 		t.Fatal("expected nil server option error")
 	}
 }
+
+func TestMountedComponentServesOverH2C(t *testing.T) {
+	component, err := NewMounted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(component.Server(), healthServer)
+	if err := component.Start(); err == nil {
+		t.Fatal("expected Start error for mounted component")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{Handler: component.Handler(), Protocols: protocols}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	conn, err := googlegrpc.NewClient(listener.Addr().String(), googlegrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("status = %v", resp.GetStatus())
+	}
+}
@@ -0,0 +1,69 @@
+package kit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithH2C serves HTTP/2 without TLS (h2c, prior knowledge) on the Service
+// address alongside HTTP/1.1. Use it behind proxies or meshes that speak
+// cleartext HTTP/2 to the pod.
+func WithH2C() Option {
+	return func(s *Service) error {
+		s.h2c = true
+		return nil
+	}
+}
+
+// WithGRPCHandler serves gRPC and HTTP on the Service address. HTTP/2
+// requests whose Content-Type starts with application/grpc go to handler,
+// typically the Handler of a kit/grpc component built with NewMounted; all
+// other requests reach the HTTP routes. The option enables h2c, since gRPC
+// clients dial cleartext HTTP/2. gRPC calls bypass WithHTTPMiddleware; use
+// gRPC interceptors instead. Service.Shutdown drains them with the HTTP
+// requests.
+func WithGRPCHandler(handler http.Handler) Option {
+	return func(s *Service) error {
+		if handler == nil {
+			return fmt.Errorf("gRPC handler cannot be nil")
+		}
+		if s.grpcHandler != nil {
+			return fmt.Errorf("gRPC handler already set")
+		}
+		s.h2c = true
+		s.grpcHandler = handler
+		return nil
+	}
+}
+
+// applyGRPCRouting puts the gRPC handler in front of the HTTP handler chain.
+func (s *Service) applyGRPCRouting(next http.Handler) http.Handler {
+	if s.grpcHandler == nil {
+		return next
+	}
+	grpc := s.grpcHandler
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			grpc.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// httpProtocols returns the protocols for the server created by Start, or nil
+// for the net/http defaults.
+func (s *Service) httpProtocols() *http.Protocols {
+	if !s.h2c {
+		return nil
+	}
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
@@ -0,0 +1,107 @@
+package kit_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/kit"
+)
+
+func startOnFreePort(t *testing.T, opts ...kit.Option) (*kit.Service, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	svc := kit.MustNew(addr, opts...)
+	if err := svc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = svc.Shutdown(ctx)
+	})
+	return svc, "http://" + addr
+}
+
+func h2cClient() *http.Client {
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+}
+
+func TestWithH2C_ServesHTTP2AndHTTP1(t *testing.T) {
+	svc, base := startOnFreePort(t, kit.WithH2C())
+	svc.HandleFunc("GET /proto", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+
+	for name, c := range map[string]*http.Client{"HTTP/2.0": h2cClient(), "HTTP/1.1": http.DefaultClient} {
+		resp, err := c.Get(base + "/proto")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != name {
+			t.Fatalf("served over %q, want %q", body, name)
+		}
+	}
+}
+
+func TestWithGRPCHandler_RoutesByContentType(t *testing.T) {
+	grpcHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "grpc")
+	})
+	var middlewareCalls int
+	svc, base := startOnFreePort(t,
+		kit.WithGRPCHandler(grpcHandler),
+		kit.WithHTTPMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				middlewareCalls++
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	svc.HandleFunc("POST /svc.Echo/Call", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "http")
+	})
+
+	call := func(c *http.Client, contentType string) string {
+		t.Helper()
+		resp, err := c.Post(base+"/svc.Echo/Call", contentType, strings.NewReader("x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	if got := call(h2cClient(), "application/grpc+proto"); got != "grpc" {
+		t.Fatalf("HTTP/2 gRPC request reached %q", got)
+	}
+	if middlewareCalls != 0 {
+		t.Fatal("gRPC request ran HTTP middleware")
+	}
+	if got := call(h2cClient(), "application/json"); got != "http" {
+		t.Fatalf("HTTP/2 JSON request reached %q", got)
+	}
+	if got := call(http.DefaultClient, "application/grpc"); got != "http" {
+		t.Fatalf("HTTP/1.1 request reached %q", got)
+	}
+
+	if _, err := kit.New(":0", kit.WithGRPCHandler(nil)); err == nil {
+		t.Fatal("expected error for nil gRPC handler")
+	}
+	if _, err := kit.New(":0", kit.WithGRPCHandler(grpcHandler), kit.WithGRPCHandler(grpcHandler)); err == nil {
+		t.Fatal("expected error for second gRPC handler")
+	}
+}
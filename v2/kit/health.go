@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -60,6 +62,25 @@ func (s *Service) healthHandler(checks []namedHealthCheck) http.HandlerFunc {
 	}
 }
 
+// ReadinessCheck returns a HealthCheck that runs every readiness check with the
+// configured per-check timeout and fails when any of them fails. Use it to
+// report readiness to an external registry, for example a Consul TTL check.
+func (s *Service) ReadinessCheck() HealthCheck {
+	return func(ctx context.Context) error {
+		status, results := runHealthChecks(ctx, s.readinessChecks, s.healthTimeout)
+		if status == "ok" {
+			return nil
+		}
+		failed := make([]string, 0, len(results))
+		for _, result := range results {
+			if result.Status != "ok" {
+				failed = append(failed, result.Name+": "+result.Error)
+			}
+		}
+		return fmt.Errorf("readiness checks failed: %s", strings.Join(failed, "; "))
+	}
+}
+
 func runHealthChecks(ctx context.Context, checks []namedHealthCheck, timeout time.Duration) (string, []healthCheckResult) {
 	if len(checks) == 0 {
 		return "ok", nil
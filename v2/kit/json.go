@@ -3,6 +3,7 @@ package kit
 import (
 	"context"
 	"net/http"
+	"reflect"
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
 	httpserver "github.com/dreamsxin/go-kit/v2/transport/http/server"
@@ -40,7 +41,7 @@ func HandleJSON[Req any](
 		panic("kit: JSON handler cannot be nil")
 	}
 	ep := endpoint.TypedEndpoint[Req, any](handler).Wrap()
-	HandleJSONEndpoint[Req](s, pattern, ep, options...)
+	handleJSONEndpoint[Req](s, pattern, ep, anyType, options)
 }
 
 // HandleJSONTyped registers a JSON endpoint with compile-time request and
@@ -54,7 +55,8 @@ func HandleJSONTyped[Req, Resp any](
 	if handler == nil {
 		panic("kit: JSON handler cannot be nil")
 	}
-	HandleJSONEndpoint[Req](s, pattern, endpoint.TypedEndpoint[Req, Resp](handler).Wrap(), options...)
+	ep := endpoint.TypedEndpoint[Req, Resp](handler).Wrap()
+	handleJSONEndpoint[Req](s, pattern, ep, reflect.TypeOf((*Resp)(nil)).Elem(), options)
 }
 
 // HandleJSONEndpoint registers an already-built endpoint.Endpoint as a strict
@@ -65,6 +67,19 @@ func HandleJSONEndpoint[Req any](
 	pattern string,
 	ep endpoint.Endpoint,
 	options ...httpserver.ServerOption,
+) {
+	handleJSONEndpoint[Req](s, pattern, ep, anyType, options)
+}
+
+// anyType documents the response of routes whose response type is unknown.
+var anyType = reflect.TypeOf((*any)(nil)).Elem()
+
+func handleJSONEndpoint[Req any](
+	s *Service,
+	pattern string,
+	ep endpoint.Endpoint,
+	response reflect.Type,
+	options []httpserver.ServerOption,
 ) {
 	if s == nil {
 		panic("kit: Service cannot be nil")
@@ -76,4 +91,5 @@ func HandleJSONEndpoint[Req any](
 	routeOptions := append(append([]httpserver.ServerOption(nil), s.jsonServerOptions...), options...)
 	h := httpserver.NewStrictJSONEndpoint[Req](ep, s.jsonMaxBodyBytes, routeOptions...)
 	s.mux.Handle(pattern, s.withHTTPContext(h))
+	s.recordRoute(pattern, reflect.TypeOf((*Req)(nil)).Elem(), response, h.FieldMaskParam())
 }
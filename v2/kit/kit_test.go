@@ -198,6 +198,37 @@ func TestService_LivezReadyz_DefaultOK(t *testing.T) {
 	}
 }
 
+func TestService_DiscoveryRouteRendersSnapshots(t *testing.T) {
+	_, ts := newSvc(t, kit.WithDiscoverySnapshot("users", func() any {
+		return map[string]any{"instances": []string{"10.0.0.1:80"}}
+	}))
+
+	resp, err := http.Get(ts.URL + kit.DiscoveryPath)
+	if err != nil {
+		t.Fatalf("GET %s: %v", kit.DiscoveryPath, err)
+	}
+	defer resp.Body.Close()
+	var body map[string]struct {
+		Instances []string `json:"instances"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode discovery body: %v", err)
+	}
+	if got := body["users"].Instances; len(got) != 1 || got[0] != "10.0.0.1:80" {
+		t.Fatalf("discovery body = %#v", body)
+	}
+
+	_, plain := newSvc(t)
+	resp, err = http.Get(plain.URL + kit.DiscoveryPath)
+	if err != nil {
+		t.Fatalf("GET %s: %v", kit.DiscoveryPath, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("route without snapshots: status %d, want 404", resp.StatusCode)
+	}
+}
+
 func TestService_ReadyzReportsReadinessFailure(t *testing.T) {
 	_, ts := newSvc(t, kit.WithReadinessCheck("db", func(context.Context) error {
 		return errors.New("db unavailable")
@@ -263,6 +294,25 @@ func TestService_ReadyzTimesOutSlowReadinessCheck(t *testing.T) {
 	}
 }
 
+func TestService_ReadinessCheckCombinesReadinessChecks(t *testing.T) {
+	svc, _ := newSvc(t,
+		kit.WithLivenessCheck("process", func(context.Context) error { return errors.New("ignored") }),
+		kit.WithReadinessCheck("cache", kit.Healthy),
+	)
+	if err := svc.ReadinessCheck()(context.Background()); err != nil {
+		t.Fatalf("ReadinessCheck: %v", err)
+	}
+
+	svc, _ = newSvc(t,
+		kit.WithReadinessCheck("cache", kit.Healthy),
+		kit.WithReadinessCheck("db", func(context.Context) error { return errors.New("db unavailable") }),
+	)
+	err := svc.ReadinessCheck()(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "db: check failed") {
+		t.Fatalf("ReadinessCheck error = %v, want db failure", err)
+	}
+}
+
 func TestService_LivezIgnoresReadinessFailure(t *testing.T) {
 	_, ts := newSvc(t, kit.WithReadinessCheck("db", func(context.Context) error {
 		return errors.New("db unavailable")
@@ -886,6 +936,10 @@ func TestKitOptions_ReturnErrorsForInvalidConfiguration(t *testing.T) {
 			name:   "readiness check nil",
 			option: kit.WithReadinessCheck("db", nil),
 		},
+		{
+			name:   "discovery snapshot nil",
+			option: kit.WithDiscoverySnapshot("users", nil),
+		},
 		{
 			name:   "liveness check empty name",
 			option: kit.WithLivenessCheck("", kit.Healthy),
@@ -1061,3 +1115,30 @@ func TestKitJSON_IsTypedHTTPHandler(t *testing.T) {
 		t.Errorf("name: got %q, want %q", result.Name, "Dave")
 	}
 }
+
+func TestHandleJSONTyped_BindsPathQueryAndHeader(t *testing.T) {
+	type updateReq struct {
+		ID     string `path:"id" json:"-"`
+		DryRun bool   `query:"dry_run" json:"-"`
+		Tenant string `header:"X-Tenant" json:"-"`
+		Name   string `json:"name"`
+	}
+	svc := kit.MustNew(":0")
+	kit.HandleJSONTyped(svc, "PUT /items/{id}", func(_ context.Context, req updateReq) (string, error) {
+		return strings.Join([]string{req.ID, req.Tenant, req.Name}, "/"), nil
+	})
+
+	r := httptest.NewRequest(http.MethodPut, "/items/7?dry_run=true", strings.NewReader(`{"name":"box"}`))
+	r.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	svc.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `"7/acme/box"` {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	svc.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/items/7?dry_run=maybe", strings.NewReader(`{}`)))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "bad_request.invalid_query") {
+		t.Fatalf("invalid query: got %d %s", w.Code, w.Body)
+	}
+}
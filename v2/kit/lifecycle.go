@@ -7,6 +7,8 @@ import (
 	"net"
 	"net/http"
 	"time"
+
+	"github.com/dreamsxin/go-kit/v2/transport/websocket"
 )
 
 // DefaultShutdownTimeout is the graceful shutdown deadline used by Run.
@@ -22,6 +24,15 @@ type Lifecycle interface {
 	Shutdown(context.Context) error
 }
 
+// Drainer is implemented by lifecycle components that must act while the
+// HTTP server still serves requests, such as a registrar that marks the
+// instance unhealthy and waits for consumers to stop routing to it. Shutdown
+// calls Drain on these components in reverse order before it stops the HTTP
+// server; the time they take counts against the shutdown deadline.
+type Drainer interface {
+	Drain(context.Context) error
+}
+
 // Run starts the configured servers and blocks until ctx is cancelled or a
 // server fails. Signal handling belongs to the calling main package.
 func (s *Service) Run(ctx context.Context) error {
@@ -90,6 +101,7 @@ func (s *Service) Start() error {
 		WriteTimeout:      s.httpConfig.WriteTimeout,
 		IdleTimeout:       s.httpConfig.IdleTimeout,
 		MaxHeaderBytes:    s.httpConfig.MaxHeaderBytes,
+		Protocols:         s.httpProtocols(),
 	}
 	s.lifecycleDone = make(chan struct{})
 	s.started = true
@@ -134,7 +146,8 @@ func (s *Service) watchLifecycle(index int, errors <-chan error, done <-chan str
 	}
 }
 
-// Shutdown gracefully stops the HTTP server and attached components.
+// Shutdown drains components that implement Drainer, then gracefully stops
+// the HTTP server and attached components.
 func (s *Service) Shutdown(ctx context.Context) error {
 	if s == nil {
 		return nil
@@ -150,6 +163,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 	srv := s.srv
 	lifecycleDone := s.lifecycleDone
 	components := append([]Lifecycle(nil), s.lifecycles...)
+	websockets := append([]*websocket.Server(nil), s.websockets...)
 	s.started = false
 	s.stopped = true
 	s.lifecycleMu.Unlock()
@@ -157,11 +171,31 @@ func (s *Service) Shutdown(ctx context.Context) error {
 	if lifecycleDone != nil {
 		close(lifecycleDone)
 	}
+	drainErr := drainLifecycles(ctx, components)
 	var httpErr error
 	if srv != nil {
 		httpErr = srv.Shutdown(ctx)
 	}
-	return errors.Join(httpErr, shutdownLifecycles(ctx, components))
+	// Upgraded WebSocket connections are hijacked, so srv.Shutdown neither
+	// closes nor waits for them.
+	for _, ws := range websockets {
+		httpErr = errors.Join(httpErr, ws.Shutdown(ctx))
+	}
+	return errors.Join(drainErr, httpErr, shutdownLifecycles(ctx, components))
+}
+
+func drainLifecycles(ctx context.Context, components []Lifecycle) error {
+	var result error
+	for i := len(components) - 1; i >= 0; i-- {
+		drainer, ok := components[i].(Drainer)
+		if !ok {
+			continue
+		}
+		if err := drainer.Drain(ctx); err != nil {
+			result = errors.Join(result, fmt.Errorf("drain lifecycle component %d: %w", i, err))
+		}
+	}
+	return result
 }
 
 func shutdownLifecycles(ctx context.Context, components []Lifecycle) error {
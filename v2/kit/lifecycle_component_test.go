@@ -3,6 +3,8 @@ package kit
 import (
 	"context"
 	"errors"
+	"net"
+	"net/http"
 	"reflect"
 	"strings"
 	"testing"
@@ -50,6 +52,69 @@ func TestLifecycleComponentsStartAndStopInDependencyOrder(t *testing.T) {
 	}
 }
 
+type drainingLifecycle struct {
+	recordingLifecycle
+	drain func(context.Context) error
+}
+
+func (c *drainingLifecycle) Drain(ctx context.Context) error {
+	*c.events = append(*c.events, "drain "+c.name)
+	return c.drain(ctx)
+}
+
+func TestLifecycleDrainRunsWhileRequestsAreServed(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+
+	var events []string
+	var served []int
+	plain := &recordingLifecycle{name: "plain", events: &events}
+	registrar := &drainingLifecycle{
+		recordingLifecycle: recordingLifecycle{name: "registrar", events: &events},
+		drain: func(ctx context.Context) error {
+			// Requests that arrive during the drain window must still succeed.
+			for range 3 {
+				req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/ping", nil)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					return err
+				}
+				resp.Body.Close()
+				served = append(served, resp.StatusCode)
+				time.Sleep(5 * time.Millisecond)
+			}
+			return nil
+		},
+	}
+	service := MustNew(addr, WithLifecycle(registrar, plain))
+	service.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if err := service.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := service.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if want := []int{http.StatusNoContent, http.StatusNoContent, http.StatusNoContent}; !reflect.DeepEqual(served, want) {
+		t.Fatalf("statuses during drain = %v, want %v", served, want)
+	}
+	want := []string{"start registrar", "start plain", "drain registrar", "stop plain", "stop registrar"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("lifecycle events = %v, want %v", events, want)
+	}
+	if _, err := http.Get("http://" + addr + "/ping"); err == nil {
+		t.Fatal("expected the HTTP server to be stopped after Shutdown")
+	}
+}
+
 func TestLifecycleStartupFailureStopsPreviouslyStartedComponents(t *testing.T) {
 	var events []string
 	first := &recordingLifecycle{name: "first", events: &events}
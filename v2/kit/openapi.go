@@ -0,0 +1,176 @@
+package kit
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/openapi"
+	httpserver "github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+// OpenAPIPath serves the document enabled by WithOpenAPI.
+const OpenAPIPath = "/openapi.json"
+
+// DocsPath serves the documentation page enabled by OpenAPIConfig.Docs.
+const DocsPath = "/docs"
+
+// OpenAPIConfig describes the API in the document served by WithOpenAPI.
+type OpenAPIConfig struct {
+	Title       string
+	Version     string
+	Description string
+	// Docs also serves a self-contained HTML page rendering the document on
+	// GET /docs.
+	Docs bool
+}
+
+// RouteDoc documents one route in the OpenAPI document. Attach it with
+// Service.Describe.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Errors lists documented error responses, for example
+	// {Status: 404, Code: "item.not_found"}.
+	Errors []openapi.ErrorResponse
+	// Request and Response are zero values whose types describe the bodies
+	// of routes registered with Handle or HandleFunc. Typed JSON routes
+	// record their types automatically.
+	Request  any
+	Response any
+	// FieldMask documents the field mask query parameter of a raw route
+	// that prunes its responses; JSON routes using server.ServerFieldMask
+	// record it automatically.
+	FieldMask string
+}
+
+// WithOpenAPI serves an OpenAPI 3.1 document on GET /openapi.json. The
+// document is reflected at request time from the request and response types
+// of every route registered with HandleJSON, HandleJSONTyped, or
+// HandleJSONEndpoint, plus routes documented with Service.Describe. JSON
+// routes whose pattern has no method are documented as POST; described raw
+// routes without a method as GET. Error responses use the
+// httpserver.ErrorResponse body of the JSON error encoder.
+func WithOpenAPI(config OpenAPIConfig) Option {
+	return func(s *Service) error {
+		if strings.TrimSpace(config.Title) == "" {
+			return fmt.Errorf("OpenAPI title cannot be empty")
+		}
+		s.openAPI = &config
+		return nil
+	}
+}
+
+type openAPIRoutes struct {
+	mu     sync.Mutex
+	order  []string
+	routes map[string]openapi.Route
+	docs   map[string]RouteDoc
+}
+
+// Describe attaches documentation to the route registered, or to be
+// registered, with pattern. Patterns that no typed JSON route uses are
+// documented from doc.Request and doc.Response. A later call for the same
+// pattern replaces the earlier one.
+//
+// Example:
+//
+//	svc.Describe("GET /items/{id}", kit.RouteDoc{
+//	    Summary: "Get an item",
+//	    Tags:    []string{"items"},
+//	    Errors:  []openapi.ErrorResponse{{Status: http.StatusNotFound, Code: "item.not_found"}},
+//	})
+func (s *Service) Describe(pattern string, doc RouteDoc) {
+	if strings.TrimSpace(pattern) == "" {
+		panic("kit: route pattern cannot be empty")
+	}
+	s.routes.mu.Lock()
+	defer s.routes.mu.Unlock()
+	if s.routes.docs == nil {
+		s.routes.docs = map[string]RouteDoc{}
+	}
+	s.routes.remember(pattern)
+	s.routes.docs[pattern] = doc
+}
+
+// recordRoute remembers the types and field mask parameter of a typed JSON
+// route for the document.
+func (s *Service) recordRoute(pattern string, request, response reflect.Type, fieldMask string) {
+	method, path := openapi.ParsePattern(pattern)
+	if method == "" {
+		method = http.MethodPost
+	}
+	s.routes.mu.Lock()
+	defer s.routes.mu.Unlock()
+	if s.routes.routes == nil {
+		s.routes.routes = map[string]openapi.Route{}
+	}
+	s.routes.remember(pattern)
+	s.routes.routes[pattern] = openapi.Route{Method: method, Path: path, Request: request, Response: response, FieldMask: fieldMask}
+}
+
+func (r *openAPIRoutes) remember(pattern string) {
+	if _, ok := r.routes[pattern]; ok {
+		return
+	}
+	if _, ok := r.docs[pattern]; ok {
+		return
+	}
+	r.order = append(r.order, pattern)
+}
+
+// OpenAPI returns the OpenAPI document of the routes registered so far. It
+// works without WithOpenAPI, for example to write the document to a file.
+func (s *Service) OpenAPI() *openapi.Document {
+	info := openapi.Info{Title: "API"}
+	if s.openAPI != nil {
+		info = openapi.Info{Title: s.openAPI.Title, Version: s.openAPI.Version, Description: s.openAPI.Description}
+	}
+
+	s.routes.mu.Lock()
+	routes := make([]openapi.Route, 0, len(s.routes.order))
+	for _, pattern := range s.routes.order {
+		route, typed := s.routes.routes[pattern]
+		doc, described := s.routes.docs[pattern]
+		if !typed {
+			route.Method, route.Path = openapi.ParsePattern(pattern)
+			route.Request = typeOf(doc.Request)
+			route.Response = typeOf(doc.Response)
+		}
+		if described {
+			route.Summary = doc.Summary
+			route.Description = doc.Description
+			route.Tags = doc.Tags
+			route.Deprecated = doc.Deprecated
+			route.Errors = doc.Errors
+			if doc.FieldMask != "" {
+				route.FieldMask = doc.FieldMask
+			}
+		}
+		routes = append(routes, route)
+	}
+	s.routes.mu.Unlock()
+
+	return openapi.Build(info, routes, openapi.WithErrorBody(reflect.TypeOf(httpserver.ErrorResponse{})))
+}
+
+func (s *Service) registerOpenAPIEndpoints() {
+	if s.openAPI == nil {
+		return
+	}
+	s.mux.Handle("GET "+OpenAPIPath, openapi.Handler(s.OpenAPI))
+	if s.openAPI.Docs {
+		s.mux.Handle("GET "+DocsPath, openapi.DocsHandler(OpenAPIPath))
+	}
+}
+
+func typeOf(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	return reflect.TypeOf(v)
+}
@@ -0,0 +1,121 @@
+package kit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/kit"
+	"github.com/dreamsxin/go-kit/v2/transport/http/openapi"
+	httpserver "github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+type docItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type getItemRequest struct {
+	ID string `path:"id" json:"-"`
+}
+
+type createItemRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func TestWithOpenAPI_ServesRegisteredRoutes(t *testing.T) {
+	svc := kit.MustNew(":0", kit.WithOpenAPI(kit.OpenAPIConfig{Title: "Items", Version: "2.0.0", Docs: true}))
+	kit.HandleJSONTyped(svc, "GET /items/{id}", func(_ context.Context, req getItemRequest) (docItem, error) {
+		return docItem{ID: req.ID}, nil
+	})
+	kit.HandleJSON(svc, "/items", func(_ context.Context, req createItemRequest) (any, error) {
+		return docItem{Name: req.Name}, nil
+	})
+	svc.Describe("GET /items/{id}", kit.RouteDoc{
+		Summary: "Get an item",
+		Tags:    []string{"items"},
+		Errors:  []openapi.ErrorResponse{{Status: http.StatusNotFound, Code: "item.not_found"}},
+	})
+	svc.HandleFunc("GET /items/{id}/raw", func(w http.ResponseWriter, _ *http.Request) {})
+	svc.Describe("GET /items/{id}/raw", kit.RouteDoc{Summary: "Raw item", Response: docItem{}})
+
+	w := httptest.NewRecorder()
+	svc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, kit.OpenAPIPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d", w.Code)
+	}
+	var doc openapi.Document
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Info.Title != "Items" || doc.Info.Version != "2.0.0" {
+		t.Fatalf("info = %+v", doc.Info)
+	}
+
+	get := doc.Paths["/items/{id}"]["get"]
+	if get == nil || get.Summary != "Get an item" || len(get.Parameters) != 1 || get.RequestBody != nil {
+		t.Fatalf("GET /items/{id} = %+v", get)
+	}
+	if ref := get.Responses["200"].Content[openapi.ContentType].Schema.Ref; ref != "#/components/schemas/docItem" {
+		t.Fatalf("response ref = %q", ref)
+	}
+	if ref := get.Responses["404"].Content[openapi.ContentType].Schema.Ref; ref != "#/components/schemas/ErrorResponse" {
+		t.Fatalf("error ref = %q", ref)
+	}
+
+	post := doc.Paths["/items"]["post"]
+	if post == nil || post.RequestBody == nil || !post.RequestBody.Required {
+		t.Fatalf("POST /items = %+v", post)
+	}
+	if raw := doc.Paths["/items/{id}/raw"]["get"]; raw == nil || raw.Summary != "Raw item" {
+		t.Fatalf("described raw route = %+v", raw)
+	}
+
+	w = httptest.NewRecorder()
+	svc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, kit.DocsPath, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), kit.OpenAPIPath) {
+		t.Fatalf("docs page: got %d", w.Code)
+	}
+}
+
+func TestOpenAPI_DisabledByDefault(t *testing.T) {
+	svc := kit.MustNew(":0")
+	kit.HandleJSONTyped(svc, "POST /items", func(_ context.Context, req createItemRequest) (docItem, error) {
+		return docItem{}, nil
+	})
+
+	w := httptest.NewRecorder()
+	svc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, kit.OpenAPIPath, nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status: got %d, want 404", w.Code)
+	}
+	if doc := svc.OpenAPI(); doc.Paths["/items"]["post"] == nil {
+		t.Fatalf("OpenAPI() paths = %v", doc.Paths)
+	}
+	if _, err := kit.New(":0", kit.WithOpenAPI(kit.OpenAPIConfig{})); err == nil {
+		t.Fatal("expected error for empty title")
+	}
+}
+
+func TestOpenAPI_DocumentsFieldMaskRoutes(t *testing.T) {
+	svc := kit.MustNew(":0", kit.WithOpenAPI(kit.OpenAPIConfig{Title: "Items"}))
+	kit.HandleJSONTyped(svc, "GET /items/{id}", func(_ context.Context, req getItemRequest) (docItem, error) {
+		return docItem{ID: req.ID, Name: "widget"}, nil
+	}, httpserver.ServerFieldMask(httpserver.FieldMaskOptions{Response: reflect.TypeOf(docItem{})}))
+
+	w := httptest.NewRecorder()
+	svc.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items/7?fields=name", nil))
+	if got := strings.TrimSpace(w.Body.String()); got != `{"name":"widget"}` {
+		t.Fatalf("masked response = %s", got)
+	}
+
+	doc := svc.OpenAPI()
+	get := doc.Paths["/items/{id}"]["get"]
+	if get == nil || len(get.Parameters) != 2 || get.Parameters[1].Name != "fields" || get.Parameters[1].In != "query" {
+		t.Fatalf("GET /items/{id} parameters = %+v", get)
+	}
+}
@@ -196,6 +196,30 @@ func isNilLifecycle(component Lifecycle) bool {
 	}
 }
 
+// WithDiscoverySnapshot renders snapshot under name on GET /debug/discovery.
+// kit does not depend on service discovery, so pass a function returning a
+// JSON-encodable value, for example a closure returning the Snapshot of an
+// sd/endpointer Endpointer or of sd/client.Snapshot. The route exposes
+// upstream addresses; protect it with HTTP middleware or keep it on an
+// internal listener.
+func WithDiscoverySnapshot(name string, snapshot func() any) Option {
+	return func(s *Service) error {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("discovery snapshot name cannot be empty")
+		}
+		if snapshot == nil {
+			return fmt.Errorf("discovery snapshot cannot be nil")
+		}
+		for _, existing := range s.discoverySnapshots {
+			if existing.name == name {
+				return fmt.Errorf("duplicate discovery snapshot %q", name)
+			}
+		}
+		s.discoverySnapshots = append(s.discoverySnapshots, namedSnapshot{name: name, snapshot: snapshot})
+		return nil
+	}
+}
+
 // WithTimeout adds a per-request context deadline.
 func WithTimeout(d time.Duration) Option {
 	return func(s *Service) error {
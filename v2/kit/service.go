@@ -10,6 +10,7 @@ import (
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
 	httpserver "github.com/dreamsxin/go-kit/v2/transport/http/server"
+	"github.com/dreamsxin/go-kit/v2/transport/websocket"
 )
 
 // Service is a ready-to-run HTTP microservice. Optional servers can be
@@ -29,10 +30,16 @@ type Service struct {
 	healthTimeout      time.Duration
 	livenessChecks     []namedHealthCheck
 	readinessChecks    []namedHealthCheck
+	discoverySnapshots []namedSnapshot
+	openAPI            *OpenAPIConfig
+	routes             openAPIRoutes
+	h2c                bool
+	grpcHandler        http.Handler
 	srv                *http.Server
 	serveErrors        chan error
 	lifecycles         []Lifecycle
 	lifecycleDone      chan struct{}
+	websockets         []*websocket.Server
 
 	lifecycleMu     sync.Mutex
 	started         bool
@@ -66,7 +73,9 @@ func New(addr string, opts ...Option) (*Service, error) {
 	}
 	s.serveErrors = make(chan error, len(s.lifecycles)+1)
 	s.registerHealthEndpoints()
-	s.httpHandler = s.applyHTTPMiddleware(s.mux)
+	s.registerDiscoveryEndpoint()
+	s.registerOpenAPIEndpoints()
+	s.httpHandler = s.applyGRPCRouting(s.applyHTTPMiddleware(s.mux))
 	return s, nil
 }
 
@@ -2,6 +2,7 @@ package kit_test
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/dreamsxin/go-kit/v2/kit"
+	"github.com/dreamsxin/go-kit/v2/transport/http/compress"
 )
 
 func newSSEServer(t *testing.T, stream func(ctx context.Context, w *kit.SSEWriter) error) *httptest.Server {
@@ -130,3 +132,37 @@ func TestHandleSSE_ClientDisconnectCancelsStream(t *testing.T) {
 		t.Fatal("stream did not observe client disconnect")
 	}
 }
+
+func TestHandleSSE_CompressedStreamDeliversEventsIncrementally(t *testing.T) {
+	release := make(chan struct{})
+	svc := kit.MustNew(":0", kit.WithHTTPMiddleware(compress.Middleware()))
+	kit.HandleSSE(svc, "GET /events", func(_ context.Context, w *kit.SSEWriter) error {
+		if err := w.Data("first"); err != nil {
+			return err
+		}
+		<-release
+		return w.Data("second")
+	})
+	srv := httptest.NewServer(svc)
+	defer srv.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q", resp.Header.Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(zr).ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Fatalf("first event = %q, %v", line, err)
+	}
+}
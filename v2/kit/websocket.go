@@ -0,0 +1,38 @@
+package kit
+
+import "github.com/dreamsxin/go-kit/v2/transport/websocket"
+
+// HandleWebSocket registers a bidirectional WebSocket stream at pattern.
+// Unlike HandleSSE, the stream endpoint can both send and receive messages;
+// see the transport/websocket package for codecs, keepalive, send queue
+// limits, and how returned errors map to close codes.
+//
+// Like HandleSSE, this is a raw HTTP escape hatch: HTTP middleware runs for
+// the opening handshake and the request ID, when enabled, is available from
+// the stream context, but endpoint middleware does not apply. Wrap an
+// endpoint with websocket.FromEndpoint to serve it one message at a time.
+// Service.Shutdown closes open connections with websocket.CloseGoingAway.
+//
+// Example:
+//
+//	kit.HandleWebSocket(svc, "GET /ws/dashboard", func(ctx context.Context, stream websocket.Stream) error {
+//		for {
+//			var cmd DashboardCommand
+//			if err := stream.Recv(&cmd); err != nil {
+//				return err
+//			}
+//			if err := stream.Send(apply(cmd)); err != nil {
+//				return err
+//			}
+//		}
+//	}, websocket.WithKeepalive(15*time.Second, 5*time.Second))
+func HandleWebSocket(s *Service, pattern string, stream websocket.StreamEndpoint, options ...websocket.Option) {
+	if stream == nil {
+		panic("kit: WebSocket stream endpoint cannot be nil")
+	}
+	ws := websocket.NewServer(stream, options...)
+	s.lifecycleMu.Lock()
+	s.websockets = append(s.websockets, ws)
+	s.lifecycleMu.Unlock()
+	s.Handle(pattern, ws)
+}
@@ -0,0 +1,79 @@
+package kit_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/kit"
+	"github.com/dreamsxin/go-kit/v2/transport/websocket"
+)
+
+func TestHandleWebSocket_StreamsWithRequestID(t *testing.T) {
+	svc := kit.MustNew(":0", kit.WithRequestID())
+	kit.HandleWebSocket(svc, "GET /ws", func(ctx context.Context, stream websocket.Stream) error {
+		var msg map[string]string
+		if err := stream.Recv(&msg); err != nil {
+			return err
+		}
+		return stream.Send(map[string]string{"echo": msg["text"], "request_id": endpoint.RequestIDFromContext(ctx)})
+	})
+	srv := httptest.NewServer(svc)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"text":"hi"}`)); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"echo":"hi"`) || strings.Contains(string(data), `"request_id":""`) {
+		t.Fatalf("message = %s, want echo with request ID", data)
+	}
+}
+
+func TestHandleWebSocket_ShutdownClosesConnections(t *testing.T) {
+	svc := kit.MustNew(":0")
+	started := make(chan struct{})
+	kit.HandleWebSocket(svc, "GET /ws", func(ctx context.Context, _ websocket.Stream) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	srv := httptest.NewServer(svc)
+	defer srv.Close()
+
+	conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := svc.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseGoingAway {
+		t.Fatalf("read error = %v, want going away close", err)
+	}
+}
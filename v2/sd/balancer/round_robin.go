@@ -9,7 +9,9 @@ import (
 	"github.com/dreamsxin/go-kit/v2/sd/endpointer"
 )
 
-// NewRoundRobin distributes calls over the current endpoint snapshot.
+// NewRoundRobin distributes calls over the current endpoint snapshot. The
+// returned balancer implements sd.InstanceBalancer when source implements
+// endpointer.InstanceEndpointer, as endpointer.NewEndpointer does.
 func NewRoundRobin(source endpointer.Endpointer) sd.Balancer {
 	return &roundRobin{source: source}
 }
@@ -19,6 +21,8 @@ type roundRobin struct {
 	next   uint64
 }
 
+var _ sd.InstanceBalancer = (*roundRobin)(nil)
+
 func (r *roundRobin) Endpoint() (endpoint.Endpoint, error) {
 	endpoints, err := r.source.Endpoints()
 	if err != nil {
@@ -30,3 +34,34 @@ func (r *roundRobin) Endpoint() (endpoint.Endpoint, error) {
 	index := atomic.AddUint64(&r.next, 1) - 1
 	return endpoints[index%uint64(len(endpoints))], nil
 }
+
+// InstanceEndpoint selects the next endpoint in rotation whose instance is not
+// excluded. When every instance is excluded, or the source cannot identify
+// instances, it behaves like Endpoint and reports an empty instance when
+// unknown.
+func (r *roundRobin) InstanceEndpoint(exclude func(string) bool) (string, endpoint.Endpoint, error) {
+	source, ok := r.source.(endpointer.InstanceEndpointer)
+	if !ok {
+		selected, err := r.Endpoint()
+		return "", selected, err
+	}
+	endpoints, err := source.InstanceEndpoints()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(endpoints) == 0 {
+		return "", nil, sd.ErrNoEndpoints
+	}
+	index := atomic.AddUint64(&r.next, 1) - 1
+	size := uint64(len(endpoints))
+	if exclude != nil {
+		for offset := uint64(0); offset < size; offset++ {
+			candidate := endpoints[(index+offset)%size]
+			if !exclude(candidate.Instance) {
+				return candidate.Instance, candidate.Endpoint, nil
+			}
+		}
+	}
+	selected := endpoints[index%size]
+	return selected.Instance, selected.Endpoint, nil
+}
@@ -95,3 +95,29 @@ func TestRoundRobin_ThreeEndpoints_Cycles(t *testing.T) {
 		t.Errorf("expected all 3 endpoints to be hit, got: %v", seen)
 	}
 }
+
+func TestRoundRobin_InstanceEndpointSkipsExcluded(t *testing.T) {
+	ep := newEndpointer(t, "a:80", "b:80", "c:80")
+	lb, ok := balancer.NewRoundRobin(ep).(sd.InstanceBalancer)
+	if !ok {
+		t.Fatal("round robin does not implement sd.InstanceBalancer")
+	}
+	excluded := map[string]bool{"a:80": true, "b:80": true}
+	for i := 0; i < 3; i++ {
+		instance, e, err := lb.InstanceEndpoint(func(instance string) bool { return excluded[instance] })
+		if err != nil {
+			t.Fatalf("InstanceEndpoint: %v", err)
+		}
+		if instance != "c:80" {
+			t.Fatalf("selected %s, want c:80", instance)
+		}
+		if resp, _ := e(context.Background(), nil); resp != "c:80" {
+			t.Fatalf("endpoint for %s answered %v", instance, resp)
+		}
+	}
+
+	instance, _, err := lb.InstanceEndpoint(func(string) bool { return true })
+	if err != nil || instance == "" {
+		t.Fatalf("all-excluded selection = %q, %v; want normal rotation", instance, err)
+	}
+}
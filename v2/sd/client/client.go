@@ -21,6 +21,10 @@ type Options struct {
 	Timeout           time.Duration
 	InvalidateOnError time.Duration
 	Retryable         retry.Classifier
+	Backoff           retry.Backoff
+	HedgeDelay        time.Duration
+	SubsetClientID    string
+	SubsetSize        int
 }
 
 // Option configures NewEndpoint.
@@ -46,6 +50,26 @@ func WithRetryable(classifier retry.Classifier) Option {
 	return func(options *Options) { options.Retryable = classifier }
 }
 
+// WithBackoff replaces retry.DefaultBackoff between attempts.
+func WithBackoff(backoff retry.Backoff) Option {
+	return func(options *Options) { options.Backoff = backoff }
+}
+
+// WithHedging starts another attempt when earlier attempts are still running
+// after delay. Hedged attempts count toward WithMaxAttempts.
+func WithHedging(delay time.Duration) Option {
+	return func(options *Options) { options.HedgeDelay = delay }
+}
+
+// WithSubset connects to a deterministic subset of size instances chosen by
+// clientID. See endpointer.Subset.
+func WithSubset(clientID string, size int) Option {
+	return func(options *Options) {
+		options.SubsetClientID = clientID
+		options.SubsetSize = size
+	}
+}
+
 // NewEndpoint composes an Endpointer, round-robin Balancer, and retry executor.
 func NewEndpoint(src sd.Instancer, factory endpointer.Factory, logger *slog.Logger, opts ...Option) (endpoint.Endpoint, io.Closer, error) {
 	options := Options{MaxAttempts: 1, Timeout: 500 * time.Millisecond}
@@ -63,12 +87,32 @@ func NewEndpoint(src sd.Instancer, factory endpointer.Factory, logger *slog.Logg
 	if options.InvalidateOnError > 0 {
 		endpointerOptions = append(endpointerOptions, endpointer.InvalidateOnError(options.InvalidateOnError))
 	}
+	if options.SubsetSize > 0 {
+		endpointerOptions = append(endpointerOptions, endpointer.Subset(options.SubsetClientID, options.SubsetSize))
+	}
 	endpointSet := endpointer.NewEndpointer(src, factory, logger, endpointerOptions...)
 	balanced := balancer.NewRoundRobin(endpointSet)
-	call := retry.WithClassifier(options.Timeout, balanced, attemptLimit(options.MaxAttempts), options.Retryable)
+	var retryOptions []retry.Option
+	if options.Backoff != nil {
+		retryOptions = append(retryOptions, retry.WithBackoff(options.Backoff))
+	}
+	if options.HedgeDelay > 0 {
+		retryOptions = append(retryOptions, retry.WithHedging(options.HedgeDelay))
+	}
+	call := retry.WithClassifier(options.Timeout, balanced, attemptLimit(options.MaxAttempts), options.Retryable, retryOptions...)
 	return call, endpointSet, nil
 }
 
+// Snapshot describes the discovery cache behind closer, which must be the
+// io.Closer returned by NewEndpoint.
+func Snapshot(closer io.Closer) (endpointer.Snapshot, error) {
+	snapshotter, ok := closer.(endpointer.Snapshotter)
+	if !ok {
+		return endpointer.Snapshot{}, fmt.Errorf("sd/client: %T does not provide discovery snapshots", closer)
+	}
+	return snapshotter.Snapshot(), nil
+}
+
 // NewEndpointWithDefaults uses one attempt, a 500ms total timeout, and a five
 // second invalidation grace period.
 func NewEndpointWithDefaults(src sd.Instancer, factory endpointer.Factory, logger *slog.Logger) (endpoint.Endpoint, io.Closer, error) {
@@ -93,6 +137,10 @@ func validate(src sd.Instancer, factory endpointer.Factory, logger *slog.Logger,
 		return fmt.Errorf("sd/client: timeout must be greater than zero")
 	case options.InvalidateOnError < 0:
 		return fmt.Errorf("sd/client: invalidate-on-error duration cannot be negative")
+	case options.HedgeDelay < 0:
+		return fmt.Errorf("sd/client: hedge delay cannot be negative")
+	case options.SubsetSize < 0:
+		return fmt.Errorf("sd/client: subset size cannot be negative")
 	default:
 		return nil
 	}
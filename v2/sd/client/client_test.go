@@ -135,6 +135,7 @@ func TestNewEndpoint_RejectsInvalidConfiguration(t *testing.T) {
 		{name: "attempts", src: cache, factory: nopFactory, logger: nopLogger(), opts: []sdclient.Option{sdclient.WithMaxAttempts(0)}, want: "max attempts"},
 		{name: "timeout", src: cache, factory: nopFactory, logger: nopLogger(), opts: []sdclient.Option{sdclient.WithTimeout(0)}, want: "timeout"},
 		{name: "invalidation", src: cache, factory: nopFactory, logger: nopLogger(), opts: []sdclient.Option{sdclient.WithInvalidateOnError(-time.Second)}, want: "invalidate-on-error"},
+		{name: "subset", src: cache, factory: nopFactory, logger: nopLogger(), opts: []sdclient.Option{sdclient.WithSubset("a", -1)}, want: "subset size"},
 		{name: "nil option", src: cache, factory: nopFactory, logger: nopLogger(), opts: []sdclient.Option{nil}, want: "option 0 is nil"},
 	}
 
@@ -181,3 +182,33 @@ func TestNewEndpoint_CloserReleasesFactoryResources(t *testing.T) {
 type closerFunc func() error
 
 func (f closerFunc) Close() error { return f() }
+
+func TestSnapshotReportsPerInstanceCalls(t *testing.T) {
+	cache := instance.NewCache()
+	cache.Update(sd.Event{Instances: []string{"a:80", "b:80"}})
+	ep, closer, err := sdclient.NewEndpoint(cache, endpointer.Factory(nopFactory), nopLogger())
+	if err != nil {
+		t.Fatalf("NewEndpoint: %v", err)
+	}
+	defer closer.Close()
+	for range 3 {
+		if _, err := ep(context.Background(), nil); err != nil {
+			t.Fatalf("call: %v", err)
+		}
+	}
+
+	snapshot, err := sdclient.Snapshot(closer)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	var calls uint64
+	for _, item := range snapshot.Instances {
+		calls += item.Calls
+	}
+	if len(snapshot.Instances) != 2 || calls != 3 {
+		t.Fatalf("snapshot = %+v, want two instances and three calls", snapshot)
+	}
+	if _, err := sdclient.Snapshot(io.NopCloser(nil)); err == nil {
+		t.Fatal("Snapshot accepted a closer that is not an endpointer")
+	}
+}
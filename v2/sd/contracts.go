@@ -35,5 +35,14 @@ type Balancer interface {
 	Endpoint() (endpoint.Endpoint, error)
 }
 
+// InstanceBalancer is a Balancer that reports which instance it selected and
+// can skip instances. Retry executors use it to avoid instances that already
+// failed within the same call. When every instance is excluded, the balancer
+// selects normally rather than failing.
+type InstanceBalancer interface {
+	Balancer
+	InstanceEndpoint(exclude func(instance string) bool) (instance string, e endpoint.Endpoint, err error)
+}
+
 // ErrNoEndpoints indicates that a balancer currently has no endpoint to select.
 var ErrNoEndpoints = errors.New("no endpoints available")
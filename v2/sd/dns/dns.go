@@ -0,0 +1,221 @@
+// Package dns discovers service instances through DNS SRV or A/AAAA records.
+//
+// The Instancer re-resolves on an interval through an injectable Resolver, so
+// tests can substitute a local stand-in for the system resolver.
+package dns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/instance"
+)
+
+const (
+	// DefaultInterval is the delay between resolutions.
+	DefaultInterval = 30 * time.Second
+	// DefaultTimeout bounds a single resolution.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Resolver is the subset of *net.Resolver used by Instancer.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var _ Resolver = (*net.Resolver)(nil)
+
+// Options controls the resolution loop.
+type Options struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Resolver Resolver
+	Logger   *slog.Logger
+}
+
+// Option configures an Instancer constructor.
+type Option func(*Options)
+
+// WithInterval sets the delay between resolutions.
+func WithInterval(interval time.Duration) Option {
+	return func(options *Options) { options.Interval = interval }
+}
+
+// WithTimeout sets the deadline for each resolution.
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *Options) { options.Timeout = timeout }
+}
+
+// WithResolver replaces net.DefaultResolver.
+func WithResolver(resolver Resolver) Option {
+	return func(options *Options) { options.Resolver = resolver }
+}
+
+// WithLogger sets the logger used for resolution failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *Options) { options.Logger = logger }
+}
+
+type lookupFunc func(ctx context.Context, resolver Resolver) ([]string, error)
+
+// Instancer publishes the instances returned by periodic DNS resolution.
+// Resolution failures are published as event errors; consumers keep their
+// previous snapshot for the grace period configured on the endpointer.
+type Instancer struct {
+	lookup   lookupFunc
+	options  Options
+	cache    *instance.Cache
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+var _ sd.Instancer = (*Instancer)(nil)
+
+// NewSRVInstancer resolves the SRV record name, for example
+// "_http._tcp.users.service.internal", and publishes "target:port" instances.
+func NewSRVInstancer(name string, opts ...Option) (*Instancer, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("sd/dns: SRV name cannot be empty")
+	}
+	return newInstancer(func(ctx context.Context, resolver Resolver) ([]string, error) {
+		_, records, err := resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		instances := make([]string, 0, len(records))
+		for _, record := range records {
+			target := strings.TrimSuffix(record.Target, ".")
+			instances = append(instances, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+		}
+		return instances, nil
+	}, opts)
+}
+
+// NewAInstancer resolves the A and AAAA records of host and publishes
+// "address:port" instances.
+func NewAInstancer(host string, port int, opts ...Option) (*Instancer, error) {
+	switch {
+	case strings.TrimSpace(host) == "":
+		return nil, fmt.Errorf("sd/dns: host cannot be empty")
+	case port <= 0 || port > 65535:
+		return nil, fmt.Errorf("sd/dns: port %d is out of range", port)
+	}
+	return newInstancer(func(ctx context.Context, resolver Resolver) ([]string, error) {
+		addrs, err := resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		instances := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			instances = append(instances, net.JoinHostPort(addr, strconv.Itoa(port)))
+		}
+		return instances, nil
+	}, opts)
+}
+
+func newInstancer(lookup lookupFunc, opts []Option) (*Instancer, error) {
+	options := Options{Interval: DefaultInterval, Timeout: DefaultTimeout}
+	for i, option := range opts {
+		if option == nil {
+			return nil, fmt.Errorf("sd/dns: option %d is nil", i)
+		}
+		option(&options)
+	}
+	switch {
+	case options.Interval <= 0:
+		return nil, fmt.Errorf("sd/dns: interval must be greater than zero")
+	case options.Timeout <= 0:
+		return nil, fmt.Errorf("sd/dns: timeout must be greater than zero")
+	}
+	if isNil(options.Resolver) {
+		options.Resolver = net.DefaultResolver
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		lookup:  lookup,
+		options: options,
+		cache:   instance.NewCache(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	s.resolve()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop()
+	}()
+	return s, nil
+}
+
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// Register implements sd.Instancer.
+func (s *Instancer) Register(ch chan sd.Event) sd.Event {
+	return s.cache.Register(ch)
+}
+
+// Deregister implements sd.Instancer.
+func (s *Instancer) Deregister(ch chan sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+// Stop cancels any in-flight resolution and waits for the loop to exit.
+func (s *Instancer) Stop() {
+	s.stopOnce.Do(s.cancel)
+	s.wg.Wait()
+}
+
+func (s *Instancer) loop() {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.resolve()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Instancer) resolve() {
+	ctx, cancel := context.WithTimeout(s.ctx, s.options.Timeout)
+	defer cancel()
+	instances, err := s.lookup(ctx, s.options.Resolver)
+	if s.ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.options.Logger.Debug("dns resolution failed", "err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return
+	}
+	s.options.Logger.Debug("dns instances resolved", "count", len(instances))
+	s.cache.Update(sd.Event{Instances: instances})
+}
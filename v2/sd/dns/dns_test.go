@@ -0,0 +1,124 @@
+package dns_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/dns"
+)
+
+// fakeResolver is a local stand-in for *net.Resolver.
+type fakeResolver struct {
+	mu    sync.Mutex
+	srv   []*net.SRV
+	hosts []string
+	err   error
+}
+
+func (r *fakeResolver) set(srv []*net.SRV, hosts []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.srv, r.hosts, r.err = srv, hosts, err
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if service != "" || proto != "" || name != "_http._tcp.users.internal" {
+		return "", nil, errors.New("unexpected SRV query")
+	}
+	return name, r.srv, r.err
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if host != "users.internal" {
+		return nil, errors.New("unexpected host query")
+	}
+	return r.hosts, r.err
+}
+
+func TestSRVInstancerResolvesOnInterval(t *testing.T) {
+	resolver := &fakeResolver{}
+	resolver.set([]*net.SRV{{Target: "b.users.internal.", Port: 8080}, {Target: "a.users.internal.", Port: 8081}}, nil, nil)
+
+	s, err := dns.NewSRVInstancer("_http._tcp.users.internal",
+		dns.WithResolver(resolver),
+		dns.WithInterval(5*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("NewSRVInstancer: %v", err)
+	}
+	defer s.Stop()
+
+	ch := make(chan sd.Event, 1)
+	initial := s.Register(ch)
+	if got := strings.Join(initial.Instances, ","); got != "a.users.internal:8081,b.users.internal:8080" {
+		t.Fatalf("initial instances = %s", got)
+	}
+
+	sentinel := errors.New("servfail")
+	resolver.set(nil, nil, sentinel)
+	waitFor(t, ch, func(event sd.Event) bool { return errors.Is(event.Err, sentinel) })
+
+	resolver.set([]*net.SRV{{Target: "c.users.internal.", Port: 9000}}, nil, nil)
+	waitFor(t, ch, func(event sd.Event) bool {
+		return strings.Join(event.Instances, ",") == "c.users.internal:9000"
+	})
+}
+
+func TestAInstancerJoinsPort(t *testing.T) {
+	resolver := &fakeResolver{}
+	resolver.set(nil, []string{"10.0.0.2", "fd00::1"}, nil)
+
+	s, err := dns.NewAInstancer("users.internal", 8080, dns.WithResolver(resolver))
+	if err != nil {
+		t.Fatalf("NewAInstancer: %v", err)
+	}
+	defer s.Stop()
+
+	got := s.Register(make(chan sd.Event, 1))
+	if strings.Join(got.Instances, ",") != "10.0.0.2:8080,[fd00::1]:8080" {
+		t.Fatalf("instances = %v", got.Instances)
+	}
+}
+
+func TestConstructorsValidateArguments(t *testing.T) {
+	if _, err := dns.NewSRVInstancer(""); err == nil {
+		t.Fatal("empty SRV name accepted")
+	}
+	if _, err := dns.NewAInstancer("users.internal", 0); err == nil {
+		t.Fatal("zero port accepted")
+	}
+	if _, err := dns.NewAInstancer("users.internal", 80, dns.WithInterval(0)); err == nil {
+		t.Fatal("zero interval accepted")
+	}
+	if _, err := dns.NewAInstancer("users.internal", 80, dns.WithTimeout(-time.Second)); err == nil {
+		t.Fatal("negative timeout accepted")
+	}
+	if _, err := dns.NewAInstancer("users.internal", 80, nil); err == nil {
+		t.Fatal("nil option accepted")
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan sd.Event, match func(sd.Event) bool) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if match(event) {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected event was not published")
+		}
+	}
+}
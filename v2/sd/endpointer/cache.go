@@ -16,10 +16,15 @@ import (
 // when non-nil, is owned by Cache and released when the instance disappears.
 type Factory func(instance string) (endpoint.Endpoint, io.Closer, error)
 
-// Options controls cache invalidation after service-discovery errors.
+// Options controls cache invalidation after service-discovery errors and
+// subsetting.
 type Options struct {
 	InvalidateOnError bool
 	InvalidateTimeout time.Duration
+	// SubsetSize limits the cache to a deterministic subset of this many
+	// instances chosen by SubsetClientID. Zero uses every instance.
+	SubsetSize     int
+	SubsetClientID string
 }
 
 // Option configures an Endpointer.
@@ -34,12 +39,39 @@ func InvalidateOnError(timeout time.Duration) Option {
 	}
 }
 
+// Subset limits endpoints, and therefore connections, to a stable subset of
+// size instances chosen by clientID, such as a pod ordinal or hostname.
+// Clients with different IDs pick different subsets so load stays evenly
+// spread, and subsets are recomputed whenever the instance set changes.
+// Sequential numeric IDs give the most even spread.
+func Subset(clientID string, size int) Option {
+	return func(options *Options) {
+		options.SubsetClientID = clientID
+		options.SubsetSize = size
+	}
+}
+
 // ErrCacheClosed is returned after a Cache has been closed.
 var ErrCacheClosed = errors.New("endpointer cache closed")
 
 type endpointCloser struct {
 	endpoint.Endpoint
 	io.Closer
+	added time.Time
+	stats *instanceStats
+}
+
+// InstanceEndpoint pairs an active endpoint with the discovered instance it
+// was built for.
+type InstanceEndpoint struct {
+	Instance string
+	Endpoint endpoint.Endpoint
+}
+
+// InstanceEndpointer is implemented by Endpointers that can identify the
+// instance behind each endpoint.
+type InstanceEndpointer interface {
+	InstanceEndpoints() ([]InstanceEndpoint, error)
 }
 
 // Cache maps discovered instance addresses to live endpoints.
@@ -49,7 +81,9 @@ type Cache struct {
 	factory            Factory
 	cache              map[string]endpointCloser
 	err                error
-	endpoints          []endpoint.Endpoint
+	lastErr            error
+	lastErrAt          time.Time
+	endpoints          []InstanceEndpoint
 	logger             *slog.Logger
 	invalidateDeadline time.Time
 	timeNow            func() time.Time
@@ -90,6 +124,8 @@ func (c *Cache) Update(event sd.Event) {
 	}
 
 	c.logger.Debug("service discovery update failed", "err", event.Err)
+	c.lastErr = event.Err
+	c.lastErrAt = c.timeNow()
 	if !c.options.InvalidateOnError || c.err != nil {
 		c.mtx.Unlock()
 		return
@@ -102,6 +138,7 @@ func (c *Cache) Update(event sd.Event) {
 func (c *Cache) updateCacheLocked(instances []string) []io.Closer {
 	instances = append([]string(nil), instances...)
 	sort.Strings(instances)
+	instances = subset(instances, c.options.SubsetClientID, c.options.SubsetSize)
 
 	cache := make(map[string]endpointCloser, len(instances))
 	stale := make([]io.Closer, 0, len(c.cache))
@@ -127,7 +164,13 @@ func (c *Cache) updateCacheLocked(instances []string) []io.Closer {
 			}
 			continue
 		}
-		cache[instance] = endpointCloser{Endpoint: service, Closer: closer}
+		stats := &instanceStats{}
+		cache[instance] = endpointCloser{
+			Endpoint: stats.count(service),
+			Closer:   closer,
+			added:    c.timeNow(),
+			stats:    stats,
+		}
 	}
 
 	for _, item := range c.cache {
@@ -136,13 +179,13 @@ func (c *Cache) updateCacheLocked(instances []string) []io.Closer {
 		}
 	}
 
-	endpoints := make([]endpoint.Endpoint, 0, len(cache))
+	endpoints := make([]InstanceEndpoint, 0, len(cache))
 	for _, instance := range instances {
 		item, ok := cache[instance]
 		if !ok {
 			continue
 		}
-		endpoints = append(endpoints, item.Endpoint)
+		endpoints = append(endpoints, InstanceEndpoint{Instance: instance, Endpoint: item.Endpoint})
 	}
 
 	c.endpoints = endpoints
@@ -152,6 +195,20 @@ func (c *Cache) updateCacheLocked(instances []string) []io.Closer {
 
 // Endpoints returns a snapshot of the active endpoints.
 func (c *Cache) Endpoints() ([]endpoint.Endpoint, error) {
+	items, err := c.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]endpoint.Endpoint, len(items))
+	for i, item := range items {
+		endpoints[i] = item.Endpoint
+	}
+	return endpoints, nil
+}
+
+// InstanceEndpoints returns a snapshot of the active endpoints together with
+// their instances, in instance order.
+func (c *Cache) InstanceEndpoints() ([]InstanceEndpoint, error) {
 	c.mtx.RLock()
 	if c.closed {
 		c.mtx.RUnlock()
@@ -159,7 +216,7 @@ func (c *Cache) Endpoints() ([]endpoint.Endpoint, error) {
 	}
 
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
-		endpoints := append([]endpoint.Endpoint(nil), c.endpoints...)
+		endpoints := append([]InstanceEndpoint(nil), c.endpoints...)
 		c.mtx.RUnlock()
 		return endpoints, nil
 	}
@@ -171,7 +228,7 @@ func (c *Cache) Endpoints() ([]endpoint.Endpoint, error) {
 		return nil, ErrCacheClosed
 	}
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
-		endpoints := append([]endpoint.Endpoint(nil), c.endpoints...)
+		endpoints := append([]InstanceEndpoint(nil), c.endpoints...)
 		c.mtx.Unlock()
 		return endpoints, nil
 	}
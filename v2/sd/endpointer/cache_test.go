@@ -164,3 +164,45 @@ func TestCacheCloseReleasesResourcesAndRejectsUpdates(t *testing.T) {
 type closerFunc func() error
 
 func (f closerFunc) Close() error { return f() }
+
+func TestCacheSnapshotReportsInstancesCountsAndErrors(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cache := NewCache(func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		return func(_ context.Context, request any) (any, error) {
+			if request == "fail" {
+				return nil, errors.New("boom")
+			}
+			return instance, nil
+		}, nil, nil
+	}, slog.New(slog.DiscardHandler), Options{InvalidateOnError: true, InvalidateTimeout: time.Minute})
+	cache.timeNow = func() time.Time { return now }
+
+	cache.Update(sd.Event{Instances: []string{"h:1"}})
+	endpoints, _ := cache.Endpoints()
+	_, _ = endpoints[0](context.Background(), "ok")
+	_, _ = endpoints[0](context.Background(), "fail")
+
+	snapshot := cache.Snapshot()
+	want := InstanceSnapshot{Instance: "h:1", AddedAt: now, Calls: 2, Errors: 1}
+	if len(snapshot.Instances) != 1 || snapshot.Instances[0] != want {
+		t.Fatalf("instances = %+v, want %+v", snapshot.Instances, want)
+	}
+	if snapshot.InGracePeriod || snapshot.LastError != "" {
+		t.Fatalf("healthy snapshot = %+v", snapshot)
+	}
+
+	cache.Update(sd.Event{Err: errors.New("consul down")})
+	snapshot = cache.Snapshot()
+	if !snapshot.InGracePeriod || snapshot.LastError != "consul down" || !snapshot.InvalidateDeadline.Equal(now.Add(time.Minute)) {
+		t.Fatalf("failing snapshot = %+v", snapshot)
+	}
+	now = now.Add(2 * time.Minute)
+	if snapshot = cache.Snapshot(); snapshot.InGracePeriod || !snapshot.Invalidated {
+		t.Fatalf("expired snapshot = %+v", snapshot)
+	}
+
+	cache.Update(sd.Event{Instances: []string{"h:1"}})
+	if snapshot = cache.Snapshot(); snapshot.Invalidated || snapshot.LastError != "consul down" {
+		t.Fatalf("recovered snapshot = %+v, want last error kept", snapshot)
+	}
+}
@@ -76,3 +76,8 @@ func (de *DefaultEndpointer) Close() error {
 func (de *DefaultEndpointer) Endpoints() ([]endpoint.Endpoint, error) {
 	return de.cache.Endpoints()
 }
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (de *DefaultEndpointer) InstanceEndpoints() ([]InstanceEndpoint, error) {
+	return de.cache.InstanceEndpoints()
+}
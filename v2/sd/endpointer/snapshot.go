@@ -0,0 +1,91 @@
+package endpointer
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// Snapshot describes a Cache for debugging, for example on an admin page
+// answering why traffic goes to an instance.
+type Snapshot struct {
+	Instances []InstanceSnapshot `json:"instances"`
+	// LastError is the most recent discovery error. It is kept after
+	// discovery recovers; compare LastErrorAt with the instance times.
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+	// InGracePeriod reports that discovery is failing and, with
+	// InvalidateOnError, the endpoints are kept until InvalidateDeadline.
+	InGracePeriod      bool      `json:"in_grace_period"`
+	InvalidateDeadline time.Time `json:"invalidate_deadline,omitzero"`
+	// Invalidated reports that the grace period has passed and the
+	// endpoints are dropped until discovery recovers.
+	Invalidated bool `json:"invalidated"`
+	Closed      bool `json:"closed,omitempty"`
+}
+
+// InstanceSnapshot describes one cached instance. Calls and Errors count
+// every call through its endpoint, including retries and hedges.
+type InstanceSnapshot struct {
+	Instance string    `json:"instance"`
+	AddedAt  time.Time `json:"added_at"`
+	Calls    uint64    `json:"calls"`
+	Errors   uint64    `json:"errors"`
+}
+
+// Snapshotter is implemented by Endpointers that can describe their cache.
+type Snapshotter interface {
+	Snapshot() Snapshot
+}
+
+type instanceStats struct {
+	calls  atomic.Uint64
+	errors atomic.Uint64
+}
+
+func (s *instanceStats) count(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		s.calls.Add(1)
+		response, err := next(ctx, request)
+		if err != nil {
+			s.errors.Add(1)
+		}
+		return response, err
+	}
+}
+
+// Snapshot returns the current cache state.
+func (c *Cache) Snapshot() Snapshot {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	snapshot := Snapshot{
+		Instances:   make([]InstanceSnapshot, 0, len(c.endpoints)),
+		LastErrorAt: c.lastErrAt,
+		Closed:      c.closed,
+	}
+	if c.lastErr != nil {
+		snapshot.LastError = c.lastErr.Error()
+	}
+	if c.err != nil && !c.closed {
+		snapshot.InvalidateDeadline = c.invalidateDeadline
+		snapshot.InGracePeriod = c.timeNow().Before(c.invalidateDeadline)
+		snapshot.Invalidated = !snapshot.InGracePeriod
+	}
+	for _, item := range c.endpoints {
+		cached := c.cache[item.Instance]
+		snapshot.Instances = append(snapshot.Instances, InstanceSnapshot{
+			Instance: item.Instance,
+			AddedAt:  cached.added,
+			Calls:    cached.stats.calls.Load(),
+			Errors:   cached.stats.errors.Load(),
+		})
+	}
+	return snapshot
+}
+
+// Snapshot implements Snapshotter.
+func (de *DefaultEndpointer) Snapshot() Snapshot {
+	return de.cache.Snapshot()
+}
@@ -0,0 +1,38 @@
+package endpointer
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+)
+
+// subset picks the instances a client connects to with the deterministic
+// subsetting algorithm from Google's SRE book. Clients are grouped into
+// rounds of len(instances)/size clients; every client in a round shuffles the
+// sorted instances with the same seed and takes a distinct slice of it, so
+// each round spreads its clients evenly over the fleet. Numeric client IDs
+// are used as-is and spread perfectly when they are sequential; other IDs are
+// hashed and spread statistically.
+func subset(instances []string, clientID string, size int) []string {
+	if size <= 0 || len(instances) <= size {
+		return instances
+	}
+	id, err := strconv.ParseUint(clientID, 10, 64)
+	if err != nil {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(clientID))
+		id = hash.Sum64()
+	}
+	count := uint64(len(instances) / size)
+	round := id / count
+	shuffled := append([]string(nil), instances...)
+	random := rand.New(rand.NewPCG(round, 0))
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	start := int(id%count) * size
+	selected := shuffled[start : start+size]
+	sort.Strings(selected)
+	return selected
+}
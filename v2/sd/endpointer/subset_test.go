@@ -0,0 +1,82 @@
+package endpointer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/sd"
+)
+
+func makeInstances(n int) []string {
+	instances := make([]string, n)
+	for i := range instances {
+		instances[i] = fmt.Sprintf("10.0.0.%d:8080", i+10)
+	}
+	return instances
+}
+
+func TestSubsetSpreadsSequentialClientsEvenly(t *testing.T) {
+	instances := makeInstances(12)
+	connections := map[string]int{}
+	for client := 0; client < 8; client++ {
+		selected := subset(instances, fmt.Sprint(client), 3)
+		if len(selected) != 3 {
+			t.Fatalf("client %d subset = %v, want 3 instances", client, selected)
+		}
+		for _, instance := range selected {
+			connections[instance]++
+		}
+	}
+	for _, instance := range instances {
+		if connections[instance] != 2 {
+			t.Fatalf("connections = %v, want every instance used twice", connections)
+		}
+	}
+}
+
+func TestSubsetIsDeterministic(t *testing.T) {
+	instances := makeInstances(20)
+	first := subset(instances, "users-7f9c", 5)
+	if again := subset(instances, "users-7f9c", 5); !reflect.DeepEqual(first, again) {
+		t.Fatalf("subset changed between calls: %v then %v", first, again)
+	}
+	if all := subset(instances[:4], "users-7f9c", 5); !reflect.DeepEqual(all, instances[:4]) {
+		t.Fatalf("subset of a small fleet = %v, want every instance", all)
+	}
+	if all := subset(instances, "users-7f9c", 0); len(all) != len(instances) {
+		t.Fatalf("zero size subset = %v, want every instance", all)
+	}
+}
+
+func TestCacheSubsetLimitsEndpointsAndRebalances(t *testing.T) {
+	created := map[string]int{}
+	factory := func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		created[instance]++
+		return func(context.Context, any) (any, error) { return instance, nil }, nil, nil
+	}
+	options := Options{}
+	Subset("3", 4)(&options)
+	cache := NewCache(factory, nil, options)
+	defer cache.Close()
+
+	cache.Update(sd.Event{Instances: makeInstances(40)})
+	endpoints, err := cache.InstanceEndpoints()
+	if err != nil || len(endpoints) != 4 || len(created) != 4 {
+		t.Fatalf("endpoints = %d, created = %d, err = %v; want 4", len(endpoints), len(created), err)
+	}
+
+	cache.Update(sd.Event{Instances: makeInstances(41)})
+	endpoints, err = cache.InstanceEndpoints()
+	if err != nil || len(endpoints) != 4 {
+		t.Fatalf("endpoints after change = %d, %v; want 4", len(endpoints), err)
+	}
+	for _, item := range endpoints {
+		if created[item.Instance] != 1 {
+			t.Fatalf("instance %s created %d times", item.Instance, created[item.Instance])
+		}
+	}
+}
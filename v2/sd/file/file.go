@@ -0,0 +1,345 @@
+// Package file discovers service instances from a JSON or YAML file.
+//
+// The Instancer polls the file so it needs no file-system notification
+// dependency. Both formats accept either a bare list of "host:port" strings or
+// an object with an "instances" list:
+//
+//	["10.0.0.1:8080", "10.0.0.2:8080"]
+//
+//	instances:
+//	  - 10.0.0.1:8080
+//	  - 10.0.0.2:8080
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/instance"
+)
+
+// DefaultInterval is the delay between file polls.
+const DefaultInterval = 5 * time.Second
+
+// Format selects the decoder for the instance file.
+type Format int
+
+const (
+	// FormatAuto picks JSON or YAML from the file extension and falls back to
+	// JSON when the extension is unknown.
+	FormatAuto Format = iota
+	// FormatJSON decodes the file as JSON.
+	FormatJSON
+	// FormatYAML decodes the file as a YAML list of scalars.
+	FormatYAML
+)
+
+// Options controls NewInstancer.
+type Options struct {
+	Interval time.Duration
+	Format   Format
+	Logger   *slog.Logger
+}
+
+// Option configures NewInstancer.
+type Option func(*Options)
+
+// WithInterval sets the delay between file polls.
+func WithInterval(interval time.Duration) Option {
+	return func(options *Options) { options.Interval = interval }
+}
+
+// WithFormat overrides extension-based format detection.
+func WithFormat(format Format) Option {
+	return func(options *Options) { options.Format = format }
+}
+
+// WithLogger sets the logger used for read and decode failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *Options) { options.Logger = logger }
+}
+
+// Instancer publishes the instances listed in a file and re-reads it when its
+// content changes. Read and decode failures are published as event errors.
+type Instancer struct {
+	path     string
+	options  Options
+	cache    *instance.Cache
+	last     []byte
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+var _ sd.Instancer = (*Instancer)(nil)
+
+// NewInstancer reads path once, publishes its instances, and starts polling.
+// A missing or malformed file is reported through the event error rather than
+// failing construction, so the file may appear after startup.
+func NewInstancer(path string, opts ...Option) (*Instancer, error) {
+	options := Options{Interval: DefaultInterval}
+	for i, option := range opts {
+		if option == nil {
+			return nil, fmt.Errorf("sd/file: option %d is nil", i)
+		}
+		option(&options)
+	}
+	switch {
+	case strings.TrimSpace(path) == "":
+		return nil, fmt.Errorf("sd/file: path cannot be empty")
+	case options.Interval <= 0:
+		return nil, fmt.Errorf("sd/file: interval must be greater than zero")
+	case options.Format < FormatAuto || options.Format > FormatYAML:
+		return nil, fmt.Errorf("sd/file: unknown format %d", options.Format)
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+	if options.Format == FormatAuto {
+		options.Format = formatForPath(path)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		path:    path,
+		options: options,
+		cache:   instance.NewCache(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	s.poll()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop()
+	}()
+	return s, nil
+}
+
+func formatForPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	default:
+		return FormatJSON
+	}
+}
+
+// Register implements sd.Instancer.
+func (s *Instancer) Register(ch chan sd.Event) sd.Event {
+	return s.cache.Register(ch)
+}
+
+// Deregister implements sd.Instancer.
+func (s *Instancer) Deregister(ch chan sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+// Stop terminates polling and waits for the loop to exit.
+func (s *Instancer) Stop() {
+	s.stopOnce.Do(s.cancel)
+	s.wg.Wait()
+}
+
+func (s *Instancer) loop() {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.poll()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Instancer) poll() {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		s.last = nil
+		s.options.Logger.Debug("read instance file failed", "path", s.path, "err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return
+	}
+	if s.last != nil && bytes.Equal(data, s.last) {
+		return
+	}
+	instances, err := Decode(data, s.options.Format)
+	if err != nil {
+		s.last = nil
+		s.options.Logger.Debug("decode instance file failed", "path", s.path, "err", err)
+		s.cache.Update(sd.Event{Err: err})
+		return
+	}
+	s.last = data
+	s.options.Logger.Debug("instance file loaded", "path", s.path, "count", len(instances))
+	s.cache.Update(sd.Event{Instances: instances})
+}
+
+// Decode parses instance file content in the given format. FormatAuto is
+// treated as JSON.
+func Decode(data []byte, format Format) ([]string, error) {
+	var (
+		instances []string
+		err       error
+	)
+	if format == FormatYAML {
+		instances, err = decodeYAML(data)
+	} else {
+		instances, err = decodeJSON(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if instances == nil {
+		instances = []string{}
+	}
+	for i, addr := range instances {
+		if strings.TrimSpace(addr) == "" {
+			return nil, fmt.Errorf("sd/file: instance %d is empty", i)
+		}
+	}
+	return instances, nil
+}
+
+func decodeJSON(data []byte) ([]string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var instances []string
+		if err := json.Unmarshal(trimmed, &instances); err != nil {
+			return nil, fmt.Errorf("sd/file: decode JSON: %w", err)
+		}
+		return instances, nil
+	}
+	var document struct {
+		Instances []string `json:"instances"`
+	}
+	if err := json.Unmarshal(trimmed, &document); err != nil {
+		return nil, fmt.Errorf("sd/file: decode JSON: %w", err)
+	}
+	return document.Instances, nil
+}
+
+// decodeYAML accepts the subset of YAML needed for instance lists: block
+// sequences of scalars, optionally nested under a top-level "instances" key,
+// and single-line flow sequences. Comments and blank lines are ignored.
+func decodeYAML(data []byte) ([]string, error) {
+	var instances []string
+	inList := true
+	for number, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' && line[0] != '-' {
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				return nil, fmt.Errorf("sd/file: decode YAML line %d: expected key or list item", number+1)
+			}
+			inList = strings.TrimSpace(key) == "instances"
+			value = strings.TrimSpace(value)
+			if inList && value != "" {
+				items, err := yamlFlowSequence(value)
+				if err != nil {
+					return nil, fmt.Errorf("sd/file: decode YAML line %d: %w", number+1, err)
+				}
+				instances = append(instances, items...)
+			}
+			continue
+		}
+		if !inList {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			items, err := yamlFlowSequence(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("sd/file: decode YAML line %d: %w", number+1, err)
+			}
+			instances = append(instances, items...)
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "-") {
+			return nil, fmt.Errorf("sd/file: decode YAML line %d: expected list item", number+1)
+		}
+		item, err := yamlScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")))
+		if err != nil {
+			return nil, fmt.Errorf("sd/file: decode YAML line %d: %w", number+1, err)
+		}
+		instances = append(instances, item)
+	}
+	return instances, nil
+}
+
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func yamlFlowSequence(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("expected a list")
+	}
+	body := strings.TrimSpace(value[1 : len(value)-1])
+	if body == "" {
+		return nil, nil
+	}
+	var items []string
+	for _, part := range strings.Split(body, ",") {
+		item, err := yamlScalar(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func yamlScalar(value string) (string, error) {
+	if value == "" {
+		return "", fmt.Errorf("empty list item")
+	}
+	switch value[0] {
+	case '"':
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", value)
+		}
+		return unquoted, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return "", fmt.Errorf("invalid quoted string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case '[', '{', '&', '*', '!', '|', '>':
+		return "", fmt.Errorf("unsupported YAML value %s", value)
+	}
+	return value, nil
+}
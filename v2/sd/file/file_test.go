@@ -0,0 +1,123 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/file"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func waitForEvent(t *testing.T, ch <-chan sd.Event, match func(sd.Event) bool) sd.Event {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if match(event) {
+				return event
+			}
+		case <-deadline:
+			t.Fatal("expected event was not published")
+		}
+	}
+}
+
+func TestInstancerPublishesFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.json")
+	writeFile(t, path, `{"instances": ["b:80", "a:80"]}`)
+
+	s, err := file.NewInstancer(path, file.WithInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+
+	ch := make(chan sd.Event, 1)
+	initial := s.Register(ch)
+	if strings.Join(initial.Instances, ",") != "a:80,b:80" {
+		t.Fatalf("initial instances = %v", initial.Instances)
+	}
+
+	writeFile(t, path, `["c:80"]`)
+	waitForEvent(t, ch, func(event sd.Event) bool {
+		return strings.Join(event.Instances, ",") == "c:80"
+	})
+
+	writeFile(t, path, `{`)
+	waitForEvent(t, ch, func(event sd.Event) bool { return event.Err != nil })
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	writeFile(t, path, `["d:80"]`)
+	waitForEvent(t, ch, func(event sd.Event) bool {
+		return strings.Join(event.Instances, ",") == "d:80"
+	})
+}
+
+func TestInstancerReportsMissingFile(t *testing.T) {
+	s, err := file.NewInstancer(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+	if event := s.Register(make(chan sd.Event, 1)); event.Err == nil {
+		t.Fatal("missing file did not publish an error")
+	}
+}
+
+func TestNewInstancerValidatesOptions(t *testing.T) {
+	if _, err := file.NewInstancer(" "); err == nil {
+		t.Fatal("empty path accepted")
+	}
+	if _, err := file.NewInstancer("x.json", file.WithInterval(0)); err == nil {
+		t.Fatal("zero interval accepted")
+	}
+	if _, err := file.NewInstancer("x.json", nil); err == nil {
+		t.Fatal("nil option accepted")
+	}
+	if _, err := file.NewInstancer("x.json", file.WithFormat(file.Format(9))); err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "sequence", in: "- a:80\n- 'b:80' # comment\n", want: "a:80,b:80"},
+		{name: "instances key", in: "# fleet\nservice: users\ninstances:\n  - \"a:80\"\n  - b:80\nother:\n  - ignored\n", want: "a:80,b:80"},
+		{name: "flow sequence", in: "instances: [a:80, b:80]\n", want: "a:80,b:80"},
+		{name: "empty", in: "instances: []\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := file.Decode([]byte(tt.in), file.FormatYAML)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Fatalf("Decode = %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"instances:\n  key: value\n", "- \n", "- &anchor a:80\n"} {
+		if _, err := file.Decode([]byte(bad), file.FormatYAML); err == nil {
+			t.Errorf("Decode(%q) returned nil error", bad)
+		}
+	}
+}
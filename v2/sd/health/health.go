@@ -0,0 +1,397 @@
+// Package health filters service-discovery snapshots through active probes.
+//
+// Instancer decorates any sd.Instancer, probes every discovered instance on an
+// interval, and publishes only the instances that pass. It gives in-memory,
+// static, and file-based discovery the health filtering that registries such
+// as Consul provide on their own.
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/instance"
+)
+
+const (
+	// DefaultInterval is the delay between probe rounds.
+	DefaultInterval = 10 * time.Second
+	// DefaultTimeout bounds a single probe.
+	DefaultTimeout = 2 * time.Second
+	// DefaultHealthyThreshold is the number of consecutive passes that
+	// restores an instance previously marked unhealthy.
+	DefaultHealthyThreshold = 1
+	// DefaultUnhealthyThreshold is the number of consecutive failures that
+	// removes a healthy instance from the published snapshot.
+	DefaultUnhealthyThreshold = 2
+	// DefaultHTTPPath is the readiness route served by kit.Service.
+	DefaultHTTPPath = "/readyz"
+)
+
+// Checker probes one discovered instance. A nil error means the instance is
+// healthy. Implementations must stop promptly when ctx is canceled.
+type Checker interface {
+	Check(ctx context.Context, instance string) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context, instance string) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context, instance string) error {
+	return f(ctx, instance)
+}
+
+// HTTPChecker probes an instance with an HTTP GET and accepts any 2xx status.
+// The zero value requests http://<instance>/readyz with http.DefaultClient.
+type HTTPChecker struct {
+	Client *http.Client
+	Scheme string
+	Path   string
+}
+
+// Check implements Checker.
+func (c HTTPChecker) Check(ctx context.Context, instance string) error {
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := c.Path
+	if path == "" {
+		path = DefaultHTTPPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+instance+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sd/health: %s returned status %d", instance, resp.StatusCode)
+	}
+	return nil
+}
+
+// TCPChecker probes an instance by opening and closing a TCP connection.
+// A nil Dialer uses a zero net.Dialer.
+type TCPChecker struct {
+	Dialer *net.Dialer
+}
+
+// Check implements Checker.
+func (c TCPChecker) Check(ctx context.Context, instance string) error {
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", instance)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Options controls probing and state transitions.
+type Options struct {
+	Interval           time.Duration
+	Timeout            time.Duration
+	HealthyThreshold   int
+	UnhealthyThreshold int
+	Logger             *slog.Logger
+}
+
+// Option configures NewInstancer.
+type Option func(*Options)
+
+// WithInterval sets the delay between probe rounds.
+func WithInterval(interval time.Duration) Option {
+	return func(options *Options) { options.Interval = interval }
+}
+
+// WithTimeout sets the deadline for each probe.
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *Options) { options.Timeout = timeout }
+}
+
+// WithHealthyThreshold sets the consecutive passes needed to restore an
+// unhealthy instance.
+func WithHealthyThreshold(threshold int) Option {
+	return func(options *Options) { options.HealthyThreshold = threshold }
+}
+
+// WithUnhealthyThreshold sets the consecutive failures needed to remove a
+// healthy instance.
+func WithUnhealthyThreshold(threshold int) Option {
+	return func(options *Options) { options.UnhealthyThreshold = threshold }
+}
+
+// WithLogger sets the logger used for probe transitions.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *Options) { options.Logger = logger }
+}
+
+type instanceState struct {
+	probed    bool
+	healthy   bool
+	successes int
+	failures  int
+}
+
+// Instancer publishes the healthy subset of another Instancer's snapshot.
+//
+// A newly discovered instance is published as soon as its first probe passes.
+// After that, UnhealthyThreshold consecutive failures remove it and
+// HealthyThreshold consecutive passes restore it. Discovery errors from the
+// source are forwarded unchanged so consumers keep their grace-period
+// behavior. Stop must be called to release the source subscription.
+type Instancer struct {
+	src      sd.Instancer
+	checker  Checker
+	options  Options
+	cache    *instance.Cache
+	ch       chan sd.Event
+	states   map[string]*instanceState
+	srcErr   error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+var _ sd.Instancer = (*Instancer)(nil)
+
+// NewInstancer subscribes to src and probes its instances with checker. The
+// first probe round runs before NewInstancer returns, so Register immediately
+// observes a filtered snapshot.
+func NewInstancer(src sd.Instancer, checker Checker, opts ...Option) (*Instancer, error) {
+	options := Options{
+		Interval:           DefaultInterval,
+		Timeout:            DefaultTimeout,
+		HealthyThreshold:   DefaultHealthyThreshold,
+		UnhealthyThreshold: DefaultUnhealthyThreshold,
+	}
+	for i, option := range opts {
+		if option == nil {
+			return nil, fmt.Errorf("sd/health: option %d is nil", i)
+		}
+		option(&options)
+	}
+	if err := validate(src, checker, options); err != nil {
+		return nil, err
+	}
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		src:     src,
+		checker: checker,
+		options: options,
+		cache:   instance.NewCache(),
+		ch:      make(chan sd.Event, 1),
+		states:  map[string]*instanceState{},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	s.update(src.Register(s.ch))
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop()
+	}()
+	return s, nil
+}
+
+func validate(src sd.Instancer, checker Checker, options Options) error {
+	switch {
+	case isNil(src):
+		return fmt.Errorf("sd/health: instancer is nil")
+	case isNil(checker):
+		return fmt.Errorf("sd/health: checker is nil")
+	case options.Interval <= 0:
+		return fmt.Errorf("sd/health: interval must be greater than zero")
+	case options.Timeout <= 0:
+		return fmt.Errorf("sd/health: timeout must be greater than zero")
+	case options.HealthyThreshold < 1:
+		return fmt.Errorf("sd/health: healthy threshold must be at least 1")
+	case options.UnhealthyThreshold < 1:
+		return fmt.Errorf("sd/health: unhealthy threshold must be at least 1")
+	default:
+		return nil
+	}
+}
+
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// Register implements sd.Instancer.
+func (s *Instancer) Register(ch chan sd.Event) sd.Event {
+	return s.cache.Register(ch)
+}
+
+// Deregister implements sd.Instancer.
+func (s *Instancer) Deregister(ch chan sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+// Stop cancels in-flight probes, waits for the probe loop, and deregisters
+// from the source Instancer.
+func (s *Instancer) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+		s.src.Deregister(s.ch)
+	})
+}
+
+func (s *Instancer) loop() {
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-s.ch:
+			if !ok {
+				return
+			}
+			s.update(event)
+		case <-ticker.C:
+			s.probe(s.instances())
+			s.publish()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// update reconciles tracked instances with a source event and probes the
+// instances that have not been checked yet.
+func (s *Instancer) update(event sd.Event) {
+	if event.Err != nil {
+		s.srcErr = event.Err
+		s.publish()
+		return
+	}
+	s.srcErr = nil
+	current := make(map[string]struct{}, len(event.Instances))
+	var fresh []string
+	for _, addr := range event.Instances {
+		current[addr] = struct{}{}
+		if _, ok := s.states[addr]; !ok {
+			s.states[addr] = &instanceState{}
+			fresh = append(fresh, addr)
+		}
+	}
+	for addr := range s.states {
+		if _, ok := current[addr]; !ok {
+			delete(s.states, addr)
+		}
+	}
+	s.probe(fresh)
+	s.publish()
+}
+
+func (s *Instancer) instances() []string {
+	out := make([]string, 0, len(s.states))
+	for addr := range s.states {
+		out = append(out, addr)
+	}
+	return out
+}
+
+func (s *Instancer) probe(instances []string) {
+	if len(instances) == 0 {
+		return
+	}
+	results := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, addr := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(s.ctx, s.options.Timeout)
+			defer cancel()
+			results[i] = s.checker.Check(ctx, addr)
+		}()
+	}
+	wg.Wait()
+	if s.ctx.Err() != nil {
+		return
+	}
+	for i, addr := range instances {
+		if state, ok := s.states[addr]; ok {
+			s.record(addr, state, results[i])
+		}
+	}
+}
+
+func (s *Instancer) record(addr string, state *instanceState, err error) {
+	first := !state.probed
+	state.probed = true
+	if err == nil {
+		state.failures = 0
+		state.successes++
+		if !state.healthy && (first || state.successes >= s.options.HealthyThreshold) {
+			state.healthy = true
+			s.options.Logger.Debug("instance healthy", "instance", addr)
+		}
+		return
+	}
+	state.successes = 0
+	state.failures++
+	if first {
+		s.options.Logger.Debug("instance probe failed", "instance", addr, "err", err)
+		return
+	}
+	if state.healthy && state.failures >= s.options.UnhealthyThreshold {
+		state.healthy = false
+		s.options.Logger.Debug("instance unhealthy", "instance", addr, "err", err)
+	}
+}
+
+func (s *Instancer) publish() {
+	if s.srcErr != nil {
+		s.cache.Update(sd.Event{Err: s.srcErr})
+		return
+	}
+	healthy := make([]string, 0, len(s.states))
+	for addr, state := range s.states {
+		if state.healthy {
+			healthy = append(healthy, addr)
+		}
+	}
+	sort.Strings(healthy)
+	s.cache.Update(sd.Event{Instances: healthy})
+}
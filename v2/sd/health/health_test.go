@@ -0,0 +1,294 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/health"
+	"github.com/dreamsxin/go-kit/v2/sd/instance"
+)
+
+// fakeChecker reports per-instance results that tests can flip at runtime.
+type fakeChecker struct {
+	mu      sync.Mutex
+	failing map[string]bool
+}
+
+func newFakeChecker(failing ...string) *fakeChecker {
+	c := &fakeChecker{failing: map[string]bool{}}
+	for _, addr := range failing {
+		c.failing[addr] = true
+	}
+	return c
+}
+
+func (c *fakeChecker) set(addr string, failing bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failing[addr] = failing
+}
+
+func (c *fakeChecker) Check(_ context.Context, addr string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing[addr] {
+		return errors.New("probe failed")
+	}
+	return nil
+}
+
+func waitForInstances(t *testing.T, ch <-chan sd.Event, want ...string) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if strings.Join(event.Instances, ",") == strings.Join(want, ",") && event.Err == nil {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("did not observe instances %v", want)
+		}
+	}
+}
+
+func TestInstancerPublishesOnlyHealthyInstances(t *testing.T) {
+	src := instance.NewCache()
+	src.Update(sd.Event{Instances: []string{"a:80", "b:80", "c:80"}})
+	checker := newFakeChecker("b:80")
+
+	s, err := health.NewInstancer(src, checker, health.WithInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+
+	got := s.Register(make(chan sd.Event, 1))
+	if strings.Join(got.Instances, ",") != "a:80,c:80" {
+		t.Fatalf("initial instances = %v, want [a:80 c:80]", got.Instances)
+	}
+}
+
+func TestInstancerProbesNewlyDiscoveredInstances(t *testing.T) {
+	src := instance.NewCache()
+	src.Update(sd.Event{Instances: []string{"a:80"}})
+	checker := newFakeChecker("bad:80")
+
+	s, err := health.NewInstancer(src, checker, health.WithInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+	ch := make(chan sd.Event, 1)
+	s.Register(ch)
+
+	src.Update(sd.Event{Instances: []string{"a:80", "bad:80", "new:80"}})
+	waitForInstances(t, ch, "a:80", "new:80")
+
+	src.Update(sd.Event{Instances: []string{"new:80"}})
+	waitForInstances(t, ch, "new:80")
+}
+
+// stepChecker passes the first probe and then blocks every probe until the
+// test supplies its result, so tests control each probe round.
+type stepChecker struct {
+	mu      sync.Mutex
+	calls   int
+	entered chan struct{}
+	results chan error
+}
+
+func (c *stepChecker) Check(ctx context.Context, _ string) error {
+	c.mu.Lock()
+	c.calls++
+	first := c.calls == 1
+	c.mu.Unlock()
+	if first {
+		return nil
+	}
+	select {
+	case c.entered <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-c.results:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestInstancerAppliesThresholds(t *testing.T) {
+	src := instance.NewCache()
+	src.Update(sd.Event{Instances: []string{"a:80"}})
+	checker := &stepChecker{entered: make(chan struct{}), results: make(chan error)}
+
+	s, err := health.NewInstancer(src, checker,
+		health.WithInterval(time.Millisecond),
+		health.WithUnhealthyThreshold(3),
+		health.WithHealthyThreshold(2),
+	)
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+
+	current := func() string {
+		ch := make(chan sd.Event, 1)
+		defer s.Deregister(ch)
+		return strings.Join(s.Register(ch).Instances, ",")
+	}
+	// round answers one probe and waits for the next round to start, which
+	// happens only after the answered round has been published.
+	round := func(err error) string {
+		t.Helper()
+		checker.results <- err
+		select {
+		case <-checker.entered:
+		case <-time.After(time.Second):
+			t.Fatal("next probe round did not start")
+		}
+		return current()
+	}
+	fail := errors.New("probe failed")
+
+	if got := current(); got != "a:80" {
+		t.Fatalf("initial instances = %q, want a:80", got)
+	}
+	<-checker.entered
+	for i := 1; i < 3; i++ {
+		if got := round(fail); got != "a:80" {
+			t.Fatalf("after %d failures instances = %q, want a:80", i, got)
+		}
+	}
+	if got := round(fail); got != "" {
+		t.Fatalf("after 3 failures instances = %q, want none", got)
+	}
+	if got := round(nil); got != "" {
+		t.Fatalf("after 1 pass instances = %q, want none", got)
+	}
+	if got := round(nil); got != "a:80" {
+		t.Fatalf("after 2 passes instances = %q, want a:80", got)
+	}
+}
+
+func TestInstancerForwardsSourceErrors(t *testing.T) {
+	src := instance.NewCache()
+	src.Update(sd.Event{Instances: []string{"a:80"}})
+
+	s, err := health.NewInstancer(src, newFakeChecker(), health.WithInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer s.Stop()
+	ch := make(chan sd.Event, 1)
+	s.Register(ch)
+
+	sentinel := errors.New("discovery down")
+	src.Update(sd.Event{Err: sentinel})
+	select {
+	case event := <-ch:
+		if !errors.Is(event.Err, sentinel) {
+			t.Fatalf("event error = %v, want %v", event.Err, sentinel)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("source error was not forwarded")
+	}
+
+	src.Update(sd.Event{Instances: []string{"a:80"}})
+	waitForInstances(t, ch, "a:80")
+}
+
+func TestInstancerStopDeregistersFromSource(t *testing.T) {
+	src := &countingInstancer{Cache: instance.NewCache()}
+	s, err := health.NewInstancer(src, newFakeChecker())
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	s.Stop()
+	s.Stop()
+	if src.deregistered != 1 {
+		t.Fatalf("Deregister calls = %d, want 1", src.deregistered)
+	}
+}
+
+type countingInstancer struct {
+	*instance.Cache
+	deregistered int
+}
+
+func (c *countingInstancer) Deregister(ch chan sd.Event) {
+	c.deregistered++
+	c.Cache.Deregister(ch)
+}
+
+func TestNewInstancerValidatesOptions(t *testing.T) {
+	src := instance.NewCache()
+	tests := []struct {
+		name    string
+		src     sd.Instancer
+		checker health.Checker
+		opts    []health.Option
+	}{
+		{name: "nil source", checker: newFakeChecker()},
+		{name: "nil checker", src: src},
+		{name: "nil option", src: src, checker: newFakeChecker(), opts: []health.Option{nil}},
+		{name: "zero interval", src: src, checker: newFakeChecker(), opts: []health.Option{health.WithInterval(0)}},
+		{name: "zero timeout", src: src, checker: newFakeChecker(), opts: []health.Option{health.WithTimeout(0)}},
+		{name: "zero healthy threshold", src: src, checker: newFakeChecker(), opts: []health.Option{health.WithHealthyThreshold(0)}},
+		{name: "zero unhealthy threshold", src: src, checker: newFakeChecker(), opts: []health.Option{health.WithUnhealthyThreshold(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := health.NewInstancer(tt.src, tt.checker, tt.opts...); err == nil {
+				t.Fatal("NewInstancer returned nil error")
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerUsesReadinessRoute(t *testing.T) {
+	paths := make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	if err := (health.HTTPChecker{}).Check(context.Background(), addr); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if path := <-paths; path != health.DefaultHTTPPath {
+		t.Fatalf("probe path = %q, want %q", path, health.DefaultHTTPPath)
+	}
+	if err := (health.HTTPChecker{Path: "/readyz?fail=1"}).Check(context.Background(), addr); err == nil {
+		t.Fatal("Check accepted a 503 response")
+	}
+}
+
+func TestTCPChecker(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	if err := (health.TCPChecker{}).Check(context.Background(), addr); err != nil {
+		t.Fatalf("Check open port: %v", err)
+	}
+	listener.Close()
+	if err := (health.TCPChecker{}).Check(context.Background(), addr); err == nil {
+		t.Fatal("Check accepted a closed port")
+	}
+}
@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotRegistered is returned by Heartbeat when the server no longer knows
+// the registration, for example after its TTL expired or the server restarted.
+var ErrNotRegistered = errors.New("sd/registry: registration not found")
+
+// Registration describes one service instance.
+type Registration struct {
+	Service string
+	ID      string
+	Address string
+	// TTL is the lease renewed by heartbeats. Zero uses the server default.
+	TTL time.Duration
+}
+
+// ClientOption configures NewClient.
+type ClientOption func(*Client)
+
+// ClientHTTPClient replaces http.DefaultClient. Long-poll requests need a
+// client without a short overall timeout.
+func ClientHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) { c.http = client }
+}
+
+// Client calls the registry HTTP API.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient creates a client for the registry at baseURL, for example
+// "http://127.0.0.1:8500".
+func NewClient(baseURL string, options ...ClientOption) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("sd/registry: invalid base URL %q", baseURL)
+	}
+	c := &Client{base: strings.TrimSuffix(parsed.String(), "/"), http: http.DefaultClient}
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("sd/registry: client option %d is nil", i)
+		}
+		option(c)
+	}
+	if c.http == nil {
+		return nil, fmt.Errorf("sd/registry: HTTP client is nil")
+	}
+	return c, nil
+}
+
+// Register creates or replaces a registration.
+func (c *Client) Register(ctx context.Context, registration Registration) error {
+	body := registrationBody{Address: registration.Address}
+	if registration.TTL > 0 {
+		body.TTL = registration.TTL.String()
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, http.MethodPut, c.instanceURL(registration.Service, registration.ID), payload, nil)
+	return err
+}
+
+// Heartbeat renews the TTL of an existing registration. It returns
+// ErrNotRegistered when the registration must be created again.
+func (c *Client) Heartbeat(ctx context.Context, service, id string) error {
+	_, err := c.do(ctx, http.MethodPut, c.instanceURL(service, id)+"/heartbeat", nil, ErrNotRegistered)
+	return err
+}
+
+// Deregister removes a registration. Removing an unknown registration succeeds.
+func (c *Client) Deregister(ctx context.Context, service, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, c.instanceURL(service, id), nil, ErrNotRegistered)
+	if errors.Is(err, ErrNotRegistered) {
+		return nil
+	}
+	return err
+}
+
+// Instances returns the current snapshot of service. When index is non-zero
+// the server blocks for up to wait while the snapshot index still equals it.
+func (c *Client) Instances(ctx context.Context, service string, index uint64, wait time.Duration) (Snapshot, error) {
+	target := c.base + "/v1/services/" + url.PathEscape(service)
+	if index > 0 {
+		query := url.Values{}
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", wait.String())
+		target += "?" + query.Encode()
+	}
+	payload, err := c.do(ctx, http.MethodGet, target, nil, nil)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(payload, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("sd/registry: decode snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+func (c *Client) instanceURL(service, id string) string {
+	return c.base + "/v1/services/" + url.PathEscape(service) + "/" + url.PathEscape(id)
+}
+
+// do sends one request. A 404 response returns notFound when it is non-nil
+// and is reported like any other failed status otherwise.
+func (c *Client) do(ctx context.Context, method, target string, body []byte, notFound error) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && notFound != nil:
+		return nil, notFound
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		var problem struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(payload, &problem) == nil && problem.Error != "" {
+			return nil, fmt.Errorf("sd/registry: %s %s: %s", method, resp.Status, problem.Error)
+		}
+		return nil, fmt.Errorf("sd/registry: %s %s", method, resp.Status)
+	}
+	return payload, nil
+}
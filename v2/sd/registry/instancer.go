@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/instance"
+)
+
+// Instancer watches one service through long-poll requests and implements
+// sd.Instancer. Stop must be called to end the watch.
+type Instancer struct {
+	client   *Client
+	service  string
+	wait     time.Duration
+	logger   *slog.Logger
+	cache    *instance.Cache
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+var _ sd.Instancer = (*Instancer)(nil)
+
+// InstancerOption configures NewInstancer.
+type InstancerOption func(*Instancer)
+
+// InstancerWait sets the long-poll duration of each watch request.
+func InstancerWait(wait time.Duration) InstancerOption {
+	return func(s *Instancer) { s.wait = wait }
+}
+
+// NewInstancer loads the current snapshot of service and keeps it updated in
+// the background.
+func NewInstancer(client *Client, logger *slog.Logger, service string, options ...InstancerOption) (*Instancer, error) {
+	if client == nil {
+		return nil, fmt.Errorf("sd/registry: client is nil")
+	}
+	if strings.TrimSpace(service) == "" {
+		return nil, fmt.Errorf("sd/registry: service name cannot be empty")
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		client:  client,
+		service: service,
+		wait:    DefaultWait,
+		logger:  logger,
+		cache:   instance.NewCache(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for i, option := range options {
+		if option == nil {
+			cancel()
+			return nil, fmt.Errorf("sd/registry: instancer option %d is nil", i)
+		}
+		option(s)
+	}
+	if s.wait <= 0 {
+		cancel()
+		return nil, fmt.Errorf("sd/registry: wait must be greater than zero")
+	}
+
+	snapshot, err := client.Instances(ctx, service, 0, 0)
+	if err != nil {
+		s.logger.Debug("registry initial query failed", "service", service, "err", err)
+		s.cache.Update(sd.Event{Err: err})
+	} else {
+		s.cache.Update(sd.Event{Instances: snapshot.Instances})
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(snapshot.Index)
+	}()
+	return s, nil
+}
+
+// Register implements sd.Instancer.
+func (s *Instancer) Register(ch chan sd.Event) sd.Event {
+	return s.cache.Register(ch)
+}
+
+// Deregister implements sd.Instancer.
+func (s *Instancer) Deregister(ch chan sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+// Stop cancels the active watch request and waits for the loop to exit.
+func (s *Instancer) Stop() {
+	s.stopOnce.Do(s.cancel)
+	s.wg.Wait()
+}
+
+func (s *Instancer) loop(index uint64) {
+	delay := 10 * time.Millisecond
+	for {
+		snapshot, err := s.client.Instances(s.ctx, s.service, index, s.wait)
+		switch {
+		case s.ctx.Err() != nil:
+			return
+		case err != nil:
+			s.logger.Debug("registry watch failed", "service", s.service, "err", err, "retry_after", delay)
+			s.cache.Update(sd.Event{Err: err})
+			if !waitForRetry(s.ctx, delay) {
+				return
+			}
+			delay = nextDelay(delay)
+			index = 0
+		case snapshot.Index < index:
+			// The server restarted and its index sequence began again.
+			s.logger.Debug("registry index regressed", "index", snapshot.Index, "previous", index)
+			index = snapshot.Index
+			s.cache.Update(sd.Event{Instances: snapshot.Instances})
+		default:
+			index = snapshot.Index
+			s.cache.Update(sd.Event{Instances: snapshot.Instances})
+			delay = 10 * time.Millisecond
+		}
+	}
+}
+
+func waitForRetry(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func nextDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	delay = time.Duration(float64(delay) * (rand.Float64() + 0.5))
+	if delay > time.Minute {
+		return time.Minute
+	}
+	return delay
+}
@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+)
+
+// Registrar registers one instance and keeps its TTL alive with background
+// heartbeats. It implements sd.Registrar.
+type Registrar struct {
+	client       *Client
+	registration Registration
+	interval     time.Duration
+	timeout      time.Duration
+	logger       *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ sd.Registrar = (*Registrar)(nil)
+
+// RegistrarOption configures NewRegistrar.
+type RegistrarOption func(*Registrar)
+
+// RegistrarHeartbeatInterval overrides the heartbeat interval, which defaults
+// to one third of the registration TTL.
+func RegistrarHeartbeatInterval(interval time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.interval = interval }
+}
+
+// RegistrarTimeout bounds each registry request. The default is 5 seconds.
+func RegistrarTimeout(timeout time.Duration) RegistrarOption {
+	return func(r *Registrar) { r.timeout = timeout }
+}
+
+// NewRegistrar validates registration and returns an unregistered Registrar.
+// A zero TTL uses DefaultTTL so the heartbeat interval matches the lease.
+func NewRegistrar(client *Client, logger *slog.Logger, registration Registration, options ...RegistrarOption) (*Registrar, error) {
+	switch {
+	case client == nil:
+		return nil, fmt.Errorf("sd/registry: client is nil")
+	case strings.TrimSpace(registration.Service) == "":
+		return nil, fmt.Errorf("sd/registry: service name cannot be empty")
+	case strings.TrimSpace(registration.ID) == "":
+		return nil, fmt.Errorf("sd/registry: instance ID cannot be empty")
+	case registration.TTL < 0:
+		return nil, fmt.Errorf("sd/registry: TTL cannot be negative")
+	}
+	if _, _, err := net.SplitHostPort(registration.Address); err != nil {
+		return nil, fmt.Errorf("sd/registry: address must be host:port: %w", err)
+	}
+	if registration.TTL == 0 {
+		registration.TTL = DefaultTTL
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	r := &Registrar{
+		client:       client,
+		registration: registration,
+		interval:     registration.TTL / 3,
+		timeout:      5 * time.Second,
+		logger:       logger,
+	}
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("sd/registry: registrar option %d is nil", i)
+		}
+		option(r)
+	}
+	if r.interval <= 0 || r.timeout <= 0 {
+		return nil, fmt.Errorf("sd/registry: heartbeat interval and timeout must be greater than zero")
+	}
+	return r, nil
+}
+
+// Register creates the registration synchronously and starts heartbeats.
+// Calling Register while already registered is a no-op.
+func (r *Registrar) Register() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return nil
+	}
+	if err := r.register(context.Background()); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.heartbeat(ctx, r.done)
+	r.logger.Info("registry instance registered", "service", r.registration.Service, "id", r.registration.ID)
+	return nil
+}
+
+// Deregister stops heartbeats and removes the registration.
+func (r *Registrar) Deregister() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		<-r.done
+		r.cancel = nil
+		r.done = nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if err := r.client.Deregister(ctx, r.registration.Service, r.registration.ID); err != nil {
+		return err
+	}
+	r.logger.Info("registry instance deregistered", "service", r.registration.Service, "id", r.registration.ID)
+	return nil
+}
+
+func (r *Registrar) register(parent context.Context) error {
+	ctx, cancel := context.WithTimeout(parent, r.timeout)
+	defer cancel()
+	return r.client.Register(ctx, r.registration)
+}
+
+// heartbeat renews the lease and re-registers when the server has forgotten
+// the instance, which happens after a registry restart or a missed TTL.
+func (r *Registrar) heartbeat(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		hbCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		err := r.client.Heartbeat(hbCtx, r.registration.Service, r.registration.ID)
+		cancel()
+		if errors.Is(err, ErrNotRegistered) {
+			r.logger.Warn("registry lost registration; re-registering", "service", r.registration.Service, "id", r.registration.ID)
+			err = r.register(ctx)
+		}
+		if err != nil && ctx.Err() == nil {
+			r.logger.Warn("registry heartbeat failed", "service", r.registration.Service, "id", r.registration.ID, "err", err)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package registry_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/sd"
+	"github.com/dreamsxin/go-kit/v2/sd/registry"
+)
+
+func newTestRegistry(t *testing.T, options ...registry.ServerOption) (*registry.Server, *registry.Client) {
+	t.Helper()
+	srv, err := registry.NewServer("", options...)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	httpServer := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		_ = srv.Shutdown(context.Background())
+		httpServer.Close()
+	})
+	client, err := registry.NewClient(httpServer.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return srv, client
+}
+
+func waitForInstances(t *testing.T, ch <-chan sd.Event, want string) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-ch:
+			if event.Err == nil && strings.Join(event.Instances, ",") == want {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("did not observe instances %q", want)
+		}
+	}
+}
+
+func TestInstancerFollowsRegistrations(t *testing.T) {
+	_, client := newTestRegistry(t)
+	ctx := context.Background()
+	if err := client.Register(ctx, registry.Registration{Service: "users", ID: "a", Address: "10.0.0.1:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	instancer, err := registry.NewInstancer(client, nil, "users", registry.InstancerWait(time.Second))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer instancer.Stop()
+	ch := make(chan sd.Event, 1)
+	if initial := instancer.Register(ch); strings.Join(initial.Instances, ",") != "10.0.0.1:80" {
+		t.Fatalf("initial instances = %v", initial.Instances)
+	}
+
+	if err := client.Register(ctx, registry.Registration{Service: "users", ID: "b", Address: "10.0.0.2:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	waitForInstances(t, ch, "10.0.0.1:80,10.0.0.2:80")
+
+	if err := client.Deregister(ctx, "users", "a"); err != nil {
+		t.Fatalf("Deregister: %v", err)
+	}
+	waitForInstances(t, ch, "10.0.0.2:80")
+}
+
+func TestInstancerFollowsServerRestart(t *testing.T) {
+	startServer := func() *registry.Server {
+		srv, err := registry.NewServer("")
+		if err != nil {
+			t.Fatalf("NewServer: %v", err)
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+		return srv
+	}
+	var handler atomic.Pointer[http.Handler]
+	setHandler := func(srv *registry.Server) {
+		h := srv.Handler()
+		handler.Store(&h)
+	}
+	old := startServer()
+	setHandler(old)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*handler.Load()).ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+	client, err := registry.NewClient(httpServer.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := client.Register(ctx, registry.Registration{Service: "users", ID: id, Address: "10.0.0.1:80"}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	instancer, err := registry.NewInstancer(client, nil, "users", registry.InstancerWait(time.Minute))
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer instancer.Stop()
+	ch := make(chan sd.Event, 1)
+	instancer.Register(ch)
+
+	// The restarted server's index starts again below the one the
+	// instancer is watching with; its watch must not wait for it to catch up.
+	setHandler(startServer())
+	_ = old.Shutdown(ctx)
+	if err := client.Register(ctx, registry.Registration{Service: "users", ID: "d", Address: "10.0.0.4:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	waitForInstances(t, ch, "10.0.0.4:80")
+}
+
+func TestClientMapsNotFoundOnlyForRegistrations(t *testing.T) {
+	httpServer := httptest.NewServer(http.NotFoundHandler())
+	defer httpServer.Close()
+	client, err := registry.NewClient(httpServer.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+	if err := client.Heartbeat(ctx, "users", "a"); !errors.Is(err, registry.ErrNotRegistered) {
+		t.Fatalf("Heartbeat error = %v, want ErrNotRegistered", err)
+	}
+	if err := client.Deregister(ctx, "users", "a"); err != nil {
+		t.Fatalf("Deregister error = %v, want nil", err)
+	}
+	if _, err := client.Instances(ctx, "users", 0, 0); err == nil || errors.Is(err, registry.ErrNotRegistered) {
+		t.Fatalf("Instances error = %v, want a status error", err)
+	}
+	err = client.Register(ctx, registry.Registration{Service: "users", ID: "a", Address: "10.0.0.1:80"})
+	if err == nil || errors.Is(err, registry.ErrNotRegistered) {
+		t.Fatalf("Register error = %v, want a status error", err)
+	}
+}
+
+func TestServerExpiresRegistrationsWithoutHeartbeats(t *testing.T) {
+	_, client := newTestRegistry(t, registry.ServerReapInterval(5*time.Millisecond))
+	ctx := context.Background()
+	err := client.Register(ctx, registry.Registration{Service: "users", ID: "a", Address: "10.0.0.1:80", TTL: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	snapshot, err := client.Instances(ctx, "users", 0, 0)
+	if err != nil || len(snapshot.Instances) != 1 {
+		t.Fatalf("Instances = %+v, %v", snapshot, err)
+	}
+
+	snapshot, err = client.Instances(ctx, "users", snapshot.Index, time.Second)
+	if err != nil {
+		t.Fatalf("long-poll Instances: %v", err)
+	}
+	if len(snapshot.Instances) != 0 {
+		t.Fatalf("instances after TTL = %v, want none", snapshot.Instances)
+	}
+	if err := client.Heartbeat(ctx, "users", "a"); err != registry.ErrNotRegistered {
+		t.Fatalf("Heartbeat error = %v, want ErrNotRegistered", err)
+	}
+}
+
+func TestRegistrarHeartbeatsAndReRegisters(t *testing.T) {
+	_, client := newTestRegistry(t, registry.ServerReapInterval(5*time.Millisecond))
+	registrar, err := registry.NewRegistrar(client, nil, registry.Registration{
+		Service: "users",
+		ID:      "a",
+		Address: "10.0.0.1:80",
+		TTL:     60 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRegistrar: %v", err)
+	}
+	if err := registrar.Register(); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	instancer, err := registry.NewInstancer(client, nil, "users")
+	if err != nil {
+		t.Fatalf("NewInstancer: %v", err)
+	}
+	defer instancer.Stop()
+	ch := make(chan sd.Event, 1)
+	instancer.Register(ch)
+
+	time.Sleep(150 * time.Millisecond)
+	snapshot, err := client.Instances(context.Background(), "users", 0, 0)
+	if err != nil || strings.Join(snapshot.Instances, ",") != "10.0.0.1:80" {
+		t.Fatalf("heartbeats did not keep the lease: %+v, %v", snapshot, err)
+	}
+
+	// Simulate a registry that lost its state; the next heartbeat re-registers.
+	if err := client.Deregister(context.Background(), "users", "a"); err != nil {
+		t.Fatalf("Deregister: %v", err)
+	}
+	waitForInstances(t, ch, "10.0.0.1:80")
+
+	if err := registrar.Deregister(); err != nil {
+		t.Fatalf("Registrar.Deregister: %v", err)
+	}
+	waitForInstances(t, ch, "")
+}
+
+func TestServerStreamsSnapshotsAsSSE(t *testing.T) {
+	srv, client := newTestRegistry(t)
+	httpServer := httptest.NewServer(srv.Handler())
+	defer httpServer.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+"/v1/services/users", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() registry.Snapshot {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				var snapshot registry.Snapshot
+				if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+					t.Fatalf("decode event: %v", err)
+				}
+				return snapshot
+			}
+		}
+	}
+
+	if first := next(); len(first.Instances) != 0 {
+		t.Fatalf("first event = %+v, want empty", first)
+	}
+	if err := client.Register(context.Background(), registry.Registration{Service: "users", ID: "a", Address: "10.0.0.1:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if second := next(); strings.Join(second.Instances, ",") != "10.0.0.1:80" {
+		t.Fatalf("second event = %+v", second)
+	}
+}
+
+func TestServerLifecycle(t *testing.T) {
+	srv, err := registry.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected duplicate Start error")
+	}
+	client, err := registry.NewClient("http://" + srv.Addr().String())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := client.Register(context.Background(), registry.Registration{Service: "users", ID: "a", Address: "10.0.0.1:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected restart error")
+	}
+}
+
+func TestServerRejectsInvalidRegistrations(t *testing.T) {
+	_, client := newTestRegistry(t)
+	ctx := context.Background()
+	if err := client.Register(ctx, registry.Registration{Service: "users", ID: "a", Address: "no-port"}); err == nil {
+		t.Fatal("address without port accepted")
+	}
+	if _, err := registry.NewRegistrar(client, nil, registry.Registration{Service: "users", Address: "h:1"}); err == nil {
+		t.Fatal("registration without ID accepted")
+	}
+	if _, err := registry.NewClient("127.0.0.1:8500"); err == nil {
+		t.Fatal("base URL without scheme accepted")
+	}
+	if _, err := registry.NewServer("", registry.ServerDefaultTTL(0)); err == nil {
+		t.Fatal("zero default TTL accepted")
+	}
+}
+
+func TestServerWatchesUnregisteredServicesWithoutRetainingThem(t *testing.T) {
+	_, client := newTestRegistry(t)
+	ctx := context.Background()
+
+	empty, err := client.Instances(ctx, "ghost", 0, 0)
+	if err != nil || empty.Index == 0 || len(empty.Instances) != 0 {
+		t.Fatalf("unregistered snapshot = %+v, %v", empty, err)
+	}
+	again, err := client.Instances(ctx, "ghost", 0, 0)
+	if err != nil || again.Index != empty.Index {
+		t.Fatalf("reading an unregistered service changed the index: %d then %d", empty.Index, again.Index)
+	}
+
+	// A watch on the unregistered name wakes on its first registration.
+	done := make(chan registry.Snapshot, 1)
+	go func() {
+		snapshot, _ := client.Instances(ctx, "ghost", empty.Index, 5*time.Second)
+		done <- snapshot
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := client.Register(ctx, registry.Registration{Service: "ghost", ID: "a", Address: "10.0.0.1:80"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	select {
+	case snapshot := <-done:
+		if strings.Join(snapshot.Instances, ",") != "10.0.0.1:80" {
+			t.Fatalf("woken snapshot = %+v", snapshot)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not wake on first registration")
+	}
+
+	if err := client.Deregister(ctx, "ghost", "a"); err != nil {
+		t.Fatalf("Deregister: %v", err)
+	}
+	gone, err := client.Instances(ctx, "ghost", 0, 0)
+	if err != nil || len(gone.Instances) != 0 || gone.Index <= empty.Index {
+		t.Fatalf("snapshot after last deregistration = %+v, %v", gone, err)
+	}
+
+	if _, err := client.Instances(ctx, strings.Repeat("x", 300), 0, 0); err == nil {
+		t.Fatal("overlong service name accepted")
+	}
+}
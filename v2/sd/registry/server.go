@@ -0,0 +1,502 @@
+// Package registry provides a small embedded HTTP service registry.
+//
+// Server keeps TTL-based registrations in memory and serves them over a JSON
+// HTTP API. Client, Instancer, and Registrar are the matching discovery and
+// registration clients. The registry is intended for local development and
+// integration tests that need real dynamic discovery without Consul; it is
+// not replicated and loses state on restart.
+//
+// HTTP API:
+//
+//	PUT    /v1/services/{service}/{id}            register or replace
+//	PUT    /v1/services/{service}/{id}/heartbeat  renew the TTL
+//	DELETE /v1/services/{service}/{id}            deregister
+//	GET    /v1/services/{service}?index=N&wait=D  snapshot or long-poll
+//
+// A GET with "Accept: text/event-stream" streams every snapshot as an SSE
+// "instances" event instead of long-polling.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL applies to registrations that do not specify a TTL.
+	DefaultTTL = 10 * time.Second
+	// DefaultWait is the long-poll duration used when a watch request does
+	// not specify one.
+	DefaultWait = 30 * time.Second
+	// MaxWait caps the long-poll duration requested by clients.
+	MaxWait = 5 * time.Minute
+	// IndexHeader carries the snapshot index on watch responses.
+	IndexHeader = "X-Registry-Index"
+
+	maxRegistrationBytes = 64 << 10
+	maxServiceNameBytes  = 256
+)
+
+// Snapshot is the set of live instances of one service at an index. The index
+// increases whenever the service's instance set changes.
+type Snapshot struct {
+	Index     uint64   `json:"index"`
+	Instances []string `json:"instances"`
+}
+
+type registrationBody struct {
+	Address string `json:"address"`
+	TTL     string `json:"ttl,omitempty"`
+}
+
+type entry struct {
+	address string
+	ttl     time.Duration
+	expires time.Time
+}
+
+type service struct {
+	index   uint64
+	entries map[string]entry
+	changed chan struct{}
+}
+
+// ServerOption configures NewServer.
+type ServerOption func(*Server)
+
+// ServerDefaultTTL sets the TTL applied when a registration omits one.
+func ServerDefaultTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) { s.defaultTTL = ttl }
+}
+
+// ServerReapInterval sets how often expired registrations are removed.
+func ServerReapInterval(interval time.Duration) ServerOption {
+	return func(s *Server) { s.reapInterval = interval }
+}
+
+// ServerLogger sets the logger used for registration changes.
+func ServerLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// Server is an in-memory registry that implements kit.Lifecycle. With a
+// non-empty address Start serves the registry API on its own listener; with an
+// empty address Start only runs TTL expiry and the API is mounted elsewhere
+// through Handler.
+type Server struct {
+	addr         string
+	defaultTTL   time.Duration
+	reapInterval time.Duration
+	logger       *slog.Logger
+	handler      http.Handler
+	timeNow      func() time.Time
+	errors       chan error
+
+	mu       sync.Mutex
+	services map[string]*service
+	index    uint64
+	// absent is closed when any service gains its first registration; it
+	// wakes watchers of services that are not registered.
+	absent chan struct{}
+
+	lifecycleMu sync.Mutex
+	listener    net.Listener
+	srv         *http.Server
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	started     bool
+	stopped     bool
+}
+
+// NewServer creates a registry server. addr may be empty when the Handler is
+// mounted into another HTTP server such as kit.Service.
+func NewServer(addr string, options ...ServerOption) (*Server, error) {
+	s := &Server{
+		addr:         addr,
+		defaultTTL:   DefaultTTL,
+		reapInterval: time.Second,
+		timeNow:      time.Now,
+		errors:       make(chan error, 1),
+		services:     map[string]*service{},
+		index:        1,
+		absent:       make(chan struct{}),
+	}
+	for i, option := range options {
+		if option == nil {
+			return nil, fmt.Errorf("sd/registry: server option %d is nil", i)
+		}
+		option(s)
+	}
+	switch {
+	case s.defaultTTL <= 0:
+		return nil, fmt.Errorf("sd/registry: default TTL must be greater than zero")
+	case s.reapInterval <= 0:
+		return nil, fmt.Errorf("sd/registry: reap interval must be greater than zero")
+	}
+	if s.logger == nil {
+		s.logger = slog.New(slog.DiscardHandler)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /v1/services/{service}/{id}", s.handleRegister)
+	mux.HandleFunc("PUT /v1/services/{service}/{id}/heartbeat", s.handleHeartbeat)
+	mux.HandleFunc("DELETE /v1/services/{service}/{id}", s.handleDeregister)
+	mux.HandleFunc("GET /v1/services/{service}", s.handleWatch)
+	s.handler = mux
+	return s, nil
+}
+
+// Handler returns the registry HTTP API. Mount it at the server root, for
+// example with kit.Service.Handle("/v1/services/", srv.Handler()).
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Addr returns the bound listener address after Start, or nil when the server
+// has no listener.
+func (s *Server) Addr() net.Addr {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Start binds the listener, when configured, and starts TTL expiry.
+func (s *Server) Start() error {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	if s.started {
+		return fmt.Errorf("sd/registry: server already started")
+	}
+	if s.stopped {
+		return fmt.Errorf("sd/registry: server cannot be restarted after shutdown")
+	}
+
+	if strings.TrimSpace(s.addr) != "" {
+		listener, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			return fmt.Errorf("sd/registry: listen: %w", err)
+		}
+		s.listener = listener
+		s.srv = &http.Server{Handler: s.handler, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := s.srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				select {
+				case s.errors <- fmt.Errorf("sd/registry: serve: %w", err):
+				default:
+				}
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.started = true
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.reapLoop(ctx)
+	}()
+	return nil
+}
+
+// Errors reports asynchronous serving failures after Start.
+func (s *Server) Errors() <-chan error {
+	return s.errors
+}
+
+// Shutdown stops TTL expiry and gracefully stops the listener, if any. Open
+// watches are released so the HTTP server can drain.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("sd/registry: nil shutdown context")
+	}
+	s.lifecycleMu.Lock()
+	if !s.started {
+		s.lifecycleMu.Unlock()
+		return nil
+	}
+	s.started = false
+	s.stopped = true
+	srv := s.srv
+	s.cancel()
+	s.lifecycleMu.Unlock()
+
+	s.wg.Wait()
+	s.mu.Lock()
+	for _, svc := range s.services {
+		close(svc.changed)
+		svc.changed = make(chan struct{})
+	}
+	close(s.absent)
+	s.absent = make(chan struct{})
+	s.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+func (s *Server) reapLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.reap()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) reap() {
+	now := s.timeNow()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, svc := range s.services {
+		expired := false
+		for id, e := range svc.entries {
+			if now.After(e.expires) {
+				delete(svc.entries, id)
+				expired = true
+				s.logger.Debug("registration expired", "service", name, "id", id)
+			}
+		}
+		if expired {
+			s.changedLocked(name, svc)
+		}
+	}
+}
+
+// registerLocked returns the named service, creating it for its first
+// registration. Only registrations create services, so watching arbitrary
+// names does not grow the registry.
+func (s *Server) registerLocked(name string) *service {
+	svc, ok := s.services[name]
+	if !ok {
+		svc = &service{entries: map[string]entry{}, changed: make(chan struct{})}
+		s.services[name] = svc
+		close(s.absent)
+		s.absent = make(chan struct{})
+	}
+	return svc
+}
+
+// changedLocked moves svc to a new index and wakes its watchers. A service
+// left without entries is removed; its watchers were just released, so none
+// remains attached to it, and they see it as not registered from then on.
+func (s *Server) changedLocked(name string, svc *service) {
+	s.index++
+	svc.index = s.index
+	close(svc.changed)
+	svc.changed = make(chan struct{})
+	if len(svc.entries) == 0 {
+		delete(s.services, name)
+	}
+}
+
+func (s *Server) snapshotLocked(svc *service) Snapshot {
+	instances := make([]string, 0, len(svc.entries))
+	seen := make(map[string]struct{}, len(svc.entries))
+	for _, e := range svc.entries {
+		if _, ok := seen[e.address]; ok {
+			continue
+		}
+		seen[e.address] = struct{}{}
+		instances = append(instances, e.address)
+	}
+	sort.Strings(instances)
+	return Snapshot{Index: svc.index, Instances: instances}
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	name, id := r.PathValue("service"), r.PathValue("id")
+	if len(name) > maxServiceNameBytes || len(id) > maxServiceNameBytes {
+		writeError(w, http.StatusBadRequest, "service name or id is too long")
+		return
+	}
+	var body registrationBody
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRegistrationBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid registration body")
+		return
+	}
+	if _, _, err := net.SplitHostPort(body.Address); err != nil {
+		writeError(w, http.StatusBadRequest, "address must be host:port")
+		return
+	}
+	ttl := s.defaultTTL
+	if body.TTL != "" {
+		parsed, err := time.ParseDuration(body.TTL)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "ttl must be a positive duration")
+			return
+		}
+		ttl = parsed
+	}
+
+	s.mu.Lock()
+	svc := s.registerLocked(name)
+	previous, existed := svc.entries[id]
+	svc.entries[id] = entry{address: body.Address, ttl: ttl, expires: s.timeNow().Add(ttl)}
+	if !existed || previous.address != body.Address {
+		s.changedLocked(name, svc)
+		s.logger.Debug("instance registered", "service", name, "id", id, "address", body.Address)
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	name, id := r.PathValue("service"), r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.services[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "registration not found")
+		return
+	}
+	e, ok := svc.entries[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "registration not found")
+		return
+	}
+	e.expires = s.timeNow().Add(e.ttl)
+	svc.entries[id] = e
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeregister(w http.ResponseWriter, r *http.Request) {
+	name, id := r.PathValue("service"), r.PathValue("id")
+	s.mu.Lock()
+	if svc, ok := s.services[name]; ok {
+		if _, ok := svc.entries[id]; ok {
+			delete(svc.entries, id)
+			s.changedLocked(name, svc)
+			s.logger.Debug("instance deregistered", "service", name, "id", id)
+		}
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("service")
+	if len(name) > maxServiceNameBytes {
+		writeError(w, http.StatusBadRequest, "service name is too long")
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamSnapshots(w, r, name)
+		return
+	}
+
+	query := r.URL.Query()
+	var index uint64
+	if raw := query.Get("index"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "index must be an unsigned integer")
+			return
+		}
+		index = parsed
+	}
+	wait := DefaultWait
+	if raw := query.Get("wait"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			writeError(w, http.StatusBadRequest, "wait must be a non-negative duration")
+			return
+		}
+		wait = min(parsed, MaxWait)
+	}
+
+	// A client index ahead of ours comes from before a restart; answer at
+	// once so the client resynchronizes instead of waiting for us to catch up.
+	snapshot, changed := s.watch(name)
+	if index > 0 && snapshot.Index == index && wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-r.Context().Done():
+		}
+		timer.Stop()
+		snapshot, _ = s.watch(name)
+	}
+	w.Header().Set(IndexHeader, strconv.FormatUint(snapshot.Index, 10))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(snapshot)
+}
+
+// watch returns the current snapshot of name and a channel closed on its next
+// change. A service that is not registered reads as empty at the current
+// global index, which is never zero because clients use zero to request a
+// snapshot without blocking.
+func (s *Server) watch(name string) (Snapshot, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.services[name]
+	if !ok {
+		return Snapshot{Index: s.index, Instances: []string{}}, s.absent
+	}
+	return s.snapshotLocked(svc), svc.changed
+}
+
+func (s *Server) streamSnapshots(w http.ResponseWriter, r *http.Request, name string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	var last uint64
+	for {
+		snapshot, changed := s.watch(name)
+		if snapshot.Index != last {
+			payload, _ := json.Marshal(snapshot)
+			if _, err := fmt.Fprintf(w, "event: instances\nid: %d\ndata: %s\n\n", snapshot.Index, payload); err != nil {
+				return
+			}
+			flusher.Flush()
+			last = snapshot.Index
+		}
+		select {
+		case <-changed:
+			if s.isStopped() {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) isStopped() bool {
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+	return s.stopped
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
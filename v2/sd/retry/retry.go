@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/internal/backoff"
 	"github.com/dreamsxin/go-kit/v2/sd"
-	"github.com/dreamsxin/go-kit/v2/sd/retry/internal/backoff"
 )
 
 // Error is returned when retry attempts are exhausted.
@@ -76,8 +76,56 @@ func WithCallback(timeout time.Duration, balancer sd.Balancer, callback Callback
 	return WithClassifier(timeout, balancer, callback, DefaultClassifier)
 }
 
+// Backoff returns the delay before the next attempt after failures
+// consecutive failed attempts within one call.
+type Backoff func(failures int) time.Duration
+
+// ExponentialBackoff doubles base after every failure up to maximum and
+// applies a uniform jitter of +/- jitter (between 0 and 1) of each delay. A
+// maximum of 0 means no cap.
+func ExponentialBackoff(base, maximum time.Duration, jitter float64) Backoff {
+	return func(failures int) time.Duration {
+		return backoff.Exponential(base, maximum, jitter, failures)
+	}
+}
+
+// DefaultBackoff starts at 10ms, doubles up to one minute, and applies 50
+// percent jitter.
+var DefaultBackoff = ExponentialBackoff(10*time.Millisecond, time.Minute, 0.5)
+
+// ErrHedge is passed to the Callback when a hedged attempt is about to start
+// because earlier attempts are still running after the hedge delay. Returning
+// false from the callback suppresses the hedge; the replacement is ignored.
+var ErrHedge = errors.New("retry: hedging delay elapsed")
+
+// Options controls optional retry behavior.
+type Options struct {
+	Backoff    Backoff
+	HedgeDelay time.Duration
+}
+
+// Option configures optional retry behavior.
+type Option func(*Options)
+
+// WithBackoff replaces DefaultBackoff.
+func WithBackoff(backoff Backoff) Option {
+	return func(options *Options) { options.Backoff = backoff }
+}
+
+// WithHedging starts the next attempt when earlier attempts have not finished
+// after delay, instead of waiting for them to fail. The first success wins and
+// the remaining attempts are canceled. Every hedge consults the Callback, so
+// attempt limits apply to hedged attempts too. Hedge only idempotent calls.
+func WithHedging(delay time.Duration) Option {
+	return func(options *Options) { options.HedgeDelay = delay }
+}
+
 // WithClassifier retries calls using explicit attempt and error policies.
-func WithClassifier(timeout time.Duration, balancer sd.Balancer, callback Callback, classifier Classifier) endpoint.Endpoint {
+//
+// When balancer implements sd.InstanceBalancer, attempts skip instances that
+// already failed within the same call, so round-robin retries do not land on
+// the same bad instance.
+func WithClassifier(timeout time.Duration, balancer sd.Balancer, callback Callback, classifier Classifier, opts ...Option) endpoint.Endpoint {
 	if callback == nil {
 		callback = alwaysRetry
 	}
@@ -87,54 +135,131 @@ func WithClassifier(timeout time.Duration, balancer sd.Balancer, callback Callba
 	if balancer == nil {
 		panic("retry: nil balancer")
 	}
+	options := Options{Backoff: DefaultBackoff}
+	for _, option := range opts {
+		if option != nil {
+			option(&options)
+		}
+	}
+	if options.Backoff == nil {
+		options.Backoff = DefaultBackoff
+	}
 
 	return func(ctx context.Context, request any) (any, error) {
 		callContext, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		responses := make(chan any, 1)
-		errorsChannel := make(chan error, 1)
+		e := execution{
+			ctx:      callContext,
+			balancer: balancer,
+			request:  request,
+			results:  make(chan attemptResult),
+			failed:   map[string]struct{}{},
+		}
 		result := Error{}
-		delay := 10 * time.Millisecond
-
-		for attempt := 1; ; attempt++ {
-			go call(callContext, balancer, request, responses, errorsChannel)
+		var hedge <-chan time.Time
+		var hedgeTimer *time.Timer
+		stopHedge := func() {
+			if hedgeTimer != nil {
+				hedgeTimer.Stop()
+				hedgeTimer, hedge = nil, nil
+			}
+		}
+		defer stopHedge()
+		armHedge := func() {
+			if options.HedgeDelay > 0 && hedgeTimer == nil {
+				hedgeTimer = time.NewTimer(options.HedgeDelay)
+				hedge = hedgeTimer.C
+			}
+		}
 
+		e.launch()
+		armHedge()
+		for {
 			select {
 			case <-callContext.Done():
 				return nil, callContext.Err()
-			case response := <-responses:
-				return response, nil
-			case callErr := <-errorsChannel:
+			case <-hedge:
+				hedgeTimer, hedge = nil, nil
+				if keepTrying, _ := callback(e.launched, ErrHedge); keepTrying {
+					e.launch()
+					armHedge()
+				}
+			case outcome := <-e.results:
+				e.inFlight--
+				if outcome.err == nil {
+					return outcome.response, nil
+				}
+				callErr := outcome.err
+				if outcome.instance != "" {
+					e.failed[outcome.instance] = struct{}{}
+				}
 				result.RawErrors = append(result.RawErrors, callErr)
-				keepTrying, replacement := callback(attempt, callErr)
+				keepTrying, replacement := callback(e.launched, callErr)
 				if replacement != nil {
 					callErr = replacement
 				}
-				if !keepTrying || !classifier(callErr) {
+				if !classifier(callErr) || (!keepTrying && e.inFlight == 0) {
 					result.Final = callErr
 					return nil, result
 				}
-				if err := sleep(callContext, delay); err != nil {
+				if !keepTrying || e.inFlight > 0 {
+					continue
+				}
+				stopHedge()
+				if err := sleep(callContext, options.Backoff(len(result.RawErrors))); err != nil {
 					return nil, err
 				}
-				delay = backoff.Next(delay)
+				e.launch()
+				armHedge()
 			}
 		}
 	}
 }
 
-func call(ctx context.Context, balancer sd.Balancer, request any, responses chan<- any, errorsChannel chan<- error) {
-	selected, err := balancer.Endpoint()
-	if err == nil {
-		var response any
-		response, err = selected(ctx, request)
+type attemptResult struct {
+	instance string
+	response any
+	err      error
+}
+
+// execution tracks the attempts of one call. It is owned by the calling
+// goroutine; attempts report back through results.
+type execution struct {
+	ctx      context.Context
+	balancer sd.Balancer
+	request  any
+	results  chan attemptResult
+	failed   map[string]struct{}
+	launched int
+	inFlight int
+}
+
+func (e *execution) launch() {
+	e.launched++
+	e.inFlight++
+	instance, selected, err := e.selectEndpoint()
+	go func() {
+		outcome := attemptResult{instance: instance, err: err}
 		if err == nil {
-			responses <- response
-			return
+			outcome.response, outcome.err = selected(e.ctx, e.request)
+		}
+		select {
+		case e.results <- outcome:
+		case <-e.ctx.Done():
 		}
+	}()
+}
+
+func (e *execution) selectEndpoint() (string, endpoint.Endpoint, error) {
+	if balancer, ok := e.balancer.(sd.InstanceBalancer); ok {
+		return balancer.InstanceEndpoint(func(instance string) bool {
+			_, failed := e.failed[instance]
+			return failed
+		})
 	}
-	errorsChannel <- err
+	selected, err := e.balancer.Endpoint()
+	return "", selected, err
 }
 
 // DefaultClassifier retries only errors that explicitly opt in and temporary
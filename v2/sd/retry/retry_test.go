@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
 	"time"
 
@@ -263,3 +264,124 @@ func TestRetryError_ErrorString_Multiple(t *testing.T) {
 		t.Errorf("Error() too short: %q", got)
 	}
 }
+
+// ── Instance exclusion, backoff and hedging ───────────────────────────────────
+
+func newMultiBalancer(t *testing.T, factory endpointer.Factory, instances ...string) sd.Balancer {
+	t.Helper()
+	cache := instance.NewCache()
+	cache.Update(sd.Event{Instances: instances})
+	ep := endpointer.NewEndpointer(cache, factory, nopLogger)
+	t.Cleanup(func() { _ = ep.Close() })
+	return balancer.NewRoundRobin(ep)
+}
+
+func TestRetry_AvoidsInstancesThatFailedWithinCall(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	f := endpointer.Factory(func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		ep := endpoint.Endpoint(func(_ context.Context, _ any) (any, error) {
+			mu.Lock()
+			calls = append(calls, addr)
+			mu.Unlock()
+			if addr != "c:80" {
+				return nil, transientError{errors.New(addr + " down")}
+			}
+			return addr, nil
+		})
+		return ep, nil, nil
+	})
+	lb := newMultiBalancer(t, f, "a:80", "b:80", "c:80")
+	noDelay := retry.WithBackoff(func(int) time.Duration { return 0 })
+
+	for i := 0; i < 3; i++ {
+		calls = nil
+		ep := retry.WithClassifier(time.Second, lb, func(n int, _ error) (bool, error) { return n < 3, nil }, nil, noDelay)
+		resp, err := ep(context.Background(), nil)
+		if err != nil || resp != "c:80" {
+			t.Fatalf("call %d = %v, %v; want c:80", i, resp, err)
+		}
+		seen := map[string]bool{}
+		for _, addr := range calls {
+			if seen[addr] {
+				t.Fatalf("call %d retried failed instance %s: %v", i, addr, calls)
+			}
+			seen[addr] = true
+		}
+	}
+}
+
+func TestRetry_UsesConfiguredBackoff(t *testing.T) {
+	f := endpointer.Factory(func(_ string) (endpoint.Endpoint, io.Closer, error) {
+		ep := endpoint.Endpoint(func(_ context.Context, _ any) (any, error) {
+			return nil, transientError{errors.New("transient")}
+		})
+		return ep, nil, nil
+	})
+	lb := newBalancer(t, f)
+	var failures []int
+	backoff := func(n int) time.Duration {
+		failures = append(failures, n)
+		return time.Millisecond
+	}
+	ep := retry.WithClassifier(time.Second, lb, func(n int, _ error) (bool, error) { return n < 3, nil }, nil, retry.WithBackoff(backoff))
+	if _, err := ep(context.Background(), nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if fmt.Sprint(failures) != "[1 2]" {
+		t.Fatalf("backoff called with %v, want [1 2]", failures)
+	}
+}
+
+func TestRetry_HedgingStartsNextAttemptAfterDelay(t *testing.T) {
+	f := endpointer.Factory(func(addr string) (endpoint.Endpoint, io.Closer, error) {
+		ep := endpoint.Endpoint(func(ctx context.Context, _ any) (any, error) {
+			if addr == "slow:80" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return addr, nil
+		})
+		return ep, nil, nil
+	})
+	lb := newMultiBalancer(t, f, "fast:80", "slow:80")
+
+	ep := retry.WithClassifier(time.Second, lb, func(n int, _ error) (bool, error) { return n < 2, nil }, nil,
+		retry.WithHedging(10*time.Millisecond))
+	for i := 0; i < 4; i++ {
+		start := time.Now()
+		resp, err := ep(context.Background(), nil)
+		if err != nil || resp != "fast:80" {
+			t.Fatalf("call %d = %v, %v; want fast:80", i, resp, err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Fatalf("hedged call took %v", elapsed)
+		}
+	}
+}
+
+func TestRetry_HedgingRespectsAttemptLimit(t *testing.T) {
+	var mu sync.Mutex
+	started := 0
+	f := endpointer.Factory(func(_ string) (endpoint.Endpoint, io.Closer, error) {
+		ep := endpoint.Endpoint(func(ctx context.Context, _ any) (any, error) {
+			mu.Lock()
+			started++
+			mu.Unlock()
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		return ep, nil, nil
+	})
+	lb := newBalancer(t, f)
+	ep := retry.WithClassifier(50*time.Millisecond, lb, func(n int, _ error) (bool, error) { return n < 2, nil }, nil,
+		retry.WithHedging(5*time.Millisecond))
+	if _, err := ep(context.Background(), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if started != 2 {
+		t.Fatalf("started %d attempts, want 2", started)
+	}
+}
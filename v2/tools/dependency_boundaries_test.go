@@ -25,20 +25,32 @@ func TestArchitectureDependencyGates(t *testing.T) {
 		{
 			name:         "http transport",
 			pattern:      "./transport/http/...",
-			allowedExact: []string{coreModulePath + "/apperror", coreModulePath + "/endpoint", coreModulePath + "/transport"},
+			allowedExact: []string{coreModulePath + "/apperror", coreModulePath + "/endpoint", coreModulePath + "/internal/backoff", coreModulePath + "/transport"},
 			allowedTrees: []string{coreModulePath + "/transport/http"},
 		},
+		{
+			name:         "websocket transport",
+			pattern:      "./transport/websocket/...",
+			allowedExact: []string{coreModulePath + "/apperror", coreModulePath + "/endpoint", coreModulePath + "/transport"},
+			allowedTrees: []string{coreModulePath + "/transport/websocket"},
+		},
+		{
+			name:         "jsonrpc transport",
+			pattern:      "./transport/jsonrpc/...",
+			allowedExact: []string{coreModulePath + "/apperror", coreModulePath + "/endpoint", coreModulePath + "/transport"},
+			allowedTrees: []string{coreModulePath + "/transport/jsonrpc"},
+		},
 		{
 			name:         "service discovery",
 			pattern:      "./sd/...",
-			allowedExact: []string{coreModulePath + "/endpoint"},
+			allowedExact: []string{coreModulePath + "/endpoint", coreModulePath + "/internal/backoff"},
 			allowedTrees: []string{coreModulePath + "/sd"},
 		},
 		{
 			name:         "kit",
 			pattern:      "./kit",
 			allowedExact: []string{coreModulePath + "/endpoint", coreModulePath + "/transport"},
-			allowedTrees: []string{coreModulePath + "/kit", coreModulePath + "/transport/http"},
+			allowedTrees: []string{coreModulePath + "/kit", coreModulePath + "/transport/http", coreModulePath + "/transport/websocket"},
 		},
 		{
 			name:         "interaction",
@@ -150,8 +162,12 @@ func TestGenericServiceDiscoveryDoesNotDependOnProviders(t *testing.T) {
 		"./sd",
 		"./sd/balancer",
 		"./sd/client",
+		"./sd/dns",
 		"./sd/endpointer",
+		"./sd/file",
+		"./sd/health",
 		"./sd/instance",
+		"./sd/registry",
 		"./sd/retry",
 	}
 	args := []string{"list", "-deps", "-f", "{{.ImportPath}}"}
@@ -213,6 +229,24 @@ func TestTransportPackagesDoNotCrossProtocolBoundaries(t *testing.T) {
 				"google.golang.org/grpc",
 			},
 		},
+		{
+			dir:     root,
+			pattern: "./transport/jsonrpc/...",
+			forbidden: []string{
+				"github.com/dreamsxin/go-kit/v2/transport/http",
+				"github.com/dreamsxin/go-kit/v2/integrations/grpc",
+				"google.golang.org/grpc",
+			},
+		},
+		{
+			dir:     root,
+			pattern: "./transport/websocket/...",
+			forbidden: []string{
+				"github.com/dreamsxin/go-kit/v2/transport/http",
+				"github.com/dreamsxin/go-kit/v2/integrations/grpc",
+				"google.golang.org/grpc",
+			},
+		},
 		{
 			dir:     filepath.Join(root, "integrations", "grpc"),
 			pattern: "./...",
@@ -0,0 +1,235 @@
+package http
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Binding tags read by Bind.
+const (
+	// PathTag names the path parameter of a field, as in `path:"id"`.
+	PathTag = "path"
+	// QueryTag names the query parameter of a field, as in `query:"page"`.
+	QueryTag = "query"
+	// HeaderTag names the request header of a field, as in `header:"X-Tenant"`.
+	HeaderTag = "header"
+	// BodyTag marks the one field that receives the whole request body, as
+	// in `body:""`. Without it the body is decoded into the struct itself.
+	BodyTag = "body"
+)
+
+// DecodeBodyFunc decodes the request body into target, which is a non-nil
+// pointer. Bind returns its errors unchanged.
+type DecodeBodyFunc func(r *nethttp.Request, target any) error
+
+// Bind fills a struct pointer from every part of the request. The body is
+// decoded first with decodeBody; then fields tagged path, query or header are
+// set from the route pattern, the query string and the request headers, so
+// URL and header values take precedence over body values.
+//
+// The body is skipped when decodeBody is nil, when the request has no body,
+// or when the struct has no body fields: every exported field carries a
+// binding tag or `json:"-"`. Give parameter fields `json:"-"` when strict body
+// decoding must reject them in the body.
+//
+// Missing parameters leave their field unchanged. Slices collect repeated
+// query parameters and header values. Parameter failures are *QueryError
+// values; field types follow ValidateQueryStruct. Reflection metadata is
+// computed once per struct type.
+//
+// Example:
+//
+//	type UpdateItemRequest struct {
+//	    ID     string `path:"id" json:"-"`
+//	    DryRun bool   `query:"dry_run" json:"-"`
+//	    Tenant string `header:"X-Tenant" json:"-"`
+//	    Name   string `json:"name"`
+//	}
+func Bind(r *nethttp.Request, target any, decodeBody DecodeBodyFunc) error {
+	if r == nil {
+		return &QueryError{Err: fmt.Errorf("nil HTTP request")}
+	}
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return &QueryError{Err: fmt.Errorf("target must be a non-nil pointer")}
+	}
+	elem := value.Elem()
+	if elem.Kind() != reflect.Struct {
+		return &QueryError{Err: fmt.Errorf("target must point to a struct")}
+	}
+	plan, err := bindPlanFor(elem.Type())
+	if err != nil {
+		return err
+	}
+
+	if decodeBody != nil && plan.hasBody && r.Body != nil && r.Body != nethttp.NoBody {
+		bodyTarget := target
+		if plan.bodyIndex != nil {
+			bodyTarget = bindField(elem, plan.bodyIndex).Addr().Interface()
+		}
+		if err := decodeBody(r, bodyTarget); err != nil {
+			return err
+		}
+	}
+
+	var query map[string][]string
+	for _, field := range plan.params {
+		var values []string
+		switch field.source {
+		case PathTag:
+			if v := r.PathValue(field.name); v != "" {
+				values = []string{v}
+			}
+		case QueryTag:
+			if query == nil {
+				query = r.URL.Query()
+			}
+			values = query[field.name]
+		case HeaderTag:
+			values = r.Header.Values(field.name)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if err := setQueryValues(bindField(elem, field.index), values); err != nil {
+			return &QueryError{Field: field.goName, Err: err}
+		}
+	}
+	return nil
+}
+
+// Bindable reports whether Bind has anything beyond the body to bind for
+// values of type T: T is a struct with at least one path, query, header or
+// body tag. It returns an error when the tags of T are invalid, for example
+// a field type Bind cannot set or two body fields, so constructors can reject
+// T up front instead of failing every request.
+func Bindable[T any]() (bool, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return false, nil
+	}
+	plan, err := bindPlanFor(typ)
+	if err != nil {
+		return false, err
+	}
+	return len(plan.params) > 0 || plan.bodyIndex != nil, nil
+}
+
+type bindPlan struct {
+	params    []bindParam
+	bodyIndex []int
+	hasBody   bool
+}
+
+type bindParam struct {
+	index  []int
+	source string
+	name   string
+	goName string
+}
+
+type bindPlanEntry struct {
+	plan *bindPlan
+	err  error
+}
+
+var bindPlans sync.Map // reflect.Type -> bindPlanEntry
+
+func bindPlanFor(typ reflect.Type) (*bindPlan, error) {
+	if cached, ok := bindPlans.Load(typ); ok {
+		entry := cached.(bindPlanEntry)
+		return entry.plan, entry.err
+	}
+	plan := &bindPlan{}
+	err := plan.collect(typ, nil, map[reflect.Type]bool{})
+	if err == nil && plan.bodyIndex != nil {
+		plan.hasBody = true
+	}
+	cached, _ := bindPlans.LoadOrStore(typ, bindPlanEntry{plan: plan, err: err})
+	entry := cached.(bindPlanEntry)
+	return entry.plan, entry.err
+}
+
+func (p *bindPlan) collect(typ reflect.Type, prefix []int, visiting map[reflect.Type]bool) error {
+	if visiting[typ] {
+		return nil
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := append(append([]int(nil), prefix...), i)
+		if !field.IsExported() && (!field.Anonymous || field.Type.Kind() == reflect.Pointer) {
+			continue
+		}
+
+		if _, ok := field.Tag.Lookup(BodyTag); ok {
+			if p.bodyIndex != nil {
+				return fmt.Errorf("bind %s: more than one field is tagged %s", typ, BodyTag)
+			}
+			p.bodyIndex = index
+			continue
+		}
+		source, name, tagged := bindTagFor(field)
+		if tagged {
+			if name == "-" {
+				continue
+			}
+			if err := validateQueryField(field.Type, typ.Name()+"."+field.Name); err != nil {
+				return fmt.Errorf("bind %s: %w", typ, err)
+			}
+			p.params = append(p.params, bindParam{index: index, source: source, name: name, goName: field.Name})
+			continue
+		}
+
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := p.collect(embedded, index, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		// Like encoding/json, only the exact tag "-" skips a field; "-,"
+		// names it "-".
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			p.hasBody = true
+		}
+	}
+	return nil
+}
+
+func bindTagFor(field reflect.StructField) (source, name string, ok bool) {
+	for _, source := range []string{PathTag, QueryTag, HeaderTag} {
+		tag, ok := field.Tag.Lookup(source)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(strings.Split(tag, ",")[0])
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		return source, name, true
+	}
+	return "", "", false
+}
+
+// bindField returns the field at index, allocating nil embedded pointers on
+// the way.
+func bindField(value reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 {
+			value = ensureQueryValue(value)
+		}
+		value = value.Field(fieldIndex)
+	}
+	return value
+}
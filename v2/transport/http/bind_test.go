@@ -0,0 +1,180 @@
+package http_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+type BindPage struct {
+	Page int `query:"page"`
+}
+
+type bindRequest struct {
+	BindPage
+	ID     int64    `path:"id" json:"-"`
+	Tags   []string `query:"tag" json:"-"`
+	Tenant string   `header:"X-Tenant" json:"-"`
+	Trace  []string `header:"X-Trace" json:"-"`
+	Name   string   `json:"name"`
+}
+
+type bindBodyRequest struct {
+	ID   string `path:"id"`
+	Item struct {
+		Name string `json:"name"`
+	} `body:""`
+}
+
+func decodeJSONBody(r *http.Request, target any) error {
+	return json.NewDecoder(r.Body).Decode(target)
+}
+
+func serveBind(t *testing.T, pattern string, r *http.Request, target any, decodeBody transporthttp.DecodeBodyFunc) error {
+	t.Helper()
+	var err error
+	mux := http.NewServeMux()
+	mux.HandleFunc(pattern, func(_ http.ResponseWriter, r *http.Request) {
+		err = transporthttp.Bind(r, target, decodeBody)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	return err
+}
+
+func TestBindReadsEverySource(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/items/42?page=3&tag=a&tag=b", strings.NewReader(`{"name":"box"}`))
+	r.Header.Set("X-Tenant", "acme")
+	r.Header.Add("X-Trace", "one")
+	r.Header.Add("X-Trace", "two")
+
+	var got bindRequest
+	if err := serveBind(t, "PUT /items/{id}", r, &got, decodeJSONBody); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if got.ID != 42 || got.Page != 3 || got.Tenant != "acme" || got.Name != "box" {
+		t.Fatalf("bound request = %+v", got)
+	}
+	if strings.Join(got.Tags, ",") != "a,b" || strings.Join(got.Trace, ",") != "one,two" {
+		t.Fatalf("repeated values: tags=%v trace=%v", got.Tags, got.Trace)
+	}
+}
+
+func TestBindParametersOverrideBody(t *testing.T) {
+	type request struct {
+		ID string `path:"id" json:"id"`
+	}
+	r := httptest.NewRequest(http.MethodPut, "/items/url", strings.NewReader(`{"id":"body"}`))
+
+	var got request
+	if err := serveBind(t, "PUT /items/{id}", r, &got, decodeJSONBody); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if got.ID != "url" {
+		t.Fatalf("ID = %q, want path value", got.ID)
+	}
+}
+
+func TestBindBodyField(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/items/7", strings.NewReader(`{"name":"box"}`))
+
+	var got bindBodyRequest
+	if err := serveBind(t, "POST /items/{id}", r, &got, decodeJSONBody); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if got.ID != "7" || got.Item.Name != "box" {
+		t.Fatalf("bound request = %+v", got)
+	}
+}
+
+func TestBindSkipsBodyWithoutBodyFields(t *testing.T) {
+	type request struct {
+		Page int `query:"page"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/items?page=2", strings.NewReader(`ignored`))
+	called := false
+	decode := func(*http.Request, any) error {
+		called = true
+		return nil
+	}
+
+	var got request
+	if err := serveBind(t, "GET /items", r, &got, decode); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	if called || got.Page != 2 {
+		t.Fatalf("decode called = %v, page = %d", called, got.Page)
+	}
+}
+
+func TestBindReportsQueryError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items/abc", nil)
+
+	var got bindRequest
+	err := serveBind(t, "GET /items/{id}", r, &got, decodeJSONBody)
+	var queryErr *transporthttp.QueryError
+	if !errors.As(err, &queryErr) || queryErr.Field != "ID" || queryErr.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("error = %#v, want QueryError for ID", err)
+	}
+}
+
+func TestBindReturnsBodyErrorsUnchanged(t *testing.T) {
+	sentinel := errors.New("bad body")
+	r := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(`{}`))
+
+	var got bindRequest
+	err := transporthttp.Bind(r, &got, func(*http.Request, any) error { return sentinel })
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("error = %v, want body error", err)
+	}
+}
+
+func TestBindRejectsInvalidTargets(t *testing.T) {
+	type twoBodies struct {
+		A string `body:""`
+		B string `body:""`
+	}
+	type unsupported struct {
+		Header map[string]string `header:"X-Map"`
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, target := range map[string]any{
+		"non-pointer": bindRequest{},
+		"non-struct":  new(string),
+		"two bodies":  &twoBodies{},
+		"unsupported": &unsupported{},
+	} {
+		if err := transporthttp.Bind(r, target, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBindable(t *testing.T) {
+	type plain struct {
+		Name string `json:"name"`
+	}
+	type twoBodies struct {
+		A string `body:""`
+		B string `body:""`
+	}
+	if ok, err := transporthttp.Bindable[plain](); ok || err != nil {
+		t.Fatalf("plain JSON struct: %v, %v", ok, err)
+	}
+	if ok, err := transporthttp.Bindable[string](); ok || err != nil {
+		t.Fatalf("string: %v, %v", ok, err)
+	}
+	if ok, err := transporthttp.Bindable[bindRequest](); !ok || err != nil {
+		t.Fatalf("tagged struct: %v, %v", ok, err)
+	}
+	if ok, err := transporthttp.Bindable[bindBodyRequest](); !ok || err != nil {
+		t.Fatalf("body-tagged struct: %v, %v", ok, err)
+	}
+	if _, err := transporthttp.Bindable[twoBodies](); err == nil {
+		t.Fatal("expected error for two body fields")
+	}
+}
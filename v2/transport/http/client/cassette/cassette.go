@@ -0,0 +1,233 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// Redacted replaces redacted header values and body fields.
+const Redacted = "REDACTED"
+
+// RecordEnv names the environment variable that switches New to record mode.
+const RecordEnv = "CASSETTE_RECORD"
+
+// Cassette is the file format: interactions in the order they were recorded.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of an outbound request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body
+}
+
+// Body holds a message body as text, or as base64 when it is not UTF-8.
+type Body struct {
+	Body         string `json:"body,omitempty"`
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+func newBody(raw []byte) Body {
+	if utf8.Valid(raw) {
+		return Body{Body: string(raw)}
+	}
+	return Body{Body: base64.StdEncoding.EncodeToString(raw), BodyEncoding: "base64"}
+}
+
+// Bytes returns the decoded body.
+func (b Body) Bytes() ([]byte, error) {
+	switch b.BodyEncoding {
+	case "":
+		return []byte(b.Body), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(b.Body)
+	default:
+		return nil, fmt.Errorf("cassette: unknown body encoding %q", b.BodyEncoding)
+	}
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("cassette: decode %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, creating parent directories.
+func (c *Cassette) Save(path string) error {
+	raw, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: encode: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(path, append(raw, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// MatchFunc reports whether a recorded request answers a live one. body is
+// the live request body after redaction.
+type MatchFunc func(live *http.Request, body []byte, recorded Request) bool
+
+// Option configures a Recorder or Replayer.
+type Option func(*config)
+
+type config struct {
+	headers map[string]bool
+	fields  map[string]bool
+	match   MatchFunc
+}
+
+func newConfig(options []Option) *config {
+	c := &config{headers: make(map[string]bool), fields: make(map[string]bool), match: DefaultMatch}
+	for _, name := range []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"} {
+		c.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, option := range options {
+		if option != nil {
+			option(c)
+		}
+	}
+	return c
+}
+
+// RedactHeaders replaces the values of the named request and response
+// headers with Redacted, in addition to the defaults.
+func RedactHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.headers[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// RedactBodyFields replaces JSON object fields with these names, at any
+// depth, with Redacted in request and response bodies.
+func RedactBodyFields(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.fields[name] = true
+		}
+	}
+}
+
+// WithMatcher replaces DefaultMatch.
+func WithMatcher(match MatchFunc) Option {
+	return func(c *config) {
+		if match != nil {
+			c.match = match
+		}
+	}
+}
+
+// DefaultMatch compares method, full URL and body. JSON bodies are compared
+// by value, so key order and whitespace do not matter.
+func DefaultMatch(live *http.Request, body []byte, recorded Request) bool {
+	if live.Method != recorded.Method || live.URL.String() != recorded.URL {
+		return false
+	}
+	want, err := recorded.Bytes()
+	if err != nil {
+		return false
+	}
+	return bodiesEqual(body, want)
+}
+
+func bodiesEqual(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	ac, _ := json.Marshal(av)
+	bc, _ := json.Marshal(bv)
+	return bytes.Equal(ac, bc)
+}
+
+func (c *config) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for name := range out {
+		if c.headers[name] {
+			out[name] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// redactBody rewrites JSON bodies with the configured fields redacted. Other
+// bodies are returned unchanged.
+func (c *config) redactBody(raw []byte) []byte {
+	if len(c.fields) == 0 || len(raw) == 0 {
+		return raw
+	}
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if decoder.Decode(&v) != nil {
+		return raw
+	}
+	if !c.redactValue(v) {
+		return raw
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+func (c *config) redactValue(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if c.fields[key] {
+				v[key] = Redacted
+				changed = true
+				continue
+			}
+			changed = c.redactValue(value) || changed
+		}
+	case []any:
+		for _, value := range v {
+			changed = c.redactValue(value) || changed
+		}
+	}
+	return changed
+}
@@ -0,0 +1,177 @@
+package cassette_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/client"
+	"github.com/dreamsxin/go-kit/v2/transport/http/client/cassette"
+)
+
+type item struct {
+	Name   string `json:"name"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+func itemServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in item
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_ = json.NewEncoder(w).Encode(item{Name: "got " + in.Name, APIKey: "server-key"})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func post(t *testing.T, httpClient client.HTTPClient, url, body string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	return httpClient.Do(req)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	srv := itemServer(t)
+	path := filepath.Join(t.TempDir(), "items.json")
+	options := []cassette.Option{cassette.RedactBodyFields("api_key")}
+
+	recorder := cassette.NewRecorder(nil, options...)
+	resp, err := post(t, recorder, srv.URL+"/items?x=1", `{"name":"a","api_key":"client-key"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "server-key") {
+		t.Fatalf("recorder altered the live response: %s", body)
+	}
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	raw, _ := os.ReadFile(path)
+	for _, secret := range []string{"client-key", "server-key", "Bearer token", "session=secret"} {
+		if strings.Contains(string(raw), secret) {
+			t.Fatalf("cassette leaks %q:\n%s", secret, raw)
+		}
+	}
+
+	replayer, err := cassette.NewReplayer(path, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Key order and the redacted field value do not affect matching.
+	resp, err = post(t, replayer, srv.URL+"/items?x=1", `{"api_key":"other-key", "name":"a"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got item
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || got.Name != "got a" || got.APIKey != cassette.Redacted {
+		t.Fatalf("replayed %d %+v", resp.StatusCode, got)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("replayed header = %v", resp.Header)
+	}
+
+	_, err = post(t, replayer, srv.URL+"/items?x=1", `{"name":"a"}`)
+	var unmatched *cassette.UnmatchedError
+	if !errors.As(err, &unmatched) || unmatched.Method != http.MethodPost {
+		t.Fatalf("second identical call: got %v, want UnmatchedError", err)
+	}
+	if _, err := post(t, replayer, srv.URL+"/items?x=2", `{"name":"a"}`); !errors.As(err, &unmatched) {
+		t.Fatalf("different URL: got %v", err)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Fatalf("unused = %+v", unused)
+	}
+}
+
+func TestReplayer_DrivesJSONClient(t *testing.T) {
+	srv := itemServer(t)
+	recorder := cassette.NewRecorder(http.DefaultClient)
+	call := func(httpClient client.HTTPClient) item {
+		ep, err := client.NewJSONClient[item](http.MethodPost, srv.URL+"/items", client.SetClient(httpClient))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ep(context.Background(), item{Name: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.(item)
+	}
+	live := call(recorder)
+
+	replayer := cassette.NewReplayerFrom(recorder.Cassette(), cassette.WithMatcher(func(live *http.Request, _ []byte, recorded cassette.Request) bool {
+		return live.Method == recorded.Method && strings.HasSuffix(recorded.URL, live.URL.Path)
+	}))
+	srv.Close()
+	if replayed := call(replayer); replayed != live {
+		t.Fatalf("replayed %+v, recorded %+v", replayed, live)
+	}
+}
+
+func TestNew_SwitchesOnRecordEnv(t *testing.T) {
+	srv := itemServer(t)
+	path := filepath.Join(t.TempDir(), "new.json")
+
+	t.Run("record", func(t *testing.T) {
+		t.Setenv(cassette.RecordEnv, "1")
+		if _, err := post(t, cassette.New(t, path, nil), srv.URL, `{"name":"c"}`); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("replay", func(t *testing.T) {
+		t.Setenv(cassette.RecordEnv, "")
+		resp, err := post(t, cassette.New(t, path, nil), srv.URL, `{"name":"c"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "got c") {
+			t.Fatalf("replayed body = %s", body)
+		}
+	})
+}
+
+func TestBinaryBodiesRoundTrip(t *testing.T) {
+	payload := []byte{0xff, 0x00, 0xfe}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(payload)
+	}))
+	defer srv.Close()
+
+	recorder := cassette.NewRecorder(nil)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/blob", nil)
+	if _, err := recorder.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	c := recorder.Cassette()
+	if c.Interactions[0].Response.BodyEncoding != "base64" {
+		t.Fatalf("binary body stored as %+v", c.Interactions[0].Response.Body)
+	}
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/blob", nil)
+	resp, err := cassette.NewReplayerFrom(c).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != string(payload) {
+		t.Fatalf("replayed body = %x", body)
+	}
+}
@@ -0,0 +1,28 @@
+// Package cassette records HTTP interactions to golden files and replays
+// them, so client tests run without live dependencies.
+//
+// A Recorder wraps a real client.HTTPClient, forwards every call, and keeps
+// the request and response; Save writes them to a JSON cassette. A Replayer
+// loads a cassette and answers calls by matching method, URL and body,
+// returning an *UnmatchedError for any call it has no recording for. Both
+// implement client.HTTPClient, so they plug into client.SetClient, generated
+// SDKs, and sd/client factories alike.
+//
+// Secrets never reach the cassette: Authorization, Cookie, Set-Cookie and
+// Proxy-Authorization headers are redacted by default, RedactHeaders and
+// RedactBodyFields add more, and the replayer applies the same redaction to
+// live requests before matching.
+//
+// New picks the mode for a test: it records when the CASSETTE_RECORD
+// environment variable is set and replays otherwise.
+//
+//	func TestListItems(t *testing.T) {
+//	    httpClient := cassette.New(t, "testdata/list_items.json", http.DefaultClient,
+//	        cassette.RedactBodyFields("api_key"))
+//	    items := sdk.NewClient(baseURL, client.SetClient(httpClient))
+//	    ...
+//	}
+//
+// Run once with CASSETTE_RECORD=1 against the real service to refresh the
+// cassette, then commit the file.
+package cassette
@@ -0,0 +1,113 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/client"
+)
+
+var (
+	_ client.HTTPClient = (*Recorder)(nil)
+	_ client.HTTPClient = (*Replayer)(nil)
+)
+
+// Recorder forwards calls to a real client and records each interaction.
+// Responses are read in full and handed back with a fresh body.
+type Recorder struct {
+	next   client.HTTPClient
+	config *config
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder around next; a nil next selects
+// http.DefaultClient.
+func NewRecorder(next client.HTTPClient, options ...Option) *Recorder {
+	if next == nil {
+		next = http.DefaultClient
+	}
+	return &Recorder{next: next, config: newConfig(options)}
+}
+
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.next.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.config.redactHeader(req.Header),
+			Body:   newBody(r.config.redactBody(body)),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.config.redactHeader(resp.Header),
+			Body:       newBody(r.config.redactBody(respBody)),
+		},
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Save writes the recorded interactions to path.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// New returns the client for one test. When the RecordEnv variable is set
+// it records through next and saves path when the test ends; otherwise it
+// replays path and fails the test on calls that match no recording.
+func New(t testing.TB, path string, next client.HTTPClient, options ...Option) client.HTTPClient {
+	t.Helper()
+	if os.Getenv(RecordEnv) != "" {
+		recorder := NewRecorder(next, options...)
+		t.Cleanup(func() {
+			if err := recorder.Save(path); err != nil {
+				t.Errorf("%v", err)
+			}
+		})
+		return recorder
+	}
+	replayer, err := NewReplayer(path, options...)
+	if err != nil {
+		t.Fatalf("%v (set %s=1 to record it)", err, RecordEnv)
+	}
+	replayer.onUnmatched = func(err error) { t.Errorf("%v", err) }
+	return replayer
+}
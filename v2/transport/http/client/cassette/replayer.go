@@ -0,0 +1,115 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// UnmatchedError reports a call for which the cassette has no unused
+// recording.
+type UnmatchedError struct {
+	Method string
+	URL    string
+}
+
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("cassette: no recorded interaction for %s %s", e.Method, e.URL)
+}
+
+// Replayer answers calls from a cassette without touching the network. Each
+// recording answers one call, in recorded order among equal requests.
+type Replayer struct {
+	config       *config
+	interactions []Interaction
+	onUnmatched  func(error)
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewReplayer loads the cassette at path.
+func NewReplayer(path string, options ...Option) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerFrom(c, options...), nil
+}
+
+// NewReplayerFrom replays an in-memory cassette, such as Recorder.Cassette.
+func NewReplayerFrom(c *Cassette, options ...Option) *Replayer {
+	return &Replayer{
+		config:       newConfig(options),
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+}
+
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+	}
+	body = r.config.redactBody(body)
+
+	r.mu.Lock()
+	index := -1
+	for i, interaction := range r.interactions {
+		if !r.used[i] && r.config.match(req, body, interaction.Request) {
+			index = i
+			r.used[i] = true
+			break
+		}
+	}
+	r.mu.Unlock()
+	if index < 0 {
+		err := &UnmatchedError{Method: req.Method, URL: req.URL.String()}
+		if r.onUnmatched != nil {
+			r.onUnmatched(err)
+		}
+		return nil, err
+	}
+
+	recorded := r.interactions[index].Response
+	respBody, err := recorded.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the recorded requests no call has matched yet, which
+// usually means the code under test stopped making them.
+func (r *Replayer) Unused() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Request
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.interactions[i].Request)
+		}
+	}
+	return unused
+}
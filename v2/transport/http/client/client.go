@@ -22,6 +22,8 @@ type Client struct {
 	after          []ResponseFunc
 	finalizer      []FinalizerFunc // Always runs, regardless of success or failure.
 	bufferedStream bool
+	compression    *compression
+	retry          *retryPolicy
 }
 
 // NewClient constructs an HTTP client using method/target-based request creation.
@@ -50,7 +52,12 @@ func NewExplicitClient(req EncodeRequestFunc, dec DecodeResponseFunc, options ..
 
 func (c Client) Endpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		ctx, cancel := context.WithCancel(ctx)
+		var cancel context.CancelFunc
+		if c.retry != nil && c.retry.TotalTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, c.retry.TotalTimeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
 
 		var (
 			resp *http.Response
@@ -75,16 +82,37 @@ func (c Client) Endpoint() endpoint.Endpoint {
 			cancel()
 			return nil, err
 		}
+		if c.compression != nil {
+			c.compression.prepare(req)
+		}
 
 		for _, f := range c.before {
 			ctx = f(ctx, req)
 		}
 
-		resp, err = c.client.Do(req.WithContext(ctx))
+		var history []Attempt
+		if c.retry != nil {
+			var cancelAttempt context.CancelFunc
+			resp, cancelAttempt, history, err = c.retry.do(ctx, c.client, req)
+			cancelCall := cancel
+			cancel = func() {
+				cancelAttempt()
+				cancelCall()
+			}
+		} else {
+			resp, err = c.client.Do(req.WithContext(ctx))
+		}
 		if err != nil {
 			cancel()
 			return nil, err
 		}
+		if c.compression != nil {
+			if err = c.compression.decode(resp); err != nil {
+				_ = resp.Body.Close()
+				cancel()
+				return nil, err
+			}
+		}
 
 		if c.bufferedStream {
 			resp.Body = bodyWithCancel{ReadCloser: resp.Body, cancel: cancel}
@@ -99,6 +127,7 @@ func (c Client) Endpoint() endpoint.Endpoint {
 
 		response, err := c.dec(ctx, resp)
 		if err != nil {
+			attachAttempts(err, history)
 			if c.bufferedStream {
 				_ = resp.Body.Close()
 			}
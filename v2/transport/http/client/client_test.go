@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"iter"
 	"math"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +16,8 @@ import (
 	"time"
 
 	httpclient "github.com/dreamsxin/go-kit/v2/transport/http/client"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+	"github.com/dreamsxin/go-kit/v2/transport/http/compress"
 )
 
 type echoReq struct {
@@ -554,3 +557,310 @@ func TestDecodeJSONResponseWithMaxBodyBytes_LimitsBody(t *testing.T) {
 		t.Fatalf("want ErrResponseBodyTooLarge, got %v", err)
 	}
 }
+
+func TestNewCodecClient_NegotiatesWithServer(t *testing.T) {
+	type greetReq struct {
+		Name string `json:"name"`
+	}
+	type greetResp struct {
+		Greeting string `json:"greeting"`
+	}
+	var gotContentType, gotAccept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotContentType, gotAccept = r.Header.Get("Content-Type"), r.Header.Get("Accept")
+		body, _ := io.ReadAll(r.Body)
+		var req greetReq
+		if err := codec.MessagePack.Unmarshal(body, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Answer in CBOR to check decoding follows the response Content-Type.
+		data, _ := codec.CBOR.Marshal(greetResp{Greeting: "hi " + req.Name})
+		w.Header().Set("Content-Type", codec.CBORContentType)
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	ep, err := httpclient.NewCodecClient[greetResp](http.MethodPost, srv.URL, codec.MessagePack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ep(context.Background(), greetReq{Name: "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.(greetResp).Greeting != "hi ada" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if gotContentType != codec.MessagePackContentType || gotAccept != codec.MessagePackContentType {
+		t.Fatalf("Content-Type = %q, Accept = %q", gotContentType, gotAccept)
+	}
+
+	dec := httpclient.DecodeCodecResponse[greetResp](nil, 1024)
+	_, err = dec(context.Background(), &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/csv"}},
+		Body:       io.NopCloser(strings.NewReader("a,b")),
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported response content type") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestAcceptCompression_DecompressesWithinLimit(t *testing.T) {
+	payload := strings.Repeat("compressible ", 200)
+	srv := httptest.NewServer(compress.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+			t.Errorf("Accept-Encoding = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(echoResp{Echo: payload}) //nolint:errcheck
+	})))
+	defer srv.Close()
+
+	ep, err := httpclient.NewJSONClient[echoResp](http.MethodPost, srv.URL, httpclient.AcceptCompression(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ep(context.Background(), echoReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.(echoResp).Echo != payload {
+		t.Fatalf("echo = %q", resp.(echoResp).Echo)
+	}
+
+	ep, _ = httpclient.NewJSONClient[echoResp](http.MethodPost, srv.URL, httpclient.AcceptCompression(512))
+	_, err = ep(context.Background(), echoReq{})
+	var tooLarge *httpclient.ResponseBodyTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 512 {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestClientRetry_RetriesIdempotentRequestsAndHonorsRetryAfter(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(echoResp{Echo: "ok"}) //nolint:errcheck
+		}
+	}))
+	defer srv.Close()
+
+	ep, err := httpclient.NewJSONClient[echoResp](http.MethodGet, srv.URL, httpclient.ClientRetry(httpclient.RetryOptions{
+		Backoff: func(int) time.Duration { return time.Hour },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ep(context.Background(), nil)
+	if err != nil || resp.(echoResp).Echo != "ok" || calls != 3 {
+		t.Fatalf("resp = %v, err = %v, calls = %d", resp, err, calls)
+	}
+}
+
+func TestClientRetry_ReplaysBodiesOnlyWithIdempotencyKey(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies)%2 == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(echoResp{Echo: "created"}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	retry := httpclient.ClientRetry(httpclient.RetryOptions{Backoff: func(int) time.Duration { return time.Millisecond }})
+
+	ep, _ := httpclient.NewJSONClient[echoResp](http.MethodPost, srv.URL, retry)
+	_, err := ep(context.Background(), echoReq{Message: "once"})
+	var statusErr *httpclient.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway || len(bodies) != 1 {
+		t.Fatalf("POST without key: err = %v, bodies = %q", err, bodies)
+	}
+
+	bodies = nil
+	ep, _ = httpclient.NewJSONClient[echoResp](http.MethodPost, srv.URL, retry,
+		httpclient.ClientBefore(func(ctx context.Context, r *http.Request) context.Context {
+			r.Header.Set(httpclient.DefaultIdempotencyKeyHeader, "key-1")
+			return ctx
+		}))
+	resp, err := ep(context.Background(), echoReq{Message: "twice"})
+	if err != nil || resp.(echoResp).Echo != "created" {
+		t.Fatalf("resp = %v, err = %v", resp, err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "twice") {
+		t.Fatalf("bodies = %q", bodies)
+	}
+}
+
+func TestClientRetry_FinalStatusErrorKeepsHistory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer srv.Close()
+
+	ep, _ := httpclient.NewJSONClient[echoResp](http.MethodGet, srv.URL, httpclient.ClientRetry(httpclient.RetryOptions{
+		MaxAttempts: 3,
+		Backoff:     func(retry int) time.Duration { return time.Duration(retry) * time.Millisecond },
+	}))
+	_, err := ep(context.Background(), nil)
+	var statusErr *httpclient.HTTPStatusError
+	if !errors.As(err, &statusErr) || len(statusErr.Attempts) != 3 {
+		t.Fatalf("err = %v", err)
+	}
+	for i, attempt := range statusErr.Attempts {
+		wantWait := time.Duration(i+1) * time.Millisecond
+		if i == 2 {
+			wantWait = 0
+		}
+		if attempt.StatusCode != http.StatusGatewayTimeout || attempt.Wait != wantWait {
+			t.Fatalf("attempt %d = %+v", i, attempt)
+		}
+	}
+}
+
+func TestClientRetry_TimeoutsAndRetryAfterCap(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/slow" && calls == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(echoResp{Echo: "ok"}) //nolint:errcheck
+	}))
+	defer srv.Close()
+	retry := httpclient.ClientRetry(httpclient.RetryOptions{
+		PerAttemptTimeout: 50 * time.Millisecond,
+		TotalTimeout:      2 * time.Second,
+		Backoff:           func(int) time.Duration { return time.Millisecond },
+	})
+
+	ep, _ := httpclient.NewJSONClient[echoResp](http.MethodGet, srv.URL+"/slow", retry)
+	if resp, err := ep(context.Background(), nil); err != nil || resp.(echoResp).Echo != "ok" || calls != 2 {
+		t.Fatalf("slow: resp = %v, err = %v, calls = %d", resp, err, calls)
+	}
+
+	calls = 0
+	ep, _ = httpclient.NewJSONClient[echoResp](http.MethodGet, srv.URL+"/busy", retry)
+	start := time.Now()
+	_, err := ep(context.Background(), nil)
+	var statusErr *httpclient.HTTPStatusError
+	if !errors.As(err, &statusErr) || calls != 1 || time.Since(start) > time.Second {
+		t.Fatalf("busy: err = %v, calls = %d", err, calls)
+	}
+}
+
+type streamRow struct {
+	ID int `json:"id"`
+}
+
+func TestNewStreamClient_DecodesNDJSONAndJSONSeq(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") || r.URL.Query().Get("from") != "2" {
+			t.Errorf("Accept = %q, query = %q", r.Header.Get("Accept"), r.URL.RawQuery)
+		}
+		if r.URL.Path == "/seq" {
+			w.Header().Set("Content-Type", "application/json-seq")
+			_, _ = io.WriteString(w, "\x1e{\"id\":2}\n\x1e{\"id\":3}\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = io.WriteString(w, "{\"id\":2}\n\n{\"id\":3}\n")
+	}))
+	defer srv.Close()
+
+	type exportReq struct {
+		From int `query:"from"`
+	}
+	for _, path := range []string{"/ndjson", "/seq"} {
+		ep, err := httpclient.NewStreamClient[streamRow](http.MethodGet, srv.URL+path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ep(context.Background(), exportReq{From: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for row, err := range resp.(iter.Seq2[streamRow, error]) {
+			if err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			ids = append(ids, row.ID)
+		}
+		if len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+			t.Fatalf("%s: ids = %v", path, ids)
+		}
+	}
+}
+
+func TestNewStreamClient_BoundsRecordsAndStopsEarly(t *testing.T) {
+	closed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if r.URL.Path == "/huge" {
+			_, _ = io.WriteString(w, `{"id":1,"pad":"`+strings.Repeat("x", 2<<20)+"\"}\n")
+			return
+		}
+		for i := 0; ; i++ {
+			if _, err := io.WriteString(w, "{\"id\":1}\n"); err != nil {
+				close(closed)
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	ep, _ := httpclient.NewStreamClient[streamRow](http.MethodGet, srv.URL+"/huge")
+	resp, err := ep(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []error
+	for _, err := range resp.(iter.Seq2[streamRow, error]) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], httpclient.ErrStreamRecordTooLarge) {
+		t.Fatalf("errs = %v", errs)
+	}
+
+	ep, _ = httpclient.NewStreamClient[streamRow](http.MethodGet, srv.URL+"/endless")
+	resp, err = ep(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	for range resp.(iter.Seq2[streamRow, error]) {
+		if n++; n == 3 {
+			break
+		}
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("breaking out of the sequence did not close the connection")
+	}
+}
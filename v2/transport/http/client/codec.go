@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+)
+
+// EncodeCodecRequest returns an EncodeRequestFunc that marshals the request
+// with c and sets Content-Type to its media type. Accept is set to the same
+// type unless the request's Headerer set one; Before hooks run later and may
+// still replace it.
+func EncodeCodecRequest(c codec.Codec) EncodeRequestFunc {
+	if c == nil {
+		panic("http client: codec cannot be nil")
+	}
+	return func(_ context.Context, req *http.Request, request any) (*http.Request, error) {
+		if req == nil {
+			return nil, fmt.Errorf("request is nil")
+		}
+		data, err := c.Marshal(request)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", c.ContentType())
+		applyRequestHeaders(req, request)
+		setDefaultAccept(req, c)
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		req.ContentLength = int64(len(data))
+		return req, nil
+	}
+}
+
+// DecodeCodecResponse returns a DecodeResponseFunc that decodes successful
+// responses into Resp with the codec registered for the response
+// Content-Type, using the registry default when the header is missing. An
+// empty body yields the zero Resp. Non-2xx responses fail with
+// HTTPStatusError, and bodies beyond maxResponseBodyBytes with
+// ResponseBodyTooLargeError. A nil registry means codec.DefaultRegistry.
+func DecodeCodecResponse[Resp any](registry *codec.Registry, maxResponseBodyBytes int64) DecodeResponseFunc {
+	if registry == nil {
+		registry = codec.DefaultRegistry()
+	}
+	return func(_ context.Context, r *http.Response) (any, error) {
+		if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
+			return nil, newHTTPStatusError(r)
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxResponseBodyBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(body)) > maxResponseBodyBytes {
+			return nil, &ResponseBodyTooLargeError{Limit: maxResponseBodyBytes}
+		}
+		var resp Resp
+		if len(body) == 0 {
+			return resp, nil
+		}
+		c := registry.Default()
+		if contentType := r.Header.Get("Content-Type"); contentType != "" {
+			var ok bool
+			if c, ok = registry.Lookup(contentType); !ok {
+				return nil, fmt.Errorf("http client: unsupported response content type %q", contentType)
+			}
+		}
+		if err := c.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// NewCodecClient creates an HTTP client endpoint that sends requests in the
+// format of c and asks for responses in the same format. GET and HEAD
+// requests encode fields into the URL instead of a body. Responses are
+// decoded by their Content-Type with codec.DefaultRegistry plus c, so a
+// server that answers in another registered format still works. Successful
+// bodies are capped at DefaultMaxJSONResponseBytes.
+//
+//	ep, err := client.NewCodecClient[UserResp](http.MethodPost, "http://users/v1/users", codec.MessagePack)
+func NewCodecClient[Resp any](method, rawURL string, c codec.Codec, options ...ClientOption) (endpoint.Endpoint, error) {
+	if c == nil {
+		return nil, fmt.Errorf("NewCodecClient: codec cannot be nil")
+	}
+	tgt, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("NewCodecClient: invalid URL %q: %w", rawURL, err)
+	}
+	registry, err := codec.DefaultRegistry().With(c)
+	if err != nil {
+		return nil, fmt.Errorf("NewCodecClient: %w", err)
+	}
+	encoder := EncodeCodecRequest(c)
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead:
+		encoder = func(ctx context.Context, req *http.Request, request any) (*http.Request, error) {
+			req, err := EncodeQueryRequest(ctx, req, request)
+			if err != nil {
+				return nil, err
+			}
+			setDefaultAccept(req, c)
+			return req, nil
+		}
+	}
+	dec := DecodeCodecResponse[Resp](registry, DefaultMaxJSONResponseBytes)
+	return NewClient(method, tgt, encoder, dec, options...).Endpoint(), nil
+}
+
+func setDefaultAccept(req *http.Request, c codec.Codec) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", c.ContentType())
+	}
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/compress"
+)
+
+type compression struct {
+	encodings []compress.Encoding
+	limit     int64
+}
+
+// AcceptCompression asks for compressed responses and decompresses them
+// before the DecodeResponseFunc runs. Accept-Encoding lists encodings,
+// compress.Gzip when none are given, unless the request already set it.
+// Decompressed bodies beyond maxDecompressedBytes fail with
+// ResponseBodyTooLargeError while being read; a value <= 0 disables the limit.
+// Responses in codings outside encodings are left as they are.
+//
+//	client.NewJSONClient[Resp](http.MethodGet, url, client.AcceptCompression(8<<20))
+func AcceptCompression(maxDecompressedBytes int64, encodings ...compress.Encoding) ClientOption {
+	comp := &compression{limit: maxDecompressedBytes}
+	for _, enc := range encodings {
+		if enc != nil {
+			comp.encodings = append(comp.encodings, enc)
+		}
+	}
+	if len(comp.encodings) == 0 {
+		comp.encodings = []compress.Encoding{compress.Gzip}
+	}
+	return func(c *Client) { c.compression = comp }
+}
+
+func (comp *compression) prepare(req *http.Request) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", compress.AcceptEncoding(comp.encodings...))
+	}
+}
+
+func (comp *compression) decode(resp *http.Response) error {
+	enc, ok := compress.Lookup(resp.Header.Get("Content-Encoding"), comp.encodings...)
+	if !ok {
+		return nil
+	}
+	reader, err := enc.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	resp.Body = compress.NewLimitedReader(reader, resp.Body, comp.limit, &ResponseBodyTooLargeError{Limit: comp.limit})
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -43,6 +43,9 @@ type HTTPStatusError struct {
 	Status     string
 	Header     http.Header
 	Body       []byte
+	// Attempts lists every attempt of the call, the final one last, when
+	// ClientRetry is configured.
+	Attempts []Attempt
 }
 
 func (e *HTTPStatusError) Error() string {
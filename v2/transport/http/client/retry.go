@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/internal/backoff"
+)
+
+// DefaultIdempotencyKeyHeader marks non-idempotent requests that ClientRetry
+// may still retry because the server deduplicates them by key.
+const DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryOptions configures ClientRetry. Zero fields take the defaults noted.
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Default 3.
+	MaxAttempts int
+
+	// PerAttemptTimeout bounds each attempt, including reading the response
+	// body of the final one. An attempt that times out is retried. Zero
+	// means no per-attempt limit.
+	PerAttemptTimeout time.Duration
+
+	// TotalTimeout bounds the whole call across attempts and waits. Zero
+	// leaves the caller's context as the only limit.
+	TotalTimeout time.Duration
+
+	// Backoff returns the wait before retry n (1 for the first retry) when
+	// the response has no Retry-After. Default: exponential from 100ms with
+	// 50 percent jitter, never more than 5s.
+	Backoff func(retry int) time.Duration
+
+	// MaxRetryAfter caps the Retry-After the client is willing to wait;
+	// longer requests end the retries. Default 30s.
+	MaxRetryAfter time.Duration
+
+	// IdempotencyKeyHeader names the header that makes POST and PATCH
+	// requests retryable. Default DefaultIdempotencyKeyHeader.
+	IdempotencyKeyHeader string
+}
+
+// Attempt records one try of a retried call.
+type Attempt struct {
+	// StatusCode is the response status, or 0 when no response arrived.
+	StatusCode int
+	// Err is the transport error when no response arrived.
+	Err error
+	// Duration is how long the attempt took until response headers or
+	// failure.
+	Duration time.Duration
+	// Wait is the delay before the next attempt; zero for the last one.
+	Wait time.Duration
+}
+
+type retryPolicy struct {
+	RetryOptions
+}
+
+// ClientRetry retries idempotent requests (GET, HEAD, OPTIONS, TRACE, PUT,
+// DELETE) and requests carrying an idempotency key when the connection
+// fails or the server answers 429, 502, 503 or 504. Waits honor Retry-After
+// in seconds or as an HTTP date and otherwise follow RetryOptions.Backoff.
+//
+// Request bodies are buffered once after encoding and replayed on each
+// attempt; ClientBefore hooks run once. When the final attempt still fails,
+// its HTTPStatusError carries the attempt history in Attempts, and
+// transport errors are wrapped with the attempt count.
+//
+//	client.NewJSONClient[Resp](http.MethodGet, url, client.ClientRetry(client.RetryOptions{
+//	    MaxAttempts:       4,
+//	    PerAttemptTimeout: 2 * time.Second,
+//	    TotalTimeout:      10 * time.Second,
+//	}))
+func ClientRetry(options RetryOptions) ClientOption {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 3
+	}
+	if options.Backoff == nil {
+		options.Backoff = defaultRetryBackoff
+	}
+	if options.MaxRetryAfter <= 0 {
+		options.MaxRetryAfter = 30 * time.Second
+	}
+	if options.IdempotencyKeyHeader == "" {
+		options.IdempotencyKeyHeader = DefaultIdempotencyKeyHeader
+	}
+	policy := &retryPolicy{RetryOptions: options}
+	return func(c *Client) { c.retry = policy }
+}
+
+func defaultRetryBackoff(retry int) time.Duration {
+	return backoff.Exponential(100*time.Millisecond, 5*time.Second, 0.5, retry)
+}
+
+func (p *retryPolicy) retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(p.IdempotencyKeyHeader) != ""
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends req until it succeeds, fails for good, or attempts run out. The
+// returned cancel releases the final attempt's context and must be called
+// once its response body is done.
+func (p *retryPolicy) do(ctx context.Context, client HTTPClient, req *http.Request) (*http.Response, context.CancelFunc, []Attempt, error) {
+	retryable := p.retryable(req)
+	if retryable {
+		if err := rewindable(req); err != nil {
+			return nil, func() {}, nil, err
+		}
+	}
+
+	var history []Attempt
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.PerAttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.PerAttemptTimeout)
+		}
+		attemptReq := req.WithContext(attemptCtx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, func() {}, history, err
+			}
+			attemptReq = req.Clone(attemptCtx)
+			attemptReq.Body = body
+		}
+
+		start := time.Now()
+		resp, err := client.Do(attemptReq)
+		record := Attempt{Err: err, Duration: time.Since(start)}
+		if resp != nil {
+			record.StatusCode = resp.StatusCode
+		}
+		history = append(history, record)
+
+		last := !retryable || attempt >= p.MaxAttempts || ctx.Err() != nil
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			last = true
+		}
+		var wait time.Duration
+		if !last {
+			wait = p.Backoff(attempt)
+			if err == nil {
+				if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+					wait = retryAfter
+				}
+			}
+			if wait > p.MaxRetryAfter {
+				last = true
+			} else if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				last = true
+			}
+		}
+		if last {
+			if err != nil {
+				cancel()
+				if len(history) > 1 {
+					err = fmt.Errorf("http client: giving up after %d attempts: %w", len(history), err)
+				}
+			}
+			return resp, cancel, history, err
+		}
+
+		history[len(history)-1].Wait = wait
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxStatusErrorBody))
+			_ = resp.Body.Close()
+		}
+		cancel()
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, func() {}, history, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rewindable makes req.GetBody available so attempts can replay the body.
+func rewindable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
+
+// parseRetryAfter reads delta-seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(min(seconds, 1<<32)) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// attachAttempts adds the retry history to the final HTTPStatusError.
+func attachAttempts(err error, history []Attempt) {
+	var statusErr *HTTPStatusError
+	if len(history) > 0 && errors.As(err, &statusErr) {
+		statusErr.Attempts = history
+	}
+}
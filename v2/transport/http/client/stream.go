@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+)
+
+// DefaultMaxStreamRecordBytes bounds one record of a streamed response.
+const DefaultMaxStreamRecordBytes = 1 << 20
+
+// ErrStreamRecordTooLarge is yielded when one streamed record exceeds the
+// configured limit.
+var ErrStreamRecordTooLarge = errors.New("http client: stream record too large")
+
+// DecodeStreamResponse returns a DecodeResponseFunc for NDJSON and JSON text
+// sequence responses, chosen by Content-Type, that returns an
+// iter.Seq2[T, error]. Values are decoded lazily while the caller ranges over
+// the sequence, so memory stays bounded by maxRecordBytes per record; a
+// larger record yields ErrStreamRecordTooLarge. The body is closed when the
+// sequence ends or the caller stops early, and the request context cancels
+// reads. The sequence can be ranged over once.
+//
+// The client must be built with BufferedStream(true) so the body outlives
+// the endpoint call; NewStreamClient does this. Non-2xx responses fail with
+// HTTPStatusError before any value is decoded.
+func DecodeStreamResponse[T any](maxRecordBytes int) DecodeResponseFunc {
+	if maxRecordBytes <= 0 {
+		maxRecordBytes = DefaultMaxStreamRecordBytes
+	}
+	return func(_ context.Context, r *http.Response) (any, error) {
+		if r.StatusCode < http.StatusOK || r.StatusCode >= http.StatusMultipleChoices {
+			err := newHTTPStatusError(r)
+			_ = r.Body.Close()
+			return nil, err
+		}
+		split := bufio.ScanLines
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == codec.JSONSeqContentType {
+			split = scanJSONSeq
+		}
+		body := r.Body
+		var seq iter.Seq2[T, error] = func(yield func(T, error) bool) {
+			defer body.Close()
+			scanner := bufio.NewScanner(body)
+			scanner.Buffer(make([]byte, 0, min(maxRecordBytes, 64<<10)), maxRecordBytes)
+			scanner.Split(split)
+			for scanner.Scan() {
+				record := bytes.TrimSpace(scanner.Bytes())
+				if len(record) == 0 {
+					continue
+				}
+				var value T
+				if err := json.Unmarshal(record, &value); err != nil {
+					yield(value, fmt.Errorf("http client: decode stream record: %w", err))
+					return
+				}
+				if !yield(value, nil) {
+					return
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				if errors.Is(err, bufio.ErrTooLong) {
+					err = fmt.Errorf("%w (limit %d bytes)", ErrStreamRecordTooLarge, maxRecordBytes)
+				}
+				var zero T
+				yield(zero, err)
+			}
+		}
+		return seq, nil
+	}
+}
+
+// scanJSONSeq splits RFC 7464 JSON text sequences on the record separator.
+func scanJSONSeq(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && data[start] == 0x1e {
+		start++
+	}
+	if i := bytes.IndexByte(data[start:], 0x1e); i >= 0 {
+		return start + i, data[start : start+i], nil
+	}
+	if atEOF {
+		if start == len(data) {
+			return len(data), nil, nil
+		}
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// NewStreamClient creates an HTTP client endpoint whose response is an
+// iter.Seq2[T, error] over an NDJSON or JSON text sequence stream. Requests
+// are encoded like NewJSONClient, Accept lists both stream formats, and
+// records are capped at DefaultMaxStreamRecordBytes.
+//
+//	ep, err := client.NewStreamClient[Row](http.MethodGet, "http://exports/v1/rows")
+//	resp, err := ep(ctx, ExportReq{Since: since})
+//	for row, err := range resp.(iter.Seq2[Row, error]) {
+//	    ...
+//	}
+func NewStreamClient[T any](method, rawURL string, options ...ClientOption) (endpoint.Endpoint, error) {
+	tgt, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("NewStreamClient: invalid URL %q: %w", rawURL, err)
+	}
+	encoder := EncodeJSONRequest
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead:
+		encoder = EncodeQueryRequest
+	}
+	accept := func(ctx context.Context, req *http.Request, request any) (*http.Request, error) {
+		req, err := encoder(ctx, req, request)
+		if err != nil {
+			return nil, err
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", codec.NDJSONContentType+", "+codec.JSONSeqContentType)
+		}
+		return req, nil
+	}
+	opts := append(append([]ClientOption(nil), options...), BufferedStream(true))
+	return NewClient(method, tgt, accept, DecodeStreamResponse[T](DefaultMaxStreamRecordBytes), opts...).Endpoint(), nil
+}
@@ -0,0 +1,323 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+
+	cborIndefinite = 31
+	cborBreak      = 0xff
+)
+
+func marshalCBOR(v any) ([]byte, error) {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendCBOR(nil, tree)
+}
+
+func unmarshalCBOR(data []byte, v any) error {
+	r := &reader{data: data}
+	tree, err := r.cbor(0)
+	if err != nil {
+		return fmt.Errorf("codec: cbor: %w", err)
+	}
+	if r.remaining() != 0 {
+		return fmt.Errorf("codec: cbor: %d trailing bytes", r.remaining())
+	}
+	return fromJSONTree(tree, v)
+}
+
+func appendCBOR(b []byte, tree any) ([]byte, error) {
+	switch v := tree.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if v {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case json.Number:
+		n, err := number(v)
+		if err != nil {
+			return nil, err
+		}
+		switch n := n.(type) {
+		case int64:
+			if n < 0 {
+				return appendCBORHead(b, cborNegint, uint64(-(n + 1))), nil
+			}
+			return appendCBORHead(b, cborUint, uint64(n)), nil
+		case uint64:
+			return appendCBORHead(b, cborUint, n), nil
+		default:
+			return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(n.(float64))), nil
+		}
+	case string:
+		b = appendCBORHead(b, cborText, uint64(len(v)))
+		return append(b, v...), nil
+	case []any:
+		b = appendCBORHead(b, cborArray, uint64(len(v)))
+		var err error
+		for _, item := range v {
+			if b, err = appendCBOR(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendCBORHead(b, cborMap, uint64(len(v)))
+		var err error
+		for _, key := range sortedKeys(v) {
+			if b, err = appendCBOR(b, key); err != nil {
+				return nil, err
+			}
+			if b, err = appendCBOR(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("codec: cbor: unsupported value %T", tree)
+	}
+}
+
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), n)
+	}
+}
+
+func (r *reader) cbor(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("exceeded max depth %d", maxDepth)
+	}
+	c, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := c>>5, c&0x1f
+	if major == cborSimple {
+		return r.cborSimple(info)
+	}
+	if info == cborIndefinite {
+		return r.cborIndefinite(major, depth)
+	}
+	n, err := r.cborArgument(info)
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegint:
+		if n > math.MaxInt64 {
+			v := new(big.Int).SetUint64(n)
+			return json.Number(v.Neg(v.Add(v, big.NewInt(1))).String()), nil
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		data, err := r.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return binaryValue(data), nil
+	case cborText:
+		data, err := r.next(n)
+		return string(data), err
+	case cborArray:
+		if err := r.checkCount(n, 1); err != nil {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = r.cbor(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if err := r.checkCount(n, 2); err != nil {
+			return nil, err
+		}
+		m := make(map[string]any, n)
+		for i := uint64(0); i < n; i++ {
+			if err := r.cborEntry(m, depth); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default: // cborTag
+		return r.cbor(depth + 1)
+	}
+}
+
+func (r *reader) cborArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return r.uint(1 << (info - 24))
+	default:
+		return 0, fmt.Errorf("invalid additional information %d", info)
+	}
+}
+
+func (r *reader) cborSimple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		bits, err := r.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return floatValue(float16(uint16(bits)))
+	case 26:
+		bits, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatValue(float64(math.Float32frombits(uint32(bits))))
+	case 27:
+		bits, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatValue(math.Float64frombits(bits))
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d", info)
+	}
+}
+
+// cborIndefinite decodes indefinite-length strings, arrays and maps, which
+// end with a break byte.
+func (r *reader) cborIndefinite(major byte, depth int) (any, error) {
+	switch major {
+	case cborBytes, cborText:
+		var sb strings.Builder
+		for {
+			if r.peekBreak() {
+				break
+			}
+			c, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if c>>5 != major || c&0x1f == cborIndefinite {
+				return nil, fmt.Errorf("invalid chunk in indefinite-length string")
+			}
+			n, err := r.cborArgument(c & 0x1f)
+			if err != nil {
+				return nil, err
+			}
+			chunk, err := r.next(n)
+			if err != nil {
+				return nil, err
+			}
+			sb.Write(chunk)
+		}
+		if major == cborBytes {
+			return binaryValue([]byte(sb.String())), nil
+		}
+		return sb.String(), nil
+	case cborArray:
+		items := []any{}
+		for !r.peekBreak() {
+			item, err := r.cbor(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		m := map[string]any{}
+		for !r.peekBreak() {
+			if err := r.cborEntry(m, depth); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("invalid indefinite length for major type %d", major)
+	}
+}
+
+func (r *reader) cborEntry(m map[string]any, depth int) error {
+	key, err := r.cbor(depth + 1)
+	if err != nil {
+		return err
+	}
+	k, err := treeKey(key)
+	if err != nil {
+		return err
+	}
+	m[k], err = r.cbor(depth + 1)
+	return err
+}
+
+// peekBreak consumes a break byte when it is next.
+func (r *reader) peekBreak() bool {
+	if r.pos < len(r.data) && r.data[r.pos] == cborBreak {
+		r.pos++
+		return true
+	}
+	return false
+}
+
+// float16 converts an IEEE 754 half-precision value.
+func float16(bits uint16) float64 {
+	exp := int(bits>>10) & 0x1f
+	mant := float64(bits & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
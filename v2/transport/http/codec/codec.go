@@ -0,0 +1,316 @@
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Media types of the built-in codecs.
+const (
+	JSONContentType        = "application/json"
+	XMLContentType         = "application/xml"
+	MessagePackContentType = "application/msgpack"
+	CBORContentType        = "application/cbor"
+	ProtobufContentType    = "application/x-protobuf"
+)
+
+// Media types of the JSON streaming formats: newline-delimited JSON and
+// RFC 7464 JSON text sequences.
+const (
+	NDJSONContentType  = "application/x-ndjson"
+	JSONSeqContentType = "application/json-seq"
+)
+
+// Codec marshals and unmarshals values for one media type.
+type Codec interface {
+	// ContentType returns the media type without parameters, for example
+	// "application/json". It is written to the Content-Type header.
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Aliaser is implemented by codecs that also read and serve other media types
+// for the same format, such as text/xml for XML.
+type Aliaser interface {
+	Aliases() []string
+}
+
+type funcCodec struct {
+	contentType string
+	aliases     []string
+	marshal     func(any) ([]byte, error)
+	unmarshal   func([]byte, any) error
+}
+
+func (c funcCodec) ContentType() string                { return c.contentType }
+func (c funcCodec) Aliases() []string                  { return append([]string(nil), c.aliases...) }
+func (c funcCodec) Marshal(v any) ([]byte, error)      { return c.marshal(v) }
+func (c funcCodec) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+// New returns a Codec for contentType backed by marshal and unmarshal. Extra
+// media types the codec should also accept may be listed in aliases. It
+// panics when contentType is not a valid media type or a function is nil.
+func New(contentType string, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error, aliases ...string) Codec {
+	if marshal == nil || unmarshal == nil {
+		panic("codec: marshal and unmarshal cannot be nil")
+	}
+	mediaType, ok := parseMediaType(contentType)
+	if !ok {
+		panic(fmt.Sprintf("codec: invalid content type %q", contentType))
+	}
+	c := funcCodec{contentType: mediaType, marshal: marshal, unmarshal: unmarshal}
+	for _, alias := range aliases {
+		aliasType, ok := parseMediaType(alias)
+		if !ok {
+			panic(fmt.Sprintf("codec: invalid alias %q for %q", alias, contentType))
+		}
+		c.aliases = append(c.aliases, aliasType)
+	}
+	return c
+}
+
+var (
+	// JSON encodes with encoding/json.
+	JSON = New(JSONContentType, json.Marshal, json.Unmarshal)
+
+	// XML encodes with encoding/xml and also accepts text/xml.
+	XML = New(XMLContentType, xml.Marshal, xml.Unmarshal, "text/xml")
+
+	// MessagePack encodes values by their JSON form, so json struct tags and
+	// json.Marshaler apply; []byte fields travel as base64 strings. It also
+	// accepts application/x-msgpack and application/vnd.msgpack. Extension
+	// types are rejected.
+	MessagePack = New(MessagePackContentType, marshalMessagePack, unmarshalMessagePack, "application/x-msgpack", "application/vnd.msgpack")
+
+	// CBOR (RFC 8949) encodes values by their JSON form, like MessagePack.
+	// Tags are skipped on decode and their content is kept.
+	CBOR = New(CBORContentType, marshalCBOR, unmarshalCBOR)
+)
+
+// Protobuf returns a protobuf codec from the given functions so this module
+// stays free of a protobuf dependency. It also accepts application/protobuf.
+// The functions should fail rather than panic on values that are not
+// messages, so error encoders can fall back to text. With
+// google.golang.org/protobuf:
+//
+//	codec.Protobuf(
+//	    func(v any) ([]byte, error) {
+//	        m, ok := v.(proto.Message)
+//	        if !ok {
+//	            return nil, fmt.Errorf("%T is not a proto.Message", v)
+//	        }
+//	        return proto.Marshal(m)
+//	    },
+//	    func(data []byte, v any) error {
+//	        m, ok := v.(proto.Message)
+//	        if !ok {
+//	            return fmt.Errorf("%T is not a proto.Message", v)
+//	        }
+//	        return proto.Unmarshal(data, m)
+//	    },
+//	)
+//
+// Request types must then be the generated message structs, whose pointers
+// implement proto.Message.
+func Protobuf(marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) Codec {
+	return New(ProtobufContentType, marshal, unmarshal, "application/protobuf")
+}
+
+// Registry maps media types to codecs. The first codec is the default, used
+// for requests without a Content-Type and for Accept: */*. A Registry is
+// immutable and safe for concurrent use.
+type Registry struct {
+	codecs []Codec
+	types  map[string]Codec
+}
+
+// NewRegistry returns a Registry holding codecs in preference order. It fails
+// when no codec is given, a codec is nil, or two codecs claim a media type.
+func NewRegistry(codecs ...Codec) (*Registry, error) {
+	if len(codecs) == 0 {
+		return nil, fmt.Errorf("codec: registry needs at least one codec")
+	}
+	r := &Registry{types: make(map[string]Codec)}
+	for i, c := range codecs {
+		if c == nil {
+			return nil, fmt.Errorf("codec: codec %d is nil", i)
+		}
+		for _, mediaType := range mediaTypes(c) {
+			if _, exists := r.types[mediaType]; exists {
+				return nil, fmt.Errorf("codec: duplicate codec for %q", mediaType)
+			}
+			r.types[mediaType] = c
+		}
+		r.codecs = append(r.codecs, c)
+	}
+	return r, nil
+}
+
+var defaultRegistry, _ = NewRegistry(JSON, XML, MessagePack, CBOR)
+
+// DefaultRegistry returns the registry of JSON, XML, MessagePack and CBOR,
+// with JSON as the default.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// With returns a copy of r with codecs added. A codec whose content type is
+// already registered replaces the old codec in place, so With(myJSON)
+// overrides JSON without changing the preference order.
+func (r *Registry) With(codecs ...Codec) (*Registry, error) {
+	merged := append([]Codec(nil), r.codecs...)
+	for i, c := range codecs {
+		if c == nil {
+			return nil, fmt.Errorf("codec: codec %d is nil", i)
+		}
+		replaced := false
+		for j, existing := range merged {
+			if existing.ContentType() == c.ContentType() {
+				merged[j], replaced = c, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, c)
+		}
+	}
+	return NewRegistry(merged...)
+}
+
+// Codecs returns the registered codecs in preference order.
+func (r *Registry) Codecs() []Codec {
+	return append([]Codec(nil), r.codecs...)
+}
+
+// Default returns the first registered codec.
+func (r *Registry) Default() Codec {
+	return r.codecs[0]
+}
+
+// ContentTypes returns the primary media types in preference order, joined
+// for use in Accept headers.
+func (r *Registry) ContentTypes() string {
+	types := make([]string, len(r.codecs))
+	for i, c := range r.codecs {
+		types[i] = c.ContentType()
+	}
+	return strings.Join(types, ", ")
+}
+
+// Lookup returns the codec for a Content-Type header value. Parameters are
+// ignored, and a structured syntax suffix falls back to its base format, so
+// application/vnd.api+json is read by the JSON codec.
+func (r *Registry) Lookup(contentType string) (Codec, bool) {
+	mediaType, ok := parseMediaType(contentType)
+	if !ok {
+		return nil, false
+	}
+	if c, ok := r.types[mediaType]; ok {
+		return c, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		c, ok := r.types["application/"+mediaType[i+1:]]
+		return c, ok
+	}
+	return nil, false
+}
+
+// Negotiate returns the codec that best satisfies an Accept header value. An
+// empty header accepts the default codec. Ranges are weighed by their q value,
+// the most specific matching range decides a codec's weight, and ties go to
+// the earlier codec. It reports false when every codec is excluded.
+func (r *Registry) Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return r.Default(), true
+	}
+	ranges := parseAccept(accept)
+	var (
+		best        Codec
+		bestQuality float64
+	)
+	for _, c := range r.codecs {
+		quality := -1.0
+		specificity := -1
+		for _, mediaType := range mediaTypes(c) {
+			for _, ar := range ranges {
+				if s := ar.match(mediaType); s > specificity || (s == specificity && ar.quality > quality) {
+					specificity, quality = s, ar.quality
+				}
+			}
+		}
+		if specificity >= 0 && quality > bestQuality {
+			best, bestQuality = c, quality
+		}
+	}
+	return best, best != nil
+}
+
+func mediaTypes(c Codec) []string {
+	types := []string{strings.ToLower(c.ContentType())}
+	if a, ok := c.(Aliaser); ok {
+		for _, alias := range a.Aliases() {
+			types = append(types, strings.ToLower(alias))
+		}
+	}
+	return types
+}
+
+func parseMediaType(value string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil || !strings.Contains(mediaType, "/") {
+		return "", false
+	}
+	return mediaType, true
+}
+
+type acceptRange struct {
+	typ, subtype string
+	quality      float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || (typ == "*" && subtype != "*") {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			quality = parsed
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, quality: quality})
+	}
+	return ranges
+}
+
+// match returns how specifically the range matches mediaType: 2 for an exact
+// match, 1 for type/*, 0 for */*, and -1 for no match.
+func (ar acceptRange) match(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case ar.typ == "*":
+		return 0
+	case ar.typ != typ:
+		return -1
+	case ar.subtype == "*":
+		return 1
+	case ar.subtype == subtype:
+		return 2
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,210 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+)
+
+type sample struct {
+	Name   string            `json:"name" xml:"name"`
+	Count  int64             `json:"count" xml:"count"`
+	Big    uint64            `json:"big" xml:"big"`
+	Ratio  float64           `json:"ratio" xml:"ratio"`
+	Active bool              `json:"active" xml:"active"`
+	Tags   []string          `json:"tags" xml:"tags"`
+	Data   []byte            `json:"data" xml:"data"`
+	Labels map[string]string `json:"labels,omitempty" xml:"-"`
+	Next   *sample           `json:"next,omitempty" xml:"next,omitempty"`
+}
+
+func TestBuiltInCodecsRoundTrip(t *testing.T) {
+	in := sample{
+		Name: "alice", Count: -70000, Big: 1 << 63, Ratio: 0.25, Active: true,
+		Tags: []string{"a", strings.Repeat("x", 300)}, Data: []byte{0, 1, 2},
+		Labels: map[string]string{"zone": "b"},
+		Next:   &sample{Name: "bob", Count: 3, Tags: []string{}},
+	}
+	for _, c := range []codec.Codec{codec.JSON, codec.MessagePack, codec.CBOR} {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			data, err := c.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out sample
+			if err := c.Unmarshal(data, &out); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(in, out) {
+				t.Fatalf("round trip = %+v, want %+v", out, in)
+			}
+		})
+	}
+
+	data, err := codec.XML.Marshal(sample{Name: "alice", Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out sample
+	if err := codec.XML.Unmarshal(data, &out); err != nil || out.Name != "alice" || out.Count != 2 {
+		t.Fatalf("xml round trip = %+v, %v", out, err)
+	}
+}
+
+func TestBinaryCodecsMatchSpecVectors(t *testing.T) {
+	value := map[string]any{"a": 1, "b": []any{true, nil, -1}, "c": 1.5}
+	tests := []struct {
+		codec codec.Codec
+		want  string
+	}{
+		{codec.MessagePack, "83a16101a16293c3c0ffa163cb3ff8000000000000"},
+		{codec.CBOR, "a3616101616283f5f6206163fb3ff8000000000000"},
+	}
+	for _, tt := range tests {
+		data, err := tt.codec.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(data); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.codec.ContentType(), got, tt.want)
+		}
+	}
+}
+
+func TestCBORDecodesForeignEncodings(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want any
+	}{
+		{"indefinite map and array", "bf616b9f0102ffff", map[string]any{"k": []any{1.0, 2.0}}},
+		{"chunked text", "7f62686962217eff", "hi!~"},
+		{"half float", "f93e00", 1.5},
+		{"tagged epoch time", "c11a514b67b0", 1363896240.0},
+		{"integer map key", "a10a6174", map[string]any{"10": "t"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := hex.DecodeString(tt.hex)
+			var got any
+			if err := codec.CBOR.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryCodecsRejectMalformedInput(t *testing.T) {
+	tests := []struct {
+		codec codec.Codec
+		hex   string
+	}{
+		{codec.MessagePack, "dd7fffffff"}, // array32 claiming 2^31 items
+		{codec.MessagePack, "a5616263"},   // truncated string
+		{codec.MessagePack, "d40100"},     // extension type
+		{codec.MessagePack, "c0c0"},       // trailing data
+		{codec.CBOR, "9b00000000ffffffff"},
+		{codec.CBOR, "7f6268"},
+		{codec.CBOR, "f97e00"}, // NaN
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		var v any
+		if err := tt.codec.Unmarshal(data, &v); err == nil {
+			t.Errorf("%s %s: expected error", tt.codec.ContentType(), tt.hex)
+		}
+	}
+
+	deep := append(bytes.Repeat([]byte{0x91}, 20000), 0xc0)
+	var v any
+	if err := codec.MessagePack.Unmarshal(deep, &v); err == nil || !strings.Contains(err.Error(), "max depth") {
+		t.Fatalf("deep nesting error = %v", err)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := codec.DefaultRegistry()
+	tests := map[string]codec.Codec{
+		"application/json; charset=utf-8": codec.JSON,
+		"application/problem+json":        codec.JSON,
+		"TEXT/XML":                        codec.XML,
+		"application/x-msgpack":           codec.MessagePack,
+		"application/cbor":                codec.CBOR,
+	}
+	for contentType, want := range tests {
+		if got, ok := r.Lookup(contentType); !ok || got.ContentType() != want.ContentType() {
+			t.Errorf("Lookup(%q) = %v, %v", contentType, got, ok)
+		}
+	}
+	for _, contentType := range []string{"text/plain", "application/x-protobuf", "garbage"} {
+		if _, ok := r.Lookup(contentType); ok {
+			t.Errorf("Lookup(%q) succeeded", contentType)
+		}
+	}
+}
+
+func TestRegistryNegotiate(t *testing.T) {
+	r := codec.DefaultRegistry()
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", codec.JSONContentType},
+		{"*/*", codec.JSONContentType},
+		{"application/cbor", codec.CBORContentType},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", codec.XMLContentType},
+		{"application/json;q=0.5, application/msgpack", codec.MessagePackContentType},
+		{"application/*;q=0.2, application/cbor;q=0.4", codec.CBORContentType},
+		{"application/json;q=0, */*", codec.XMLContentType},
+		{"application/vnd.msgpack", codec.MessagePackContentType},
+	}
+	for _, tt := range tests {
+		c, ok := r.Negotiate(tt.accept)
+		if !ok || c.ContentType() != tt.want {
+			t.Errorf("Negotiate(%q) = %v, %v; want %s", tt.accept, c, ok, tt.want)
+		}
+	}
+	for _, accept := range []string{"text/html", "application/json;q=0, application/xml;q=0, application/msgpack;q=0, application/cbor;q=0"} {
+		if c, ok := r.Negotiate(accept); ok {
+			t.Errorf("Negotiate(%q) = %s, want no match", accept, c.ContentType())
+		}
+	}
+}
+
+func TestRegistryWith(t *testing.T) {
+	proto := codec.Protobuf(
+		func(any) ([]byte, error) { return []byte("pb"), nil },
+		func([]byte, any) error { return nil },
+	)
+	custom := codec.New("application/json", codec.JSON.Marshal, codec.JSON.Unmarshal)
+	r, err := codec.DefaultRegistry().With(proto, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.ContentTypes(); got != "application/json, application/xml, application/msgpack, application/cbor, application/x-protobuf" {
+		t.Fatalf("ContentTypes = %q", got)
+	}
+	if c, ok := r.Lookup("application/protobuf"); !ok || c.ContentType() != codec.ProtobufContentType {
+		t.Fatalf("protobuf alias lookup = %v, %v", c, ok)
+	}
+	if len(codec.DefaultRegistry().Codecs()) != 4 {
+		t.Fatal("With modified the receiver")
+	}
+
+	if _, err := codec.NewRegistry(); err == nil {
+		t.Fatal("expected error for empty registry")
+	}
+	if _, err := codec.NewRegistry(codec.JSON, nil); err == nil {
+		t.Fatal("expected error for nil codec")
+	}
+	if _, err := codec.NewRegistry(codec.XML, codec.New("text/xml", codec.XML.Marshal, codec.XML.Unmarshal)); err == nil {
+		t.Fatal("expected error for duplicate media type")
+	}
+}
@@ -0,0 +1,10 @@
+// Package codec provides the wire formats shared by the HTTP server and
+// client transports and the content negotiation between them.
+//
+// A Registry maps media types to Codecs. The default registry serves JSON,
+// XML, MessagePack and CBOR, all implemented with the standard library;
+// protobuf and other formats plug in through New or Protobuf. Servers pick
+// the decoder with Registry.Lookup on Content-Type and the encoder with
+// Registry.Negotiate on Accept; see server.NewCodecServer and
+// client.NewCodecClient.
+package codec
@@ -0,0 +1,259 @@
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+func marshalMessagePack(v any) ([]byte, error) {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendMessagePack(nil, tree)
+}
+
+func unmarshalMessagePack(data []byte, v any) error {
+	r := &reader{data: data}
+	tree, err := r.messagePack(0)
+	if err != nil {
+		return fmt.Errorf("codec: msgpack: %w", err)
+	}
+	if r.remaining() != 0 {
+		return fmt.Errorf("codec: msgpack: %d trailing bytes", r.remaining())
+	}
+	return fromJSONTree(tree, v)
+}
+
+func appendMessagePack(b []byte, tree any) ([]byte, error) {
+	switch v := tree.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		n, err := number(v)
+		if err != nil {
+			return nil, err
+		}
+		switch n := n.(type) {
+		case int64:
+			if n < 0 {
+				return appendMessagePackInt(b, n), nil
+			}
+			return appendMessagePackUint(b, uint64(n)), nil
+		case uint64:
+			return appendMessagePackUint(b, n), nil
+		default:
+			b = append(b, 0xcb)
+			return binary.BigEndian.AppendUint64(b, math.Float64bits(n.(float64))), nil
+		}
+	case string:
+		b = appendMessagePackHead(b, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		return append(b, v...), nil
+	case []any:
+		b = appendMessagePackHead(b, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		var err error
+		for _, item := range v {
+			if b, err = appendMessagePack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendMessagePackHead(b, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		var err error
+		for _, key := range sortedKeys(v) {
+			if b, err = appendMessagePack(b, key); err != nil {
+				return nil, err
+			}
+			if b, err = appendMessagePack(b, v[key]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("codec: msgpack: unsupported value %T", tree)
+	}
+}
+
+// appendMessagePackHead writes a string, array or map header: the fix form
+// below fixLimit, then the 8-bit (when the format has one), 16-bit and 32-bit
+// forms.
+func appendMessagePackHead(b []byte, n int, fix byte, fixLimit int, code8, code16, code32 byte) []byte {
+	switch {
+	case n < fixLimit:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+	}
+}
+
+func appendMessagePackUint(b []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(b, byte(n))
+	case n <= math.MaxUint8:
+		return append(b, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), n)
+	}
+}
+
+func appendMessagePackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+	}
+}
+
+func (r *reader) messagePack(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("exceeded max depth %d", maxDepth)
+	}
+	c, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return r.messagePackMap(uint64(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return r.messagePackArray(uint64(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return r.messagePackString(uint64(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return binaryValue(data), nil
+	case 0xca:
+		bits, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatValue(float64(math.Float32frombits(uint32(bits))))
+	case 0xcb:
+		bits, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatValue(math.Float64frombits(bits))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := r.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := r.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.messagePackString(n)
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.messagePackArray(n, depth)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.messagePackMap(n, depth)
+	default:
+		return nil, fmt.Errorf("unsupported type 0x%02x", c)
+	}
+}
+
+func (r *reader) messagePackString(n uint64) (string, error) {
+	data, err := r.next(n)
+	return string(data), err
+}
+
+func (r *reader) messagePackArray(n uint64, depth int) ([]any, error) {
+	if err := r.checkCount(n, 1); err != nil {
+		return nil, err
+	}
+	items := make([]any, n)
+	for i := range items {
+		item, err := r.messagePack(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (r *reader) messagePackMap(n uint64, depth int) (map[string]any, error) {
+	if err := r.checkCount(n, 2); err != nil {
+		return nil, err
+	}
+	m := make(map[string]any, n)
+	for i := uint64(0); i < n; i++ {
+		key, err := r.messagePack(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		k, err := treeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if m[k], err = r.messagePack(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,143 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// maxDepth bounds nesting in binary formats, matching encoding/json.
+const maxDepth = 10000
+
+var errTruncated = errors.New("unexpected end of data")
+
+// jsonTree converts v to the generic tree of its JSON form: nil, bool,
+// json.Number, string, []any and map[string]any.
+func jsonTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree any
+	err = decoder.Decode(&tree)
+	return tree, err
+}
+
+// fromJSONTree stores a decoded tree into v through its JSON form.
+func fromJSONTree(tree any, v any) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// number classifies a json.Number as int64, uint64 or float64.
+func number(n json.Number) (any, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u, nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return nil, fmt.Errorf("codec: number %s out of range", n)
+	}
+	return f, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// treeKey converts a decoded map key to the string JSON objects need.
+func treeKey(key any) (string, error) {
+	switch k := key.(type) {
+	case string:
+		return k, nil
+	case int64:
+		return strconv.FormatInt(k, 10), nil
+	case uint64:
+		return strconv.FormatUint(k, 10), nil
+	case bool:
+		return strconv.FormatBool(k), nil
+	case json.Number:
+		return string(k), nil
+	default:
+		return "", fmt.Errorf("unsupported map key type %T", key)
+	}
+}
+
+// binaryValue stores binary data the way encoding/json stores []byte.
+func binaryValue(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func floatValue(f float64) (any, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float value %v", f)
+	}
+	return f, nil
+}
+
+// reader walks a binary buffer for the MessagePack and CBOR decoders.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) remaining() int { return len(r.data) - r.pos }
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errTruncated
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) next(n uint64) ([]byte, error) {
+	if n > uint64(r.remaining()) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// uint reads an n-byte big-endian unsigned integer.
+func (r *reader) uint(n int) (uint64, error) {
+	b, err := r.next(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// checkCount rejects element counts the remaining input cannot hold, so a
+// forged length cannot force a large allocation. Every element takes at least
+// minSize bytes.
+func (r *reader) checkCount(n uint64, minSize int) error {
+	if n > uint64(r.remaining()/minSize) {
+		return errTruncated
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package compress_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/compress"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+var deflate = compress.New("deflate",
+	func(w io.Writer) (compress.Writer, error) { return flate.NewWriter(w, flate.DefaultCompression) },
+	func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil },
+)
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNegotiate(t *testing.T) {
+	encodings := []compress.Encoding{compress.Gzip, deflate}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"x-gzip", "gzip"},
+		{"*", "gzip"},
+		{"gzip;q=0, *;q=0.1", "deflate"},
+		{"br, zstd", ""},
+		{"", ""},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		enc, ok := compress.Negotiate(tt.accept, encodings...)
+		got := ""
+		if ok {
+			got = enc.Name()
+		}
+		if got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareCompressesEligibleResponses(t *testing.T) {
+	large := strings.Repeat(`{"name":"ada"}`, 200)
+	h := compress.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "2800")
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, large)
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{}`)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = io.WriteString(w, large)
+		case "/encoded":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = io.WriteString(w, large)
+		case "/sniffed":
+			_, _ = io.WriteString(w, "<html>"+large)
+		}
+	}))
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		return serve(h, req)
+	}
+
+	rec := get("/large", "gzip, deflate")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" || rec.Header().Get("ETag") != `W/"v1"` {
+		t.Fatalf("Vary = %q, ETag = %q", rec.Header().Get("Vary"), rec.Header().Get("ETag"))
+	}
+	if got := gunzip(t, rec.Body.Bytes()); got != large {
+		t.Fatalf("body = %q", got)
+	}
+
+	rec = get("/large", "")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != large || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("identity response: headers = %v", rec.Header())
+	}
+	rec = get("/small", "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "{}" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("small response: headers = %v, body = %q", rec.Header(), rec.Body)
+	}
+	for _, path := range []string{"/image", "/encoded"} {
+		rec = get(path, "gzip")
+		if rec.Header().Get("Content-Encoding") == "gzip" || rec.Header().Get("Vary") != "" || rec.Body.String() != large {
+			t.Fatalf("%s should pass through: headers = %v", path, rec.Header())
+		}
+	}
+	rec = get("/sniffed", "gzip")
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("sniffed response: headers = %v", rec.Header())
+	}
+}
+
+func TestMiddlewareFlushStartsCompressedStream(t *testing.T) {
+	h := compress.Middleware(compress.WithEncodings(deflate, compress.Gzip))(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, "data: two\n\n")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.8, deflate")
+	rec := serve(h, req)
+	if rec.Header().Get("Content-Encoding") != "deflate" || !rec.Flushed {
+		t.Fatalf("Content-Encoding = %q, flushed = %v", rec.Header().Get("Content-Encoding"), rec.Flushed)
+	}
+	body, err := io.ReadAll(flate.NewReader(rec.Body))
+	if err != nil || string(body) != "data: one\n\ndata: two\n\n" {
+		t.Fatalf("body = %q, %v", body, err)
+	}
+}
+
+func TestMiddlewareWrapsServerInterceptingWriter(t *testing.T) {
+	var code int
+	var written int64
+	ep := func(context.Context, any) (any, error) {
+		return map[string]string{"data": strings.Repeat("x", 4096)}, nil
+	}
+	srv := server.NewServer(ep, server.NopRequestDecoder, server.EncodeJSONResponse,
+		server.ServerFinalizer(func(_ context.Context, _ *http.Request, iw *server.InterceptingWriter) {
+			code, written = iw.GetCode(), iw.GetWritten()
+		}),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := serve(compress.Middleware()(srv), req)
+	if rec.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(gunzip(t, rec.Body.Bytes()), "xxxx") {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+	if code != http.StatusOK || written <= 4096 {
+		t.Fatalf("finalizer saw code %d, %d bytes", code, written)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestNewLimitedReader(t *testing.T) {
+	errTooLarge := errors.New("too large")
+	read := func(body string, limit int64) (string, bool, error) {
+		decoded := &closeRecorder{Reader: strings.NewReader(body)}
+		compressed := &closeRecorder{Reader: strings.NewReader("")}
+		r := compress.NewLimitedReader(decoded, compressed, limit, errTooLarge)
+		data, err := io.ReadAll(r)
+		_ = r.Close()
+		return string(data), decoded.closed && compressed.closed, err
+	}
+
+	if data, closed, err := read("12345", 5); data != "12345" || err != nil || !closed {
+		t.Fatalf("at limit: %q, %v, closed %v", data, err, closed)
+	}
+	if _, _, err := read("123456", 5); err != errTooLarge {
+		t.Fatalf("over limit: err = %v, want tooLarge", err)
+	}
+	if data, _, err := read("123456", 0); data != "123456" || err != nil {
+		t.Fatalf("unlimited: %q, %v", data, err)
+	}
+}
+
+func TestMiddlewareDecompressesRequests(t *testing.T) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *compress.BodyTooLargeError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), tooLarge.StatusCode())
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "" {
+			t.Errorf("Content-Encoding still set")
+		}
+		_, _ = w.Write(body)
+	}
+	h := compress.Middleware(compress.WithMaxDecompressedBytes(100))(http.HandlerFunc(echo))
+	post := func(encoding string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", encoding)
+		return serve(h, req)
+	}
+
+	if rec := post("gzip", gzipBytes(t, "hello")); rec.Code != http.StatusOK || rec.Body.String() != "hello" {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body)
+	}
+	if rec := post("gzip", gzipBytes(t, strings.Repeat("a", 101))); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("bomb status = %d", rec.Code)
+	}
+	if rec := post("gzip", []byte("not gzip")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("corrupt status = %d", rec.Code)
+	}
+	rec := post("br", []byte("x"))
+	if rec.Code != http.StatusUnsupportedMediaType || rec.Header().Get("Accept-Encoding") != "gzip" {
+		t.Fatalf("status = %d, Accept-Encoding = %q", rec.Code, rec.Header().Get("Accept-Encoding"))
+	}
+}
@@ -0,0 +1,12 @@
+// Package compress provides HTTP content-coding support shared by the HTTP
+// server and client transports.
+//
+// Middleware compresses responses negotiated from Accept-Encoding and
+// decompresses request bodies sent with Content-Encoding. It is a standard
+// func(http.Handler) http.Handler, so it plugs into kit.WithHTTPMiddleware or
+// wraps any handler. The client side lives in client.AcceptCompression.
+//
+// Gzip is built in. Zstandard is not part of the standard library, so Zstd
+// builds an Encoding from the functions of a zstd implementation of the
+// caller's choice; New does the same for any other coding.
+package compress
@@ -0,0 +1,232 @@
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content-coding names of the built-in and pluggable encodings.
+const (
+	GzipName = "gzip"
+	ZstdName = "zstd"
+)
+
+// Writer is a compressing writer. Flush writes pending compressed data so
+// streamed responses reach the client; Close finishes the stream.
+type Writer interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
+// Encoding is one HTTP content coding.
+type Encoding interface {
+	// Name returns the lower-case coding token used in Accept-Encoding and
+	// Content-Encoding, for example "gzip".
+	Name() string
+	NewWriter(w io.Writer) (Writer, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+type funcEncoding struct {
+	name      string
+	newWriter func(io.Writer) (Writer, error)
+	newReader func(io.Reader) (io.ReadCloser, error)
+}
+
+func (e funcEncoding) Name() string                                 { return e.name }
+func (e funcEncoding) NewWriter(w io.Writer) (Writer, error)        { return e.newWriter(w) }
+func (e funcEncoding) NewReader(r io.Reader) (io.ReadCloser, error) { return e.newReader(r) }
+
+// New returns an Encoding named name backed by newWriter and newReader. It
+// panics when name is not a valid token or a function is nil.
+func New(name string, newWriter func(io.Writer) (Writer, error), newReader func(io.Reader) (io.ReadCloser, error)) Encoding {
+	if newWriter == nil || newReader == nil {
+		panic("compress: newWriter and newReader cannot be nil")
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "identity" || name == "*" || strings.ContainsAny(name, " \t,;\"") {
+		panic(fmt.Sprintf("compress: invalid encoding name %q", name))
+	}
+	return funcEncoding{name: name, newWriter: newWriter, newReader: newReader}
+}
+
+// Gzip compresses with compress/gzip at the default level. Writers are pooled.
+var Gzip = GzipLevel(gzip.DefaultCompression)
+
+// GzipLevel returns a gzip Encoding that compresses at level, one of the
+// compress/gzip level constants. It panics on an invalid level.
+func GzipLevel(level int) Encoding {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Sprintf("compress: %v", err))
+	}
+	pool := &sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}}
+	return New(GzipName,
+		func(w io.Writer) (Writer, error) {
+			gz := pool.Get().(*gzip.Writer)
+			gz.Reset(w)
+			return &pooledGzipWriter{Writer: gz, pool: pool}, nil
+		},
+		func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	)
+}
+
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (w *pooledGzipWriter) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	w.pool.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
+
+// Zstd returns a zstd Encoding from the given functions so this module stays
+// free of a zstd dependency. With github.com/klauspost/compress/zstd:
+//
+//	compress.Zstd(
+//	    func(w io.Writer) (compress.Writer, error) { return zstd.NewWriter(w) },
+//	    func(r io.Reader) (io.ReadCloser, error) {
+//	        d, err := zstd.NewReader(r)
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        return d.IOReadCloser(), nil
+//	    },
+//	)
+func Zstd(newWriter func(io.Writer) (Writer, error), newReader func(io.Reader) (io.ReadCloser, error)) Encoding {
+	return New(ZstdName, newWriter, newReader)
+}
+
+// Negotiate picks the encoding for an Accept-Encoding header value. Among
+// encodings with the highest q-value the earliest in encodings wins; "*"
+// matches encodings not listed explicitly and x-gzip is treated as gzip. It
+// reports false when the client accepts none of encodings, in which case the
+// response should be sent uncompressed.
+func Negotiate(acceptEncoding string, encodings ...Encoding) (Encoding, bool) {
+	explicit := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || strings.TrimSpace(strings.ToLower(key)) != "q" {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if name == "x-gzip" {
+			name = GzipName
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		explicit[name] = q
+	}
+
+	var best Encoding
+	bestQ := 0.0
+	for _, enc := range encodings {
+		q, ok := explicit[enc.Name()]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best, best != nil
+}
+
+// Lookup returns the encoding in encodings for a Content-Encoding token,
+// treating x-gzip as gzip.
+func Lookup(name string, encodings ...Encoding) (Encoding, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "x-gzip" {
+		name = GzipName
+	}
+	for _, enc := range encodings {
+		if enc.Name() == name {
+			return enc, true
+		}
+	}
+	return nil, false
+}
+
+// AcceptEncoding formats encodings as an Accept-Encoding header value.
+func AcceptEncoding(encodings ...Encoding) string {
+	list := make([]string, len(encodings))
+	for i, enc := range encodings {
+		list[i] = enc.Name()
+	}
+	return strings.Join(list, ", ")
+}
+
+// NewLimitedReader returns the body of a decompressed message. It reads from
+// decoded, the Encoding reader over compressed, and fails with tooLarge once
+// more than limit decompressed bytes are available; a limit <= 0 disables
+// the check. Close closes both readers. The server middleware and
+// transport/http/client share it so compression bombs fail the same way.
+func NewLimitedReader(decoded, compressed io.ReadCloser, limit int64, tooLarge error) io.ReadCloser {
+	return &limitedReader{decoded: decoded, compressed: compressed, remaining: limit, limit: limit, tooLarge: tooLarge}
+}
+
+type limitedReader struct {
+	decoded    io.ReadCloser
+	compressed io.ReadCloser
+	remaining  int64
+	limit      int64
+	tooLarge   error
+}
+
+func (b *limitedReader) Read(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.decoded.Read(p)
+	}
+	if b.remaining <= 0 {
+		var probe [1]byte
+		n, err := b.decoded.Read(probe[:])
+		if n > 0 {
+			return 0, b.tooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.decoded.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedReader) Close() error {
+	err := b.decoded.Close()
+	if closeErr := b.compressed.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,312 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMinSize is the smallest response body, in bytes, that
+	// Middleware compresses unless the handler flushes first.
+	DefaultMinSize = 1024
+	// DefaultMaxDecompressedBytes bounds decompressed request bodies.
+	DefaultMaxDecompressedBytes int64 = 32 << 20
+)
+
+// DefaultContentTypes lists the media types Middleware compresses by
+// default. A "*" in a pattern matches any run of characters.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/x-ndjson",
+	"application/xml",
+	"application/*+xml",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// ErrBodyTooLarge indicates that a decompressed request body exceeded the
+// configured limit.
+var ErrBodyTooLarge = errors.New("compress: decompressed body too large")
+
+// BodyTooLargeError reports the decompressed request body limit. It renders
+// as 413 through the HTTP server error encoders.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("%v (limit %d bytes)", ErrBodyTooLarge, e.Limit)
+}
+
+func (e *BodyTooLargeError) Unwrap() error { return ErrBodyTooLarge }
+
+func (e *BodyTooLargeError) StatusCode() int { return http.StatusRequestEntityTooLarge }
+
+func (e *BodyTooLargeError) ErrorCode() string { return "request_too_large" }
+
+type config struct {
+	encodings       []Encoding
+	minSize         int
+	contentTypes    []string
+	maxDecompressed int64
+}
+
+// Option configures Middleware.
+type Option func(*config)
+
+// WithEncodings sets the supported encodings in server preference order,
+// replacing the default of Gzip. Nil encodings are ignored.
+func WithEncodings(encodings ...Encoding) Option {
+	return func(c *config) {
+		c.encodings = c.encodings[:0]
+		for _, enc := range encodings {
+			if enc != nil {
+				c.encodings = append(c.encodings, enc)
+			}
+		}
+	}
+}
+
+// WithMinSize sets the smallest body, in bytes, worth compressing. Smaller
+// responses are sent as they are. A value <= 0 compresses every eligible
+// response.
+func WithMinSize(n int) Option {
+	return func(c *config) { c.minSize = n }
+}
+
+// WithContentTypes replaces DefaultContentTypes with patterns such as
+// "text/*" or "application/*+json".
+func WithContentTypes(patterns ...string) Option {
+	return func(c *config) {
+		c.contentTypes = c.contentTypes[:0]
+		for _, pattern := range patterns {
+			c.contentTypes = append(c.contentTypes, strings.ToLower(strings.TrimSpace(pattern)))
+		}
+	}
+}
+
+// WithMaxDecompressedBytes bounds decompressed request bodies. Reads past
+// the limit fail with BodyTooLargeError. A value <= 0 disables the limit.
+func WithMaxDecompressedBytes(n int64) Option {
+	return func(c *config) { c.maxDecompressed = n }
+}
+
+// Middleware returns HTTP middleware that compresses responses and
+// decompresses request bodies.
+//
+// Responses are compressed with the encoding negotiated from
+// Accept-Encoding when the Content-Type matches the allowlist and the body
+// reaches the minimum size; a Flush before that point commits to
+// compression, so Server-Sent Events and other streams are compressed and
+// still delivered event by event. Eligible responses carry
+// Vary: Accept-Encoding. Responses that already set Content-Encoding, partial
+// content, and upgrade requests pass through untouched, and strong ETags of
+// compressed responses are weakened.
+//
+// Request bodies with a supported Content-Encoding are decompressed before
+// the handler runs, within WithMaxDecompressedBytes. Unsupported codings are
+// rejected with 415 and an Accept-Encoding header listing the supported ones.
+//
+//	svc, err := kit.New(":8080", kit.WithHTTPMiddleware(compress.Middleware()))
+func Middleware(options ...Option) func(http.Handler) http.Handler {
+	c := &config{
+		encodings:       []Encoding{Gzip},
+		minSize:         DefaultMinSize,
+		contentTypes:    append([]string(nil), DefaultContentTypes...),
+		maxDecompressed: DefaultMaxDecompressedBytes,
+	}
+	for _, option := range options {
+		if option != nil {
+			option(c)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if coding := r.Header.Get("Content-Encoding"); coding != "" && !strings.EqualFold(coding, "identity") {
+				enc, ok := Lookup(coding, c.encodings...)
+				if !ok {
+					w.Header().Set("Accept-Encoding", AcceptEncoding(c.encodings...))
+					http.Error(w, "unsupported content encoding", http.StatusUnsupportedMediaType)
+					return
+				}
+				body, err := enc.NewReader(r.Body)
+				if err != nil {
+					http.Error(w, "invalid compressed request body", http.StatusBadRequest)
+					return
+				}
+				r = r.Clone(r.Context())
+				r.Body = NewLimitedReader(body, r.Body, c.maxDecompressed, &BodyTooLargeError{Limit: c.maxDecompressed})
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			}
+			if r.Header.Get("Upgrade") != "" || len(c.encodings) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			enc, _ := Negotiate(r.Header.Get("Accept-Encoding"), c.encodings...)
+			cw := &responseWriter{ResponseWriter: w, config: c, encoding: enc, status: http.StatusOK, head: r.Method == http.MethodHead}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// responseWriter buffers the start of the body until it knows whether to
+// compress: when the buffer reaches minSize, on Flush, or when the handler
+// returns.
+type responseWriter struct {
+	http.ResponseWriter
+	config   *config
+	encoding Encoding
+	status   int
+	head     bool
+
+	buf     []byte
+	decided bool
+	writer  Writer
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+	if !bodyAllowed(code) || w.head {
+		w.decide(false)
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.config.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(false); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.writer != nil {
+		return w.writer.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.writer != nil {
+		if err := w.writer.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.writer != nil {
+		_ = w.writer.Close()
+	}
+}
+
+// decide writes the header, choosing compression when streaming or when the
+// buffered body reached minSize, then writes the buffered body.
+func (w *responseWriter) decide(streaming bool) error {
+	w.decided = true
+	header := w.Header()
+	if _, set := header["Content-Type"]; !set && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	eligible := bodyAllowed(w.status) &&
+		w.status != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" &&
+		w.compressible(header.Get("Content-Type"))
+	if eligible {
+		addVary(header, "Accept-Encoding")
+	}
+	if eligible && w.encoding != nil && !w.head && (streaming || len(w.buf) >= w.config.minSize) {
+		if writer, err := w.encoding.NewWriter(w.ResponseWriter); err == nil {
+			w.writer = writer
+			header.Set("Content-Encoding", w.encoding.Name())
+			header.Del("Content-Length")
+			if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+				header.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *responseWriter) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range w.config.contentTypes {
+		if matchPattern(pattern, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, mediaType string) bool {
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == mediaType
+	}
+	return len(mediaType) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(mediaType, prefix) &&
+		strings.HasSuffix(mediaType, suffix)
+}
+
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) || strings.TrimSpace(existing) == "*" {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
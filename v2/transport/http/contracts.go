@@ -21,3 +21,10 @@ type ErrorCoder interface {
 type PublicMessager interface {
 	PublicMessage() string
 }
+
+// ETagger provides the entity tag of a response without encoding it. The
+// value may be a bare opaque string or a full entity-tag such as "v1" or
+// W/"v1" with quotes.
+type ETagger interface {
+	ETag() string
+}
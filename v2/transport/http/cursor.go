@@ -0,0 +1,233 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// CursorDirection tells the repository which way to read from a cursor.
+type CursorDirection string
+
+const (
+	// CursorNext reads the items after the cursor key in sort order.
+	CursorNext CursorDirection = "next"
+	// CursorPrev reads the items before the cursor key in sort order.
+	CursorPrev CursorDirection = "prev"
+)
+
+// ErrInvalidCursor is returned by CursorCodec.Decode for malformed, tampered
+// or foreign cursors.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorCodec turns a position into an opaque cursor token and back. The
+// token is base64url JSON; with a Secret it also carries an HMAC-SHA256
+// signature, so clients cannot forge positions. Without one the token is
+// only encoded, which suits keys that are not sensitive.
+type CursorCodec struct {
+	Secret []byte
+}
+
+type cursorToken struct {
+	Direction CursorDirection `json:"d"`
+	Key       json.RawMessage `json:"k"`
+}
+
+// Encode returns the token for reading in direction from key, which must be
+// JSON-encodable.
+func (c *CursorCodec) Encode(direction CursorDirection, key any) (string, error) {
+	rawKey, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	raw, err := json.Marshal(cursorToken{Direction: direction, Key: rawKey})
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	if c != nil && len(c.Secret) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(c.sign(token))
+	}
+	return token, nil
+}
+
+// Decode verifies token and returns its direction and raw JSON key.
+func (c *CursorCodec) Decode(token string) (CursorDirection, json.RawMessage, error) {
+	payload := token
+	if c != nil && len(c.Secret) > 0 {
+		var signature string
+		var ok bool
+		payload, signature, ok = strings.Cut(token, ".")
+		if !ok {
+			return "", nil, ErrInvalidCursor
+		}
+		got, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(got, c.sign(payload)) {
+			return "", nil, ErrInvalidCursor
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	var decoded cursorToken
+	if err := json.Unmarshal(raw, &decoded); err != nil || len(decoded.Key) == 0 {
+		return "", nil, ErrInvalidCursor
+	}
+	if decoded.Direction != CursorNext && decoded.Direction != CursorPrev {
+		return "", nil, ErrInvalidCursor
+	}
+	return decoded.Direction, decoded.Key, nil
+}
+
+func (c *CursorCodec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, c.Secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// CursorPage describes a requested keyset window. A zero Direction is the
+// first page.
+type CursorPage struct {
+	Size      int
+	Direction CursorDirection
+	key       json.RawMessage
+}
+
+// ParseCursorPage parses the cursor and size query parameters. size follows
+// the ParsePage rules; a cursor that codec rejects returns an
+// *endpoint.ValidationError for "cursor", which encodes as 400.
+//
+//	page, err := transporthttp.ParseCursorPage(r, codec)
+//	var after int64
+//	if err := page.Key(&after); err != nil { ... }
+//	if page.Backward() {
+//	    // WHERE id < after ORDER BY id DESC LIMIT page.Limit()
+//	} else {
+//	    // WHERE id > after ORDER BY id ASC LIMIT page.Limit()
+//	}
+func ParseCursorPage(r *http.Request, codec *CursorCodec) (CursorPage, error) {
+	if r == nil || r.URL == nil {
+		return CursorPage{}, endpoint.NewValidationError("cursor", "missing request URL")
+	}
+	query := r.URL.Query()
+
+	size := DefaultPageSize
+	if raw := query.Get("size"); raw != "" {
+		s, err := strconv.Atoi(raw)
+		if err != nil || s < 1 {
+			return CursorPage{}, endpoint.NewValidationError("size", "must be an integer >= 1")
+		}
+		if s > MaxPageSize {
+			return CursorPage{}, endpoint.NewValidationError("size", "must not exceed "+strconv.Itoa(MaxPageSize))
+		}
+		size = s
+	}
+
+	page := CursorPage{Size: size}
+	if token := query.Get("cursor"); token != "" {
+		direction, key, err := codec.Decode(token)
+		if err != nil {
+			return CursorPage{}, endpoint.NewValidationError("cursor", "is not a valid cursor")
+		}
+		page.Direction, page.key = direction, key
+	}
+	return page, nil
+}
+
+// First reports whether the request carried no cursor.
+func (p CursorPage) First() bool { return p.Direction == "" }
+
+// Backward reports whether the repository should read before the cursor key,
+// in reverse sort order.
+func (p CursorPage) Backward() bool { return p.Direction == CursorPrev }
+
+// Key decodes the cursor position into v. It leaves v unchanged on the first
+// page.
+func (p CursorPage) Key(v any) error {
+	if p.First() {
+		return nil
+	}
+	if err := json.Unmarshal(p.key, v); err != nil {
+		return endpoint.NewValidationError("cursor", "is not a valid cursor")
+	}
+	return nil
+}
+
+// Limit returns the SQL LIMIT value: one more than Size, so
+// NewCursorResult can tell whether another page exists.
+func (p CursorPage) Limit() int { return p.Size + 1 }
+
+// CursorResult is the standard wire shape for one keyset page. Next and Prev
+// are request-relative links carrying the cursors.
+type CursorResult[T any] struct {
+	Items      []T    `json:"items"`
+	Size       int    `json:"size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	HasNext    bool   `json:"has_next"`
+	HasPrev    bool   `json:"has_prev"`
+}
+
+// NewCursorResult assembles one keyset page. items holds up to page.Limit()
+// rows as the repository read them, in reverse order for Backward pages; key
+// returns the cursor position of an item, typically its sort columns and ID.
+func NewCursorResult[T any](r *http.Request, codec *CursorCodec, page CursorPage, items []T, key func(T) any) (CursorResult[T], error) {
+	more := len(items) > page.Size
+	if more {
+		items = items[:page.Size]
+	}
+	if page.Backward() {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	result := CursorResult[T]{Items: items, Size: page.Size}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	if page.Backward() {
+		result.HasNext, result.HasPrev = true, more
+	} else {
+		result.HasNext, result.HasPrev = more, !page.First()
+	}
+	if len(items) == 0 {
+		result.HasNext, result.HasPrev = false, false
+		return result, nil
+	}
+
+	var err error
+	if result.HasNext {
+		if result.NextCursor, err = codec.Encode(CursorNext, key(items[len(items)-1])); err != nil {
+			return CursorResult[T]{}, err
+		}
+		result.Next = cursorLink(r, result.NextCursor)
+	}
+	if result.HasPrev {
+		if result.PrevCursor, err = codec.Encode(CursorPrev, key(items[0])); err != nil {
+			return CursorResult[T]{}, err
+		}
+		result.Prev = cursorLink(r, result.PrevCursor)
+	}
+	return result, nil
+}
+
+// cursorLink returns the request path and query with cursor replaced.
+func cursorLink(r *http.Request, cursor string) string {
+	if r == nil || r.URL == nil {
+		return ""
+	}
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,265 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MaxFieldMaskPaths bounds the number of paths ParseFieldMask accepts.
+const MaxFieldMaskPaths = 64
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// FieldMask selects parts of a JSON document, like google.protobuf.FieldMask.
+// Each path names a field by its JSON name, with dots for nested fields, such
+// as "address.city". Arrays are selected through: "items.name" keeps the name
+// of every item. Its text form is the comma-separated list of paths, which is
+// also the protobuf JSON form, so a FieldMask binds as a query parameter.
+type FieldMask struct {
+	Paths []string
+}
+
+// ParseFieldMask parses a comma-separated list of paths. Blank input yields
+// an empty mask; empty path segments and duplicate paths are rejected.
+func ParseFieldMask(s string) (FieldMask, error) {
+	var mask FieldMask
+	if strings.TrimSpace(s) == "" {
+		return mask, nil
+	}
+	seen := make(map[string]bool)
+	for raw := range strings.SplitSeq(s, ",") {
+		path := strings.TrimSpace(raw)
+		for segment := range strings.SplitSeq(path, ".") {
+			if strings.TrimSpace(segment) == "" {
+				return FieldMask{}, fmt.Errorf("invalid field path %q", path)
+			}
+		}
+		if seen[path] {
+			return FieldMask{}, fmt.Errorf("duplicate field path %q", path)
+		}
+		seen[path] = true
+		mask.Paths = append(mask.Paths, path)
+	}
+	if len(mask.Paths) > MaxFieldMaskPaths {
+		return FieldMask{}, fmt.Errorf("more than %d field paths", MaxFieldMaskPaths)
+	}
+	return mask, nil
+}
+
+// Empty reports whether m selects nothing, which callers treat as the whole
+// document.
+func (m FieldMask) Empty() bool { return len(m.Paths) == 0 }
+
+func (m FieldMask) String() string { return strings.Join(m.Paths, ",") }
+
+// MarshalText implements encoding.TextMarshaler.
+func (m FieldMask) MarshalText() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (m *FieldMask) UnmarshalText(text []byte) error {
+	parsed, err := ParseFieldMask(string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Validate checks every path against the JSON encoding of type t, following
+// json tags, embedded structs, pointers, slices and maps. Map keys and values
+// of interface type accept any path below them; types with their own JSON or
+// text encoding, such as time.Time, cannot be selected into.
+func (m FieldMask) Validate(t reflect.Type) error {
+	for _, path := range m.Paths {
+		if !validFieldPath(t, strings.Split(path, ".")) {
+			return fmt.Errorf("unknown field %q", path)
+		}
+	}
+	return nil
+}
+
+func validFieldPath(t reflect.Type, segments []string) bool {
+	for len(segments) > 0 {
+		if t == nil || t.Kind() == reflect.Interface {
+			return true
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if implementsEither(t, jsonMarshalerType, textMarshalerType) {
+			return false
+		}
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				return false
+			}
+			t = t.Elem()
+		case reflect.Map:
+			t, segments = t.Elem(), segments[1:]
+		case reflect.Struct:
+			field, ok := jsonField(t, segments[0])
+			if !ok {
+				return false
+			}
+			t, segments = field, segments[1:]
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func implementsEither(t reflect.Type, ifaces ...reflect.Type) bool {
+	for _, iface := range ifaces {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonField finds the type of the field encoding/json writes as name,
+// looking through embedded structs.
+func jsonField(t reflect.Type, name string) (reflect.Type, bool) {
+	var embedded []reflect.Type
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && tagName == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if tagName == "" {
+			tagName = field.Name
+		}
+		if tagName == name {
+			return field.Type, true
+		}
+	}
+	for _, e := range embedded {
+		if ft, ok := jsonField(e, name); ok {
+			return ft, true
+		}
+	}
+	return nil, false
+}
+
+// fieldMaskTree holds the selected keys of one object level; a nil subtree
+// keeps the whole value.
+type fieldMaskTree map[string]fieldMaskTree
+
+func (m FieldMask) tree() fieldMaskTree {
+	root := fieldMaskTree{}
+	for _, path := range m.Paths {
+		node := root
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			child, exists := node[segment]
+			if exists && child == nil {
+				break // an ancestor is already kept whole
+			}
+			if i == len(segments)-1 {
+				node[segment] = nil
+				break
+			}
+			if !exists {
+				child = fieldMaskTree{}
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// Prune returns the JSON document data reduced to the paths of m, keeping
+// the original key order. An empty mask returns data unchanged.
+func (m FieldMask) Prune(data []byte) ([]byte, error) {
+	if m.Empty() {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	if err := pruneJSON(&buf, data, m.tree()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func pruneJSON(dst *bytes.Buffer, raw []byte, tree fieldMaskTree) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return fmt.Errorf("prune JSON: empty value")
+	}
+	switch raw[0] {
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("prune JSON: %w", err)
+		}
+		dst.WriteByte('{')
+		first := true
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("prune JSON: %w", err)
+			}
+			key, _ := tok.(string)
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return fmt.Errorf("prune JSON: %w", err)
+			}
+			subtree, selected := tree[key]
+			if !selected {
+				continue
+			}
+			if !first {
+				dst.WriteByte(',')
+			}
+			first = false
+			encodedKey, _ := json.Marshal(key)
+			dst.Write(encodedKey)
+			dst.WriteByte(':')
+			if subtree == nil {
+				dst.Write(value)
+			} else if err := pruneJSON(dst, value, subtree); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte('}')
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return fmt.Errorf("prune JSON: %w", err)
+		}
+		dst.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				dst.WriteByte(',')
+			}
+			if err := pruneJSON(dst, item, tree); err != nil {
+				return err
+			}
+		}
+		dst.WriteByte(']')
+	default:
+		dst.Write(raw)
+	}
+	return nil
+}
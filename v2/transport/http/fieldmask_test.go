@@ -0,0 +1,83 @@
+package http_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+type maskBase struct {
+	ID string `json:"id"`
+}
+
+type maskItem struct {
+	maskBase
+	Name    string            `json:"name"`
+	Secret  string            `json:"-"`
+	Created time.Time         `json:"created"`
+	Labels  map[string]string `json:"labels"`
+	Extra   any               `json:"extra"`
+	Parent  *maskItem         `json:"parent,omitempty"`
+}
+
+type maskList struct {
+	Items []maskItem `json:"items"`
+	Total int        `json:"total"`
+}
+
+func TestParseFieldMask(t *testing.T) {
+	mask, err := transporthttp.ParseFieldMask(" items.name , total")
+	if err != nil || !reflect.DeepEqual(mask.Paths, []string{"items.name", "total"}) {
+		t.Fatalf("parse: %v %v", mask.Paths, err)
+	}
+	if text, _ := mask.MarshalText(); string(text) != "items.name,total" {
+		t.Errorf("text: %s", text)
+	}
+	for _, bad := range []string{"a,", "a..b", ".a", "a,a"} {
+		if _, err := transporthttp.ParseFieldMask(bad); err == nil {
+			t.Errorf("parse %q: want error", bad)
+		}
+	}
+}
+
+func TestFieldMask_Validate(t *testing.T) {
+	typ := reflect.TypeOf(maskList{})
+	valid := []string{"total", "items", "items.id", "items.created", "items.labels.any", "items.extra.deep.path", "items.parent.parent.name"}
+	for _, path := range valid {
+		if err := (transporthttp.FieldMask{Paths: []string{path}}).Validate(typ); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+	invalid := []string{"Total", "items.Secret", "items.created.year", "total.x", "missing"}
+	for _, path := range invalid {
+		if err := (transporthttp.FieldMask{Paths: []string{path}}).Validate(typ); err == nil {
+			t.Errorf("%s: want error", path)
+		}
+	}
+}
+
+func TestFieldMask_Prune(t *testing.T) {
+	data := []byte(`{"items":[{"id":"1","name":"a","labels":{"k":"v"}},{"id":"2","name":"b","labels":null}],"total":2,"next":"x"}`)
+	cases := map[string]string{
+		"total":                    `{"total":2}`,
+		"items.name,total":         `{"items":[{"name":"a"},{"name":"b"}],"total":2}`,
+		"items.labels.k":           `{"items":[{"labels":{"k":"v"}},{"labels":null}]}`,
+		"items.id,items,total":     `{"items":[{"id":"1","name":"a","labels":{"k":"v"}},{"id":"2","name":"b","labels":null}],"total":2}`,
+		"total,next,items.missing": `{"items":[{},{}],"total":2,"next":"x"}`,
+	}
+	for fields, want := range cases {
+		mask, err := transporthttp.ParseFieldMask(fields)
+		if err != nil {
+			t.Fatalf("parse %q: %v", fields, err)
+		}
+		got, err := mask.Prune(data)
+		if err != nil {
+			t.Fatalf("prune %q: %v", fields, err)
+		}
+		if string(got) != want {
+			t.Errorf("prune %q:\n got %s\nwant %s", fields, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operator is a comparison operator.
+type Operator string
+
+// Comparison operators.
+const (
+	Eq         Operator = "eq"
+	Ne         Operator = "ne"
+	Gt         Operator = "gt"
+	Ge         Operator = "ge"
+	Lt         Operator = "lt"
+	Le         Operator = "le"
+	In         Operator = "in"
+	Contains   Operator = "contains"
+	StartsWith Operator = "startswith"
+)
+
+var operators = map[string]Operator{
+	"eq": Eq, "ne": Ne, "gt": Gt, "ge": Ge, "lt": Lt, "le": Le,
+	"in": In, "contains": Contains, "startswith": StartsWith,
+}
+
+// Node is a filter expression: *And, *Or, *Not or *Comparison.
+type Node interface {
+	// String renders the node in filter syntax.
+	String() string
+	node()
+}
+
+// And matches when both operands match.
+type And struct{ Left, Right Node }
+
+// Or matches when either operand matches.
+type Or struct{ Left, Right Node }
+
+// Not matches when its operand does not.
+type Not struct{ Operand Node }
+
+// Comparison compares a field with a literal. Value holds string, int64,
+// float64, bool, time.Time or nil for null; In holds a []any of them. After
+// checking against a Schema, values have the field's kind.
+type Comparison struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+func (*And) node()        {}
+func (*Or) node()         {}
+func (*Not) node()        {}
+func (*Comparison) node() {}
+
+func (n *And) String() string { return group(n.Left, n) + " and " + group(n.Right, n) }
+func (n *Or) String() string  { return group(n.Left, n) + " or " + group(n.Right, n) }
+func (n *Not) String() string { return "not " + group(n.Operand, n) }
+
+func (n *Comparison) String() string {
+	if values, ok := n.Value.([]any); ok {
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = literal(value)
+		}
+		return n.Field + " " + string(n.Op) + " (" + strings.Join(parts, ", ") + ")"
+	}
+	return n.Field + " " + string(n.Op) + " " + literal(n.Value)
+}
+
+// group parenthesizes child when it binds more loosely than parent.
+func group(child, parent Node) string {
+	switch child.(type) {
+	case *Or:
+		if _, ok := parent.(*Or); !ok {
+			return "(" + child.String() + ")"
+		}
+	case *And:
+		if _, ok := parent.(*Not); ok {
+			return "(" + child.String() + ")"
+		}
+	}
+	return child.String()
+}
+
+func literal(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	default:
+		return "null"
+	}
+}
+
+// SortField is one sort key.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+func (f SortField) String() string {
+	if f.Descending {
+		return "-" + f.Field
+	}
+	return f.Field
+}
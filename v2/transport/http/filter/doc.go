@@ -0,0 +1,47 @@
+// Package filter parses a small, safe filter and sort query language and
+// renders it as SQL.
+//
+// Filters compare fields with literals and combine comparisons with and, or,
+// not and parentheses:
+//
+//	?filter=status eq 'active' and (age gt 30 or vip eq true)
+//	?filter=name startswith 'Jo' and deleted_at eq null
+//	?filter=country in ('DE', 'FR')
+//	?sort=-created_at,name
+//
+// The operators are eq, ne, gt, ge, lt, le, in, contains and startswith.
+// Strings use single quotes; a quote inside a string is doubled. Times are quoted
+// RFC 3339 strings.
+//
+// Only fields declared on a struct can be used. The filter tag names a
+// filterable field and may restrict its operators or map it to a column; the
+// sort tag marks it sortable:
+//
+//	type UserFields struct {
+//	    Status    string     `filter:"status,ops=eq|ne|in" sort:"status"`
+//	    Age       int        `filter:"age" sort:"age"`
+//	    CreatedAt time.Time  `filter:"created_at,column=u.created_at" sort:"created_at"`
+//	    DeletedAt *time.Time `filter:"deleted_at"`
+//	}
+//
+// Literals are converted to the Go type of the field, and null is accepted
+// only for pointer fields. Filter[T] and Sort[T] parse and check their input
+// in UnmarshalText, so they bind as query parameters of a request struct and
+// reject bad input with 400:
+//
+//	type ListUsersRequest struct {
+//	    Filter filter.Filter[UserFields] `query:"filter"`
+//	    Sort   filter.Sort[UserFields]   `query:"sort"`
+//	}
+//
+// The repository then renders them with bound arguments only:
+//
+//	where, args, err := req.Filter.SQL(filter.Question)
+//	if err != nil {
+//	    return err
+//	}
+//	orderBy, err := req.Sort.SQL()
+//	if err != nil {
+//	    return err
+//	}
+package filter
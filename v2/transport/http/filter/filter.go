@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Filter is a filter expression checked against the fields declared on T.
+// Its zero value matches everything.
+type Filter[T any] struct {
+	Expr Node
+}
+
+// Sort is a sort order checked against the sortable fields declared on T.
+// Its zero value leaves the order to the repository.
+type Sort[T any] struct {
+	Fields []SortField
+}
+
+func schemaFor[T any]() (*Schema, error) {
+	return SchemaOf(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// ParseFilter parses and checks input against T.
+func ParseFilter[T any](input string) (Filter[T], error) {
+	var f Filter[T]
+	err := f.UnmarshalText([]byte(input))
+	return f, err
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *Filter[T]) UnmarshalText(text []byte) error {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return err
+	}
+	node, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	checked, err := schema.Check(node)
+	if err != nil {
+		return err
+	}
+	f.Expr = checked
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f Filter[T]) MarshalText() ([]byte, error) {
+	if f.Expr == nil {
+		return nil, nil
+	}
+	return []byte(f.Expr.String()), nil
+}
+
+// Empty reports whether f matches everything.
+func (f Filter[T]) Empty() bool { return f.Expr == nil }
+
+// SQL renders f as a WHERE condition and its arguments. An empty filter
+// renders as "". It fails if T declares invalid tags or if Expr was built by
+// hand and does not check against T.
+func (f Filter[T]) SQL(placeholder Placeholder) (string, []any, error) {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return "", nil, err
+	}
+	return SQL(f.Expr, schema, placeholder)
+}
+
+// ParseSortOf parses and checks input against T.
+func ParseSortOf[T any](input string) (Sort[T], error) {
+	var s Sort[T]
+	err := s.UnmarshalText([]byte(input))
+	return s, err
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Sort[T]) UnmarshalText(text []byte) error {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return err
+	}
+	fields, err := ParseSort(string(text))
+	if err != nil {
+		return err
+	}
+	if err := schema.CheckSort(fields); err != nil {
+		return err
+	}
+	s.Fields = fields
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Sort[T]) MarshalText() ([]byte, error) {
+	parts := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		parts[i] = f.String()
+	}
+	return []byte(strings.Join(parts, ",")), nil
+}
+
+// Empty reports whether s has no sort fields.
+func (s Sort[T]) Empty() bool { return len(s.Fields) == 0 }
+
+// SQL renders s as an ORDER BY list. An empty sort renders as "". It fails
+// if T declares invalid tags or if Fields names a field T does not mark
+// sortable.
+func (s Sort[T]) SQL() (string, error) {
+	schema, err := schemaFor[T]()
+	if err != nil {
+		return "", err
+	}
+	return SortSQL(s.Fields, schema)
+}
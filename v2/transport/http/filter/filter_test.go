@@ -0,0 +1,254 @@
+package filter_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+	"github.com/dreamsxin/go-kit/v2/transport/http/filter"
+)
+
+type userFields struct {
+	Status    string     `filter:"status,ops=eq|ne|in" sort:"status"`
+	Name      string     `filter:"name" sort:"name"`
+	Age       int        `filter:"age" sort:"age"`
+	Score     float64    `filter:"score"`
+	VIP       bool       `filter:"vip"`
+	CreatedAt time.Time  `filter:"created_at,column=u.created_at" sort:"created_at"`
+	DeletedAt *time.Time `filter:"deleted_at"`
+	Internal  string
+}
+
+func TestParse_RoundTrips(t *testing.T) {
+	cases := map[string]string{
+		"status eq 'active'":                            "status eq 'active'",
+		"a eq 1 AND (b eq 2 OR c eq 3)":                 "a eq 1 and (b eq 2 or c eq 3)",
+		"not (a eq 1 and b ne 'x''y')":                  "not (a eq 1 and b ne 'x''y')",
+		"a in (1, 2.5, 'z') or b eq null and c eq true": "a in (1, 2.5, 'z') or b eq null and c eq true",
+	}
+	for input, want := range cases {
+		node, err := filter.Parse(input)
+		if err != nil {
+			t.Fatalf("parse %q: %v", input, err)
+		}
+		if got := node.String(); got != want {
+			t.Errorf("parse %q: got %q, want %q", input, got, want)
+		}
+	}
+	if node, err := filter.Parse("  "); node != nil || err != nil {
+		t.Errorf("blank input: got %v, %v", node, err)
+	}
+}
+
+func TestParse_RejectsMalformedInput(t *testing.T) {
+	deep := strings.Repeat("(", filter.MaxDepth+1) + "a eq 1" + strings.Repeat(")", filter.MaxDepth+1)
+	for _, input := range []string{
+		"status",
+		"status eq",
+		"status like 'x'",
+		"status eq 'open",
+		"a eq 1 and",
+		"(a eq 1",
+		"a in ()",
+		"a eq 1; drop table users",
+		deep,
+		strings.Repeat("a", filter.MaxLength+1),
+	} {
+		var syntaxErr *filter.SyntaxError
+		if _, err := filter.Parse(input); !errors.As(err, &syntaxErr) {
+			t.Errorf("parse %.40q: got %v, want SyntaxError", input, err)
+		}
+	}
+}
+
+func TestSchema_Check(t *testing.T) {
+	schema, err := filter.SchemaOf(reflect.TypeOf(userFields{}))
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	valid := []string{
+		"status in ('active', 'invited')",
+		"name contains 'jo' and age ge 18",
+		"score gt 1 and vip eq true",
+		"created_at lt '2024-01-02T03:04:05Z'",
+		"deleted_at eq null",
+	}
+	for _, input := range valid {
+		node, _ := filter.Parse(input)
+		if _, err := schema.Check(node); err != nil {
+			t.Errorf("check %q: %v", input, err)
+		}
+	}
+	invalid := []string{
+		"Internal eq 'x'",
+		"password eq 'x'",
+		"status contains 'a'",
+		"age eq 'ten'",
+		"age eq 1.5",
+		"vip gt false",
+		"created_at gt 'yesterday'",
+		"name eq null",
+		"deleted_at gt null",
+		"status in ('a', null)",
+	}
+	for _, input := range invalid {
+		node, err := filter.Parse(input)
+		if err != nil {
+			t.Fatalf("parse %q: %v", input, err)
+		}
+		if _, err := schema.Check(node); err == nil {
+			t.Errorf("check %q: want error", input)
+		}
+	}
+}
+
+func TestSchemaOf_RejectsBadTags(t *testing.T) {
+	types := []any{
+		struct {
+			A int `filter:"a,ops=contains"`
+		}{},
+		struct {
+			A string `filter:"a,column=a; drop"`
+		}{},
+		struct {
+			A []string `filter:"a"`
+		}{},
+		struct {
+			A string `filter:"a"`
+			B string `filter:"a"`
+		}{},
+	}
+	for _, v := range types {
+		if _, err := filter.SchemaOf(reflect.TypeOf(v)); err == nil {
+			t.Errorf("%T: want error", v)
+		}
+	}
+}
+
+func TestFilter_SQL(t *testing.T) {
+	f, err := filter.ParseFilter[userFields]("status eq 'active' and (age gt 30 or name startswith 'J_o%') and not deleted_at eq null")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	where, args, err := f.SQL(filter.Dollar)
+	if err != nil {
+		t.Fatalf("sql: %v", err)
+	}
+	want := "status = $1 AND (age > $2 OR name LIKE $3 ESCAPE '!') AND NOT (deleted_at IS NULL)"
+	if where != want {
+		t.Errorf("where:\n got %s\nwant %s", where, want)
+	}
+	if len(args) != 3 || args[0] != "active" || args[1] != int64(30) || args[2] != "J!_o!%%" {
+		t.Errorf("args: %#v", args)
+	}
+
+	f, err = filter.ParseFilter[userFields]("created_at ge '2024-01-02T00:00:00Z' and status in ('a', 'b')")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	where, args, err = f.SQL(filter.Question)
+	if err != nil {
+		t.Fatalf("sql: %v", err)
+	}
+	if where != "u.created_at >= ? AND status IN (?, ?)" {
+		t.Errorf("where: %s", where)
+	}
+	if ts, ok := args[0].(time.Time); !ok || !ts.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("time arg: %#v", args[0])
+	}
+
+	where, args, err = f.SQL(filter.DollarFrom(2))
+	if err != nil || where != "u.created_at >= $3 AND status IN ($4, $5)" || len(args) != 3 {
+		t.Errorf("offset where: %s", where)
+	}
+
+	var empty filter.Filter[userFields]
+	if where, args, err := empty.SQL(filter.Question); err != nil || where != "" || args != nil {
+		t.Errorf("empty filter: %q %v %v", where, args, err)
+	}
+
+	// An expression built by hand is checked when it is rendered.
+	unchecked := filter.Filter[userFields]{Expr: &filter.Comparison{Field: "password", Op: filter.Eq, Value: "x"}}
+	if where, _, err := unchecked.SQL(filter.Question); err == nil {
+		t.Errorf("unchecked filter rendered %q, want error", where)
+	}
+}
+
+func TestFilter_ValuesNeverReachSQL(t *testing.T) {
+	input := "name eq 'x'' OR 1=1 --' or name contains ''');drop table users;--'"
+	f, err := filter.ParseFilter[userFields](input)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	where, args, err := f.SQL(filter.Question)
+	if err != nil {
+		t.Fatalf("sql: %v", err)
+	}
+	if where != "(name = ? OR name LIKE ? ESCAPE '!')" {
+		t.Errorf("where: %s", where)
+	}
+	if args[0] != "x' OR 1=1 --" {
+		t.Errorf("args: %#v", args)
+	}
+}
+
+func TestSort(t *testing.T) {
+	s, err := filter.ParseSortOf[userFields]("-created_at, +name,age")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got, err := s.SQL(); err != nil || got != "u.created_at DESC, name ASC, age ASC" {
+		t.Errorf("sql: %s %v", got, err)
+	}
+	unchecked := filter.Sort[userFields]{Fields: []filter.SortField{{Field: "deleted_at"}}}
+	if got, err := unchecked.SQL(); err == nil {
+		t.Errorf("unchecked sort rendered %q, want error", got)
+	}
+	if text, _ := s.MarshalText(); string(text) != "-created_at,name,age" {
+		t.Errorf("text: %s", text)
+	}
+	for _, input := range []string{"score", "deleted_at", "name,-name", "na me", "-", "name;drop"} {
+		if _, err := filter.ParseSortOf[userFields](input); err == nil {
+			t.Errorf("sort %q: want error", input)
+		}
+	}
+}
+
+type listUsersRequest struct {
+	Filter filter.Filter[userFields] `query:"filter"`
+	Sort   filter.Sort[userFields]   `query:"sort"`
+}
+
+func TestFilter_BindsAsQueryParameter(t *testing.T) {
+	query := url.Values{"filter": {"age ge 21"}, "sort": {"-age"}}
+	r := httptest.NewRequest(http.MethodGet, "/users?"+query.Encode(), nil)
+	var req listUsersRequest
+	if err := transporthttp.Bind(r, &req, nil); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	where, _, err := req.Filter.SQL(filter.Question)
+	if err != nil {
+		t.Fatalf("filter sql: %v", err)
+	}
+	orderBy, err := req.Sort.SQL()
+	if err != nil {
+		t.Fatalf("sort sql: %v", err)
+	}
+	if where != "age >= ?" || orderBy != "age DESC" {
+		t.Errorf("bound: %q %q", where, orderBy)
+	}
+
+	query = url.Values{"filter": {"password eq 'x'"}}
+	r = httptest.NewRequest(http.MethodGet, "/users?"+query.Encode(), nil)
+	err = transporthttp.Bind(r, &listUsersRequest{}, nil)
+	var queryErr *transporthttp.QueryError
+	if !errors.As(err, &queryErr) || queryErr.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("error = %v, want QueryError", err)
+	}
+}
@@ -0,0 +1,326 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parser limits. Longer or deeper input is rejected before it reaches a
+// repository.
+const (
+	MaxLength = 2048
+	MaxDepth  = 16
+	// MaxSortFields bounds the number of sort keys.
+	MaxSortFields = 8
+)
+
+// SyntaxError reports malformed filter or sort input.
+type SyntaxError struct {
+	// Offset is the byte offset of the problem in the input.
+	Offset  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: %s at offset %d", e.Message, e.Offset)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokenComma, ",", i})
+			i++
+		case c == '\'':
+			var b strings.Builder
+			start := i
+			i++
+			for {
+				if i >= len(input) {
+					return nil, &SyntaxError{Offset: start, Message: "unterminated string"}
+				}
+				if input[i] == '\'' {
+					if i+1 < len(input) && input[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, b.String(), start})
+		case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(input) && strings.IndexByte("0123456789.eE+-", input[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, input[start:i], start})
+		case isIdentStart(c):
+			start := i
+			for i < len(input) && isIdentPart(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, input[start:i], start})
+		default:
+			return nil, &SyntaxError{Offset: i, Message: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, offset: len(input)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse parses a filter expression. An empty or blank input returns a nil
+// Node. The result is unchecked; see Schema.Check.
+func Parse(input string) (Node, error) {
+	if len(input) > MaxLength {
+		return nil, &SyntaxError{Offset: MaxLength, Message: fmt.Sprintf("input longer than %d bytes", MaxLength)}
+	}
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "unexpected %q", tok.text)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(tok token, word string) bool {
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &SyntaxError{Offset: tok.offset, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	tok := p.peek()
+	if p.depth >= MaxDepth {
+		return nil, p.errorf(tok, "expression nested deeper than %d", MaxDepth)
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.keyword(tok, "not") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Operand: operand}, nil
+	}
+	if tok.kind == tokenLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected )")
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokenIdent {
+		return nil, p.errorf(field, "expected field name")
+	}
+	opToken := p.next()
+	op, ok := operators[strings.ToLower(opToken.text)]
+	if opToken.kind != tokenIdent || !ok {
+		return nil, p.errorf(opToken, "expected operator after %s", field.text)
+	}
+	if op != In {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &Comparison{Field: field.text, Op: op, Value: value}, nil
+	}
+
+	if open := p.next(); open.kind != tokenLParen {
+		return nil, p.errorf(open, "expected ( after in")
+	}
+	var values []any
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		sep := p.next()
+		if sep.kind == tokenRParen {
+			break
+		}
+		if sep.kind != tokenComma {
+			return nil, p.errorf(sep, "expected , or )")
+		}
+	}
+	return &Comparison{Field: field.text, Op: In, Value: values}, nil
+}
+
+func (p *parser) parseValue() (any, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return tok.text, nil
+	case tokenNumber:
+		if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %q", tok.text)
+		}
+		return f, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return nil, p.errorf(tok, "expected value")
+}
+
+// ParseSort parses a comma-separated list of field names, each optionally
+// prefixed with - for descending or + for ascending order. An empty input
+// returns no fields.
+func ParseSort(input string) ([]SortField, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if len(input) > MaxLength {
+		return nil, &SyntaxError{Offset: MaxLength, Message: fmt.Sprintf("input longer than %d bytes", MaxLength)}
+	}
+	var fields []SortField
+	seen := make(map[string]bool)
+	offset := 0
+	for part := range strings.SplitSeq(input, ",") {
+		term := strings.TrimSpace(part)
+		field := SortField{}
+		switch {
+		case strings.HasPrefix(term, "-"):
+			field.Descending = true
+			term = term[1:]
+		case strings.HasPrefix(term, "+"):
+			term = term[1:]
+		}
+		if term == "" || !isIdentStart(term[0]) || strings.IndexFunc(term, func(r rune) bool { return r > 127 || !isIdentPart(byte(r)) }) >= 0 {
+			return nil, &SyntaxError{Offset: offset, Message: fmt.Sprintf("invalid sort field %q", strings.TrimSpace(part))}
+		}
+		if seen[term] {
+			return nil, &SyntaxError{Offset: offset, Message: fmt.Sprintf("duplicate sort field %q", term)}
+		}
+		seen[term] = true
+		field.Field = term
+		fields = append(fields, field)
+		offset += len(part) + 1
+	}
+	if len(fields) > MaxSortFields {
+		return nil, &SyntaxError{Offset: 0, Message: fmt.Sprintf("more than %d sort fields", MaxSortFields)}
+	}
+	return fields, nil
+}
@@ -0,0 +1,361 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxInValues bounds the list of an in comparison.
+const MaxInValues = 100
+
+// Kind is the value kind of a field.
+type Kind int
+
+// Field kinds.
+const (
+	KindString Kind = iota
+	KindInt
+	KindUint
+	KindFloat
+	KindBool
+	KindTime
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	orderedOps = []Operator{Eq, Ne, Gt, Ge, Lt, Le, In}
+	kindOps    = map[Kind][]Operator{
+		KindString: append(slices.Clone(orderedOps), Contains, StartsWith),
+		KindInt:    orderedOps,
+		KindUint:   orderedOps,
+		KindFloat:  orderedOps,
+		KindTime:   orderedOps,
+		KindBool:   {Eq, Ne},
+	}
+)
+
+// Field describes one field clients may filter or sort on.
+type Field struct {
+	Name   string
+	Column string
+	Kind   Kind
+	// Nullable fields, declared as pointers, accept eq null and ne null.
+	Nullable   bool
+	Ops        []Operator
+	Filterable bool
+	Sortable   bool
+}
+
+// Schema is the allowlist of fields declared on a struct.
+type Schema struct {
+	fields map[string]Field
+}
+
+var schemaCache sync.Map // reflect.Type -> schemaEntry
+
+type schemaEntry struct {
+	schema *Schema
+	err    error
+}
+
+// SchemaOf reads the filter and sort tags of struct type t. The filter tag is
+// "name[,ops=eq|in][,column=col]"; the sort tag is "name[,column=col]". An
+// empty name in either tag falls back to the other tag's name. Results are
+// cached per type.
+func SchemaOf(t reflect.Type) (*Schema, error) {
+	if cached, ok := schemaCache.Load(t); ok {
+		entry := cached.(schemaEntry)
+		return entry.schema, entry.err
+	}
+	schema, err := buildSchema(t)
+	schemaCache.Store(t, schemaEntry{schema: schema, err: err})
+	return schema, err
+}
+
+func buildSchema(t reflect.Type) (*Schema, error) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("filter: schema type %v is not a struct", t)
+	}
+	s := &Schema{fields: make(map[string]Field)}
+	if err := s.addFields(t); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Schema) addFields(t reflect.Type) error {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		filterTag, hasFilter := sf.Tag.Lookup("filter")
+		sortTag, hasSort := sf.Tag.Lookup("sort")
+		if sf.Anonymous && !hasFilter && !hasSort {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := s.addFields(embedded); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if (!hasFilter && !hasSort) || filterTag == "-" || !sf.IsExported() {
+			continue
+		}
+		field, err := newField(sf, filterTag, hasFilter, sortTag, hasSort)
+		if err != nil {
+			return err
+		}
+		if _, dup := s.fields[field.Name]; dup {
+			return fmt.Errorf("filter: duplicate field %q", field.Name)
+		}
+		s.fields[field.Name] = field
+	}
+	return nil
+}
+
+func newField(sf reflect.StructField, filterTag string, hasFilter bool, sortTag string, hasSort bool) (Field, error) {
+	field := Field{Filterable: hasFilter, Sortable: hasSort}
+	t := sf.Type
+	if t.Kind() == reflect.Pointer {
+		field.Nullable = true
+		t = t.Elem()
+	}
+	kind, ok := kindOf(t)
+	if !ok {
+		return Field{}, fmt.Errorf("filter: field %s has unsupported type %v", sf.Name, sf.Type)
+	}
+	field.Kind = kind
+
+	filterName, filterOptions, _ := strings.Cut(filterTag, ",")
+	sortName, sortOptions, _ := strings.Cut(sortTag, ",")
+	field.Name = cmpOr(filterName, sortName)
+	if field.Name == "" {
+		return Field{}, fmt.Errorf("filter: field %s needs a name in its filter or sort tag", sf.Name)
+	}
+	if hasFilter && hasSort && filterName != "" && sortName != "" && filterName != sortName {
+		return Field{}, fmt.Errorf("filter: field %s has different filter and sort names", sf.Name)
+	}
+
+	allowed := kindOps[kind]
+	field.Ops = allowed
+	for _, option := range strings.Split(filterOptions+","+sortOptions, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch key {
+		case "":
+		case "column":
+			if !validColumn(value) {
+				return Field{}, fmt.Errorf("filter: field %s has invalid column %q", sf.Name, value)
+			}
+			field.Column = value
+		case "ops":
+			field.Ops = nil
+			for name := range strings.SplitSeq(value, "|") {
+				op, ok := operators[name]
+				if !ok || !slices.Contains(allowed, op) {
+					return Field{}, fmt.Errorf("filter: field %s cannot use operator %q", sf.Name, name)
+				}
+				field.Ops = append(field.Ops, op)
+			}
+		default:
+			return Field{}, fmt.Errorf("filter: field %s has unknown tag option %q", sf.Name, key)
+		}
+	}
+	if field.Column == "" {
+		if !validColumn(field.Name) {
+			return Field{}, fmt.Errorf("filter: field name %q is not a valid column; set column=", field.Name)
+		}
+		field.Column = field.Name
+	}
+	return field, nil
+}
+
+func cmpOr(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func kindOf(t reflect.Type) (Kind, bool) {
+	if t == timeType {
+		return KindTime, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return KindString, true
+	case reflect.Bool:
+		return KindBool, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindUint, true
+	case reflect.Float32, reflect.Float64:
+		return KindFloat, true
+	}
+	return 0, false
+}
+
+// validColumn accepts identifiers optionally qualified with dots, so column
+// names can be written into SQL unquoted.
+func validColumn(name string) bool {
+	if name == "" || !isIdentStart(name[0]) || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentPart(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Field returns the declared field called name.
+func (s *Schema) Field(name string) (Field, bool) {
+	field, ok := s.fields[name]
+	return field, ok
+}
+
+// Check verifies that node only uses filterable fields with their allowed
+// operators, and returns a copy whose values have the fields' kinds.
+func (s *Schema) Check(node Node) (Node, error) {
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case *And:
+		left, right, err := s.checkPair(n.Left, n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &And{Left: left, Right: right}, nil
+	case *Or:
+		left, right, err := s.checkPair(n.Left, n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &Or{Left: left, Right: right}, nil
+	case *Not:
+		operand, err := s.Check(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Operand: operand}, nil
+	case *Comparison:
+		return s.checkComparison(n)
+	default:
+		return nil, fmt.Errorf("filter: unknown node %T", node)
+	}
+}
+
+func (s *Schema) checkPair(a, b Node) (Node, Node, error) {
+	left, err := s.Check(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err := s.Check(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+func (s *Schema) checkComparison(n *Comparison) (Node, error) {
+	field, ok := s.fields[n.Field]
+	if !ok || !field.Filterable {
+		return nil, fmt.Errorf("filter: field %q is not filterable", n.Field)
+	}
+	if !slices.Contains(field.Ops, n.Op) {
+		return nil, fmt.Errorf("filter: operator %s is not allowed on %q", n.Op, n.Field)
+	}
+	if n.Op == In {
+		values, _ := n.Value.([]any)
+		if len(values) == 0 || len(values) > MaxInValues {
+			return nil, fmt.Errorf("filter: in on %q needs 1 to %d values", n.Field, MaxInValues)
+		}
+		converted := make([]any, len(values))
+		for i, value := range values {
+			if value == nil {
+				return nil, fmt.Errorf("filter: in on %q cannot contain null", n.Field)
+			}
+			v, err := convert(field, value)
+			if err != nil {
+				return nil, err
+			}
+			converted[i] = v
+		}
+		return &Comparison{Field: n.Field, Op: In, Value: converted}, nil
+	}
+	if n.Value == nil {
+		if !field.Nullable || (n.Op != Eq && n.Op != Ne) {
+			return nil, fmt.Errorf("filter: %q cannot be compared with null", n.Field)
+		}
+		return &Comparison{Field: n.Field, Op: n.Op, Value: nil}, nil
+	}
+	value, err := convert(field, n.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Field: n.Field, Op: n.Op, Value: value}, nil
+}
+
+func convert(field Field, value any) (any, error) {
+	mismatch := fmt.Errorf("filter: invalid value %s for %q", literal(value), field.Name)
+	switch field.Kind {
+	case KindString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case KindBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case KindInt:
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+	case KindUint:
+		if v, ok := value.(int64); ok && v >= 0 {
+			return v, nil
+		}
+	case KindFloat:
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			if !math.IsInf(v, 0) && !math.IsNaN(v) {
+				return v, nil
+			}
+		}
+	case KindTime:
+		if v, ok := value.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, mismatch
+}
+
+// CheckSort verifies that every sort field is declared sortable.
+func (s *Schema) CheckSort(fields []SortField) error {
+	for _, f := range fields {
+		field, ok := s.fields[f.Field]
+		if !ok || !field.Sortable {
+			return fmt.Errorf("filter: field %q is not sortable", f.Field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placeholder renders the n-th (1-based) bind parameter of a statement.
+type Placeholder func(n int) string
+
+// Question renders ? placeholders, as used by MySQL and SQLite.
+func Question(int) string { return "?" }
+
+// Dollar renders $1, $2, ... placeholders, as used by PostgreSQL.
+func Dollar(n int) string { return "$" + strconv.Itoa(n) }
+
+// DollarFrom renders $n placeholders numbered after offset existing
+// parameters, for filters appended to a statement that already binds some.
+func DollarFrom(offset int) Placeholder {
+	return func(n int) string { return Dollar(offset + n) }
+}
+
+// likeEscape is the LIKE escape character. It is not a backslash, whose
+// meaning inside string literals differs between databases.
+const likeEscape = "!"
+
+var likeReplacer = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// SQL renders a checked node as a WHERE condition. Field names become their
+// schema columns and every value becomes a bound argument, so the result is
+// safe to concatenate into a statement. A nil node renders as "" with no
+// arguments; callers then omit the WHERE clause.
+func SQL(node Node, schema *Schema, placeholder Placeholder) (string, []any, error) {
+	if node == nil {
+		return "", nil, nil
+	}
+	if schema == nil {
+		return "", nil, fmt.Errorf("filter: nil schema")
+	}
+	if placeholder == nil {
+		placeholder = Question
+	}
+	w := &sqlWriter{schema: schema, placeholder: placeholder}
+	if err := w.write(node); err != nil {
+		return "", nil, err
+	}
+	return w.b.String(), w.args, nil
+}
+
+type sqlWriter struct {
+	schema      *Schema
+	placeholder Placeholder
+	b           strings.Builder
+	args        []any
+}
+
+func (w *sqlWriter) bind(value any) string {
+	w.args = append(w.args, value)
+	return w.placeholder(len(w.args))
+}
+
+func (w *sqlWriter) write(node Node) error {
+	switch n := node.(type) {
+	case *And:
+		return w.binary(n.Left, " AND ", n.Right)
+	case *Or:
+		w.b.WriteByte('(')
+		if err := w.binary(n.Left, " OR ", n.Right); err != nil {
+			return err
+		}
+		w.b.WriteByte(')')
+		return nil
+	case *Not:
+		w.b.WriteString("NOT (")
+		if err := w.write(n.Operand); err != nil {
+			return err
+		}
+		w.b.WriteByte(')')
+		return nil
+	case *Comparison:
+		return w.comparison(n)
+	default:
+		return fmt.Errorf("filter: unknown node %T", node)
+	}
+}
+
+func (w *sqlWriter) binary(left Node, op string, right Node) error {
+	if err := w.write(left); err != nil {
+		return err
+	}
+	w.b.WriteString(op)
+	return w.write(right)
+}
+
+var sqlOperators = map[Operator]string{
+	Eq: " = ", Ne: " <> ", Gt: " > ", Ge: " >= ", Lt: " < ", Le: " <= ",
+}
+
+func (w *sqlWriter) comparison(n *Comparison) error {
+	field, ok := w.schema.Field(n.Field)
+	if !ok || !field.Filterable {
+		return fmt.Errorf("filter: field %q is not filterable", n.Field)
+	}
+	column := field.Column
+	switch n.Op {
+	case Eq, Ne:
+		if n.Value == nil {
+			w.b.WriteString(column)
+			if n.Op == Eq {
+				w.b.WriteString(" IS NULL")
+			} else {
+				w.b.WriteString(" IS NOT NULL")
+			}
+			return nil
+		}
+		w.b.WriteString(column + sqlOperators[n.Op] + w.bind(n.Value))
+	case Gt, Ge, Lt, Le:
+		w.b.WriteString(column + sqlOperators[n.Op] + w.bind(n.Value))
+	case In:
+		values, _ := n.Value.([]any)
+		if len(values) == 0 {
+			return fmt.Errorf("filter: in on %q has no values", n.Field)
+		}
+		w.b.WriteString(column + " IN (")
+		for i, value := range values {
+			if i > 0 {
+				w.b.WriteString(", ")
+			}
+			w.b.WriteString(w.bind(value))
+		}
+		w.b.WriteByte(')')
+	case Contains, StartsWith:
+		s, ok := n.Value.(string)
+		if !ok {
+			return fmt.Errorf("filter: %s on %q needs a string", n.Op, n.Field)
+		}
+		pattern := likeReplacer.Replace(s) + "%"
+		if n.Op == Contains {
+			pattern = "%" + pattern
+		}
+		w.b.WriteString(column + " LIKE " + w.bind(pattern) + " ESCAPE '" + likeEscape + "'")
+	default:
+		return fmt.Errorf("filter: unknown operator %q", n.Op)
+	}
+	return nil
+}
+
+// SortSQL renders checked sort fields as an ORDER BY list such as
+// "u.created_at DESC, name ASC". No fields render as "".
+func SortSQL(fields []SortField, schema *Schema) (string, error) {
+	if len(fields) == 0 {
+		return "", nil
+	}
+	if schema == nil {
+		return "", fmt.Errorf("filter: nil schema")
+	}
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		field, ok := schema.Field(f.Field)
+		if !ok || !field.Sortable {
+			return "", fmt.Errorf("filter: field %q is not sortable", f.Field)
+		}
+		direction := " ASC"
+		if f.Descending {
+			direction = " DESC"
+		}
+		parts[i] = field.Column + direction
+	}
+	return strings.Join(parts, ", "), nil
+}
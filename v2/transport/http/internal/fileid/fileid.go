@@ -0,0 +1,18 @@
+// Package fileid validates caller-supplied IDs that become file names.
+package fileid
+
+// Valid reports whether id is 1 to 128 letters, digits, '-' and '_', which
+// keeps it safe as a file name and a URL path segment.
+func Valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,361 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+// ContentType is the media type of request and response bodies in built
+// documents.
+const ContentType = "application/json"
+
+// Route describes one operation for Build.
+type Route struct {
+	// Method is the HTTP method, such as "GET".
+	Method string
+	// Path is the ServeMux path, such as "/items/{id}". Wildcards like
+	// {rest...} are documented as {rest}.
+	Path string
+	// Request is the request type; nil means no parameters and no body.
+	// Fields tagged path, query or header become parameters.
+	Request reflect.Type
+	// Response is the type of the 200 response body; nil means no body.
+	Response reflect.Type
+	// FieldMask is the query parameter of a route using
+	// server.ServerFieldMask, usually "fields"; empty means the route
+	// always returns whole responses.
+	FieldMask string
+
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Errors lists the error responses the operation documents. A request
+	// type adds a 400 response when none is listed.
+	Errors []ErrorResponse
+}
+
+// ErrorResponse documents one error outcome. Several errors may share a
+// status; their codes are listed in the response description.
+type ErrorResponse struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+// Option configures Build.
+type Option func(*builder)
+
+type builder struct {
+	errorBody reflect.Type
+}
+
+// WithErrorBody sets the type of error response bodies, such as
+// server.ErrorResponse. Without it error responses have no schema.
+func WithErrorBody(t reflect.Type) Option {
+	return func(b *builder) { b.errorBody = t }
+}
+
+// Build returns the document for routes. Operations appear under their path
+// and method; a later route replaces an earlier one with the same method and
+// path.
+func Build(info Info, routes []Route, options ...Option) *Document {
+	var b builder
+	for _, option := range options {
+		if option != nil {
+			option(&b)
+		}
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+	}
+	s := newSchemas()
+	tags := map[string]bool{}
+	for _, route := range routes {
+		path := documentPath(route.Path)
+		method := strings.ToLower(route.Method)
+		if method == "" {
+			method = "get"
+		}
+		op := b.operation(s, route, method, path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][method] = op
+		for _, tag := range route.Tags {
+			tags[tag] = true
+		}
+	}
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	doc.Components.Schemas = s.components
+	return doc
+}
+
+// ParsePattern splits a ServeMux pattern such as "PUT example.com/items/{id}"
+// into its method and path. The method is empty when the pattern has none.
+func ParsePattern(pattern string) (method, path string) {
+	pattern = strings.TrimSpace(pattern)
+	if m, rest, ok := strings.Cut(pattern, " "); ok && !strings.Contains(m, "/") {
+		method, pattern = m, strings.TrimSpace(rest)
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+	return method, pattern
+}
+
+func (b *builder) operation(s *schemas, route Route, method, path string) *Operation {
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        append([]string(nil), route.Tags...),
+		Deprecated:  route.Deprecated,
+		Responses:   map[string]Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(method, path)
+	}
+
+	if route.Request != nil {
+		op.Parameters, op.RequestBody = requestParts(s, route.Request)
+	}
+	for _, name := range pathParameterNames(path) {
+		if !hasParameter(op.Parameters, name, "path") {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	if route.FieldMask != "" && !hasParameter(op.Parameters, route.FieldMask, "query") {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        route.FieldMask,
+			In:          "query",
+			Description: "Comma-separated response fields to return; nested fields use dots, such as address.city.",
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	ok := Response{Description: http.StatusText(http.StatusOK)}
+	if route.Response != nil {
+		ok.Content = map[string]MediaType{ContentType: {Schema: s.of(route.Response)}}
+	}
+	op.Responses[strconv.Itoa(http.StatusOK)] = ok
+
+	errors := route.Errors
+	if (route.Request != nil || route.FieldMask != "") && !hasStatus(errors, http.StatusBadRequest) {
+		errors = append([]ErrorResponse{{Status: http.StatusBadRequest, Description: "Invalid request"}}, errors...)
+	}
+	for _, e := range errors {
+		key := strconv.Itoa(e.Status)
+		resp, exists := op.Responses[key]
+		description := errorDescription(e)
+		if exists {
+			resp.Description += "; " + description
+		} else {
+			resp = Response{Description: description}
+			if b.errorBody != nil {
+				resp.Content = map[string]MediaType{ContentType: {Schema: s.of(b.errorBody)}}
+			}
+		}
+		op.Responses[key] = resp
+	}
+	return op
+}
+
+// requestParts splits a request type into parameters and a body, following
+// the tag rules of transporthttp.Bind.
+func requestParts(s *schemas, t reflect.Type) ([]Parameter, *RequestBody) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, &RequestBody{Required: true, Content: map[string]MediaType{ContentType: {Schema: s.of(t)}}}
+	}
+
+	var parts requestFields
+	parts.collect(t, map[reflect.Type]bool{})
+	params := make([]Parameter, 0, len(parts.params))
+	for _, p := range parts.params {
+		schema := s.parameter(p.field.Type)
+		required := applyValidation(schema, p.field)
+		params = append(params, Parameter{Name: p.name, In: p.in, Required: required || p.in == "path", Schema: schema})
+	}
+
+	var body *RequestBody
+	switch {
+	case parts.body != nil:
+		body = &RequestBody{Content: map[string]MediaType{ContentType: {Schema: s.of(parts.body.Type)}}}
+	case parts.hasBody:
+		// Bound requests may omit the body; plain JSON requests may not.
+		body = &RequestBody{Required: len(params) == 0, Content: map[string]MediaType{ContentType: {Schema: s.of(t)}}}
+	}
+	return params, body
+}
+
+type requestParam struct {
+	field reflect.StructField
+	in    string
+	name  string
+}
+
+type requestFields struct {
+	params  []requestParam
+	body    *reflect.StructField
+	hasBody bool
+}
+
+func (r *requestFields) collect(t reflect.Type, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && (!field.Anonymous || field.Type.Kind() == reflect.Pointer) {
+			continue
+		}
+		if _, ok := field.Tag.Lookup(transporthttp.BodyTag); ok {
+			if r.body == nil {
+				r.body = &field
+			}
+			continue
+		}
+		if in, name, ok := parameterTag(field); ok {
+			if name != "-" {
+				r.params = append(r.params, requestParam{field: field, in: in, name: name})
+			}
+			continue
+		}
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.collect(embedded, visiting)
+				continue
+			}
+		}
+		// The same rule as the bind plan and encoding/json: only the exact
+		// tag "-" skips a field.
+		if field.IsExported() && field.Tag.Get("json") != "-" {
+			r.hasBody = true
+		}
+	}
+}
+
+func parameterTag(field reflect.StructField) (in, name string, ok bool) {
+	for _, tag := range []string{transporthttp.PathTag, transporthttp.QueryTag, transporthttp.HeaderTag} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(value, ",")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if tag == transporthttp.HeaderTag && name != "-" {
+			name = http.CanonicalHeaderKey(name)
+		}
+		return tag, name, true
+	}
+	return "", "", false
+}
+
+// documentPath rewrites ServeMux wildcards to OpenAPI path templates.
+func documentPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	path = strings.ReplaceAll(path, "{$}", "")
+	path = strings.ReplaceAll(path, "...}", "}")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func pathParameterNames(path string) []string {
+	var names []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return names
+		}
+		end := strings.Index(path[start:], "}")
+		if end < 0 {
+			return names
+		}
+		names = append(names, path[start+1:start+end])
+		path = path[start+end+1:]
+	}
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+func hasStatus(errors []ErrorResponse, status int) bool {
+	for _, e := range errors {
+		if e.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func errorDescription(e ErrorResponse) string {
+	description := e.Description
+	if description == "" {
+		description = http.StatusText(e.Status)
+	}
+	if description == "" {
+		description = "Error"
+	}
+	if e.Code != "" {
+		description += " (" + e.Code + ")"
+	}
+	return description
+}
+
+// operationID derives an identifier such as "putItemsId" from the method
+// and path.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
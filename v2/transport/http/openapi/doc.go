@@ -0,0 +1,26 @@
+// Package openapi builds OpenAPI 3.1 documents at runtime from the Go types
+// behind HTTP routes.
+//
+// Build takes one Route per operation: a ServeMux pattern, the request and
+// response types, and optional documentation such as a summary, tags and
+// error responses. Request fields tagged path, query or header (see
+// transporthttp.Bind) become parameters; the remaining JSON fields, or the
+// field tagged body, become the request body. Named struct types are emitted
+// once under components/schemas and referenced with $ref.
+//
+// Schemas follow encoding/json: json tags name and hide fields, embedded
+// structs are flattened, time.Time is a date-time string, and types that
+// implement encoding.TextMarshaler are strings. Validation rules in validate
+// tags (required, min, max, len, gt, gte, lt, lte, oneof, email, url and
+// uuid) become the matching JSON Schema keywords.
+//
+// The validate tags are documentation only: nothing in go-kit enforces them.
+// Requests are checked by the request type's Validate method (see
+// endpoint.Validator), so a tag must describe a rule that Validate, or a
+// validator library it calls, already applies. Otherwise the document
+// promises a constraint the server does not check.
+//
+// Handler serves a document as JSON, and DocsHandler serves a small,
+// self-contained HTML page that renders it without external assets.
+// kit.WithOpenAPI wires both into a Service.
+package openapi
@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+// Handler serves the document returned by document as JSON. document runs on
+// every request, so routes added after the handler was created still appear.
+func Handler(document func() *Document) http.Handler {
+	if document == nil {
+		panic("openapi: document function cannot be nil")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		body, err := json.Marshal(document())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(body)
+	})
+}
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// DocsHandler serves an HTML page that renders the document at specURL. The
+// page is self-contained: it loads no scripts, styles or fonts from other
+// origins.
+func DocsHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = docsTemplate.Execute(w, struct{ SpecURL string }{specURL})
+	})
+}
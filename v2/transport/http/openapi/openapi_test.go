@@ -0,0 +1,284 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/openapi"
+)
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Item struct {
+	Audit
+	ID       string            `json:"id"`
+	Name     string            `json:"name" validate:"required,min=1,max=64"`
+	Price    float64           `json:"price" validate:"gt=0"`
+	Status   string            `json:"status" validate:"oneof=active archived"`
+	Tags     []string          `json:"tags,omitempty" validate:"max=5"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *Item             `json:"parent,omitempty"`
+	Internal string            `json:"-"`
+	Count    int64             `json:"count,string"`
+}
+
+type UpdateItemRequest struct {
+	ID     string `path:"id" json:"-"`
+	DryRun bool   `query:"dry_run" json:"-"`
+	Tenant string `header:"x-tenant" json:"-" validate:"required"`
+	Name   string `json:"name" validate:"required"`
+}
+
+type ListItemsRequest struct {
+	Page   int      `query:"page" validate:"min=1"`
+	Status []string `query:"status"`
+}
+
+type CreateItemRequest struct {
+	Project string `path:"project"`
+	Item    Item   `body:""`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func build(routes ...openapi.Route) *openapi.Document {
+	return openapi.Build(openapi.Info{Title: "Items"}, routes, openapi.WithErrorBody(reflect.TypeOf(errorBody{})))
+}
+
+func TestBuildComponentsFollowJSONAndValidationTags(t *testing.T) {
+	doc := build(openapi.Route{Method: "GET", Path: "/items/{id}", Response: reflect.TypeOf(Item{})})
+
+	if doc.OpenAPI != openapi.Version || doc.Info.Version != "1.0.0" {
+		t.Fatalf("header = %q %+v", doc.OpenAPI, doc.Info)
+	}
+	item := doc.Components.Schemas["Item"]
+	if item == nil {
+		t.Fatalf("Item component missing: %v", doc.Components.Schemas)
+	}
+	for _, name := range []string{"created_at", "id", "name", "price", "status", "tags", "labels", "parent", "count"} {
+		if item.Properties[name] == nil {
+			t.Errorf("property %q missing", name)
+		}
+	}
+	if item.Properties["Internal"] != nil || item.Properties["Audit"] != nil {
+		t.Fatalf("hidden or embedded field exposed: %v", item.Properties)
+	}
+	if got := strings.Join(item.Required, ","); got != "name" {
+		t.Fatalf("required = %q", got)
+	}
+	name := item.Properties["name"]
+	if *name.MinLength != 1 || *name.MaxLength != 64 {
+		t.Fatalf("name bounds = %d..%d", *name.MinLength, *name.MaxLength)
+	}
+	if price := item.Properties["price"]; price.ExclusiveMinimum == nil || *price.ExclusiveMinimum != 0 {
+		t.Fatalf("price = %+v", price)
+	}
+	if status := item.Properties["status"]; len(status.Enum) != 2 || status.Enum[1] != "archived" {
+		t.Fatalf("status enum = %v", status.Enum)
+	}
+	if tags := item.Properties["tags"]; tags.Type != "array" || *tags.MaxItems != 5 {
+		t.Fatalf("tags = %+v", tags)
+	}
+	if parent := item.Properties["parent"]; parent.Ref != "#/components/schemas/Item" {
+		t.Fatalf("recursive ref = %q", parent.Ref)
+	}
+	if created := item.Properties["created_at"]; created.Format != "date-time" {
+		t.Fatalf("created_at = %+v", created)
+	}
+	if count := item.Properties["count"]; count.Type != "string" {
+		t.Fatalf("count with string option = %+v", count)
+	}
+	if labels := item.Properties["labels"]; labels.AdditionalProperties == nil || labels.AdditionalProperties.Type != "string" {
+		t.Fatalf("labels = %+v", labels)
+	}
+}
+
+type versioned struct {
+	Version int `json:"version"`
+	Rev     string
+}
+
+type revision struct {
+	Rev  string
+	Note string
+}
+
+type Release struct {
+	versioned
+	revision
+	Version string `json:"version"`
+}
+
+func TestBuildResolvesDuplicateJSONNamesLikeEncodingJSON(t *testing.T) {
+	doc := build(openapi.Route{Method: "GET", Path: "/release", Response: reflect.TypeOf(Release{})})
+	release := doc.Components.Schemas["Release"]
+
+	encoded, _ := json.Marshal(Release{})
+	var keys map[string]any
+	_ = json.Unmarshal(encoded, &keys)
+	if len(release.Properties) != len(keys) {
+		t.Fatalf("properties = %v, encoding/json keys = %v", release.Properties, keys)
+	}
+	for name := range keys {
+		if release.Properties[name] == nil {
+			t.Errorf("property %q missing", name)
+		}
+	}
+	if version := release.Properties["version"]; version == nil || version.Type != "string" {
+		t.Fatalf("version = %+v, want the shallower string field", version)
+	}
+}
+
+func TestBuildSplitsBoundRequests(t *testing.T) {
+	doc := build(openapi.Route{
+		Method:   "PUT",
+		Path:     "/items/{id}",
+		Request:  reflect.TypeOf(UpdateItemRequest{}),
+		Response: reflect.TypeOf(Item{}),
+		Summary:  "Update an item",
+		Tags:     []string{"items"},
+		Errors:   []openapi.ErrorResponse{{Status: 404, Code: "item.not_found"}},
+	})
+
+	op := doc.Paths["/items/{id}"]["put"]
+	if op == nil {
+		t.Fatalf("operation missing: %v", doc.Paths)
+	}
+	if op.OperationID != "putItemsId" || op.Summary != "Update an item" {
+		t.Fatalf("operation = %+v", op)
+	}
+	want := map[string]openapi.Parameter{
+		"id":       {In: "path", Required: true},
+		"dry_run":  {In: "query"},
+		"X-Tenant": {In: "header", Required: true},
+	}
+	if len(op.Parameters) != len(want) {
+		t.Fatalf("parameters = %+v", op.Parameters)
+	}
+	for _, p := range op.Parameters {
+		w, ok := want[p.Name]
+		if !ok || w.In != p.In || w.Required != p.Required {
+			t.Errorf("parameter %+v, want %+v", p, w)
+		}
+	}
+	body := op.RequestBody
+	if body == nil || body.Required || body.Content[openapi.ContentType].Schema.Ref != "#/components/schemas/UpdateItemRequest" {
+		t.Fatalf("request body = %+v", body)
+	}
+	if props := doc.Components.Schemas["UpdateItemRequest"].Properties; len(props) != 1 || props["name"] == nil {
+		t.Fatalf("request component properties = %v", props)
+	}
+	if _, ok := op.Responses["400"]; !ok {
+		t.Fatal("400 response not added")
+	}
+	notFound := op.Responses["404"]
+	if !strings.Contains(notFound.Description, "item.not_found") || notFound.Content[openapi.ContentType].Schema.Ref != "#/components/schemas/errorBody" {
+		t.Fatalf("404 response = %+v", notFound)
+	}
+	if len(doc.Tags) != 1 || doc.Tags[0].Name != "items" {
+		t.Fatalf("tags = %+v", doc.Tags)
+	}
+}
+
+func TestBuildQueryOnlyAndBodyFieldRequests(t *testing.T) {
+	doc := build(
+		openapi.Route{Method: "GET", Path: "/items", Request: reflect.TypeOf(ListItemsRequest{})},
+		openapi.Route{Method: "POST", Path: "/projects/{project}/items/{rest...}", Request: reflect.TypeOf(CreateItemRequest{})},
+	)
+
+	list := doc.Paths["/items"]["get"]
+	if list.RequestBody != nil {
+		t.Fatalf("query-only request has a body: %+v", list.RequestBody)
+	}
+	for _, p := range list.Parameters {
+		switch p.Name {
+		case "page":
+			if p.Schema.Minimum == nil || *p.Schema.Minimum != 1 {
+				t.Errorf("page schema = %+v", p.Schema)
+			}
+		case "status":
+			if p.Schema.Type != "array" || p.Schema.Items.Type != "string" {
+				t.Errorf("status schema = %+v", p.Schema)
+			}
+		}
+	}
+
+	create := doc.Paths["/projects/{project}/items/{rest}"]["post"]
+	if create == nil {
+		t.Fatalf("wildcard path not rewritten: %v", doc.Paths)
+	}
+	if create.RequestBody.Content[openapi.ContentType].Schema.Ref != "#/components/schemas/Item" {
+		t.Fatalf("body field schema = %+v", create.RequestBody)
+	}
+	if len(create.Parameters) != 2 || create.Parameters[1].Name != "rest" || !create.Parameters[1].Required {
+		t.Fatalf("parameters = %+v", create.Parameters)
+	}
+}
+
+func TestBuildFieldMaskParameter(t *testing.T) {
+	doc := build(openapi.Route{Method: "GET", Path: "/items/{id}", Response: reflect.TypeOf(Item{}), FieldMask: "fields"})
+
+	op := doc.Paths["/items/{id}"]["get"]
+	if len(op.Parameters) != 2 {
+		t.Fatalf("parameters = %+v", op.Parameters)
+	}
+	if p := op.Parameters[1]; p.Name != "fields" || p.In != "query" || p.Required || p.Schema.Type != "string" {
+		t.Errorf("fields parameter = %+v", p)
+	}
+	if _, ok := op.Responses["400"]; !ok {
+		t.Errorf("responses = %v, want 400 for unknown fields", op.Responses)
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	for pattern, want := range map[string][2]string{
+		"/items":                        {"", "/items"},
+		"GET /items/{id}":               {"GET", "/items/{id}"},
+		"POST example.com/items":        {"POST", "/items"},
+		"example.com/items/{rest...}":   {"", "/items/{rest...}"},
+		"DELETE   /items/{id}/tags/{$}": {"DELETE", "/items/{id}/tags/{$}"},
+	} {
+		method, path := openapi.ParsePattern(pattern)
+		if method != want[0] || path != want[1] {
+			t.Errorf("ParsePattern(%q) = %q, %q; want %q, %q", pattern, method, path, want[0], want[1])
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	calls := 0
+	h := openapi.Handler(func() *openapi.Document {
+		calls++
+		return build(openapi.Route{Method: "GET", Path: "/items", Response: reflect.TypeOf([]Item{})})
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc openapi.Document
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || doc.Paths["/items"]["get"] == nil || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("calls=%d doc=%+v", calls, doc)
+	}
+
+	w = httptest.NewRecorder()
+	openapi.DocsHandler("/spec</script>.json").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	page, _ := io.ReadAll(w.Body)
+	if !strings.Contains(string(page), `"/spec\u003c/script\u003e.json"`) {
+		t.Fatalf("spec URL not escaped into the page:\n%s", page)
+	}
+	if strings.Contains(string(page), "https://") {
+		t.Fatal("docs page references an external origin")
+	}
+}
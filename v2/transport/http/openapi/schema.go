@@ -0,0 +1,369 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeArgPackagePaths = regexp.MustCompile(`[\w.\-]+/`)
+	invalidNameChars    = regexp.MustCompile(`[^A-Za-z0-9._\-]+`)
+)
+
+// schemas reflects Go types into JSON Schemas and collects the named struct
+// types as components.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of values of type t as encoding/json writes them.
+func (s *schemas) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case implements(t, jsonMarshalerType):
+		return &Schema{}
+	case implements(t, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !implements(t.Elem(), textMarshalerType) {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: s.of(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: refPrefix + s.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component registers the named struct type t and returns its component name.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := s.uniqueName(t)
+	s.names[t] = name
+	schema := &Schema{}
+	s.components[name] = schema
+	*schema = *s.object(t)
+	return name
+}
+
+func (s *schemas) uniqueName(t reflect.Type) string {
+	base := invalidNameChars.ReplaceAllString(typeArgPackagePaths.ReplaceAllString(t.Name(), ""), "_")
+	name := base
+	if _, taken := s.components[name]; taken && t.PkgPath() != "" {
+		name = path.Base(t.PkgPath()) + "." + base
+	}
+	for i := 2; ; i++ {
+		if _, taken := s.components[name]; !taken {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	var fields []jsonField
+	collectJSONFields(&fields, t, 0, map[reflect.Type]bool{})
+	for _, field := range dominantFields(fields) {
+		s.addField(object, field)
+	}
+	return object
+}
+
+// jsonField is a field encoding/json would consider for a name, with the
+// embedding depth it was found at.
+type jsonField struct {
+	field  reflect.StructField
+	name   string
+	depth  int
+	tagged bool
+}
+
+// collectJSONFields lists the JSON fields of struct type t in declaration
+// order, flattening embedded structs the way encoding/json does.
+func collectJSONFields(fields *[]jsonField, t reflect.Type, depth int, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		name, _ := jsonName(field)
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				collectJSONFields(fields, embedded, depth+1, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		*fields = append(*fields, jsonField{field: field, name: name, depth: depth, tagged: tagged})
+	}
+}
+
+// dominantFields applies encoding/json's precedence to fields sharing a
+// name: the shallowest wins, a tagged field beats untagged ones at the same
+// depth, and any other tie drops the name.
+func dominantFields(fields []jsonField) []jsonField {
+	byName := make(map[string][]jsonField, len(fields))
+	var names []string
+	for _, f := range fields {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	dominant := make([]jsonField, 0, len(names))
+	for _, name := range names {
+		candidates := byName[name]
+		depth := candidates[0].depth
+		for _, f := range candidates[1:] {
+			depth = min(depth, f.depth)
+		}
+		var shallowest, tagged []jsonField
+		for _, f := range candidates {
+			if f.depth != depth {
+				continue
+			}
+			shallowest = append(shallowest, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		switch {
+		case len(shallowest) == 1:
+			dominant = append(dominant, shallowest[0])
+		case len(tagged) == 1:
+			dominant = append(dominant, tagged[0])
+		}
+	}
+	return dominant
+}
+
+// addField adds the schema of one JSON field to object.
+func (s *schemas) addField(object *Schema, f jsonField) {
+	_, options := jsonName(f.field)
+	schema := s.of(f.field.Type)
+	if hasOption(options, "string") {
+		switch schema.Type {
+		case "integer", "number", "boolean":
+			schema = &Schema{Type: "string"}
+		}
+	}
+	if applyValidation(schema, f.field) {
+		object.Required = append(object.Required, f.name)
+	}
+	object.Properties[f.name] = schema
+}
+
+// parameter returns the schema of a path, query or header parameter, which
+// uses the text forms accepted by transporthttp.Bind.
+func (s *schemas) parameter(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string", Format: "duration"}
+	case t.Kind() == reflect.Slice && t != rawMessageType:
+		return &Schema{Type: "array", Items: s.parameter(t.Elem())}
+	}
+	return s.of(t)
+}
+
+// applyValidation maps the validate tag of field onto schema and reports
+// whether the field is required. It only describes the rules; enforcing them
+// is up to the request type's Validate method.
+func applyValidation(schema *Schema, field reflect.StructField) bool {
+	tag, ok := field.Tag.Lookup("validate")
+	if !ok {
+		return false
+	}
+	t := field.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch key {
+		case "dive":
+			// Later rules apply to elements.
+			return required
+		case "required":
+			required = true
+		case "min", "gte":
+			setBound(schema, t, value, true, false)
+		case "max", "lte":
+			setBound(schema, t, value, false, false)
+		case "gt":
+			setBound(schema, t, value, true, true)
+		case "lt":
+			setBound(schema, t, value, false, true)
+		case "len":
+			setBound(schema, t, value, true, false)
+			setBound(schema, t, value, false, false)
+		case "oneof":
+			for _, option := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, enumValue(schema.Type, option))
+			}
+		case "email":
+			schema.Format = "email"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, t reflect.Type, value string, lower, exclusive bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "integer", "number":
+		switch {
+		case lower && exclusive:
+			schema.ExclusiveMinimum = &n
+		case lower:
+			schema.Minimum = &n
+		case exclusive:
+			schema.ExclusiveMaximum = &n
+		default:
+			schema.Maximum = &n
+		}
+		return
+	}
+	count := int(n)
+	if exclusive {
+		if lower {
+			count++
+		} else {
+			count--
+		}
+	}
+	var target **int
+	switch {
+	case t.Kind() == reflect.Map:
+		target = pick(lower, &schema.MinProperties, &schema.MaxProperties)
+	case schema.Type == "array":
+		target = pick(lower, &schema.MinItems, &schema.MaxItems)
+	case schema.Type == "string":
+		target = pick(lower, &schema.MinLength, &schema.MaxLength)
+	default:
+		return
+	}
+	*target = &count
+}
+
+func pick(lower bool, min, max **int) **int {
+	if lower {
+		return min
+	}
+	return max
+}
+
+func enumValue(schemaType, option string) any {
+	switch schemaType {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(option, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(option); err == nil {
+			return b
+		}
+	}
+	return option
+}
+
+func jsonName(field reflect.StructField) (string, string) {
+	tag := field.Tag.Get("json")
+	name, options, _ := strings.Cut(tag, ",")
+	return name, options
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func float(v float64) *float64 { return &v }
@@ -141,3 +141,101 @@ func TestValidateQueryStruct_SkipsDashTaggedFields(t *testing.T) {
 		t.Fatalf("skipped field should not fail validation: %v", err)
 	}
 }
+
+func TestCursorCodec_RejectsTamperedTokens(t *testing.T) {
+	codec := &transporthttp.CursorCodec{Secret: []byte("secret")}
+	token, err := codec.Encode(transporthttp.CursorNext, 42)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	direction, key, err := codec.Decode(token)
+	if err != nil || direction != transporthttp.CursorNext || string(key) != "42" {
+		t.Fatalf("decode: got %q %s %v", direction, key, err)
+	}
+
+	unsigned, err := (&transporthttp.CursorCodec{}).Encode(transporthttp.CursorNext, 43)
+	if err != nil {
+		t.Fatalf("encode unsigned: %v", err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+	for _, bad := range []string{unsigned, unsigned + "." + signature, payload, "garbage"} {
+		if _, _, err := codec.Decode(bad); !errors.Is(err, transporthttp.ErrInvalidCursor) {
+			t.Errorf("decode %q: got %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+}
+
+func TestParseCursorPage_RejectsInvalidCursor(t *testing.T) {
+	codec := &transporthttp.CursorCodec{Secret: []byte("secret")}
+	_, err := transporthttp.ParseCursorPage(pageRequest(t, "cursor=bogus"), codec)
+	var verr *endpoint.ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != "cursor" {
+		t.Fatalf("got %v, want cursor validation error", err)
+	}
+}
+
+func TestCursorPagination_NavigatesBothWays(t *testing.T) {
+	codec := &transporthttp.CursorCodec{Secret: []byte("secret")}
+	ids := []int{1, 2, 3, 4, 5, 6, 7}
+	// fetch reads like a keyset query: WHERE id > key ORDER BY id ASC, or
+	// WHERE id < key ORDER BY id DESC for backward pages.
+	fetch := func(rawQuery string) transporthttp.CursorResult[int] {
+		t.Helper()
+		r := pageRequest(t, rawQuery)
+		page, err := transporthttp.ParseCursorPage(r, codec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", rawQuery, err)
+		}
+		var key int
+		if err := page.Key(&key); err != nil {
+			t.Fatalf("key: %v", err)
+		}
+		var rows []int
+		if page.Backward() {
+			for i := len(ids) - 1; i >= 0 && len(rows) < page.Limit(); i-- {
+				if ids[i] < key {
+					rows = append(rows, ids[i])
+				}
+			}
+		} else {
+			for _, id := range ids {
+				if (page.First() || id > key) && len(rows) < page.Limit() {
+					rows = append(rows, id)
+				}
+			}
+		}
+		result, err := transporthttp.NewCursorResult(r, codec, page, rows, func(id int) any { return id })
+		if err != nil {
+			t.Fatalf("result: %v", err)
+		}
+		return result
+	}
+	query := func(link string) string {
+		_, rawQuery, _ := strings.Cut(link, "?")
+		return rawQuery
+	}
+
+	first := fetch("size=3&status=active")
+	if got := first.Items; len(got) != 3 || got[0] != 1 || !first.HasNext || first.HasPrev {
+		t.Fatalf("first page: %+v", first)
+	}
+	if !strings.HasPrefix(first.Next, "/items?") || !strings.Contains(first.Next, "status=active") {
+		t.Errorf("next link: %q", first.Next)
+	}
+	second := fetch(query(first.Next))
+	if got := second.Items; len(got) != 3 || got[0] != 4 || !second.HasNext || !second.HasPrev {
+		t.Fatalf("second page: %+v", second)
+	}
+	last := fetch(query(second.Next))
+	if got := last.Items; len(got) != 1 || got[0] != 7 || last.HasNext || !last.HasPrev {
+		t.Fatalf("last page: %+v", last)
+	}
+	back := fetch(query(last.Prev))
+	if got := back.Items; len(got) != 3 || got[0] != 4 || got[2] != 6 || !back.HasNext || !back.HasPrev {
+		t.Fatalf("back page: %+v", back)
+	}
+	start := fetch(query(back.Prev))
+	if got := start.Items; len(got) != 3 || got[0] != 1 || start.HasPrev || !start.HasNext {
+		t.Fatalf("start page: %+v", start)
+	}
+}
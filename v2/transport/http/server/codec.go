@@ -115,26 +115,32 @@ func RawBodyCodecWithMaxBytes(unmarshal UnmarshalFunc, marshal MarshalFunc, cont
 		if err != nil {
 			return err
 		}
-		w.Header().Set("Content-Type", contentType)
-		// Honor the same transport contracts as the JSON encoder.
-		if headerer, ok := response.(transporthttp.Headerer); ok {
-			for k, values := range headerer.Headers() {
-				for _, v := range values {
-					w.Header().Add(k, v)
-				}
-			}
-		}
-		code := http.StatusOK
-		if sc, ok := response.(transporthttp.StatusCoder); ok {
-			code = sc.StatusCode()
-		}
-		w.WriteHeader(code)
-		if code == http.StatusNoContent {
-			return nil
-		}
-		_, err = w.Write(data)
-		return err
+		return writeRawResponse(w, contentType, data, response)
 	}
 
 	return decode, encode
 }
+
+// writeRawResponse writes data with the same transport contracts as the JSON
+// encoder: Headerer adds headers, StatusCoder sets the status, and 204
+// responses carry no body.
+func writeRawResponse(w http.ResponseWriter, contentType string, data []byte, response any) error {
+	w.Header().Set("Content-Type", contentType)
+	if headerer, ok := response.(transporthttp.Headerer); ok {
+		for k, values := range headerer.Headers() {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	}
+	code := http.StatusOK
+	if sc, ok := response.(transporthttp.StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	w.WriteHeader(code)
+	if code == http.StatusNoContent {
+		return nil
+	}
+	_, err := w.Write(data)
+	return err
+}
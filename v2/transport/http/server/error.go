@@ -17,9 +17,9 @@ type ErrorEncoder func(ctx context.Context, err error, w http.ResponseWriter)
 
 // ErrorResponse is the default JSON shape emitted by JSONErrorEncoder.
 type ErrorResponse struct {
-	Code      string `json:"code"`
-	Message   string `json:"message"`
-	RequestID string `json:"request_id,omitempty"`
+	Code      string `json:"code" xml:"code"`
+	Message   string `json:"message" xml:"message"`
+	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty"`
 }
 
 // HTTPError is a small helper for returning transport-aware errors from
@@ -183,15 +183,27 @@ func statusWithMapper(err error, mapper func(apperror.Kind) int) int {
 func encodeJSONErrorWithStatus(ctx context.Context, err error, w http.ResponseWriter, status int) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	var h transporthttp.Headerer
-	if errors.As(err, &h) {
-		for k, vals := range h.Headers() {
-			for _, v := range vals {
-				w.Header().Add(k, v)
-			}
-		}
-	}
+	addErrorHeaders(w, err)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(newErrorResponse(ctx, err, status))
+}
+
+var JSONErrorEncoder ErrorEncoder = func(ctx context.Context, err error, w http.ResponseWriter) {
+	encodeJSONError(ctx, err, w)
+}
 
+func encodeJSONError(ctx context.Context, err error, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	addErrorHeaders(w, err)
+	code := httpStatus(err)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(newErrorResponse(ctx, err, code))
+}
+
+// newErrorResponse builds the body shared by the structured error encoders.
+// 5xx messages stay opaque unless the error provides a public message.
+func newErrorResponse(ctx context.Context, err error, status int) ErrorResponse {
 	message := http.StatusText(status)
 	if message == "" {
 		message = "HTTP error"
@@ -213,21 +225,14 @@ func encodeJSONErrorWithStatus(ctx context.Context, err error, w http.ResponseWr
 		errorCode = ec.ErrorCode()
 	}
 
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(ErrorResponse{
+	return ErrorResponse{
 		Code:      errorCode,
 		Message:   message,
 		RequestID: endpoint.RequestIDFromContext(ctx),
-	})
-}
-
-var JSONErrorEncoder ErrorEncoder = func(ctx context.Context, err error, w http.ResponseWriter) {
-	encodeJSONError(ctx, err, w)
+	}
 }
 
-func encodeJSONError(ctx context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-
+func addErrorHeaders(w http.ResponseWriter, err error) {
 	var h transporthttp.Headerer
 	if errors.As(err, &h) {
 		for k, vals := range h.Headers() {
@@ -236,36 +241,6 @@ func encodeJSONError(ctx context.Context, err error, w http.ResponseWriter) {
 			}
 		}
 	}
-
-	code := httpStatus(err)
-
-	message := http.StatusText(code)
-	if message == "" {
-		message = "HTTP error"
-	}
-	var pm transporthttp.PublicMessager
-	if errors.As(err, &pm) && pm.PublicMessage() != "" {
-		message = pm.PublicMessage()
-	} else if code < http.StatusInternalServerError && err != nil {
-		message = err.Error()
-	}
-
-	errorCode := defaultErrorCode(code)
-	var verr *endpoint.ValidationError
-	if errors.As(err, &verr) {
-		errorCode = "bad_request.validation"
-	}
-	var ec transporthttp.ErrorCoder
-	if errors.As(err, &ec) && ec.ErrorCode() != "" {
-		errorCode = ec.ErrorCode()
-	}
-
-	w.WriteHeader(code)
-	_ = json.NewEncoder(w).Encode(ErrorResponse{
-		Code:      errorCode,
-		Message:   message,
-		RequestID: endpoint.RequestIDFromContext(ctx),
-	})
 }
 
 // HTTPStatusForError returns the HTTP status the built-in error encoders use
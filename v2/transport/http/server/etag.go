@@ -0,0 +1,286 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+var (
+	// ErrPreconditionFailed is wrapped by the 412 error returned when
+	// If-Match does not match the current entity tag.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired is wrapped by the 428 error returned when
+	// ETagOptions.RequireIfMatch is set and a mutating request has no
+	// If-Match header.
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+// ETagOptions configures ServerETag.
+type ETagOptions struct {
+	// Weak marks generated entity tags as weak (W/"..."), for responses whose
+	// encoding may vary while the resource stays semantically the same.
+	Weak bool
+
+	// CurrentETag returns the current entity tag of the resource a mutating
+	// request (POST, PUT, PATCH, DELETE) targets. When set, If-Match is
+	// checked against it after decoding and before the endpoint runs. An
+	// empty tag means the resource does not exist. Without it, handlers
+	// check If-Match themselves with CheckIfMatch.
+	CurrentETag func(ctx context.Context, request any) (string, error)
+
+	// RequireIfMatch rejects mutating requests without If-Match with 428,
+	// so clients cannot skip the optimistic concurrency check.
+	RequireIfMatch bool
+}
+
+type etagConfig struct {
+	ETagOptions
+}
+
+type conditionalKey struct{}
+
+// conditional holds the request's precondition headers.
+type conditional struct {
+	method      string
+	ifMatch     string
+	ifNoneMatch string
+}
+
+// ServerETag adds entity tags to successful responses and evaluates
+// conditional requests.
+//
+// The tag comes from a transporthttp.ETagger response, which skips encoding
+// when the client's copy is current, or else from a hash of the encoded
+// body. GET and HEAD requests whose If-None-Match matches get 304 Not
+// Modified without a body. Mutating requests are checked against If-Match
+// as described on ETagOptions and fail with 412 Precondition Failed when the
+// resource has changed.
+//
+//	kit.HandleJSONTyped(svc, "GET /users/{id}", getUser, server.ServerETag(server.ETagOptions{}))
+func ServerETag(options ETagOptions) ServerOption {
+	return func(s *Server) { s.etag = &etagConfig{ETagOptions: options} }
+}
+
+// CheckIfMatch evaluates the request's If-Match header against the current
+// entity tag of the resource, for handlers of routes using ServerETag. It
+// returns nil when the header is absent or matches, and the 412 error
+// otherwise. An empty current tag means the resource does not exist.
+func CheckIfMatch(ctx context.Context, current string) error {
+	cond, _ := ctx.Value(conditionalKey{}).(conditional)
+	if cond.ifMatch == "" || ifMatch(cond.ifMatch, current) {
+		return nil
+	}
+	return preconditionFailed()
+}
+
+func preconditionFailed() error {
+	return &HTTPError{
+		Status:  http.StatusPreconditionFailed,
+		Code:    "precondition_failed",
+		Message: "resource has been modified",
+		Err:     ErrPreconditionFailed,
+	}
+}
+
+func (c *etagConfig) withConditional(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, conditionalKey{}, conditional{
+		method:      r.Method,
+		ifMatch:     r.Header.Get("If-Match"),
+		ifNoneMatch: r.Header.Get("If-None-Match"),
+	})
+}
+
+// checkPreconditions enforces If-Match on mutating requests before the
+// endpoint runs.
+func (c *etagConfig) checkPreconditions(ctx context.Context, request any) error {
+	cond, _ := ctx.Value(conditionalKey{}).(conditional)
+	switch cond.method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil
+	}
+	if cond.ifMatch == "" {
+		if c.RequireIfMatch {
+			return &HTTPError{
+				Status:  http.StatusPreconditionRequired,
+				Code:    "precondition_required",
+				Message: "If-Match header is required",
+				Err:     ErrPreconditionRequired,
+			}
+		}
+		return nil
+	}
+	if c.CurrentETag == nil {
+		return nil
+	}
+	current, err := c.CurrentETag(ctx, request)
+	if err != nil {
+		return err
+	}
+	if !ifMatch(cond.ifMatch, current) {
+		return preconditionFailed()
+	}
+	return nil
+}
+
+// encode writes response with its entity tag, or 304 when the client's
+// copy is current. The response is encoded into a buffer first so the tag
+// can be computed from the body and encode errors still reach the error
+// encoder.
+func (c *etagConfig) encode(ctx context.Context, w http.ResponseWriter, response any, enc EncodeResponseFunc) error {
+	cond, _ := ctx.Value(conditionalKey{}).(conditional)
+	cacheable := cond.method == http.MethodGet || cond.method == http.MethodHead
+	if tagger, ok := response.(transporthttp.ETagger); ok {
+		if tag := c.format(tagger.ETag()); tag != "" {
+			if cacheable && ifNoneMatch(cond.ifNoneMatch, tag) {
+				writeNotModified(w, w.Header(), tag)
+				return nil
+			}
+			w.Header().Set("ETag", tag)
+		}
+		return enc(ctx, w, response)
+	}
+
+	buf := &bufferedResponseWriter{header: http.Header{}, status: http.StatusOK}
+	if err := enc(ctx, buf, response); err != nil {
+		return err
+	}
+	tag := buf.header.Get("ETag")
+	if tag == "" && buf.status >= 200 && buf.status < 300 && buf.status != http.StatusNoContent {
+		sum := sha256.Sum256(buf.body.Bytes())
+		tag = c.format(base64.RawURLEncoding.EncodeToString(sum[:18]))
+		buf.header.Set("ETag", tag)
+	}
+	if tag != "" && cacheable && buf.status == http.StatusOK && ifNoneMatch(cond.ifNoneMatch, tag) {
+		writeNotModified(w, buf.header, tag)
+		return nil
+	}
+	for key, values := range buf.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(buf.status)
+	_, err := w.Write(buf.body.Bytes())
+	return err
+}
+
+// format quotes a bare tag and applies Weak; full entity-tags are kept.
+func (c *etagConfig) format(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || strings.HasPrefix(tag, `"`) || strings.HasPrefix(tag, `W/"`) {
+		return tag
+	}
+	tag = `"` + strings.ReplaceAll(tag, `"`, "") + `"`
+	if c.Weak {
+		tag = "W/" + tag
+	}
+	return tag
+}
+
+// writeNotModified sends 304 with the headers a 200 would carry except
+// those describing the body.
+func writeNotModified(w http.ResponseWriter, header http.Header, tag string) {
+	dst := w.Header()
+	for key, values := range header {
+		switch key {
+		case "Content-Type", "Content-Length", "Content-Encoding":
+			continue
+		}
+		dst[key] = values
+	}
+	dst.Del("Content-Type")
+	dst.Del("Content-Length")
+	dst.Set("ETag", tag)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// ifNoneMatch reports whether header lists tag under weak comparison.
+func ifNoneMatch(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	opaque := strings.TrimPrefix(tag, "W/")
+	for _, candidate := range splitETags(header) {
+		if strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch reports whether header matches current under strong comparison.
+func ifMatch(header, current string) bool {
+	if current == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strings.HasPrefix(current, "W/") {
+		return false
+	}
+	for _, candidate := range splitETags(header) {
+		if candidate == current {
+			return true
+		}
+	}
+	return false
+}
+
+// splitETags splits a comma-separated entity-tag list; commas may appear
+// inside quoted tags.
+func splitETags(header string) []string {
+	var tags []string
+	for header = strings.TrimSpace(header); header != ""; header = strings.TrimLeft(header, ", \t") {
+		weak := strings.HasPrefix(header, "W/")
+		rest := strings.TrimPrefix(header, "W/")
+		if !strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(header, ',')
+			if end < 0 {
+				end = len(header)
+			}
+			header = header[end:]
+			continue
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			break
+		}
+		tag := rest[:end+2]
+		if weak {
+			tag = "W/" + tag
+		}
+		tags = append(tags, tag)
+		header = rest[end+2:]
+	}
+	return tags
+}
+
+type bufferedResponseWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header { return w.header }
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(p)
+}
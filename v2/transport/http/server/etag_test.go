@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+type document struct {
+	ID      string `json:"id"`
+	Body    string `json:"body"`
+	Version int    `json:"version"`
+}
+
+type versionedDocument struct {
+	document
+}
+
+func (d versionedDocument) ETag() string { return "v" + strconv.Itoa(d.Version) }
+
+func conditionalRequest(method, header, value, body string) *http.Request {
+	req := httptest.NewRequest(method, "/docs/1", strings.NewReader(body))
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	return req
+}
+
+func TestServerETag_HashedTagAndNotModified(t *testing.T) {
+	h := server.NewTypedJSONServer(func(context.Context, struct{}) (document, error) {
+		return document{ID: "1", Body: "hello", Version: 1}, nil
+	}, server.ServerETag(server.ETagOptions{}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, conditionalRequest(http.MethodGet, "", "", "{}"))
+	tag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(tag, `"`) || !strings.Contains(rec.Body.String(), "hello") {
+		t.Fatalf("status = %d, ETag = %q, body = %q", rec.Code, tag, rec.Body)
+	}
+
+	for _, header := range []string{tag, `"other", ` + tag, "W/" + tag, "*"} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, conditionalRequest(http.MethodGet, "If-None-Match", header, "{}"))
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != tag {
+			t.Fatalf("If-None-Match %s: status = %d, body = %q", header, rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Content-Type") != "" {
+			t.Fatalf("304 carries Content-Type %q", rec.Header().Get("Content-Type"))
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, conditionalRequest(http.MethodGet, "If-None-Match", `"stale"`, "{}"))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != tag {
+		t.Fatalf("stale status = %d", rec.Code)
+	}
+}
+
+func TestServerETag_ETaggerSkipsEncoding(t *testing.T) {
+	h := server.NewTypedJSONServer(func(context.Context, struct{}) (versionedDocument, error) {
+		return versionedDocument{document{ID: "1", Version: 3}}, nil
+	}, server.ServerETag(server.ETagOptions{Weak: true}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, conditionalRequest(http.MethodGet, "If-None-Match", `W/"v3"`, "{}"))
+	if rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `W/"v3"` {
+		t.Fatalf("status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestServerETag_IfMatchOptimisticConcurrency(t *testing.T) {
+	current := versionedDocument{document{ID: "1", Body: "old", Version: 1}}
+	var updates int
+	h := server.NewTypedJSONServer(func(_ context.Context, req document) (versionedDocument, error) {
+		updates++
+		current = versionedDocument{document{ID: "1", Body: req.Body, Version: current.Version + 1}}
+		return current, nil
+	}, server.ServerETag(server.ETagOptions{
+		CurrentETag: func(context.Context, any) (string, error) {
+			return `"` + current.ETag() + `"`, nil
+		},
+		RequireIfMatch: true,
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, conditionalRequest(http.MethodPut, "", "", `{"body":"new"}`))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("missing If-Match status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, conditionalRequest(http.MethodPut, "If-Match", `"v1"`, `{"body":"new"}`))
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"v2"` {
+		t.Fatalf("matching If-Match status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	for _, stale := range []string{`"v1"`, `W/"v2"`} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, conditionalRequest(http.MethodPut, "If-Match", stale, `{"body":"lost update"}`))
+		if rec.Code != http.StatusPreconditionFailed || !strings.Contains(rec.Body.String(), "precondition_failed") {
+			t.Fatalf("If-Match %s: status = %d, body = %q", stale, rec.Code, rec.Body)
+		}
+	}
+	if updates != 1 || current.Body != "new" {
+		t.Fatalf("updates = %d, body = %q", updates, current.Body)
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	h := server.NewTypedJSONServer(func(ctx context.Context, _ document) (document, error) {
+		if err := server.CheckIfMatch(ctx, `"v5"`); err != nil {
+			return document{}, err
+		}
+		return document{ID: "1"}, nil
+	}, server.ServerETag(server.ETagOptions{}))
+
+	for header, want := range map[string]int{`"v5"`: http.StatusOK, `"v4", "v5"`: http.StatusOK, "*": http.StatusOK, `"v4"`: http.StatusPreconditionFailed} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, conditionalRequest(http.MethodPatch, "If-Match", header, "{}"))
+		if rec.Code != want {
+			t.Errorf("If-Match %s: status = %d, want %d", header, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+// DefaultFieldMaskParam is the query parameter ServerFieldMask reads.
+const DefaultFieldMaskParam = "fields"
+
+// FieldMaskOptions configures ServerFieldMask.
+type FieldMaskOptions struct {
+	// Param is the query parameter holding the comma-separated paths. The
+	// default is DefaultFieldMaskParam.
+	Param string
+
+	// Response is the response type paths are checked against before the
+	// endpoint runs. Without it they are checked against the type of each
+	// response after the endpoint returns.
+	Response reflect.Type
+
+	// FromRequest returns a mask carried by the decoded request, such as a
+	// protobuf-style read_mask field. A non-empty result takes precedence
+	// over the query parameter.
+	FromRequest func(request any) transporthttp.FieldMask
+}
+
+type fieldMaskConfig struct {
+	FieldMaskOptions
+}
+
+// ServerFieldMask lets clients request a sparse response: only the JSON
+// fields named by ?fields=name,address.city are encoded. Nested paths use
+// dots and select through arrays. Malformed or unknown paths fail with a
+// *transporthttp.QueryError, which encodes as 400. Requests without a mask
+// get the whole response.
+//
+// The response is pruned when it is marshaled, so status codes, headers and
+// response envelopes built with WrapJSONResponse keep working, and ServerETag
+// tags the pruned body.
+//
+//	kit.HandleJSONTyped(svc, "GET /users/{id}", getUser, server.ServerFieldMask(server.FieldMaskOptions{
+//	    Response: reflect.TypeOf(User{}),
+//	}))
+func ServerFieldMask(options FieldMaskOptions) ServerOption {
+	if options.Param == "" {
+		options.Param = DefaultFieldMaskParam
+	}
+	return func(s *Server) { s.fieldMask = &fieldMaskConfig{FieldMaskOptions: options} }
+}
+
+// FieldMaskParam returns the query parameter of a server configured with
+// ServerFieldMask, or "" without one. Route documentation uses it to list
+// the parameter.
+func (s Server) FieldMaskParam() string {
+	if s.fieldMask == nil {
+		return ""
+	}
+	return s.fieldMask.Param
+}
+
+// parse reads the request's mask and checks it against Response when set.
+func (c *fieldMaskConfig) parse(r *http.Request, request any) (transporthttp.FieldMask, error) {
+	var mask transporthttp.FieldMask
+	if c.FromRequest != nil {
+		mask = c.FromRequest(request)
+	}
+	if mask.Empty() {
+		var err error
+		if mask, err = transporthttp.ParseFieldMask(r.URL.Query().Get(c.Param)); err != nil {
+			return mask, &transporthttp.QueryError{Field: c.Param, Err: err}
+		}
+	}
+	if !mask.Empty() && c.Response != nil {
+		if err := mask.Validate(c.Response); err != nil {
+			return mask, &transporthttp.QueryError{Field: c.Param, Err: err}
+		}
+	}
+	return mask, nil
+}
+
+// apply wraps response so it marshals pruned to mask.
+func (c *fieldMaskConfig) apply(mask transporthttp.FieldMask, response any) (any, error) {
+	if mask.Empty() || response == nil {
+		return response, nil
+	}
+	if c.Response == nil {
+		if err := mask.Validate(reflect.TypeOf(response)); err != nil {
+			return nil, &transporthttp.QueryError{Field: c.Param, Err: err}
+		}
+	}
+	return maskedResponse{response: response, mask: mask}, nil
+}
+
+// maskedResponse marshals its response pruned to mask and forwards the
+// status and headers of the original value.
+type maskedResponse struct {
+	response any
+	mask     transporthttp.FieldMask
+}
+
+func (m maskedResponse) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(m.response)
+	if err != nil {
+		return nil, err
+	}
+	return m.mask.Prune(data)
+}
+
+func (m maskedResponse) StatusCode() int {
+	if sc, ok := m.response.(transporthttp.StatusCoder); ok {
+		return sc.StatusCode()
+	}
+	return http.StatusOK
+}
+
+func (m maskedResponse) Headers() http.Header {
+	if headerer, ok := m.response.(transporthttp.Headerer); ok {
+		return headerer.Headers()
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+type maskAddress struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+type maskUser struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Email   string      `json:"email"`
+	Address maskAddress `json:"address"`
+	Tags    []string    `json:"tags"`
+}
+
+func (maskUser) Headers() http.Header { return http.Header{"X-Resource": {"user"}} }
+
+func serveMasked(t *testing.T, target string, options server.FieldMaskOptions, extra ...server.ServerOption) *httptest.ResponseRecorder {
+	t.Helper()
+	h := server.NewTypedJSONServer(func(context.Context, struct{}) (maskUser, error) {
+		return maskUser{ID: "1", Name: "Ada", Email: "ada@example.com", Address: maskAddress{City: "London", Country: "UK"}, Tags: []string{"x"}}, nil
+	}, append([]server.ServerOption{server.ServerFieldMask(options)}, extra...)...)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, strings.NewReader("{}")))
+	return rec
+}
+
+func TestServerFieldMask_PrunesNestedPaths(t *testing.T) {
+	rec := serveMasked(t, "/users/1?fields=name,address.city", server.FieldMaskOptions{Response: reflect.TypeOf(maskUser{})})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"name":"Ada","address":{"city":"London"}}` {
+		t.Errorf("body = %s", got)
+	}
+	if rec.Header().Get("X-Resource") != "user" {
+		t.Errorf("headers of the original response were dropped: %v", rec.Header())
+	}
+
+	rec = serveMasked(t, "/users/1", server.FieldMaskOptions{})
+	if !strings.Contains(rec.Body.String(), `"email"`) {
+		t.Errorf("unmasked body = %s", rec.Body)
+	}
+}
+
+func TestServerFieldMask_RejectsUnknownFields(t *testing.T) {
+	for _, options := range []server.FieldMaskOptions{{Response: reflect.TypeOf(maskUser{})}, {}} {
+		for _, query := range []string{"fields=password", "fields=address.zip", "fields=name.first", "fields=name,,id"} {
+			rec := serveMasked(t, "/users/1?"+query, options)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "bad_request.invalid_query") {
+				t.Errorf("%s (static type %v): status = %d, body = %s", query, options.Response != nil, rec.Code, rec.Body)
+			}
+		}
+	}
+}
+
+func TestServerFieldMask_FromRequestAndCustomParam(t *testing.T) {
+	rec := serveMasked(t, "/users/1?only=id", server.FieldMaskOptions{Param: "only"})
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"id":"1"}` {
+		t.Errorf("custom param body = %s", got)
+	}
+	rec = serveMasked(t, "/users/1?fields=name", server.FieldMaskOptions{
+		FromRequest: func(any) transporthttp.FieldMask { return transporthttp.FieldMask{Paths: []string{"email"}} },
+	})
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"email":"ada@example.com"}` {
+		t.Errorf("request mask body = %s", got)
+	}
+}
+
+func TestServerFieldMask_WorksWithEnvelopeAndETag(t *testing.T) {
+	wrap := server.ServerResponseEncoder(server.WrapJSONResponse(func(response any) any {
+		return map[string]any{"data": response}
+	}))
+	full := serveMasked(t, "/users/1", server.FieldMaskOptions{}, wrap, server.ServerETag(server.ETagOptions{}))
+	masked := serveMasked(t, "/users/1?fields=id", server.FieldMaskOptions{}, wrap, server.ServerETag(server.ETagOptions{}))
+	if got := strings.TrimSpace(masked.Body.String()); got != `{"data":{"id":"1"}}` {
+		t.Errorf("body = %s", got)
+	}
+	if tag := masked.Header().Get("ETag"); tag == "" || tag == full.Header().Get("ETag") {
+		t.Errorf("masked ETag %q should differ from full ETag %q", tag, full.Header().Get("ETag"))
+	}
+}
+
+func TestFieldMask_QueryErrorField(t *testing.T) {
+	h := server.NewTypedJSONServer(func(context.Context, struct{}) (maskUser, error) {
+		return maskUser{}, nil
+	}, server.ServerFieldMask(server.FieldMaskOptions{}), server.ServerErrorEncoder(func(_ context.Context, err error, w http.ResponseWriter) {
+		var queryErr *transporthttp.QueryError
+		if !errors.As(err, &queryErr) || queryErr.Field != "fields" {
+			t.Errorf("error = %v, want QueryError for fields", err)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1?fields=nope", strings.NewReader("{}")))
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
 )
 
 // NewJSONServer creates an HTTP server that automatically handles JSON
@@ -115,22 +116,31 @@ func NewTypedJSONServerWithMiddleware[Req, Resp any](
 }
 
 // DecodeJSONRequest returns a DecodeRequestFunc that strictly decodes the HTTP
-// request body as JSON into a value of type T.
+// request body as JSON into a value of type T. Like
+// DecodeJSONRequestWithOptions, it binds tagged fields with BindJSONRequest.
 func DecodeJSONRequest[T any]() DecodeRequestFunc {
-	return func(_ context.Context, r *http.Request) (any, error) {
-		var v T
-		if err := DecodeJSONBody(r, &v, StrictJSONDecodeOptions(DefaultMaxJSONBodyBytes)); err != nil {
-			return nil, JSONDecodeError{Err: err}
-		}
-		return v, nil
-	}
+	return DecodeJSONRequestWithOptions[T](StrictJSONDecodeOptions(DefaultMaxJSONBodyBytes))
 }
 
 // DecodeJSONRequestWithOptions returns a DecodeRequestFunc that decodes the
-// HTTP request body as JSON into T using the supplied options.
+// HTTP request body as JSON into T using the supplied options. When T has
+// path, query, header or body tags (see transporthttp.Bind), the request is
+// bound with BindJSONRequest instead, so NewTypedJSONServer and the other JSON
+// constructors accept such types unchanged. It panics when the tags of T are
+// invalid, so the mistake surfaces when the route is registered.
 func DecodeJSONRequestWithOptions[T any](options JSONDecodeOptions) DecodeRequestFunc {
+	bind, err := transporthttp.Bindable[T]()
+	if err != nil {
+		panic(fmt.Sprintf("server: %v", err))
+	}
 	return func(_ context.Context, r *http.Request) (any, error) {
 		var v T
+		if bind {
+			if err := BindJSONRequest(r, &v, options); err != nil {
+				return nil, err
+			}
+			return v, nil
+		}
 		if err := DecodeJSONBody(r, &v, options); err != nil {
 			return nil, JSONDecodeError{Err: err}
 		}
@@ -138,6 +148,30 @@ func DecodeJSONRequestWithOptions[T any](options JSONDecodeOptions) DecodeReques
 	}
 }
 
+// BindJSONRequest fills a struct pointer from the path, query string, headers
+// and JSON body of r, following the struct tags described at
+// transporthttp.Bind. Body failures are JSONDecodeError values and parameter
+// failures are *transporthttp.QueryError values; both answer 400. A request
+// without a body leaves the body fields unchanged.
+//
+// Example:
+//
+//	type UpdateItemRequest struct {
+//	    ID     string `path:"id" json:"-"`
+//	    Tenant string `header:"X-Tenant" json:"-"`
+//	    Name   string `json:"name"`
+//	}
+//
+//	mux.Handle("PUT /items/{id}", server.NewTypedJSONServer(updateItem))
+func BindJSONRequest(r *http.Request, target any, options JSONDecodeOptions) error {
+	return transporthttp.Bind(r, target, func(r *http.Request, body any) error {
+		if err := DecodeJSONBody(r, body, options); err != nil {
+			return JSONDecodeError{Err: err}
+		}
+		return nil
+	})
+}
+
 // JSONDecodeOptions controls optional safety checks for JSON request bodies.
 // A zero value disables the optional checks.
 type JSONDecodeOptions struct {
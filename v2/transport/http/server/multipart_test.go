@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dreamsxin/go-kit/v2/transport/http/server"
 )
@@ -170,3 +171,39 @@ func TestWriteAttachment_UnknownExtensionIsOctetStream(t *testing.T) {
 		t.Errorf("Content-Type: got %q, want application/octet-stream", got)
 	}
 }
+
+func TestServeAttachment_Range(t *testing.T) {
+	modtime := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	serve := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/download", nil)
+		r.Header = header
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", `"v1"`)
+		server.ServeAttachment(rec, r, "report.txt", modtime, strings.NewReader("0123456789"))
+		return rec
+	}
+
+	rec := serve(http.Header{"Range": {"bytes=2-5"}})
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Fatalf("range: got %d %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 2-5/10" {
+		t.Errorf("Content-Range: got %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, "report.txt") {
+		t.Errorf("Content-Disposition: got %q", got)
+	}
+
+	rec = serve(http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"v1"`}})
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("matching If-Range: got %d", rec.Code)
+	}
+	rec = serve(http.Header{"Range": {"bytes=2-5"}, "If-Range": {`"v0"`}})
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" {
+		t.Fatalf("stale If-Range: got %d %q", rec.Code, rec.Body)
+	}
+	rec = serve(http.Header{"Range": {"bytes=20-"}})
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: got %d", rec.Code)
+	}
+}
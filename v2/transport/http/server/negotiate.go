@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+)
+
+var (
+	// ErrUnsupportedMediaType is wrapped by the 415 error returned when no
+	// registered codec reads the request Content-Type.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrNotAcceptable is wrapped by the 406 error returned when the request
+	// Accept header excludes every registered codec.
+	ErrNotAcceptable = errors.New("not acceptable")
+)
+
+// CodecDecodeError marks request bodies a codec failed to decode as client
+// errors while preserving the underlying error for errors.Is/errors.As.
+type CodecDecodeError struct {
+	ContentType string
+	Err         error
+}
+
+func (e CodecDecodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("invalid %s request body", e.ContentType)
+	}
+	return e.Err.Error()
+}
+
+func (e CodecDecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e CodecDecodeError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e CodecDecodeError) ErrorCode() string {
+	return "bad_request.invalid_body"
+}
+
+type negotiationKey struct{}
+
+// negotiation is the response codec picked from Accept, or the 406 error
+// when nothing fits.
+type negotiation struct {
+	codec codec.Codec
+	err   error
+}
+
+// NegotiateCodecs returns a RequestFunc that picks the response codec from
+// the Accept header and stores it in the context for EncodeCodecResponse and
+// CodecErrorEncoder. NewCodecEndpoint installs it; custom servers add it with
+// ServerBefore. A nil registry means codec.DefaultRegistry.
+func NegotiateCodecs(registry *codec.Registry) RequestFunc {
+	registry = registryOrDefault(registry)
+	return func(ctx context.Context, r *http.Request) context.Context {
+		c, ok := registry.Negotiate(r.Header.Get("Accept"))
+		if !ok {
+			return context.WithValue(ctx, negotiationKey{}, negotiation{err: &HTTPError{
+				Status:  http.StatusNotAcceptable,
+				Code:    "not_acceptable",
+				Message: fmt.Sprintf("no acceptable response format; available: %s", registry.ContentTypes()),
+				Err:     ErrNotAcceptable,
+			}})
+		}
+		return context.WithValue(ctx, negotiationKey{}, negotiation{codec: c})
+	}
+}
+
+// NegotiatedCodec returns the response codec NegotiateCodecs picked for the
+// request. It reports false when negotiation did not run or failed.
+func NegotiatedCodec(ctx context.Context) (codec.Codec, bool) {
+	n, ok := ctx.Value(negotiationKey{}).(negotiation)
+	return n.codec, ok && n.codec != nil
+}
+
+// DecodeCodecRequest returns a DecodeRequestFunc that decodes the body into
+// Req with the codec registered for its Content-Type. A body without a
+// Content-Type uses the registry default, and an empty body yields the zero
+// Req, so bodiless GET routes work unchanged. An unknown Content-Type fails
+// with 415 and lists the supported types in an Accept header; an Accept
+// header that excludes every codec fails with 406 before the endpoint runs.
+// Bodies beyond maxBodyBytes fail with 413; a value <= 0 means unlimited.
+func DecodeCodecRequest[Req any](registry *codec.Registry, maxBodyBytes int64) DecodeRequestFunc {
+	registry = registryOrDefault(registry)
+	return func(ctx context.Context, r *http.Request) (any, error) {
+		if n, ok := ctx.Value(negotiationKey{}).(negotiation); ok && n.err != nil {
+			return nil, n.err
+		}
+		var req Req
+		reader := io.Reader(r.Body)
+		if maxBodyBytes > 0 {
+			reader = &limitedBodyReader{reader: r.Body, remaining: maxBodyBytes}
+		}
+		body, err := io.ReadAll(reader)
+		if errors.Is(err, ErrJSONBodyTooLarge) {
+			return nil, WrapHTTPError(http.StatusRequestEntityTooLarge, "", "request body too large", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(body) == 0 {
+			return req, nil
+		}
+
+		c := registry.Default()
+		if contentType := r.Header.Get("Content-Type"); contentType != "" {
+			var ok bool
+			if c, ok = registry.Lookup(contentType); !ok {
+				return nil, &HTTPError{
+					Status:  http.StatusUnsupportedMediaType,
+					Code:    "unsupported_media_type",
+					Message: fmt.Sprintf("unsupported content type %q", contentType),
+					Err:     ErrUnsupportedMediaType,
+					Header:  http.Header{"Accept": {registry.ContentTypes()}},
+				}
+			}
+		}
+		if err := c.Unmarshal(body, &req); err != nil {
+			return nil, CodecDecodeError{ContentType: c.ContentType(), Err: err}
+		}
+		return req, nil
+	}
+}
+
+// EncodeCodecResponse returns an EncodeResponseFunc that writes the response
+// with the codec NegotiateCodecs picked, falling back to the registry
+// default. Headerer and StatusCoder are honored as by EncodeJSONResponse, and
+// Vary: Accept is added for caches.
+func EncodeCodecResponse(registry *codec.Registry) EncodeResponseFunc {
+	registry = registryOrDefault(registry)
+	return func(ctx context.Context, w http.ResponseWriter, response any) error {
+		c := responseCodec(ctx, registry)
+		data, err := c.Marshal(response)
+		if err != nil {
+			return err
+		}
+		w.Header().Add("Vary", "Accept")
+		return writeRawResponse(w, c.ContentType(), data, response)
+	}
+}
+
+// CodecErrorEncoder returns an ErrorEncoder that writes ErrorResponse in the
+// negotiated format, so clients get errors in the format they asked for. The
+// status follows HTTPStatusForError and the body matches JSONErrorEncoder.
+// When negotiation failed the registry default is used, and when the codec
+// cannot encode ErrorResponse (protobuf, for example) the message is written
+// as plain text.
+func CodecErrorEncoder(registry *codec.Registry) ErrorEncoder {
+	registry = registryOrDefault(registry)
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		c := responseCodec(ctx, registry)
+		status := httpStatus(err)
+		body := newErrorResponse(ctx, err, status)
+		contentType := c.ContentType()
+		data, marshalErr := c.Marshal(body)
+		if marshalErr != nil {
+			contentType, data = "text/plain; charset=utf-8", []byte(body.Message)
+		}
+		addErrorHeaders(w, err)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	}
+}
+
+// NewCodecEndpoint creates an HTTP server for an existing endpoint that
+// serves every format in registry: requests are decoded by Content-Type,
+// responses and errors encoded by Accept. A nil registry means
+// codec.DefaultRegistry. Request bodies are capped at DefaultRawBodyBytes.
+//
+//	registry, _ := codec.DefaultRegistry().With(codec.Protobuf(marshal, unmarshal))
+//	handler := server.NewCodecEndpoint[CreateReq](ep, registry)
+func NewCodecEndpoint[Req any](e endpoint.Endpoint, registry *codec.Registry, options ...ServerOption) *Server {
+	registry = registryOrDefault(registry)
+	opts := append([]ServerOption{
+		ServerBefore(NegotiateCodecs(registry)),
+		ServerErrorEncoder(CodecErrorEncoder(registry)),
+	}, options...)
+	return NewServer(e, DecodeCodecRequest[Req](registry, DefaultRawBodyBytes), EncodeCodecResponse(registry), opts...)
+}
+
+// NewTypedCodecServer is NewCodecEndpoint for a typed handler function.
+func NewTypedCodecServer[Req, Resp any](
+	handler func(ctx context.Context, req Req) (Resp, error),
+	registry *codec.Registry,
+	options ...ServerOption,
+) *Server {
+	return NewCodecEndpoint[Req](endpoint.TypedEndpoint[Req, Resp](handler).Wrap(), registry, options...)
+}
+
+func responseCodec(ctx context.Context, registry *codec.Registry) codec.Codec {
+	if c, ok := NegotiatedCodec(ctx); ok {
+		return c
+	}
+	return registry.Default()
+}
+
+func registryOrDefault(registry *codec.Registry) *codec.Registry {
+	if registry == nil {
+		return codec.DefaultRegistry()
+	}
+	return registry
+}
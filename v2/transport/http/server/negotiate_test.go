@@ -0,0 +1,181 @@
+package server_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+type greetReq struct {
+	Name string `json:"name" xml:"name"`
+}
+
+type greetResp struct {
+	XMLName  xml.Name `json:"-" xml:"greeting"`
+	Greeting string   `json:"greeting" xml:"text"`
+}
+
+func newGreetServer() *server.Server {
+	return server.NewTypedCodecServer(func(_ context.Context, req greetReq) (greetResp, error) {
+		if req.Name == "" {
+			return greetResp{}, apperror.New(apperror.KindInvalidArgument, "missing_name", "name is required")
+		}
+		return greetResp{Greeting: "hello " + req.Name}, nil
+	}, nil)
+}
+
+func serveCodec(t *testing.T, h http.Handler, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/greet", strings.NewReader(string(body)))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCodecServer_DecodesContentTypeAndEncodesAccept(t *testing.T) {
+	h := newGreetServer()
+	for _, requestCodec := range []codec.Codec{codec.JSON, codec.XML, codec.MessagePack, codec.CBOR} {
+		for _, responseCodec := range []codec.Codec{codec.JSON, codec.XML, codec.MessagePack, codec.CBOR} {
+			body, err := requestCodec.Marshal(greetReq{Name: "ada"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := serveCodec(t, h, requestCodec.ContentType(), responseCodec.ContentType(), body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s -> %s: status = %d, body %q", requestCodec.ContentType(), responseCodec.ContentType(), rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != responseCodec.ContentType() {
+				t.Fatalf("Content-Type = %q, want %q", got, responseCodec.ContentType())
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Fatalf("Vary = %q", rec.Header().Get("Vary"))
+			}
+			var resp greetResp
+			if err := responseCodec.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Greeting != "hello ada" {
+				t.Fatalf("%s response = %+v, %v", responseCodec.ContentType(), resp, err)
+			}
+		}
+	}
+}
+
+func TestCodecServer_DefaultsWithoutHeaders(t *testing.T) {
+	rec := serveCodec(t, newGreetServer(), "", "", []byte(`{"name":"ada"}`))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != codec.JSONContentType {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/greet", nil)
+	rec = httptest.NewRecorder()
+	newGreetServer().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty body should reach the endpoint with the zero request, got %d", rec.Code)
+	}
+}
+
+func TestCodecServer_UnsupportedMediaType(t *testing.T) {
+	var reached bool
+	h := server.NewTypedCodecServer(func(context.Context, greetReq) (greetResp, error) {
+		reached = true
+		return greetResp{}, nil
+	}, nil)
+	rec := serveCodec(t, h, "text/csv", codec.XMLContentType, []byte("name\nada"))
+	if rec.Code != http.StatusUnsupportedMediaType || reached {
+		t.Fatalf("status = %d, reached = %v", rec.Code, reached)
+	}
+	if got := rec.Header().Get("Accept"); got != codec.DefaultRegistry().ContentTypes() {
+		t.Fatalf("Accept = %q", got)
+	}
+	var body server.ErrorResponse
+	if err := codec.XML.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "unsupported_media_type" {
+		t.Fatalf("error should follow Accept: %+v, %v (%q)", body, err, rec.Body)
+	}
+}
+
+func TestCodecServer_NotAcceptable(t *testing.T) {
+	var reached bool
+	h := server.NewTypedCodecServer(func(context.Context, greetReq) (greetResp, error) {
+		reached = true
+		return greetResp{}, nil
+	}, nil)
+	rec := serveCodec(t, h, codec.JSONContentType, "text/html", []byte(`{"name":"ada"}`))
+	if rec.Code != http.StatusNotAcceptable || reached {
+		t.Fatalf("status = %d, reached = %v", rec.Code, reached)
+	}
+	if rec.Header().Get("Content-Type") != codec.JSONContentType {
+		t.Fatalf("406 body should use the default codec, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestCodecServer_ErrorsFollowNegotiatedFormat(t *testing.T) {
+	h := newGreetServer()
+	body, _ := codec.CBOR.Marshal(greetReq{})
+	rec := serveCodec(t, h, codec.CBORContentType, codec.MessagePackContentType, body)
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != codec.MessagePackContentType {
+		t.Fatalf("status = %d, Content-Type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var resp server.ErrorResponse
+	if err := codec.MessagePack.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != "missing_name" {
+		t.Fatalf("error body = %+v, %v", resp, err)
+	}
+
+	rec = serveCodec(t, h, codec.MessagePackContentType, "", []byte{0xc1})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body status = %d", rec.Code)
+	}
+	if err := codec.JSON.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Code != "bad_request.invalid_body" {
+		t.Fatalf("decode error body = %+v, %v", resp, err)
+	}
+}
+
+func TestCodecServer_CustomCodecAndTextFallback(t *testing.T) {
+	errNotMessage := errors.New("not a message")
+	text := codec.Protobuf(
+		func(v any) ([]byte, error) {
+			if r, ok := v.(greetResp); ok {
+				return []byte(r.Greeting), nil
+			}
+			return nil, errNotMessage
+		},
+		func(data []byte, v any) error {
+			r, ok := v.(*greetReq)
+			if !ok {
+				return errNotMessage
+			}
+			r.Name = string(data)
+			return nil
+		},
+	)
+	registry, err := codec.DefaultRegistry().With(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.NewTypedCodecServer(func(_ context.Context, req greetReq) (greetResp, error) {
+		if req.Name == "fail" {
+			return greetResp{}, server.NewHTTPError(http.StatusConflict, "conflict", "already greeted")
+		}
+		return greetResp{Greeting: "hi " + req.Name}, nil
+	}, registry)
+
+	rec := serveCodec(t, h, "application/protobuf", codec.ProtobufContentType, []byte("bob"))
+	if rec.Code != http.StatusOK || rec.Body.String() != "hi bob" {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body)
+	}
+	rec = serveCodec(t, h, codec.ProtobufContentType, codec.ProtobufContentType, []byte("fail"))
+	if rec.Code != http.StatusConflict || rec.Body.String() != "already greeted" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("status = %d, Content-Type = %q, body = %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+)
+
+// ProblemContentType is the RFC 9457 media type written by
+// ProblemErrorEncoder.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is the RFC 9457 body emitted by ProblemErrorEncoder. Code,
+// RequestID and Errors are extension members carrying the same information
+// as ErrorResponse.
+type ProblemDetails struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      string              `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []ProblemFieldError `json:"errors,omitempty"`
+}
+
+// ProblemFieldError is one field violation from an endpoint.ValidationError.
+type ProblemFieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ProblemErrorEncoder is an ErrorEncoder that writes RFC 9457 problem
+// details as application/problem+json. Status and headers follow the same
+// rules as JSONErrorEncoder; type is "about:blank", title the status text,
+// and detail the public message, omitted for opaque 5xx errors. The error
+// code, request ID, and ValidationError field violations are added as the
+// code, request_id, and errors extension members. instance is the request
+// path when transporthttp.PopulateRequestContext runs as a ServerBefore hook.
+//
+//	server.NewJSONServer[Req](handler,
+//	    server.ServerBefore(transporthttp.PopulateRequestContext),
+//	    server.ServerErrorEncoder(server.ProblemErrorEncoder),
+//	)
+var ProblemErrorEncoder ErrorEncoder = func(ctx context.Context, err error, w http.ResponseWriter) {
+	encodeProblem(ctx, err, w, "")
+}
+
+// ProblemErrorEncoderWithTypeBase returns a ProblemErrorEncoder whose type
+// member is typeBase followed by the error code, for example
+// "https://errors.example.com/" + "user_not_found", so each code can link to
+// its documentation. An empty typeBase keeps "about:blank".
+func ProblemErrorEncoderWithTypeBase(typeBase string) ErrorEncoder {
+	return func(ctx context.Context, err error, w http.ResponseWriter) {
+		encodeProblem(ctx, err, w, typeBase)
+	}
+}
+
+// NewProblemDetails builds the body ProblemErrorEncoder writes for err, for
+// custom encoders that need to adjust members before writing.
+func NewProblemDetails(ctx context.Context, err error) ProblemDetails {
+	return newProblemDetails(ctx, err, httpStatus(err), "")
+}
+
+func encodeProblem(ctx context.Context, err error, w http.ResponseWriter, typeBase string) {
+	status := httpStatus(err)
+	problem := newProblemDetails(ctx, err, status, typeBase)
+	addErrorHeaders(w, err)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+func newProblemDetails(ctx context.Context, err error, status int, typeBase string) ProblemDetails {
+	body := newErrorResponse(ctx, err, status)
+	problem := ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Code:      body.Code,
+		RequestID: body.RequestID,
+	}
+	if problem.Title == "" {
+		problem.Title = "HTTP error"
+	}
+	if typeBase != "" {
+		problem.Type = typeBase + body.Code
+	}
+	if body.Message != problem.Title {
+		problem.Detail = body.Message
+	}
+	if path, ok := ctx.Value(transporthttp.ContextKeyRequestPath).(string); ok {
+		problem.Instance = path
+	}
+	var verr *endpoint.ValidationError
+	if errors.As(err, &verr) {
+		for _, field := range verr.Fields {
+			problem.Errors = append(problem.Errors, ProblemFieldError{Field: field.Field, Reason: field.Reason})
+		}
+	}
+	return problem
+}
@@ -0,0 +1,98 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); got != server.ProblemContentType {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestProblemErrorEncoder_ApplicationError(t *testing.T) {
+	h := server.NewJSONServer[struct{}](func(context.Context, struct{}) (any, error) {
+		return nil, apperror.New(apperror.KindNotFound, "user_not_found", "user 42 does not exist")
+	},
+		server.ServerBefore(transporthttp.PopulateRequestContext),
+		server.ServerErrorEncoder(server.ProblemErrorEncoder),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/users/42", strings.NewReader("{}"))
+	ctx := endpoint.WithRequestID(req.Context(), "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req.WithContext(ctx))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := decodeProblem(t, rec)
+	want := map[string]any{
+		"type":       "about:blank",
+		"title":      "Not Found",
+		"status":     float64(404),
+		"detail":     "user 42 does not exist",
+		"instance":   "/users/42",
+		"code":       "user_not_found",
+		"request_id": "req-1",
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %v, want %v", key, body[key], value)
+		}
+	}
+}
+
+func TestProblemErrorEncoder_ValidationErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := endpoint.NewValidationError("name", "is required").Add("age", "must be positive")
+	server.ProblemErrorEncoderWithTypeBase("https://errors.example.com/")(context.Background(), err, rec)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var problem server.ProblemDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if problem.Type != "https://errors.example.com/bad_request.validation" || problem.Code != "bad_request.validation" {
+		t.Fatalf("type = %q, code = %q", problem.Type, problem.Code)
+	}
+	if len(problem.Errors) != 2 || problem.Errors[1] != (server.ProblemFieldError{Field: "age", Reason: "must be positive"}) {
+		t.Fatalf("errors = %+v", problem.Errors)
+	}
+}
+
+func TestProblemErrorEncoder_InternalErrorsStayOpaque(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := server.WrapHTTPError(http.StatusServiceUnavailable, "", "", errors.New("db password rejected"))
+	err.Header = http.Header{"Retry-After": {"5"}}
+	server.ProblemErrorEncoder(context.Background(), err, rec)
+
+	body := decodeProblem(t, rec)
+	if _, ok := body["detail"]; ok {
+		t.Fatalf("5xx detail leaked: %v", body["detail"])
+	}
+	if body["title"] != "Service Unavailable" || body["code"] != "service_unavailable" {
+		t.Fatalf("body = %v", body)
+	}
+	if rec.Header().Get("Retry-After") != "5" {
+		t.Fatalf("Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+}
@@ -15,10 +15,12 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
 	"github.com/dreamsxin/go-kit/v2/transport"
+	transporthttp "github.com/dreamsxin/go-kit/v2/transport/http"
 )
 
 // Server wraps an Endpoint and implements http.Handler.
@@ -32,6 +34,8 @@ type Server struct {
 	errorEncoder ErrorEncoder
 	finalizer    []FinalizerFunc
 	errorHandler transport.ErrorHandler
+	etag         *etagConfig
+	fieldMask    *fieldMaskConfig
 }
 
 // NewServer constructs an HTTP Server for the given Endpoint.
@@ -79,6 +83,10 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}()
 	}
 
+	if s.etag != nil {
+		ctx = s.etag.withConditional(ctx, r)
+	}
+
 	for _, f := range s.before {
 		ctx = f(ctx, r)
 	}
@@ -90,6 +98,23 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.etag != nil {
+		if err := s.etag.checkPreconditions(ctx, request); err != nil {
+			s.errorHandler.Handle(ctx, err)
+			s.errorEncoder(ctx, err, responseWriter)
+			return
+		}
+	}
+
+	var mask transporthttp.FieldMask
+	if s.fieldMask != nil {
+		if mask, err = s.fieldMask.parse(r, request); err != nil {
+			s.errorHandler.Handle(ctx, err)
+			s.errorEncoder(ctx, err, responseWriter)
+			return
+		}
+	}
+
 	response, err := s.e(ctx, request)
 	if err != nil {
 		s.errorHandler.Handle(ctx, err)
@@ -101,8 +126,31 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctx = f(ctx, r, iw)
 	}
 
-	if err := s.enc(ctx, responseWriter, response); err != nil {
+	if s.fieldMask != nil {
+		if response, err = s.fieldMask.apply(mask, response); err != nil {
+			s.errorHandler.Handle(ctx, err)
+			s.errorEncoder(ctx, err, responseWriter)
+			return
+		}
+	}
+
+	if s.etag != nil {
+		err = s.etag.encode(ctx, responseWriter, response, s.enc)
+	} else {
+		err = s.enc(ctx, responseWriter, response)
+	}
+	if err != nil {
 		s.errorHandler.Handle(ctx, err)
+		var streamErr *StreamError
+		if errors.As(err, &streamErr) {
+			if r.Context().Err() != nil {
+				// The client is gone; there is nobody left to tell.
+				return
+			}
+			// The response has started; abort it so clients see the
+			// truncation instead of an error body appended to the stream.
+			panic(http.ErrAbortHandler)
+		}
 		s.errorEncoder(ctx, err, responseWriter)
 		return
 	}
@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/dreamsxin/go-kit/v2/endpoint"
@@ -124,6 +126,67 @@ func TestNopRequestDecoder(t *testing.T) {
 	}
 }
 
+type boundReq struct {
+	ID     int    `path:"id" json:"-"`
+	Tenant string `header:"X-Tenant" json:"-"`
+	Name   string `json:"name"`
+}
+
+func TestNewTypedJSONServer_BindsTaggedFields(t *testing.T) {
+	h := server.NewTypedJSONServer(func(_ context.Context, req boundReq) (boundReq, error) {
+		return req, nil
+	})
+	mux := http.NewServeMux()
+	mux.Handle("PUT /items/{id}", h)
+
+	r := httptest.NewRequest(http.MethodPut, "/items/9", bytes.NewBufferString(`{"name":"box"}`))
+	r.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, body %s", w.Code, w.Body)
+	}
+	if got := w.Body.String(); got != `{"name":"box"}`+"\n" {
+		t.Fatalf("body: got %q", got)
+	}
+}
+
+func TestNewTypedJSONServer_PanicsOnInvalidBindTags(t *testing.T) {
+	type twoBodies struct {
+		A boundReq `body:""`
+		B boundReq `body:""`
+	}
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "more than one field") {
+			t.Fatalf("recover() = %v, want bind tag panic", r)
+		}
+	}()
+	server.NewTypedJSONServer(func(_ context.Context, req twoBodies) (twoBodies, error) {
+		return req, nil
+	})
+}
+
+func TestBindJSONRequest_Errors(t *testing.T) {
+	mux := http.NewServeMux()
+	var err error
+	mux.HandleFunc("POST /items/{id}", func(_ http.ResponseWriter, r *http.Request) {
+		var req boundReq
+		err = server.BindJSONRequest(r, &req, server.StrictJSONDecodeOptions(128))
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/x", bytes.NewBufferString(`{}`)))
+	var queryErr *transporthttp.QueryError
+	if !errors.As(err, &queryErr) || queryErr.Field != "ID" {
+		t.Fatalf("path error: got %v, want QueryError", err)
+	}
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/1", bytes.NewBufferString(`{"id":1}`)))
+	var decodeErr server.JSONDecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("body error: got %T %v, want JSONDecodeError", err, err)
+	}
+}
+
 // ── EncodeJSONResponse ────────────────────────────────────────────────────────
 
 func TestEncodeJSONResponse_Basic(t *testing.T) {
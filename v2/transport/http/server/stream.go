@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/codec"
+)
+
+// StreamFormat selects the framing of a streamed JSON response.
+type StreamFormat string
+
+const (
+	// NDJSON writes one JSON value per line as application/x-ndjson.
+	NDJSON StreamFormat = codec.NDJSONContentType
+	// JSONSeq writes RFC 7464 JSON text sequences as application/json-seq:
+	// each value is preceded by an ASCII record separator and followed by a
+	// line feed.
+	JSONSeq StreamFormat = codec.JSONSeqContentType
+)
+
+// StreamError reports a failure after a streamed response has started. The
+// status line is already sent by then, so the server passes the error to its
+// ErrorHandler and aborts the connection instead of running the
+// ErrorEncoder; clients see a truncated stream rather than a clean end.
+//
+// The abort is a panic with http.ErrAbortHandler, which net/http handles
+// without logging. Recover middleware around the server must re-panic that
+// value rather than turn it into a 500, and a test calling ServeHTTP directly
+// must expect it. A stream ended by the request context, such as a client
+// disconnecting, is only reported and does not panic.
+type StreamError struct {
+	Err error
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("stream interrupted: %v", e.Err)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// EncodeStreamResponse returns an EncodeResponseFunc that streams the
+// response in format, flushing after every value so memory stays flat and
+// clients see rows as they are produced. The response must be an
+// iter.Seq2[T, error], an iter.Seq[T], or a channel of T.
+//
+// Headers are written with the first value. An error from the iterator
+// before that reaches the ErrorEncoder as usual; a later error, or the
+// request context ending, interrupts the stream with StreamError, and a
+// Server then aborts the response by panicking with http.ErrAbortHandler
+// unless the request context has ended; see StreamError. Channels are read
+// until closed or until the request context ends.
+func EncodeStreamResponse[T any](format StreamFormat) EncodeResponseFunc {
+	switch format {
+	case NDJSON, JSONSeq:
+	default:
+		panic(fmt.Sprintf("http server: unsupported stream format %q", format))
+	}
+	return func(ctx context.Context, w http.ResponseWriter, response any) error {
+		seq, err := streamValues[T](ctx, response)
+		if err != nil {
+			return err
+		}
+		sw := &streamWriter{w: w, format: format}
+		sw.flusher, _ = w.(http.Flusher)
+		for value, err := range seq {
+			if err != nil {
+				return sw.fail(err)
+			}
+			if err := ctx.Err(); err != nil {
+				return sw.fail(err)
+			}
+			if err := sw.write(value); err != nil {
+				return sw.fail(err)
+			}
+		}
+		if !sw.started {
+			sw.start()
+		}
+		return nil
+	}
+}
+
+// NewTypedStreamServer creates an HTTP server for a handler that returns a
+// sequence of values, streamed in format with EncodeStreamResponse. A request
+// body is decoded by its Content-Type with codec.DefaultRegistry, and
+// bodiless GET routes get the zero Req. Errors returned before streaming
+// starts use JSONErrorEncoder; a failure after it starts aborts the response
+// with an http.ErrAbortHandler panic, as described on StreamError.
+//
+//	h := server.NewTypedStreamServer(func(ctx context.Context, req ExportReq) (iter.Seq2[Row, error], error) {
+//	    return rows.Iterate(ctx, req.Since), nil
+//	}, server.NDJSON)
+func NewTypedStreamServer[Req, T any](
+	handler func(ctx context.Context, req Req) (iter.Seq2[T, error], error),
+	format StreamFormat,
+	options ...ServerOption,
+) *Server {
+	opts := append([]ServerOption{ServerErrorEncoder(JSONErrorEncoder)}, options...)
+	ep := endpoint.TypedEndpoint[Req, iter.Seq2[T, error]](handler).Wrap()
+	return NewServer(ep, DecodeCodecRequest[Req](codec.DefaultRegistry(), DefaultMaxJSONBodyBytes), EncodeStreamResponse[T](format), opts...)
+}
+
+// streamValues adapts the supported response shapes to one sequence.
+func streamValues[T any](ctx context.Context, response any) (iter.Seq2[T, error], error) {
+	switch values := response.(type) {
+	case iter.Seq2[T, error]:
+		return values, nil
+	case func(func(T, error) bool):
+		return values, nil
+	case iter.Seq[T]:
+		return adaptSeq(values), nil
+	case func(func(T) bool):
+		return adaptSeq(values), nil
+	case <-chan T:
+		return adaptChan(ctx, values), nil
+	case chan T:
+		return adaptChan(ctx, values), nil
+	}
+	return nil, fmt.Errorf("http server: stream response is %T, want iter.Seq2[%T, error], iter.Seq or channel", response, *new(T))
+}
+
+func adaptSeq[T any](seq iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for value := range seq {
+			if !yield(value, nil) {
+				return
+			}
+		}
+	}
+}
+
+func adaptChan[T any](ctx context.Context, ch <-chan T) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				var zero T
+				yield(zero, ctx.Err())
+				return
+			case value, ok := <-ch:
+				if !ok || !yield(value, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+type streamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	format  StreamFormat
+	started bool
+	buf     []byte
+}
+
+func (sw *streamWriter) start() {
+	sw.started = true
+	sw.w.Header().Set("Content-Type", string(sw.format))
+	sw.w.Header().Set("X-Content-Type-Options", "nosniff")
+	sw.w.Header().Del("Content-Length")
+	sw.w.WriteHeader(http.StatusOK)
+}
+
+func (sw *streamWriter) write(value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if !sw.started {
+		sw.start()
+	}
+	sw.buf = sw.buf[:0]
+	if sw.format == JSONSeq {
+		sw.buf = append(sw.buf, 0x1e)
+	}
+	sw.buf = append(append(sw.buf, data...), '\n')
+	if _, err := sw.w.Write(sw.buf); err != nil {
+		return err
+	}
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+	return nil
+}
+
+// fail returns err unchanged before the stream starts, so the ErrorEncoder
+// can still write a proper response, and as StreamError afterwards.
+func (sw *streamWriter) fail(err error) error {
+	if !sw.started {
+		return err
+	}
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		return err
+	}
+	return &StreamError{Err: err}
+}
@@ -0,0 +1,192 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+type row struct {
+	ID int `json:"id"`
+}
+
+func rows(n int, failAt int) iter.Seq2[row, error] {
+	return func(yield func(row, error) bool) {
+		for i := 1; i <= n; i++ {
+			if i == failAt {
+				yield(row{}, errors.New("database gone"))
+				return
+			}
+			if !yield(row{ID: i}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestTypedStreamServer_WritesNDJSON(t *testing.T) {
+	h := server.NewTypedStreamServer(func(_ context.Context, req struct {
+		Limit int `json:"limit"`
+	}) (iter.Seq2[row, error], error) {
+		return rows(req.Limit, 0), nil
+	}, server.NDJSON)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(`{"limit":3}`)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" || !rec.Flushed {
+		t.Fatalf("status = %d, Content-Type = %q, flushed = %v", rec.Code, rec.Header().Get("Content-Type"), rec.Flushed)
+	}
+	if got := rec.Body.String(); got != "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n" {
+		t.Fatalf("body = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("empty stream: status = %d, body = %q", rec.Code, rec.Body)
+	}
+}
+
+func TestEncodeStreamResponse_JSONSeqFromChannel(t *testing.T) {
+	ch := make(chan row, 2)
+	ch <- row{ID: 7}
+	ch <- row{ID: 8}
+	close(ch)
+	h := server.NewServer(func(context.Context, any) (any, error) { return (<-chan row)(ch), nil },
+		server.NopRequestDecoder, server.EncodeStreamResponse[row](server.JSONSeq))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Body.String(); got != "\x1e{\"id\":7}\n\x1e{\"id\":8}\n" || rec.Header().Get("Content-Type") != "application/json-seq" {
+		t.Fatalf("Content-Type = %q, body = %q", rec.Header().Get("Content-Type"), got)
+	}
+}
+
+func TestEncodeStreamResponse_ErrorBeforeFirstValueUsesErrorEncoder(t *testing.T) {
+	h := server.NewTypedStreamServer(func(context.Context, struct{}) (iter.Seq2[row, error], error) {
+		return func(yield func(row, error) bool) {
+			yield(row{}, apperror.New(apperror.KindUnavailable, "export_unavailable", "export is paused"))
+		}, nil
+	}, server.NDJSON)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "export_unavailable") {
+		t.Fatalf("status = %d, body = %q", rec.Code, rec.Body)
+	}
+}
+
+func TestEncodeStreamResponse_MidStreamErrorAbortsConnection(t *testing.T) {
+	handled := make(chan error, 1)
+	h := server.NewTypedStreamServer(func(context.Context, struct{}) (iter.Seq2[row, error], error) {
+		return rows(5, 3), nil
+	}, server.NDJSON, server.ServerErrorHandler(errorHandlerFunc(func(_ context.Context, err error) { handled <- err })))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Fatalf("expected truncated stream, got clean body %q", body)
+	}
+	if string(body) != "{\"id\":1}\n{\"id\":2}\n" {
+		t.Fatalf("body = %q", body)
+	}
+	var streamErr *server.StreamError
+	if err := <-handled; !errors.As(err, &streamErr) {
+		t.Fatalf("handled = %v", err)
+	}
+}
+
+func TestTypedStreamServer_BehindRecoverMiddleware(t *testing.T) {
+	var recovered atomic.Value
+	recoverMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if v := recover(); v != nil {
+					recovered.Store(v)
+					if v == http.ErrAbortHandler {
+						panic(v)
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+	handled := make(chan error, 1)
+	h := server.NewTypedStreamServer(func(ctx context.Context, _ struct{}) (iter.Seq2[row, error], error) {
+		return func(yield func(row, error) bool) {
+			for i := 1; ; i++ {
+				if err := ctx.Err(); err != nil {
+					yield(row{}, err)
+					return
+				}
+				if !yield(row{ID: i}, nil) {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}, nil
+	}, server.NDJSON, server.ServerErrorHandler(errorHandlerFunc(func(_ context.Context, err error) { handled <- err })))
+	srv := httptest.NewServer(recoverMiddleware(h))
+	defer srv.Close()
+
+	// A client leaving mid-stream ends it without a panic.
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	select {
+	case err := <-handled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("handled = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not end after the client left")
+	}
+	if v := recovered.Load(); v != nil {
+		t.Fatalf("middleware recovered %v after a client disconnect", v)
+	}
+
+	// A failure mid-stream aborts with http.ErrAbortHandler, which the
+	// middleware has to pass on for the client to see the truncation.
+	failing := httptest.NewServer(recoverMiddleware(server.NewTypedStreamServer(func(context.Context, struct{}) (iter.Seq2[row, error], error) {
+		return rows(5, 3), nil
+	}, server.NDJSON)))
+	defer failing.Close()
+	resp, err = http.Get(failing.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil {
+		t.Fatal("expected truncated stream")
+	}
+	if v := recovered.Load(); v != http.ErrAbortHandler {
+		t.Fatalf("middleware recovered %v, want http.ErrAbortHandler", v)
+	}
+}
+
+type errorHandlerFunc func(context.Context, error)
+
+func (f errorHandlerFunc) Handle(ctx context.Context, err error) { f(ctx, err) }
@@ -0,0 +1,502 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport"
+)
+
+// TusVersion is the tus resumable upload protocol version UploadHandler
+// speaks.
+const TusVersion = "1.0.0"
+
+// UploadContentType is the media type of PATCH request bodies.
+const UploadContentType = "application/offset+octet-stream"
+
+// Upload defaults. Handlers that need other bounds pass UploadMaxSize and
+// UploadExpiry.
+const (
+	DefaultMaxUploadBytes int64 = 1 << 30 // 1 GiB per upload
+	DefaultUploadExpiry         = 24 * time.Hour
+)
+
+// StatusChecksumMismatch is the tus status for a chunk whose Upload-Checksum
+// does not match its body.
+const StatusChecksumMismatch = 460
+
+var uploadChecksums = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// CompletedUpload is the request passed to the UploadOnComplete endpoint.
+type CompletedUpload struct {
+	UploadInfo
+	store UploadStore
+}
+
+// Open returns the uploaded bytes. The caller closes the reader.
+func (u CompletedUpload) Open(ctx context.Context) (io.ReadSeekCloser, error) {
+	return u.store.Open(ctx, u.ID)
+}
+
+// UploadHandler serves resumable uploads with the tus 1.0.0 protocol: core,
+// creation, creation-with-upload, expiration, checksum and termination.
+// Clients create an upload with POST and an Upload-Length header, then send
+// the bytes with PATCH requests carrying Upload-Offset, resuming after a HEAD
+// request reports how much arrived. Any tus client works against it.
+//
+// Mount it on a prefix; the last path segment names the upload:
+//
+//	store, err := server.NewFileUploadStore("/var/lib/app/uploads")
+//	...
+//	uploads := server.NewUploadHandler(store,
+//	    server.UploadMaxSize(512<<20),
+//	    server.UploadOnComplete(endpoint.TypedEndpoint[server.CompletedUpload, any](importFile).Wrap()),
+//	)
+//	mux.Handle("/files/", uploads)
+//
+// Errors are written with the error encoder, JSONErrorEncoder by default.
+type UploadHandler struct {
+	store        UploadStore
+	maxSize      int64
+	expiry       time.Duration
+	onComplete   endpoint.Endpoint
+	errorEncoder ErrorEncoder
+	errorHandler transport.ErrorHandler
+	now          func() time.Time
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// UploadOption configures an UploadHandler.
+type UploadOption func(*UploadHandler)
+
+// UploadMaxSize caps the declared length of one upload. The default is
+// DefaultMaxUploadBytes; non-positive values are ignored.
+func UploadMaxSize(n int64) UploadOption {
+	return func(h *UploadHandler) {
+		if n > 0 {
+			h.maxSize = n
+		}
+	}
+}
+
+// UploadExpiry sets how long an incomplete upload is kept after creation.
+// The default is DefaultUploadExpiry; zero or negative keeps uploads forever.
+func UploadExpiry(d time.Duration) UploadOption {
+	return func(h *UploadHandler) { h.expiry = d }
+}
+
+// UploadOnComplete runs ep with a CompletedUpload request once the last byte
+// of an upload arrives. An error fails the final PATCH through the error
+// encoder; the data stays in the store.
+func UploadOnComplete(ep endpoint.Endpoint) UploadOption {
+	return func(h *UploadHandler) { h.onComplete = ep }
+}
+
+// UploadErrorEncoder sets how errors are written. The default is
+// JSONErrorEncoder.
+func UploadErrorEncoder(ee ErrorEncoder) UploadOption {
+	return func(h *UploadHandler) {
+		if ee != nil {
+			h.errorEncoder = ee
+		}
+	}
+}
+
+// UploadErrorHandler receives store and completion failures.
+func UploadErrorHandler(errorHandler transport.ErrorHandler) UploadOption {
+	return func(h *UploadHandler) {
+		if errorHandler != nil {
+			h.errorHandler = errorHandler
+		}
+	}
+}
+
+// NewUploadHandler returns a tus upload handler backed by store.
+func NewUploadHandler(store UploadStore, options ...UploadOption) *UploadHandler {
+	if store == nil {
+		panic("server: upload store cannot be nil")
+	}
+	h := &UploadHandler{
+		store:        store,
+		maxSize:      DefaultMaxUploadBytes,
+		expiry:       DefaultUploadExpiry,
+		errorEncoder: JSONErrorEncoder,
+		errorHandler: transport.NopErrorHandler,
+		now:          time.Now,
+		busy:         make(map[string]bool),
+	}
+	for _, option := range options {
+		if option != nil {
+			option(h)
+		}
+	}
+	return h
+}
+
+func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Method == http.MethodOptions {
+		h.serveOptions(w)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		h.fail(w, r, NewHTTPError(http.StatusPreconditionFailed, "upload.unsupported_version", "unsupported Tus-Resumable version"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.create(w, r)
+	case http.MethodHead:
+		h.head(w, r)
+	case http.MethodPatch:
+		h.patch(w, r)
+	case http.MethodDelete:
+		h.delete(w, r)
+	default:
+		w.Header().Set("Allow", "OPTIONS, POST, HEAD, PATCH, DELETE")
+		h.fail(w, r, NewHTTPError(http.StatusMethodNotAllowed, "method_not_allowed", http.StatusText(http.StatusMethodNotAllowed)))
+	}
+}
+
+// Cleanup deletes incomplete uploads past their expiry and returns how many
+// were removed. Run it periodically, for example from a ticker.
+func (h *UploadHandler) Cleanup(ctx context.Context) (int, error) {
+	uploads, err := h.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := h.now()
+	removed := 0
+	for _, info := range uploads {
+		if !info.Expired(now) || !h.acquire(info.ID) {
+			continue
+		}
+		err := h.store.Delete(ctx, info.ID)
+		h.release(info.ID)
+		if err != nil && !errors.Is(err, ErrUploadNotFound) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (h *UploadHandler) serveOptions(w http.ResponseWriter) {
+	algorithms := make([]string, 0, len(uploadChecksums))
+	for name := range uploadChecksums {
+		algorithms = append(algorithms, name)
+	}
+	sort.Strings(algorithms)
+	w.Header().Set("Tus-Version", TusVersion)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	w.Header().Set("Tus-Extension", "creation,creation-with-upload,expiration,checksum,termination")
+	w.Header().Set("Tus-Checksum-Algorithm", strings.Join(algorithms, ","))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		h.fail(w, r, NewHTTPError(http.StatusBadRequest, "upload.invalid_length", "Upload-Length must be a non-negative integer"))
+		return
+	}
+	if length > h.maxSize {
+		h.fail(w, r, NewHTTPError(http.StatusRequestEntityTooLarge, "upload.too_large", "upload exceeds the maximum size"))
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		h.fail(w, r, NewHTTPError(http.StatusBadRequest, "upload.invalid_metadata", err.Error()))
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	now := h.now()
+	info := UploadInfo{ID: id, Length: length, Metadata: metadata, CreatedAt: now}
+	if h.expiry > 0 {
+		info.ExpiresAt = now.Add(h.expiry)
+	}
+	if err := h.store.Create(r.Context(), info); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, id))
+	h.setExpires(w, info)
+	h.acquire(id)
+	defer h.release(id)
+	switch {
+	case r.Header.Get("Content-Type") == UploadContentType && r.ContentLength != 0:
+		// creation-with-upload: the body carries the first chunk.
+		info, err = h.write(r, info)
+		w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	case length == 0:
+		err = h.complete(r, info)
+	}
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *UploadHandler) head(w http.ResponseWriter, r *http.Request) {
+	info, err := h.lookup(r, false)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(info.Length, 10))
+	if len(info.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", formatUploadMetadata(info.Metadata))
+	}
+	h.setExpires(w, info)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UploadHandler) patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != UploadContentType {
+		h.fail(w, r, NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", "Content-Type must be "+UploadContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		h.fail(w, r, NewHTTPError(http.StatusBadRequest, "upload.invalid_offset", "Upload-Offset must be a non-negative integer"))
+		return
+	}
+	id := path.Base(r.URL.Path)
+	if !h.acquire(id) {
+		h.fail(w, r, NewHTTPError(http.StatusLocked, "upload.locked", "upload is being written by another request"))
+		return
+	}
+	defer h.release(id)
+
+	info, err := h.lookup(r, true)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if offset != info.Offset {
+		h.fail(w, r, NewHTTPError(http.StatusConflict, "upload.offset_mismatch", "Upload-Offset does not match the current offset"))
+		return
+	}
+	info, err = h.write(r, info)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	h.setExpires(w, info)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id := path.Base(r.URL.Path)
+	if !h.acquire(id) {
+		h.fail(w, r, NewHTTPError(http.StatusLocked, "upload.locked", "upload is being written by another request"))
+		return
+	}
+	defer h.release(id)
+	if err := h.store.Delete(r.Context(), id); err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// write stores the request body at info.Offset, verifies Upload-Checksum,
+// and runs the completion endpoint when the upload is complete. The caller
+// holds the upload.
+func (h *UploadHandler) write(r *http.Request, info UploadInfo) (UploadInfo, error) {
+	remaining := info.Length - info.Offset
+	if r.ContentLength > remaining {
+		return info, NewHTTPError(http.StatusRequestEntityTooLarge, "upload.too_large", "chunk exceeds the declared Upload-Length")
+	}
+
+	var sum hash.Hash
+	var want []byte
+	if header := r.Header.Get("Upload-Checksum"); header != "" {
+		name, encoded, _ := strings.Cut(header, " ")
+		newHash, ok := uploadChecksums[name]
+		if !ok {
+			return info, NewHTTPError(http.StatusBadRequest, "upload.unsupported_checksum", "unsupported checksum algorithm "+name)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return info, NewHTTPError(http.StatusBadRequest, "upload.invalid_checksum", "Upload-Checksum is not valid base64")
+		}
+		sum, want = newHash(), decoded
+	}
+
+	body := io.Reader(io.LimitReader(r.Body, remaining))
+	if sum != nil {
+		body = io.TeeReader(body, sum)
+	}
+	n, writeErr := h.store.WriteChunk(r.Context(), info.ID, info.Offset, body)
+	overflow := writeErr == nil && n == remaining && bodyHasMore(r.Body)
+
+	// A failed or oversized chunk is kept for resumption unless a checksum
+	// was sent, since the partial bytes can no longer be verified.
+	if overflow || (sum != nil && (writeErr != nil || !bytes.Equal(sum.Sum(nil), want))) {
+		if err := h.store.Truncate(r.Context(), info.ID, info.Offset); err != nil {
+			return info, err
+		}
+		switch {
+		case overflow:
+			return info, NewHTTPError(http.StatusRequestEntityTooLarge, "upload.too_large", "chunk exceeds the declared Upload-Length")
+		case writeErr == nil:
+			return info, NewHTTPError(StatusChecksumMismatch, "upload.checksum_mismatch", "Upload-Checksum does not match the chunk")
+		}
+	}
+	if writeErr != nil {
+		return info, writeErr
+	}
+	info.Offset += n
+	if info.Complete() {
+		return info, h.complete(r, info)
+	}
+	return info, nil
+}
+
+func (h *UploadHandler) complete(r *http.Request, info UploadInfo) error {
+	if h.onComplete == nil {
+		return nil
+	}
+	_, err := h.onComplete(r.Context(), CompletedUpload{UploadInfo: info, store: h.store})
+	return err
+}
+
+// lookup loads the upload named by the request path and reports expired
+// uploads as gone. When the caller holds the upload, an expired one is also
+// removed; otherwise a write may still be in progress, and removal is left
+// to Cleanup or a later request that holds it.
+func (h *UploadHandler) lookup(r *http.Request, held bool) (UploadInfo, error) {
+	info, err := h.store.Info(r.Context(), path.Base(r.URL.Path))
+	if err != nil {
+		return info, err
+	}
+	if info.Expired(h.now()) {
+		if held {
+			_ = h.store.Delete(r.Context(), info.ID)
+		}
+		return info, NewHTTPError(http.StatusGone, "upload.expired", "upload expired")
+	}
+	return info, nil
+}
+
+func (h *UploadHandler) setExpires(w http.ResponseWriter, info UploadInfo) {
+	if !info.ExpiresAt.IsZero() && !info.Complete() {
+		w.Header().Set("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (h *UploadHandler) acquire(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[id] {
+		return false
+	}
+	h.busy[id] = true
+	return true
+}
+
+func (h *UploadHandler) release(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.busy, id)
+}
+
+func (h *UploadHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrUploadNotFound) {
+		err = WrapHTTPError(http.StatusNotFound, "upload.not_found", "upload not found", err)
+	}
+	if httpStatus(err) >= http.StatusInternalServerError {
+		h.errorHandler.Handle(r.Context(), err)
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(httpStatus(err))
+		return
+	}
+	h.errorEncoder(r.Context(), err, w)
+}
+
+func bodyHasMore(body io.Reader) bool {
+	var probe [1]byte
+	n, _ := body.Read(probe[:])
+	return n > 0
+}
+
+func newUploadID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("upload: generate id: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// parseUploadMetadata decodes "key base64value,key2" pairs.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty key in Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in Upload-Metadata value of %s", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatUploadMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + " " + base64.StdEncoding.EncodeToString([]byte(metadata[key]))
+	}
+	return strings.Join(pairs, ",")
+}
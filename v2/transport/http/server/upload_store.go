@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/internal/fileid"
+)
+
+// ErrUploadNotFound is returned by an UploadStore for unknown upload IDs.
+var ErrUploadNotFound = errors.New("upload not found")
+
+// UploadInfo describes one resumable upload.
+type UploadInfo struct {
+	ID string `json:"id"`
+	// Length is the total size in bytes declared when the upload was created.
+	Length int64 `json:"length"`
+	// Offset is the number of bytes received so far.
+	Offset int64 `json:"offset"`
+	// Metadata holds the decoded Upload-Metadata pairs sent on creation.
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	// ExpiresAt is when an incomplete upload may be removed. Zero means never.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Complete reports whether every declared byte has been received.
+func (i UploadInfo) Complete() bool { return i.Offset >= i.Length }
+
+// Expired reports whether the upload is incomplete and past ExpiresAt.
+func (i UploadInfo) Expired(now time.Time) bool {
+	return !i.Complete() && !i.ExpiresAt.IsZero() && now.After(i.ExpiresAt)
+}
+
+// UploadStore persists resumable uploads for UploadHandler. The handler
+// serializes writes to one upload within a process; stores shared by several
+// processes must guard WriteChunk themselves.
+type UploadStore interface {
+	// Create stores a new, empty upload. info.Offset is zero.
+	Create(ctx context.Context, info UploadInfo) error
+	// Info returns the upload with its current Offset, or ErrUploadNotFound.
+	Info(ctx context.Context, id string) (UploadInfo, error)
+	// WriteChunk appends src at offset, which equals the current Offset, and
+	// returns the number of bytes stored, including when src fails midway.
+	WriteChunk(ctx context.Context, id string, offset int64, src io.Reader) (int64, error)
+	// Truncate discards every byte after size, which is at most Offset.
+	Truncate(ctx context.Context, id string, size int64) error
+	// Open returns the received bytes for reading.
+	Open(ctx context.Context, id string) (io.ReadSeekCloser, error)
+	// Delete removes the upload and its data.
+	Delete(ctx context.Context, id string) error
+	// List returns every stored upload.
+	List(ctx context.Context) ([]UploadInfo, error)
+}
+
+// FileUploadStore is an UploadStore that keeps each upload in a directory as
+// two files: <id>.bin holds the data and <id>.info its JSON description.
+type FileUploadStore struct {
+	dir string
+}
+
+// NewFileUploadStore returns a store in dir, creating the directory if needed.
+func NewFileUploadStore(dir string) (*FileUploadStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("upload store: directory cannot be empty")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("upload store: %w", err)
+	}
+	return &FileUploadStore{dir: dir}, nil
+}
+
+func (s *FileUploadStore) Create(_ context.Context, info UploadInfo) error {
+	dataPath, infoPath, err := s.paths(info.ID)
+	if err != nil {
+		return err
+	}
+	data, err := os.OpenFile(dataPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("upload store: create %s: %w", info.ID, err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("upload store: create %s: %w", info.ID, err)
+	}
+	info.Offset = 0
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("upload store: encode %s: %w", info.ID, err)
+	}
+	if err := os.WriteFile(infoPath, raw, 0o640); err != nil {
+		_ = os.Remove(dataPath)
+		return fmt.Errorf("upload store: create %s: %w", info.ID, err)
+	}
+	return nil
+}
+
+func (s *FileUploadStore) Info(_ context.Context, id string) (UploadInfo, error) {
+	dataPath, infoPath, err := s.paths(id)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	raw, err := os.ReadFile(infoPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	if err != nil {
+		return UploadInfo{}, fmt.Errorf("upload store: read %s: %w", id, err)
+	}
+	var info UploadInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return UploadInfo{}, fmt.Errorf("upload store: decode %s: %w", id, err)
+	}
+	stat, err := os.Stat(dataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return UploadInfo{}, ErrUploadNotFound
+	}
+	if err != nil {
+		return UploadInfo{}, fmt.Errorf("upload store: stat %s: %w", id, err)
+	}
+	info.Offset = stat.Size()
+	return info, nil
+}
+
+func (s *FileUploadStore) WriteChunk(_ context.Context, id string, offset int64, src io.Reader) (int64, error) {
+	dataPath, _, err := s.paths(id)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(dataPath, os.O_WRONLY, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrUploadNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("upload store: open %s: %w", id, err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return 0, fmt.Errorf("upload store: seek %s: %w", id, err)
+	}
+	n, copyErr := io.Copy(f, src)
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("upload store: write %s: %w", id, err)
+	}
+	return n, copyErr
+}
+
+func (s *FileUploadStore) Truncate(_ context.Context, id string, size int64) error {
+	dataPath, _, err := s.paths(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Truncate(dataPath, size); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrUploadNotFound
+		}
+		return fmt.Errorf("upload store: truncate %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *FileUploadStore) Open(_ context.Context, id string) (io.ReadSeekCloser, error) {
+	dataPath, _, err := s.paths(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(dataPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("upload store: open %s: %w", id, err)
+	}
+	return f, nil
+}
+
+func (s *FileUploadStore) Delete(_ context.Context, id string) error {
+	dataPath, infoPath, err := s.paths(id)
+	if err != nil {
+		return err
+	}
+	infoErr := os.Remove(infoPath)
+	dataErr := os.Remove(dataPath)
+	if errors.Is(infoErr, fs.ErrNotExist) && errors.Is(dataErr, fs.ErrNotExist) {
+		return ErrUploadNotFound
+	}
+	for _, err := range []error{infoErr, dataErr} {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("upload store: delete %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileUploadStore) List(ctx context.Context) ([]UploadInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("upload store: list: %w", err)
+	}
+	var uploads []UploadInfo
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := s.Info(ctx, id)
+		if errors.Is(err, ErrUploadNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, info)
+	}
+	return uploads, nil
+}
+
+func (s *FileUploadStore) paths(id string) (data, info string, err error) {
+	if !fileid.Valid(id) {
+		return "", "", ErrUploadNotFound
+	}
+	base := filepath.Join(s.dir, id)
+	return base + ".bin", base + ".info", nil
+}
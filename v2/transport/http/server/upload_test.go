@@ -0,0 +1,241 @@
+package server_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/server"
+)
+
+func newUploadServer(t *testing.T, options ...server.UploadOption) (*httptest.Server, *server.FileUploadStore) {
+	t.Helper()
+	store, err := server.NewFileUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/files/", server.NewUploadHandler(store, options...))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func tusRequest(t *testing.T, method, url string, body io.Reader, headers map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Tus-Resumable", server.TusVersion)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+func createUpload(t *testing.T, srv *httptest.Server, length int) string {
+	t.Helper()
+	resp := tusRequest(t, http.MethodPost, srv.URL+"/files/", nil, map[string]string{
+		"Upload-Length":   strconv.Itoa(length),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("notes.txt")),
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, "/files/") {
+		t.Fatalf("Location: got %q", location)
+	}
+	return srv.URL + location
+}
+
+func patchChunk(t *testing.T, url string, offset int, chunk string, headers map[string]string) *http.Response {
+	t.Helper()
+	all := map[string]string{
+		"Content-Type":  server.UploadContentType,
+		"Upload-Offset": strconv.Itoa(offset),
+	}
+	for key, value := range headers {
+		all[key] = value
+	}
+	return tusRequest(t, http.MethodPatch, url, strings.NewReader(chunk), all)
+}
+
+func TestUploadHandler_ResumesAndCompletes(t *testing.T) {
+	completed := make(chan string, 1)
+	onComplete := endpoint.TypedEndpoint[server.CompletedUpload, any](func(ctx context.Context, upload server.CompletedUpload) (any, error) {
+		f, err := upload.Open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		completed <- upload.Metadata["filename"] + ":" + string(data)
+		return nil, err
+	}).Wrap()
+	srv, _ := newUploadServer(t, server.UploadOnComplete(onComplete))
+
+	url := createUpload(t, srv, 11)
+	if resp := patchChunk(t, url, 0, "hello ", nil); resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-Offset") != "6" {
+		t.Fatalf("first chunk: got %d offset %q", resp.StatusCode, resp.Header.Get("Upload-Offset"))
+	}
+
+	head := tusRequest(t, http.MethodHead, url, nil, nil)
+	if head.StatusCode != http.StatusOK || head.Header.Get("Upload-Offset") != "6" || head.Header.Get("Upload-Length") != "11" {
+		t.Fatalf("HEAD: got %d %v", head.StatusCode, head.Header)
+	}
+	if head.Header.Get("Upload-Expires") == "" || head.Header.Get("Cache-Control") != "no-store" {
+		t.Fatalf("HEAD headers: %v", head.Header)
+	}
+
+	if resp := patchChunk(t, url, 3, "world", nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("wrong offset: got %d, want 409", resp.StatusCode)
+	}
+	if resp := patchChunk(t, url, 6, "world", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("last chunk: got %d", resp.StatusCode)
+	}
+	select {
+	case got := <-completed:
+		if got != "notes.txt:hello world" {
+			t.Fatalf("completed upload: got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("completion endpoint not called")
+	}
+}
+
+func TestUploadHandler_ChecksumMismatchDiscardsChunk(t *testing.T) {
+	srv, store := newUploadServer(t)
+	url := createUpload(t, srv, 4)
+
+	sum := sha1.Sum([]byte("abcd"))
+	resp := patchChunk(t, url, 0, "abcx", map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString(sum[:])})
+	if resp.StatusCode != server.StatusChecksumMismatch {
+		t.Fatalf("mismatch: got %d, want 460", resp.StatusCode)
+	}
+	id := url[strings.LastIndex(url, "/")+1:]
+	if info, err := store.Info(context.Background(), id); err != nil || info.Offset != 0 {
+		t.Fatalf("offset after mismatch: %+v %v", info, err)
+	}
+
+	if resp := patchChunk(t, url, 0, "x", map[string]string{"Upload-Checksum": "crc32 AAAA"}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unsupported algorithm: got %d", resp.StatusCode)
+	}
+	resp = patchChunk(t, url, 0, "abcd", map[string]string{"Upload-Checksum": "sha1 " + base64.StdEncoding.EncodeToString(sum[:])})
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-Offset") != "4" {
+		t.Fatalf("verified chunk: got %d", resp.StatusCode)
+	}
+}
+
+func TestUploadHandler_ProtocolErrors(t *testing.T) {
+	srv, _ := newUploadServer(t, server.UploadMaxSize(8))
+
+	options := tusRequest(t, http.MethodOptions, srv.URL+"/files/", nil, nil)
+	if options.StatusCode != http.StatusNoContent || !strings.Contains(options.Header.Get("Tus-Extension"), "checksum") || options.Header.Get("Tus-Max-Size") != "8" {
+		t.Fatalf("OPTIONS: got %d %v", options.StatusCode, options.Header)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/files/", nil)
+	req.Header.Set("Upload-Length", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("Tus-Version") != server.TusVersion {
+		t.Fatalf("missing Tus-Resumable: got %d", resp.StatusCode)
+	}
+
+	if resp := tusRequest(t, http.MethodPost, srv.URL+"/files/", nil, map[string]string{"Upload-Length": "9"}); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("too large: got %d", resp.StatusCode)
+	}
+	url := createUpload(t, srv, 4)
+	if resp := patchChunk(t, url, 0, "abcdef", nil); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("chunk beyond length: got %d", resp.StatusCode)
+	}
+	if resp := tusRequest(t, http.MethodPatch, url, strings.NewReader("ab"), map[string]string{"Upload-Offset": "0"}); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("wrong content type: got %d", resp.StatusCode)
+	}
+	if resp := tusRequest(t, http.MethodHead, srv.URL+"/files/missing", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown upload: got %d", resp.StatusCode)
+	}
+
+	if resp := tusRequest(t, http.MethodDelete, url, nil, nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d", resp.StatusCode)
+	}
+	if resp := tusRequest(t, http.MethodHead, url, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("HEAD after DELETE: got %d", resp.StatusCode)
+	}
+}
+
+func TestUploadHandler_CreationWithUpload(t *testing.T) {
+	srv, _ := newUploadServer(t)
+	resp := tusRequest(t, http.MethodPost, srv.URL+"/files/", strings.NewReader("abc"), map[string]string{
+		"Upload-Length": "5",
+		"Content-Type":  server.UploadContentType,
+	})
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Upload-Offset") != "3" {
+		t.Fatalf("create with upload: got %d offset %q", resp.StatusCode, resp.Header.Get("Upload-Offset"))
+	}
+}
+
+func TestUploadHandler_ExpiresIncompleteUploads(t *testing.T) {
+	store, err := server.NewFileUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := server.NewUploadHandler(store, server.UploadExpiry(time.Millisecond))
+	mux := http.NewServeMux()
+	mux.Handle("/files/", h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	url := createUpload(t, srv, 4)
+	createUpload(t, srv, 4)
+	time.Sleep(5 * time.Millisecond)
+
+	if resp := tusRequest(t, http.MethodHead, url, nil, nil); resp.StatusCode != http.StatusGone {
+		t.Fatalf("expired upload: got %d, want 410", resp.StatusCode)
+	}
+	// HEAD does not hold the upload, so it leaves removal to Cleanup.
+	if _, err := store.Info(context.Background(), path.Base(url)); err != nil {
+		t.Fatalf("HEAD removed the expired upload: %v", err)
+	}
+	removed, err := h.Cleanup(context.Background())
+	if err != nil || removed != 2 {
+		t.Fatalf("Cleanup: removed %d, err %v", removed, err)
+	}
+	uploads, err := store.List(context.Background())
+	if err != nil || len(uploads) != 0 {
+		t.Fatalf("uploads after cleanup: %v %v", uploads, err)
+	}
+}
+
+func TestFileUploadStore_RejectsUnsafeIDs(t *testing.T) {
+	store, err := server.NewFileUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "../etc", "a/b", "x.info"} {
+		if _, err := store.Info(context.Background(), id); !errors.Is(err, server.ErrUploadNotFound) {
+			t.Errorf("Info(%q): got %v, want ErrUploadNotFound", id, err)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/internal/backoff"
+	"github.com/dreamsxin/go-kit/v2/transport"
+)
+
+// Dispatcher defaults. Eight attempts starting 30 seconds apart span about
+// an hour before a delivery is dead-lettered.
+const (
+	DefaultMaxAttempts    = 8
+	DefaultAttemptTimeout = 15 * time.Second
+	DefaultPollInterval   = time.Second
+	DefaultConcurrency    = 4
+)
+
+// DeliveryError reports a non-2xx answer from a subscriber.
+type DeliveryError struct {
+	StatusCode int
+}
+
+func (e *DeliveryError) Error() string {
+	return "webhook: subscriber answered " + strconv.Itoa(e.StatusCode)
+}
+
+// DeadLetterFunc receives deliveries that will not be attempted again,
+// together with the error of the last attempt. The delivery is removed from
+// the queue after the function returns.
+type DeadLetterFunc func(ctx context.Context, d Delivery, err error)
+
+// Event is the JSON body Send posts, following the Standard Webhooks
+// payload structure.
+type Event struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher delivers queued events. Each attempt POSTs the payload with
+// Standard Webhooks headers; a 2xx answer acknowledges the delivery. Other
+// answers and transport errors are retried after Backoff until MaxAttempts,
+// except 410 Gone, which dead-letters at once. Calls to a subscriber URL go
+// through its own endpoint.CircuitBreaker; while a breaker is open the
+// deliveries for that URL are postponed without using up attempts.
+type Dispatcher struct {
+	queue          Queue
+	client         *http.Client
+	maxAttempts    int
+	attemptTimeout time.Duration
+	backoff        func(attempt int) time.Duration
+	pollInterval   time.Duration
+	concurrency    int
+	breakerOptions []endpoint.BreakerOption
+	deadLetter     DeadLetterFunc
+	errorHandler   transport.ErrorHandler
+	now            func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]endpoint.Endpoint
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// Option configures a Dispatcher.
+type Option func(*Dispatcher)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		if client != nil {
+			d.client = client
+		}
+	}
+}
+
+// WithMaxAttempts sets the number of attempts before dead-lettering.
+// Default DefaultMaxAttempts.
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		if n > 0 {
+			d.maxAttempts = n
+		}
+	}
+}
+
+// WithAttemptTimeout bounds each POST. Default DefaultAttemptTimeout.
+func WithAttemptTimeout(timeout time.Duration) Option {
+	return func(d *Dispatcher) {
+		if timeout > 0 {
+			d.attemptTimeout = timeout
+		}
+	}
+}
+
+// WithBackoff sets the wait after failed attempt n (1 for the first
+// failure). Default ExponentialBackoff(30*time.Second, 6*time.Hour).
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(d *Dispatcher) {
+		if backoff != nil {
+			d.backoff = backoff
+		}
+	}
+}
+
+// WithPollInterval sets how often Start looks for due deliveries.
+// Default DefaultPollInterval.
+func WithPollInterval(interval time.Duration) Option {
+	return func(d *Dispatcher) {
+		if interval > 0 {
+			d.pollInterval = interval
+		}
+	}
+}
+
+// WithConcurrency sets how many deliveries run at once. Default
+// DefaultConcurrency.
+func WithConcurrency(n int) Option {
+	return func(d *Dispatcher) {
+		if n > 0 {
+			d.concurrency = n
+		}
+	}
+}
+
+// WithBreakerOptions configures the per-URL circuit breakers.
+func WithBreakerOptions(options ...endpoint.BreakerOption) Option {
+	return func(d *Dispatcher) { d.breakerOptions = append(d.breakerOptions, options...) }
+}
+
+// WithDeadLetter sets the function that receives abandoned deliveries.
+func WithDeadLetter(fn DeadLetterFunc) Option {
+	return func(d *Dispatcher) { d.deadLetter = fn }
+}
+
+// WithErrorHandler receives queue failures and dead-lettered delivery errors.
+func WithErrorHandler(errorHandler transport.ErrorHandler) Option {
+	return func(d *Dispatcher) {
+		if errorHandler != nil {
+			d.errorHandler = errorHandler
+		}
+	}
+}
+
+// ExponentialBackoff returns a WithBackoff schedule that waits base before
+// the first retry and twice as long before each later one, never more than
+// maximum, with 20 percent jitter. A maximum of 0 or less leaves the delay
+// uncapped rather than retrying immediately.
+func ExponentialBackoff(base, maximum time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		return backoff.Exponential(base, maximum, 0.2, attempt)
+	}
+}
+
+// NewDispatcher returns a Dispatcher reading from queue. It panics if queue
+// is nil.
+func NewDispatcher(queue Queue, options ...Option) *Dispatcher {
+	if queue == nil {
+		panic("webhook: nil queue")
+	}
+	d := &Dispatcher{
+		queue:          queue,
+		client:         http.DefaultClient,
+		maxAttempts:    DefaultMaxAttempts,
+		attemptTimeout: DefaultAttemptTimeout,
+		backoff:        ExponentialBackoff(30*time.Second, 6*time.Hour),
+		pollInterval:   DefaultPollInterval,
+		concurrency:    DefaultConcurrency,
+		errorHandler:   transport.NopErrorHandler,
+		now:            time.Now,
+		breakers:       make(map[string]endpoint.Endpoint),
+	}
+	for _, option := range options {
+		if option != nil {
+			option(d)
+		}
+	}
+	return d
+}
+
+// Send queues an Event wrapping data for sub and returns the delivery ID.
+func (d *Dispatcher) Send(ctx context.Context, sub Subscriber, eventType string, data any) (string, error) {
+	now := d.now()
+	payload, err := json.Marshal(Event{Type: eventType, Timestamp: now.UTC(), Data: data})
+	if err != nil {
+		return "", fmt.Errorf("webhook: encode event: %w", err)
+	}
+	return d.SendRaw(ctx, sub, eventType, payload)
+}
+
+// SendRaw queues an already encoded JSON payload for sub and returns the
+// delivery ID.
+func (d *Dispatcher) SendRaw(ctx context.Context, sub Subscriber, eventType string, payload []byte) (string, error) {
+	if err := validateSubscriber(sub); err != nil {
+		return "", err
+	}
+	if !json.Valid(payload) {
+		return "", fmt.Errorf("webhook: payload is not valid JSON")
+	}
+	id, err := newDeliveryID()
+	if err != nil {
+		return "", err
+	}
+	now := d.now()
+	delivery := Delivery{
+		ID:          id,
+		Subscriber:  sub,
+		EventType:   eventType,
+		Payload:     json.RawMessage(payload),
+		NextAttempt: now,
+		CreatedAt:   now,
+	}
+	if err := d.queue.Enqueue(ctx, delivery); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ProcessDue delivers the deliveries due when it is called and returns how
+// many were attempted. Start calls it on every poll; tests and cron-style
+// callers may call it directly.
+func (d *Dispatcher) ProcessDue(ctx context.Context) (int, error) {
+	var (
+		now   = d.now()
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, d.concurrency)
+		count int
+	)
+	defer wg.Wait()
+	for ctx.Err() == nil {
+		slots <- struct{}{}
+		delivery, ok, err := d.queue.Next(ctx, now)
+		if err != nil || !ok {
+			<-slots
+			return count, err
+		}
+		count++
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			d.deliver(context.WithoutCancel(ctx), delivery)
+		}()
+	}
+	return count, ctx.Err()
+}
+
+// Start polls the queue in the background until Shutdown.
+func (d *Dispatcher) Start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done != nil {
+		return fmt.Errorf("webhook: dispatcher already started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go d.run(ctx, d.done)
+	return nil
+}
+
+// Errors returns nil: delivery failures go to the dead-letter function and
+// queue failures to the error handler.
+func (d *Dispatcher) Errors() <-chan error { return nil }
+
+// Shutdown stops polling and waits for in-flight attempts until ctx ends.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	cancel, done := d.cancel, d.done
+	d.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.ProcessDue(ctx); err != nil && ctx.Err() == nil {
+			d.errorHandler.Handle(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	_, err := d.breaker(delivery.Subscriber.URL)(ctx, delivery)
+	switch {
+	case err == nil:
+		d.handleQueueError(ctx, d.queue.Ack(ctx, delivery.ID))
+		return
+	case errors.Is(err, endpoint.ErrCircuitOpen):
+		// Postpone without spending an attempt.
+		delivery.NextAttempt = d.now().Add(d.backoff(delivery.Attempts + 1))
+		d.handleQueueError(ctx, d.queue.Retry(ctx, delivery))
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	var status *DeliveryError
+	gone := errors.As(err, &status) && status.StatusCode == http.StatusGone
+	if gone || delivery.Attempts >= d.maxAttempts {
+		if d.deadLetter != nil {
+			d.deadLetter(ctx, delivery, err)
+		}
+		d.errorHandler.Handle(ctx, fmt.Errorf("webhook: delivery %s abandoned after %d attempts: %w", delivery.ID, delivery.Attempts, err))
+		d.handleQueueError(ctx, d.queue.Ack(ctx, delivery.ID))
+		return
+	}
+	delivery.NextAttempt = d.now().Add(d.backoff(delivery.Attempts))
+	d.handleQueueError(ctx, d.queue.Retry(ctx, delivery))
+}
+
+func (d *Dispatcher) handleQueueError(ctx context.Context, err error) {
+	if err != nil {
+		d.errorHandler.Handle(ctx, err)
+	}
+}
+
+// breaker returns the circuit-broken post endpoint for a subscriber URL.
+func (d *Dispatcher) breaker(target string) endpoint.Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ep, ok := d.breakers[target]
+	if !ok {
+		ep = endpoint.NewCircuitBreaker(d.breakerOptions...).Middleware()(d.post)
+		d.breakers[target] = ep
+	}
+	return ep
+}
+
+func (d *Dispatcher) post(ctx context.Context, request any) (any, error) {
+	delivery := request.(Delivery)
+	ctx, cancel := context.WithTimeout(ctx, d.attemptTimeout)
+	defer cancel()
+
+	now := d.now()
+	signature, err := Sign(delivery.Subscriber.Secret, delivery.ID, now, delivery.Payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscriber.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("webhook: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, signature)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &DeliveryError{StatusCode: resp.StatusCode}
+	}
+	return nil, nil
+}
+
+func validateSubscriber(sub Subscriber) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook: subscriber URL %q must be an absolute http(s) URL", sub.URL)
+	}
+	_, err = decodeSecret(sub.Secret)
+	return err
+}
+
+func newDeliveryID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("webhook: generate delivery id: %w", err)
+	}
+	return "msg_" + hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,27 @@
+// Package webhook sends signed events to subscriber URLs and verifies them on
+// the receiving side.
+//
+// Signatures follow the Standard Webhooks scheme: every request carries the
+// webhook-id, webhook-timestamp and webhook-signature headers, and the
+// signature is an HMAC-SHA256 over "id.timestamp.body" keyed with a
+// "whsec_"-prefixed base64 secret. Sign and NewSecret cover the sending side;
+// Verifier checks incoming requests and its Middleware guards a handler.
+//
+// A Dispatcher accepts events with Send, stores them in a Queue and delivers
+// them in the background. Failed attempts are retried with exponential
+// backoff, each subscriber URL has its own endpoint.CircuitBreaker, and
+// deliveries that exhaust their attempts go to a dead-letter function.
+// MemoryQueue keeps deliveries in process; FileQueue keeps them in a
+// directory so they survive restarts. Delivery is at least once: receivers
+// should deduplicate by webhook-id.
+//
+// Dispatcher implements kit's Lifecycle, so it can run alongside a service:
+//
+//	queue, _ := webhook.NewFileQueue("/var/lib/app/webhooks")
+//	dispatcher := webhook.NewDispatcher(queue,
+//	    webhook.WithDeadLetter(func(ctx context.Context, d webhook.Delivery, err error) {
+//	        logger.Error("webhook dropped", "id", d.ID, "err", err)
+//	    }))
+//	svc := kit.MustNew(":8080", kit.WithLifecycle(dispatcher))
+//	id, err := dispatcher.Send(ctx, webhook.Subscriber{URL: url, Secret: secret}, "invoice.paid", invoice)
+package webhook
@@ -0,0 +1,304 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/transport/http/internal/fileid"
+)
+
+// ErrDeliveryNotFound is returned by a Queue for unknown delivery IDs.
+var ErrDeliveryNotFound = errors.New("webhook: delivery not found")
+
+// Subscriber is a receiving endpoint.
+type Subscriber struct {
+	// ID optionally names the subscription for dead-letter handling.
+	ID string `json:"id,omitempty"`
+	// URL receives the POST requests.
+	URL string `json:"url"`
+	// Secret signs the requests; see NewSecret.
+	Secret string `json:"secret"`
+}
+
+// Delivery is one event queued for one subscriber.
+type Delivery struct {
+	// ID is sent as webhook-id and stays the same across attempts.
+	ID         string          `json:"id"`
+	Subscriber Subscriber      `json:"subscriber"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	// Attempts counts the failed delivery attempts so far.
+	Attempts int `json:"attempts"`
+	// NextAttempt is when the delivery is due.
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+	// LastError describes the most recent failure.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Queue stores deliveries until they succeed or are dead-lettered. Next
+// claims a delivery so concurrent callers do not receive it twice; Retry
+// stores its updated state and releases the claim, and Ack removes it.
+// Claims are not durable: a delivery claimed when the process stops is
+// handed out again after a restart.
+type Queue interface {
+	// Enqueue stores a new delivery.
+	Enqueue(ctx context.Context, d Delivery) error
+	// Next claims the unclaimed delivery with the earliest NextAttempt at or
+	// before now. It reports false when none is due.
+	Next(ctx context.Context, now time.Time) (Delivery, bool, error)
+	// Retry replaces a claimed delivery and releases it.
+	Retry(ctx context.Context, d Delivery) error
+	// Ack removes a claimed delivery.
+	Ack(ctx context.Context, id string) error
+}
+
+// MemoryQueue is an in-process Queue. Deliveries are lost when the process
+// exits.
+type MemoryQueue struct {
+	mu         sync.Mutex
+	deliveries map[string]Delivery
+	claimed    map[string]bool
+}
+
+// NewMemoryQueue returns an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{deliveries: make(map[string]Delivery), claimed: make(map[string]bool)}
+}
+
+func (q *MemoryQueue) Enqueue(_ context.Context, d Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.deliveries[d.ID]; ok {
+		return fmt.Errorf("webhook: delivery %s already queued", d.ID)
+	}
+	q.deliveries[d.ID] = d
+	return nil
+}
+
+func (q *MemoryQueue) Next(_ context.Context, now time.Time) (Delivery, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var next Delivery
+	found := false
+	for id, d := range q.deliveries {
+		if q.claimed[id] || d.NextAttempt.After(now) {
+			continue
+		}
+		if !found || d.NextAttempt.Before(next.NextAttempt) {
+			next, found = d, true
+		}
+	}
+	if found {
+		q.claimed[next.ID] = true
+	}
+	return next, found, nil
+}
+
+func (q *MemoryQueue) Retry(_ context.Context, d Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.deliveries[d.ID]; !ok {
+		return ErrDeliveryNotFound
+	}
+	q.deliveries[d.ID] = d
+	delete(q.claimed, d.ID)
+	return nil
+}
+
+func (q *MemoryQueue) Ack(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.deliveries[id]; !ok {
+		return ErrDeliveryNotFound
+	}
+	delete(q.deliveries, id)
+	delete(q.claimed, id)
+	return nil
+}
+
+// Len returns the number of queued deliveries, claimed or not.
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.deliveries)
+}
+
+// FileQueue is a Queue that keeps each delivery as a JSON file in a
+// directory, so pending deliveries survive restarts. Files include the
+// subscriber secret and are created with mode 0600. Next scans the
+// directory, which suits modest backlogs. A file that no longer decodes is
+// renamed with a .corrupt suffix for inspection and reported once, so it
+// does not block the deliveries queued after it.
+type FileQueue struct {
+	dir string
+
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+// NewFileQueue returns a queue in dir, creating the directory if needed.
+func NewFileQueue(dir string) (*FileQueue, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("webhook: queue directory cannot be empty")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("webhook: queue: %w", err)
+	}
+	return &FileQueue{dir: dir, claimed: make(map[string]bool)}, nil
+}
+
+func (q *FileQueue) Enqueue(_ context.Context, d Delivery) error {
+	path, err := q.path(d.ID)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("webhook: delivery %s already queued", d.ID)
+	}
+	return q.write(path, d)
+}
+
+func (q *FileQueue) Next(_ context.Context, now time.Time) (Delivery, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return Delivery{}, false, fmt.Errorf("webhook: queue: %w", err)
+	}
+	var next Delivery
+	var corrupt error
+	found := false
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || q.claimed[id] {
+			continue
+		}
+		path := filepath.Join(q.dir, entry.Name())
+		d, err := q.read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		var decodeErr *decodeError
+		if errors.As(err, &decodeErr) {
+			corrupt = errors.Join(corrupt, q.setAside(path, err))
+			continue
+		}
+		if err != nil {
+			return Delivery{}, false, err
+		}
+		if d.NextAttempt.After(now) {
+			continue
+		}
+		if !found || d.NextAttempt.Before(next.NextAttempt) {
+			next, found = d, true
+		}
+	}
+	if corrupt != nil {
+		// Report before claiming anything; the files are out of the way, so
+		// the next call proceeds normally.
+		return Delivery{}, false, corrupt
+	}
+	if found {
+		q.claimed[next.ID] = true
+	}
+	return next, found, nil
+}
+
+// setAside renames an undecodable delivery file so later scans skip it.
+func (q *FileQueue) setAside(path string, err error) error {
+	if renameErr := os.Rename(path, path+".corrupt"); renameErr != nil {
+		return errors.Join(err, fmt.Errorf("webhook: set aside %s: %w", filepath.Base(path), renameErr))
+	}
+	return fmt.Errorf("%w (moved to %s.corrupt)", err, filepath.Base(path))
+}
+
+func (q *FileQueue) Retry(_ context.Context, d Delivery) error {
+	path, err := q.path(d.ID)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return ErrDeliveryNotFound
+	}
+	if err := q.write(path, d); err != nil {
+		return err
+	}
+	delete(q.claimed, d.ID)
+	return nil
+}
+
+func (q *FileQueue) Ack(_ context.Context, id string) error {
+	path, err := q.path(id)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.claimed, id)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrDeliveryNotFound
+		}
+		return fmt.Errorf("webhook: ack %s: %w", id, err)
+	}
+	return nil
+}
+
+func (q *FileQueue) path(id string) (string, error) {
+	if !fileid.Valid(id) {
+		return "", fmt.Errorf("webhook: invalid delivery id %q", id)
+	}
+	return filepath.Join(q.dir, id+".json"), nil
+}
+
+func (q *FileQueue) read(path string) (Delivery, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Delivery{}, err
+	}
+	var d Delivery
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return Delivery{}, &decodeError{file: filepath.Base(path), err: err}
+	}
+	return d, nil
+}
+
+// decodeError reports a queue file that is not a valid delivery.
+type decodeError struct {
+	file string
+	err  error
+}
+
+func (e *decodeError) Error() string { return "webhook: decode " + e.file + ": " + e.err.Error() }
+
+func (e *decodeError) Unwrap() error { return e.err }
+
+// write replaces path atomically so a crash never leaves a partial file.
+func (q *FileQueue) write(path string, d Delivery) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("webhook: encode %s: %w", d.ID, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("webhook: write %s: %w", d.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("webhook: write %s: %w", d.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport/http/webhook"
+)
+
+func TestSign_StandardWebhooksVector(t *testing.T) {
+	got, err := webhook.Sign("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", "msg_p5jXN8AQM9LWM0D4loKWxJek", time.Unix(1614265330, 0), []byte(`{"test": 2432232314}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="; got != want {
+		t.Fatalf("Sign = %q, want %q", got, want)
+	}
+}
+
+func signedHeader(t *testing.T, secret, id string, at time.Time, body string) http.Header {
+	t.Helper()
+	signature, err := webhook.Sign(secret, id, at, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set(webhook.HeaderID, id)
+	header.Set(webhook.HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+	header.Set(webhook.HeaderSignature, signature)
+	return header
+}
+
+func TestVerifier_AcceptsRotatedSecretsAndRejectsTampering(t *testing.T) {
+	oldSecret, _ := webhook.NewSecret()
+	newSecret, _ := webhook.NewSecret()
+	v, err := webhook.NewVerifier([]string{newSecret, oldSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"type":"invoice.paid"}`
+	if err := v.Verify(signedHeader(t, oldSecret, "msg_1", time.Now(), body), []byte(body)); err != nil {
+		t.Fatalf("old secret: %v", err)
+	}
+
+	header := signedHeader(t, newSecret, "msg_1", time.Now(), body)
+	header.Set(webhook.HeaderSignature, "v1,bm9wZQ== "+header.Get(webhook.HeaderSignature))
+	if err := v.Verify(header, []byte(body)); err != nil {
+		t.Fatalf("signature list: %v", err)
+	}
+	if err := v.Verify(header, []byte(`{"type":"invoice.void"}`)); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("tampered body: got %v", err)
+	}
+	stale := signedHeader(t, newSecret, "msg_1", time.Now().Add(-10*time.Minute), body)
+	if err := v.Verify(stale, []byte(body)); !errors.Is(err, webhook.ErrInvalidTimestamp) {
+		t.Fatalf("stale timestamp: got %v", err)
+	}
+	if err := v.Verify(http.Header{}, []byte(body)); !errors.Is(err, webhook.ErrMissingHeaders) {
+		t.Fatalf("missing headers: got %v", err)
+	}
+	if _, err := webhook.NewVerifier([]string{"whsec_!!"}); err == nil {
+		t.Fatal("expected error for malformed secret")
+	}
+}
+
+func TestVerifier_Middleware(t *testing.T) {
+	secret, _ := webhook.NewSecret()
+	v, _ := webhook.NewVerifier([]string{secret}, webhook.VerifyMaxBodyBytes(64))
+	h := v.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	body := `{"ok":true}`
+	req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+	req.Header = signedHeader(t, secret, "msg_1", time.Now(), body)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != body {
+		t.Fatalf("verified request: got %d %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(`{"ok":false}`))
+	req.Header = signedHeader(t, secret, "msg_1", time.Now(), body)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "webhook.invalid_signature") {
+		t.Fatalf("forged request: got %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(strings.Repeat("x", 65)))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: got %d", w.Code)
+	}
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	secret, _ := webhook.NewSecret()
+	v, _ := webhook.NewVerifier([]string{secret})
+	var calls atomic.Int32
+	received := make(chan webhook.Event, 1)
+	srv := httptest.NewServer(v.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event webhook.Event
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		received <- event
+	})))
+	defer srv.Close()
+
+	queue := webhook.NewMemoryQueue()
+	d := webhook.NewDispatcher(queue, webhook.WithBackoff(func(int) time.Duration { return 0 }))
+	if _, err := d.Send(context.Background(), webhook.Subscriber{URL: srv.URL, Secret: secret}, "invoice.paid", map[string]int{"amount": 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.ProcessDue(context.Background()); n != 1 || err != nil {
+		t.Fatalf("first pass: %d %v", n, err)
+	}
+	if queue.Len() != 1 {
+		t.Fatal("failed delivery was not kept for retry")
+	}
+	if n, err := d.ProcessDue(context.Background()); n != 1 || err != nil {
+		t.Fatalf("second pass: %d %v", n, err)
+	}
+	event := <-received
+	if event.Type != "invoice.paid" || event.Data.(map[string]any)["amount"] != float64(42) {
+		t.Fatalf("event = %+v", event)
+	}
+	if queue.Len() != 0 {
+		t.Fatal("delivered message still queued")
+	}
+}
+
+func TestDispatcher_DeadLettersAndBreaksCircuit(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	secret, _ := webhook.NewSecret()
+	var (
+		mu   sync.Mutex
+		dead []webhook.Delivery
+	)
+	queue := webhook.NewMemoryQueue()
+	d := webhook.NewDispatcher(queue,
+		webhook.WithMaxAttempts(2),
+		webhook.WithConcurrency(1),
+		webhook.WithBackoff(func(int) time.Duration { return 0 }),
+		webhook.WithBreakerOptions(endpoint.WithBreakerFailureThreshold(3), endpoint.WithBreakerOpenTimeout(time.Hour)),
+		webhook.WithDeadLetter(func(_ context.Context, delivery webhook.Delivery, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dead = append(dead, delivery)
+		}),
+	)
+	sub := webhook.Subscriber{URL: srv.URL, Secret: secret}
+	for range 2 {
+		if _, err := d.SendRaw(context.Background(), sub, "ping", []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range 3 {
+		if _, err := d.ProcessDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Three failures open the breaker, so the fourth attempt never leaves.
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("subscriber calls = %d, want 3", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dead) != 1 || dead[0].Attempts != 2 || !strings.Contains(dead[0].LastError, "500") {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+	if queue.Len() != 1 {
+		t.Fatalf("postponed delivery not kept: %d queued", queue.Len())
+	}
+}
+
+func TestDispatcher_GoneDeadLettersImmediately(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	secret, _ := webhook.NewSecret()
+	var deadErr error
+	d := webhook.NewDispatcher(webhook.NewMemoryQueue(), webhook.WithDeadLetter(func(_ context.Context, _ webhook.Delivery, err error) {
+		deadErr = err
+	}))
+	if _, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: srv.URL, Secret: secret}, "ping", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = d.ProcessDue(context.Background())
+	var status *webhook.DeliveryError
+	if !errors.As(deadErr, &status) || status.StatusCode != http.StatusGone {
+		t.Fatalf("dead letter error = %v", deadErr)
+	}
+
+	if _, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: "ftp://example.com", Secret: secret}, "ping", []byte(`{}`)); err == nil {
+		t.Fatal("expected error for non-HTTP subscriber URL")
+	}
+	if _, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: srv.URL, Secret: secret}, "ping", []byte(`{`)); err == nil {
+		t.Fatal("expected error for invalid JSON payload")
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := webhook.ExponentialBackoff(time.Second, time.Minute)
+	if got := backoff(1); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+		t.Fatalf("first delay = %v, want 1s +/- 20%%", got)
+	}
+	if got := backoff(30); got > time.Minute {
+		t.Fatalf("capped delay = %v, want <= 1m", got)
+	}
+	uncapped := webhook.ExponentialBackoff(time.Second, 0)
+	if got := uncapped(4); got < 6400*time.Millisecond {
+		t.Fatalf("uncapped delay = %v, want about 8s", got)
+	}
+}
+
+func TestFileQueue_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := webhook.NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := webhook.NewSecret()
+	d := webhook.NewDispatcher(queue)
+	id, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: "http://127.0.0.1:1/hook", Secret: secret}, "ping", []byte(`{"n":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := queue.Next(context.Background(), time.Now()); !ok {
+		t.Fatal("queued delivery not due")
+	}
+	if _, ok, _ := queue.Next(context.Background(), time.Now()); ok {
+		t.Fatal("claimed delivery handed out twice")
+	}
+
+	reopened, err := webhook.NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery, ok, err := reopened.Next(context.Background(), time.Now())
+	if err != nil || !ok || delivery.ID != id || string(delivery.Payload) != `{"n":1}` {
+		t.Fatalf("after restart: %+v %v %v", delivery, ok, err)
+	}
+	delivery.Attempts = 1
+	delivery.NextAttempt = time.Now().Add(time.Hour)
+	if err := reopened.Retry(context.Background(), delivery); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := reopened.Next(context.Background(), time.Now()); ok {
+		t.Fatal("postponed delivery handed out early")
+	}
+	if err := reopened.Ack(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Ack(context.Background(), id); !errors.Is(err, webhook.ErrDeliveryNotFound) {
+		t.Fatalf("second Ack: got %v", err)
+	}
+}
+
+func TestFileQueue_SetsAsideCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := webhook.NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret, _ := webhook.NewSecret()
+	d := webhook.NewDispatcher(queue)
+	id, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: "http://127.0.0.1:1/hook", Secret: secret}, "ping", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := queue.Next(context.Background(), time.Now()); ok || err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("first Next: ok %v, err %v; want the corrupt file reported", ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "broken.json.corrupt")); err != nil {
+		t.Fatalf("corrupt file not set aside: %v", err)
+	}
+	delivery, ok, err := queue.Next(context.Background(), time.Now())
+	if err != nil || !ok || delivery.ID != id {
+		t.Fatalf("second Next: %+v %v %v", delivery, ok, err)
+	}
+}
+
+func TestDispatcher_Lifecycle(t *testing.T) {
+	delivered := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(delivered)
+	}))
+	defer srv.Close()
+
+	secret, _ := webhook.NewSecret()
+	d := webhook.NewDispatcher(webhook.NewMemoryQueue(), webhook.WithPollInterval(5*time.Millisecond))
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err == nil {
+		t.Fatal("expected error for second Start")
+	}
+	if _, err := d.SendRaw(context.Background(), webhook.Subscriber{URL: srv.URL, Secret: secret}, "ping", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("delivery not attempted")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,167 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// ErrMessageTooLarge is returned when a response body or stream line exceeds
+// the configured message size.
+var ErrMessageTooLarge = errors.New("jsonrpc: message too large")
+
+// Client calls methods on a remote JSON-RPC server. It is safe for
+// concurrent use. Remote failures are returned as *Error values.
+type Client struct {
+	conn   clientConn
+	nextID atomic.Uint64
+}
+
+// clientConn sends one encoded message or batch and returns the responses
+// for ids, which are the encoded ids of the requests that expect one.
+type clientConn interface {
+	roundTrip(ctx context.Context, payload []byte, ids []string) ([]Response, error)
+	close() error
+}
+
+func newClient(conn clientConn) *Client {
+	return &Client{conn: conn}
+}
+
+// Call invokes method with params and decodes the result into result, which
+// may be nil to discard it. params must encode to a JSON object or array, or
+// be nil.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	call := &Call{Method: method, Params: params, Result: result}
+	if err := c.Batch(ctx, call); err != nil {
+		return err
+	}
+	return call.Err
+}
+
+// Notify sends method as a notification. The server sends no response, so
+// only transport failures are reported.
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	return c.Batch(ctx, &Call{Method: method, Params: params, Notify: true})
+}
+
+// Call is one entry of a batch. After Batch returns without error, Err holds
+// the outcome of the call and Result its decoded result.
+type Call struct {
+	Method string
+	Params any
+	Result any
+	Notify bool
+	Err    error
+}
+
+// Batch sends calls in one message; a single call is sent without the batch
+// array. The returned error reports transport failures; per-call errors are
+// stored in each Call's Err.
+func (c *Client) Batch(ctx context.Context, calls ...*Call) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	requests := make([]Request, len(calls))
+	byID := make(map[string]*Call, len(calls))
+	var ids []string
+	for i, call := range calls {
+		req := Request{JSONRPC: Version, Method: call.Method}
+		if call.Params != nil {
+			params, err := json.Marshal(call.Params)
+			if err != nil {
+				return fmt.Errorf("jsonrpc: encode params of %s: %w", call.Method, err)
+			}
+			if !validParams(params) || string(params) == "null" {
+				return fmt.Errorf("jsonrpc: params of %s must encode to a JSON object or array", call.Method)
+			}
+			req.Params = params
+		}
+		if !call.Notify {
+			id := strconv.FormatUint(c.nextID.Add(1), 10)
+			req.ID = json.RawMessage(id)
+			ids = append(ids, id)
+			byID[id] = call
+		}
+		requests[i] = req
+	}
+
+	var payload []byte
+	var err error
+	if len(requests) == 1 {
+		payload, err = json.Marshal(requests[0])
+	} else {
+		payload, err = json.Marshal(requests)
+	}
+	if err != nil {
+		return fmt.Errorf("jsonrpc: encode request: %w", err)
+	}
+
+	responses, err := c.conn.roundTrip(ctx, payload, ids)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		call, ok := byID[string(resp.ID)]
+		if !ok {
+			continue
+		}
+		delete(byID, string(resp.ID))
+		switch {
+		case resp.Error != nil:
+			call.Err = resp.Error
+		case call.Result != nil:
+			if err := json.Unmarshal(resp.Result, call.Result); err != nil {
+				call.Err = fmt.Errorf("jsonrpc: decode result of %s: %w", call.Method, err)
+			}
+		}
+	}
+	for _, call := range byID {
+		call.Err = fmt.Errorf("jsonrpc: no response for %s", call.Method)
+	}
+	return nil
+}
+
+// Close releases the underlying connection. It is a no-op for HTTP clients.
+func (c *Client) Close() error { return c.conn.close() }
+
+// ClientEndpoint adapts one remote method to an endpoint.Endpoint. The
+// request is sent as params and the result is decoded into a Resp value.
+//
+// Example:
+//
+//	add := jsonrpc.ClientEndpoint[AddResponse](client, "math.add")
+//	add = endpoint.NewBuilder(add).WithTimeout(time.Second).Build()
+func ClientEndpoint[Resp any](c *Client, method string) endpoint.Endpoint {
+	if c == nil {
+		panic("jsonrpc: client cannot be nil")
+	}
+	return func(ctx context.Context, request any) (any, error) {
+		var resp Resp
+		if err := c.Call(ctx, method, request, &resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// decodeResponses parses a single response or a batch of responses.
+func decodeResponses(payload []byte) ([]Response, error) {
+	if isBatch(payload) {
+		var responses []Response
+		if err := json.Unmarshal(payload, &responses); err != nil {
+			return nil, fmt.Errorf("jsonrpc: decode response: %w", err)
+		}
+		return responses, nil
+	}
+	var resp Response
+	if err := json.Unmarshal(payload, &resp); err != nil {
+		return nil, fmt.Errorf("jsonrpc: decode response: %w", err)
+	}
+	return []Response{resp}, nil
+}
@@ -0,0 +1,21 @@
+// Package jsonrpc serves and calls endpoints over JSON-RPC 2.0.
+//
+// A Server maps method names to endpoint.Endpoint values. HandleEndpoint and
+// HandleTyped decode params into the request type; Server.Handle takes a
+// custom decoder. The same Server answers HTTP POST requests through
+// ServeHTTP and newline-delimited streams, such as pipes or TCP
+// connections, through ServeStream. Batches and notifications follow the
+// specification: notifications get no response, and a batch of only
+// notifications produces no output.
+//
+// Errors use the standard codes. Invalid params and validation failures are
+// CodeInvalidParams; unclassified errors are CodeInternalError and never
+// expose their text. Other apperror kinds are CodeServerError. Classified
+// errors carry their kind and application code in the error data, as
+// ErrorData, so a Client turns them back into errors that satisfy
+// apperror.Kinder.
+//
+// Client calls a remote server over HTTP (NewHTTPClient) or over a stream
+// (NewStreamClient), and ClientEndpoint adapts one remote method to an
+// endpoint.Endpoint for use with endpoint middleware.
+package jsonrpc
@@ -0,0 +1,78 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// ErrorFor returns the JSON-RPC error the server sends when an endpoint
+// fails with err:
+//
+//   - An *Error is sent as-is.
+//   - endpoint.ValidationError and the apperror invalid_argument kind are
+//     CodeInvalidParams; validation failures list their fields in the data.
+//   - The endpoint rejection errors (ErrBackpressure, ErrBulkheadFull,
+//     ErrCircuitOpen, ErrRateLimited) are CodeServerError with the
+//     resource_exhausted kind and the rejection's own message.
+//   - Other apperror kinds are CodeServerError, except internal, which is
+//     CodeInternalError.
+//   - Unclassified errors are CodeInternalError with a generic message.
+//
+// Classified errors carry an ErrorData with their kind, application code,
+// and the request ID from ctx. The message is the public message when the
+// error has one.
+func ErrorFor(ctx context.Context, err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	requestID := endpoint.RequestIDFromContext(ctx)
+
+	var verr *endpoint.ValidationError
+	if errors.As(err, &verr) {
+		data := ErrorData{Kind: apperror.KindInvalidArgument, Code: "validation", RequestID: requestID}
+		for _, field := range verr.Fields {
+			data.Fields = append(data.Fields, FieldError{Field: field.Field, Reason: field.Reason})
+		}
+		return NewError(CodeInvalidParams, verr.Error(), data)
+	}
+	for _, rejection := range []error{endpoint.ErrBackpressure, endpoint.ErrBulkheadFull, endpoint.ErrCircuitOpen, endpoint.ErrRateLimited} {
+		if errors.Is(err, rejection) {
+			// Send the sentinel's text, not err's, which may wrap details.
+			return NewError(CodeServerError, rejection.Error(), ErrorData{Kind: apperror.KindResourceExhausted, RequestID: requestID})
+		}
+	}
+
+	var kinder apperror.Kinder
+	if !errors.As(err, &kinder) {
+		return NewError(CodeInternalError, "internal error", nil)
+	}
+	kind := kinder.ErrorKind()
+	data := ErrorData{Kind: kind, RequestID: requestID}
+	var coder interface{ ErrorCode() string }
+	if errors.As(err, &coder) {
+		data.Code = coder.ErrorCode()
+	}
+
+	code := CodeServerError
+	message := string(kind)
+	switch kind {
+	case apperror.KindInvalidArgument:
+		code, message = CodeInvalidParams, "invalid params"
+	case apperror.KindInternal:
+		code, message = CodeInternalError, "internal error"
+	}
+	var pm interface{ PublicMessage() string }
+	if errors.As(err, &pm) && pm.PublicMessage() != "" {
+		message = pm.PublicMessage()
+	}
+	return NewError(code, message, data)
+}
+
+// CodeForError returns the JSON-RPC error code ErrorFor uses for err.
+func CodeForError(err error) int {
+	return ErrorFor(context.Background(), err).Code
+}
@@ -0,0 +1,151 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ContentType is the media type of JSON-RPC messages over HTTP.
+const ContentType = "application/json"
+
+// ServeHTTP answers JSON-RPC over HTTP: one POST carries one message or
+// batch. Responses are 200 with the JSON-RPC response, or 204 when only
+// notifications were sent. JSON-RPC errors, including parse errors, are
+// reported in the response body with status 200.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxMessageBytes))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	resp := s.Serve(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = w.Write(resp)
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	httpClient      *http.Client
+	header          http.Header
+	maxMessageBytes int64
+}
+
+func newClientConfig(options []ClientOption) clientConfig {
+	cfg := clientConfig{
+		httpClient:      http.DefaultClient,
+		header:          make(http.Header),
+		maxMessageBytes: DefaultMaxMessageBytes,
+	}
+	for _, option := range options {
+		if option != nil {
+			option(&cfg)
+		}
+	}
+	return cfg
+}
+
+// ClientHTTPClient sets the *http.Client NewHTTPClient sends requests with.
+// The default is http.DefaultClient.
+func ClientHTTPClient(client *http.Client) ClientOption {
+	return func(cfg *clientConfig) {
+		if client != nil {
+			cfg.httpClient = client
+		}
+	}
+}
+
+// ClientHeader adds a header, such as Authorization, to every HTTP request.
+func ClientHeader(key, value string) ClientOption {
+	return func(cfg *clientConfig) { cfg.header.Add(key, value) }
+}
+
+// ClientMaxMessageBytes bounds one HTTP response body or stream line. The
+// default is DefaultMaxMessageBytes; non-positive values are ignored.
+func ClientMaxMessageBytes(n int64) ClientOption {
+	return func(cfg *clientConfig) {
+		if n > 0 {
+			cfg.maxMessageBytes = n
+		}
+	}
+}
+
+// NewHTTPClient returns a Client that POSTs each call or batch to url.
+func NewHTTPClient(url string, options ...ClientOption) *Client {
+	cfg := newClientConfig(options)
+	return newClient(&httpConn{url: url, cfg: cfg})
+}
+
+type httpConn struct {
+	url string
+	cfg clientConfig
+}
+
+func (c *httpConn) roundTrip(ctx context.Context, payload []byte, ids []string) ([]Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: build request: %w", err)
+	}
+	for key, values := range c.cfg.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Accept", ContentType)
+
+	resp, err := c.cfg.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		if len(ids) > 0 {
+			return nil, fmt.Errorf("jsonrpc: server sent no response")
+		}
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		return nil, fmt.Errorf("jsonrpc: unexpected HTTP status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.cfg.maxMessageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: read response: %w", err)
+	}
+	if int64(len(body)) > c.cfg.maxMessageBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrMessageTooLarge, c.cfg.maxMessageBytes)
+	}
+	responses, err := decodeResponses(body)
+	if err != nil {
+		return nil, err
+	}
+	// A server that failed to parse the request answers with a null id;
+	// attribute it to the only call that was waiting.
+	if len(ids) == 1 && len(responses) == 1 && (responses[0].ID == nil || string(responses[0].ID) == "null") {
+		responses[0].ID = []byte(ids[0])
+	}
+	return responses, nil
+}
+
+func (c *httpConn) close() error { return nil }
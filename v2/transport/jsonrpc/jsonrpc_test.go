@@ -0,0 +1,371 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport"
+	"github.com/dreamsxin/go-kit/v2/transport/jsonrpc"
+)
+
+type addRequest struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type addResponse struct {
+	Sum int `json:"sum"`
+}
+
+func newTestServer(notified *atomic.Int32) *jsonrpc.Server {
+	srv := jsonrpc.NewServer()
+	jsonrpc.HandleTyped(srv, "math.add", func(_ context.Context, req addRequest) (addResponse, error) {
+		return addResponse{Sum: req.A + req.B}, nil
+	})
+	jsonrpc.HandleEndpoint[[]int](srv, "math.sum", func(_ context.Context, request any) (any, error) {
+		total := 0
+		for _, n := range request.([]int) {
+			total += n
+		}
+		return total, nil
+	})
+	jsonrpc.HandleEndpoint[struct{}](srv, "users.get", func(context.Context, any) (any, error) {
+		return nil, apperror.New(apperror.KindNotFound, "user_not_found", "user not found")
+	})
+	jsonrpc.HandleEndpoint[struct{}](srv, "users.create", func(context.Context, any) (any, error) {
+		return nil, endpoint.NewValidationError("name", "is required")
+	})
+	jsonrpc.HandleEndpoint[struct{}](srv, "crash", func(context.Context, any) (any, error) {
+		return nil, errors.New("database password leaked")
+	})
+	srv.Handle("log", func(context.Context, any) (any, error) {
+		if notified != nil {
+			notified.Add(1)
+		}
+		return nil, nil
+	}, nil)
+	return srv
+}
+
+func TestServer_ServeFollowsSpecification(t *testing.T) {
+	srv := newTestServer(nil)
+	cases := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{
+			name:    "named params",
+			payload: `{"jsonrpc":"2.0","method":"math.add","params":{"a":1,"b":2},"id":1}`,
+			want:    `{"jsonrpc":"2.0","id":1,"result":{"sum":3}}`,
+		},
+		{
+			name:    "positional params",
+			payload: `{"jsonrpc":"2.0","method":"math.sum","params":[1,2,3],"id":"a"}`,
+			want:    `{"jsonrpc":"2.0","id":"a","result":6}`,
+		},
+		{
+			name:    "method not found",
+			payload: `{"jsonrpc":"2.0","method":"nope","id":2}`,
+			want:    `{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"method not found","data":{"method":"nope"}}}`,
+		},
+		{
+			name:    "invalid params",
+			payload: `{"jsonrpc":"2.0","method":"math.add","params":[1,2],"id":3}`,
+			want:    `{"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"invalid params","data":{"kind":"invalid_argument"}}}`,
+		},
+		{
+			name:    "parse error",
+			payload: `{"jsonrpc":"2.0","method":"math.add",`,
+			want:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		},
+		{
+			name:    "invalid request",
+			payload: `{"jsonrpc":"1.0","method":"math.add","id":4}`,
+			want:    `{"jsonrpc":"2.0","id":4,"error":{"code":-32600,"message":"invalid request"}}`,
+		},
+		{
+			name:    "empty batch",
+			payload: `[]`,
+			want:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}`,
+		},
+		{
+			name:    "notification",
+			payload: `{"jsonrpc":"2.0","method":"log","params":["hi"]}`,
+			want:    ``,
+		},
+		{
+			name:    "batch of notifications",
+			payload: `[{"jsonrpc":"2.0","method":"log"},{"jsonrpc":"2.0","method":"nope"}]`,
+			want:    ``,
+		},
+		{
+			name:    "mixed batch",
+			payload: `[{"jsonrpc":"2.0","method":"math.add","params":{"a":2,"b":2},"id":1},{"jsonrpc":"2.0","method":"log"},1]`,
+			want:    `[{"jsonrpc":"2.0","id":1,"result":{"sum":4}},{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}]`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(srv.Serve(context.Background(), []byte(tc.payload)))
+			if got != tc.want {
+				t.Fatalf("Serve:\n got  %s\n want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServer_KeepsDecoderErrorsFromThePeer(t *testing.T) {
+	var handled error
+	srv := jsonrpc.NewServer(jsonrpc.ServerErrorHandler(transport.ErrorHandlerFunc(func(_ context.Context, err error) { handled = err })))
+	srv.Handle("users.find", func(context.Context, any) (any, error) { return nil, nil }, func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("lookup table users_v2 missing")
+	})
+	got := string(srv.Serve(context.Background(), []byte(`{"jsonrpc":"2.0","method":"users.find","params":{},"id":1}`)))
+	if strings.Contains(got, "users_v2") || !strings.Contains(got, `"message":"invalid params"`) {
+		t.Fatalf("response exposes decoder detail: %s", got)
+	}
+	if handled == nil || !strings.Contains(handled.Error(), "users_v2") {
+		t.Fatalf("error handler got %v, want the decoder detail", handled)
+	}
+}
+
+func TestErrorFor_MapsApplicationErrors(t *testing.T) {
+	ctx := endpoint.WithRequestID(context.Background(), "req-1")
+	cases := []struct {
+		name        string
+		err         error
+		wantCode    int
+		wantMessage string
+		wantData    string
+	}{
+		{"apperror", apperror.New(apperror.KindNotFound, "user_not_found", "user not found"), jsonrpc.CodeServerError, "user not found", `{"kind":"not_found","code":"user_not_found","request_id":"req-1"}`},
+		{"invalid argument", apperror.New(apperror.KindInvalidArgument, "bad_email", ""), jsonrpc.CodeInvalidParams, "invalid params", `{"kind":"invalid_argument","code":"bad_email","request_id":"req-1"}`},
+		{"validation", endpoint.NewValidationError("name", "is required"), jsonrpc.CodeInvalidParams, "invalid request: name: is required", `{"kind":"invalid_argument","code":"validation","fields":[{"field":"name","reason":"is required"}],"request_id":"req-1"}`},
+		{"rate limited", endpoint.ErrRateLimited, jsonrpc.CodeServerError, "rate limit exceeded", `{"kind":"resource_exhausted","request_id":"req-1"}`},
+		{"wrapped rejection", fmt.Errorf("tenant 42 pool db-3: %w", endpoint.ErrBackpressure), jsonrpc.CodeServerError, endpoint.ErrBackpressure.Error(), `{"kind":"resource_exhausted","request_id":"req-1"}`},
+		{"unclassified", errors.New("secret"), jsonrpc.CodeInternalError, "internal error", ``},
+		{"rpc error", jsonrpc.NewError(-32050, "custom", []int{1}), -32050, "custom", `[1]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := jsonrpc.ErrorFor(ctx, tc.err)
+			if got.Code != tc.wantCode || got.Message != tc.wantMessage || string(got.Data) != tc.wantData {
+				t.Fatalf("ErrorFor = %d %q %s, want %d %q %s", got.Code, got.Message, got.Data, tc.wantCode, tc.wantMessage, tc.wantData)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_CallsBatchesAndClassifiesErrors(t *testing.T) {
+	var notified atomic.Int32
+	ts := httptest.NewServer(newTestServer(&notified))
+	defer ts.Close()
+	client := jsonrpc.NewHTTPClient(ts.URL)
+	ctx := context.Background()
+
+	var sum addResponse
+	if err := client.Call(ctx, "math.add", addRequest{A: 40, B: 2}, &sum); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if sum.Sum != 42 {
+		t.Fatalf("sum = %d, want 42", sum.Sum)
+	}
+
+	err := client.Call(ctx, "users.get", map[string]string{"id": "7"}, nil)
+	var kinder apperror.Kinder
+	if !errors.As(err, &kinder) || kinder.ErrorKind() != apperror.KindNotFound {
+		t.Fatalf("Call error = %v, want not_found kind", err)
+	}
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != "user_not_found" || rpcErr.PublicMessage() != "user not found" {
+		t.Fatalf("Call error = %#v, want user_not_found", err)
+	}
+
+	err = client.Call(ctx, "crash", nil, nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInternalError || strings.Contains(err.Error(), "password") {
+		t.Fatalf("Call error = %v, want redacted internal error", err)
+	}
+
+	var total int
+	calls := []*jsonrpc.Call{
+		{Method: "math.sum", Params: []int{1, 2, 3}, Result: &total},
+		{Method: "log", Params: []string{"batched"}, Notify: true},
+		{Method: "users.create", Params: map[string]string{}},
+	}
+	if err := client.Batch(ctx, calls...); err != nil {
+		t.Fatalf("Batch: %v", err)
+	}
+	if calls[0].Err != nil || total != 6 {
+		t.Fatalf("batch sum = %d, %v; want 6", total, calls[0].Err)
+	}
+	var data jsonrpc.ErrorData
+	if !errors.As(calls[2].Err, &rpcErr) || rpcErr.DecodeData(&data) != nil || len(data.Fields) != 1 || data.Fields[0].Field != "name" {
+		t.Fatalf("batch validation error = %v, data %+v", calls[2].Err, data)
+	}
+
+	if err := client.Notify(ctx, "log", nil); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if notified.Load() != 2 {
+		t.Fatalf("notifications handled = %d, want 2", notified.Load())
+	}
+}
+
+func TestServeHTTP_StatusCodes(t *testing.T) {
+	srv := newTestServer(nil)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rpc", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET status = %d, want 405", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"log"}`)))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("notification status = %d body %q, want 204 and no body", rec.Code, rec.Body)
+	}
+
+	small := jsonrpc.NewServer(jsonrpc.ServerMaxMessageBytes(16))
+	rec = httptest.NewRecorder()
+	small.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"log","id":1}`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized status = %d, want 413", rec.Code)
+	}
+}
+
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+func TestStreamClient_ConcurrentCallsOverPipes(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	srv := newTestServer(nil)
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.ServeStream(context.Background(), serverIn, serverOut)
+		_ = serverOut.Close()
+	}()
+
+	client := jsonrpc.NewStreamClient(pipeConn{Reader: clientIn, WriteCloser: clientOut})
+	add := jsonrpc.ClientEndpoint[addResponse](client, "math.add")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := add(ctx, addRequest{A: i, B: i})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := resp.(addResponse).Sum; got != 2*i {
+				errs <- errors.New("wrong sum")
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("call: %v", err)
+	}
+
+	err := client.Call(context.Background(), "users.get", nil, nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorKind() != apperror.KindNotFound {
+		t.Fatalf("Call error = %v, want not_found", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("ServeStream: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeStream did not return after the client closed")
+	}
+	if err := client.Call(context.Background(), "math.add", addRequest{}, nil); err == nil {
+		t.Fatal("Call after Close succeeded")
+	}
+}
+
+func TestStreamClient_FailsCallsOnUnmatchedResponses(t *testing.T) {
+	for _, tc := range []struct {
+		name, reply string
+		check       func(error) bool
+	}{
+		{"undecodable", "not json", func(err error) bool { return strings.Contains(err.Error(), "invalid response") }},
+		{"null id error", `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`, func(err error) bool {
+			var rpcErr *jsonrpc.Error
+			return errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc.CodeParseError
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			serverIn, clientOut := io.Pipe()
+			clientIn, serverOut := io.Pipe()
+			go func() {
+				// Answer the first message with the reply, whatever it was.
+				buf := make([]byte, 4<<10)
+				if _, err := serverIn.Read(buf); err == nil {
+					_, _ = io.WriteString(serverOut, tc.reply+"\n")
+				}
+			}()
+			client := jsonrpc.NewStreamClient(pipeConn{Reader: clientIn, WriteCloser: clientOut})
+			defer client.Close()
+
+			errc := make(chan error, 1)
+			go func() { errc <- client.Call(context.Background(), "math.add", addRequest{}, nil) }()
+			select {
+			case err := <-errc:
+				if err == nil || !tc.check(err) {
+					t.Fatalf("Call error = %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Call still waiting after the reply")
+			}
+		})
+	}
+}
+
+func TestServeStream_WritesOneResponsePerLine(t *testing.T) {
+	in := strings.NewReader(`{"jsonrpc":"2.0","method":"math.add","params":{"a":1,"b":1},"id":1}` + "\n\n" +
+		`{"jsonrpc":"2.0","method":"log"}` + "\n" +
+		`not json` + "\n")
+	var out strings.Builder
+	srv := newTestServer(nil)
+	if err := srv.ServeStream(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStream: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d response lines, want 2: %q", len(lines), out.String())
+	}
+	for _, line := range lines {
+		var resp jsonrpc.Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+	}
+}
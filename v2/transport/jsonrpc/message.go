@@ -0,0 +1,143 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+)
+
+// Version is the value of the "jsonrpc" member of every message.
+const Version = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification. Codes from -32000
+// to -32099 are reserved for implementation-defined server errors;
+// CodeServerError is the one this package uses for classified application
+// errors.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// Request is one JSON-RPC request or notification. A nil ID marks a
+// notification; a JSON null ID is a request whose response has a null ID.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Notification reports whether the request expects no response.
+func (r Request) Notification() bool { return r.ID == nil }
+
+// Response is one JSON-RPC response. Exactly one of Result and Error is set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object. It implements error, and endpoints may
+// return one to control the code, message, and data sent to the client.
+//
+// On the client side, errors whose data is an ErrorData expose its kind and
+// code through ErrorKind and ErrorCode, and their message through
+// PublicMessage, so they classify like the apperror.Error that produced
+// them.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// ErrorData is the data member the server attaches to classified errors.
+type ErrorData struct {
+	Kind      apperror.Kind `json:"kind"`
+	Code      string        `json:"code,omitempty"`
+	Fields    []FieldError  `json:"fields,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// FieldError is one invalid params field reported by an
+// endpoint.ValidationError.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// NewError returns an Error with data marshaled from data. A nil data or one
+// that cannot be marshaled is omitted.
+func NewError(code int, message string, data any) *Error {
+	e := &Error{Code: code, Message: message}
+	if data != nil {
+		if raw, err := json.Marshal(data); err == nil {
+			e.Data = raw
+		}
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return "jsonrpc: " + e.Message + " (" + strconv.Itoa(e.Code) + ")"
+}
+
+// DecodeData unmarshals the error data into v.
+func (e *Error) DecodeData(v any) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
+// ErrorKind returns the apperror kind carried in the data. Errors without
+// one classify by code: CodeInvalidParams and CodeInvalidRequest as
+// invalid_argument, CodeMethodNotFound as not_found, and the rest as
+// internal.
+func (e *Error) ErrorKind() apperror.Kind {
+	if data, ok := e.errorData(); ok && data.Kind != "" {
+		return data.Kind
+	}
+	switch e.Code {
+	case CodeInvalidParams, CodeInvalidRequest:
+		return apperror.KindInvalidArgument
+	case CodeMethodNotFound:
+		return apperror.KindNotFound
+	default:
+		return apperror.KindInternal
+	}
+}
+
+// ErrorCode returns the application error code carried in the data.
+func (e *Error) ErrorCode() string {
+	data, _ := e.errorData()
+	return data.Code
+}
+
+// PublicMessage returns the error message, which the server only fills with
+// text that is safe to expose.
+func (e *Error) PublicMessage() string { return e.Message }
+
+func (e *Error) errorData() (ErrorData, bool) {
+	var data ErrorData
+	if len(e.Data) == 0 || e.Data[0] != '{' {
+		return data, false
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return ErrorData{}, false
+	}
+	return data, true
+}
+
+// isBatch reports whether payload is a JSON array, ignoring leading
+// whitespace.
+func isBatch(payload []byte) bool {
+	trimmed := bytes.TrimLeft(payload, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
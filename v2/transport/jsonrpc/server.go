@@ -0,0 +1,286 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport"
+)
+
+// DefaultMaxMessageBytes bounds one HTTP request body or stream line.
+const DefaultMaxMessageBytes = 1 << 20
+
+// DefaultStreamConcurrency bounds the requests ServeStream runs at once on
+// one stream.
+const DefaultStreamConcurrency = 16
+
+// DecodeParamsFunc turns the raw params member into the endpoint request.
+// params is nil when the request has no params.
+type DecodeParamsFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches JSON-RPC requests to endpoints by method name.
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]method
+
+	errorHandler      transport.ErrorHandler
+	errorMapper       func(ctx context.Context, err error) *Error
+	maxMessageBytes   int64
+	streamConcurrency int
+}
+
+type method struct {
+	ep     endpoint.Endpoint
+	decode DecodeParamsFunc
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// ServerErrorHandler sets the handler that receives endpoint errors,
+// including errors from notifications, which have no response to carry
+// them. The default discards them.
+func ServerErrorHandler(errorHandler transport.ErrorHandler) ServerOption {
+	return func(s *Server) {
+		if errorHandler != nil {
+			s.errorHandler = errorHandler
+		}
+	}
+}
+
+// ServerErrorMapper replaces ErrorFor as the function that turns endpoint
+// errors into JSON-RPC errors. Return nil to fall back to ErrorFor.
+func ServerErrorMapper(mapper func(ctx context.Context, err error) *Error) ServerOption {
+	return func(s *Server) { s.errorMapper = mapper }
+}
+
+// ServerMaxMessageBytes bounds one HTTP request body or stream line. The
+// default is DefaultMaxMessageBytes; non-positive values are ignored.
+func ServerMaxMessageBytes(n int64) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.maxMessageBytes = n
+		}
+	}
+}
+
+// ServerStreamConcurrency bounds the requests ServeStream runs at once on
+// one stream. The default is DefaultStreamConcurrency; use 1 to handle
+// requests strictly in order.
+func ServerStreamConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.streamConcurrency = n
+		}
+	}
+}
+
+// NewServer returns a Server with no methods.
+func NewServer(options ...ServerOption) *Server {
+	s := &Server{
+		methods:           make(map[string]method),
+		errorHandler:      transport.NopErrorHandler,
+		maxMessageBytes:   DefaultMaxMessageBytes,
+		streamConcurrency: DefaultStreamConcurrency,
+	}
+	for _, option := range options {
+		if option != nil {
+			option(s)
+		}
+	}
+	return s
+}
+
+// Handle registers ep for name. decode turns params into the endpoint
+// request; when it is nil the endpoint receives the raw json.RawMessage.
+// Handle panics when name is empty, uses the reserved "rpc." prefix, or is
+// already registered, and when ep is nil.
+func (s *Server) Handle(name string, ep endpoint.Endpoint, decode DecodeParamsFunc) {
+	if name == "" || strings.HasPrefix(name, "rpc.") {
+		panic(fmt.Sprintf("jsonrpc: invalid method name %q", name))
+	}
+	if ep == nil {
+		panic("jsonrpc: endpoint cannot be nil")
+	}
+	if decode == nil {
+		decode = func(_ context.Context, params json.RawMessage) (any, error) { return params, nil }
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.methods[name]; exists {
+		panic(fmt.Sprintf("jsonrpc: method %q already registered", name))
+	}
+	s.methods[name] = method{ep: ep, decode: decode}
+}
+
+// HandleEndpoint registers ep for name and decodes params into a Req value.
+// Named params decode into struct fields; positional params decode only when
+// Req is a slice or array. Missing params leave Req at its zero value.
+//
+// Example:
+//
+//	jsonrpc.HandleEndpoint[AddRequest](srv, "math.add", makeAddEndpoint(svc))
+func HandleEndpoint[Req any](s *Server, name string, ep endpoint.Endpoint) {
+	s.Handle(name, ep, DecodeParams[Req])
+}
+
+// HandleTyped registers a TypedEndpoint for name, like HandleEndpoint.
+func HandleTyped[Req, Resp any](s *Server, name string, ep endpoint.TypedEndpoint[Req, Resp]) {
+	if ep == nil {
+		panic("jsonrpc: endpoint cannot be nil")
+	}
+	HandleEndpoint[Req](s, name, ep.Wrap())
+}
+
+// DecodeParams is the DecodeParamsFunc HandleEndpoint uses: it unmarshals
+// params into a Req value.
+func DecodeParams[Req any](_ context.Context, params json.RawMessage) (any, error) {
+	var req Req
+	if len(params) == 0 {
+		return req, nil
+	}
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Serve handles one JSON-RPC payload, a single message or a batch, and
+// returns the encoded response. It returns nil when nothing is due, which is
+// the case for notifications and batches made only of notifications.
+func (s *Server) Serve(ctx context.Context, payload []byte) []byte {
+	if !isBatch(payload) {
+		resp, ok := s.handle(ctx, payload)
+		if !ok {
+			return nil
+		}
+		return encodeResponse(resp)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(payload, &items); err != nil {
+		return encodeResponse(errorResponse(nil, NewError(CodeParseError, "parse error", nil)))
+	}
+	if len(items) == 0 {
+		return encodeResponse(errorResponse(nil, NewError(CodeInvalidRequest, "invalid request", nil)))
+	}
+	responses := make([]Response, 0, len(items))
+	for _, item := range items {
+		if resp, ok := s.handle(ctx, item); ok {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	out, err := json.Marshal(responses)
+	if err != nil {
+		return encodeResponse(errorResponse(nil, NewError(CodeInternalError, "internal error", nil)))
+	}
+	return out
+}
+
+// handle processes one request object and reports whether a response is
+// due.
+func (s *Server) handle(ctx context.Context, raw json.RawMessage) (Response, bool) {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		if !json.Valid(raw) {
+			return errorResponse(nil, NewError(CodeParseError, "parse error", nil)), true
+		}
+		return errorResponse(nil, NewError(CodeInvalidRequest, "invalid request", nil)), true
+	}
+	if string(req.Params) == "null" {
+		req.Params = nil
+	}
+	if req.JSONRPC != Version || req.Method == "" || !validID(req.ID) || !validParams(req.Params) {
+		id := req.ID
+		if !validID(id) {
+			id = nil
+		}
+		return errorResponse(id, NewError(CodeInvalidRequest, "invalid request", nil)), true
+	}
+
+	result, err := s.call(ctx, req)
+	if req.Notification() {
+		if err != nil {
+			s.errorHandler.Handle(ctx, err)
+		}
+		return Response{}, false
+	}
+	if err != nil {
+		s.errorHandler.Handle(ctx, err)
+		return errorResponse(req.ID, s.mapError(ctx, err)), true
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		s.errorHandler.Handle(ctx, fmt.Errorf("jsonrpc: encode result of %s: %w", req.Method, err))
+		return errorResponse(req.ID, NewError(CodeInternalError, "internal error", nil)), true
+	}
+	return Response{JSONRPC: Version, ID: req.ID, Result: encoded}, true
+}
+
+func (s *Server) call(ctx context.Context, req Request) (any, error) {
+	s.mu.RLock()
+	m, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, NewError(CodeMethodNotFound, "method not found", map[string]string{"method": req.Method})
+	}
+	request, err := m.decode(ctx, req.Params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		// The decoder's text may describe internals; the peer gets the fixed
+		// invalid params message and the error handler gets the detail.
+		return nil, apperror.Wrap(apperror.KindInvalidArgument, "", "", fmt.Errorf("jsonrpc: decode params of %s: %w", req.Method, err))
+	}
+	return m.ep(ctx, request)
+}
+
+func (s *Server) mapError(ctx context.Context, err error) *Error {
+	if s.errorMapper != nil {
+		if rpcErr := s.errorMapper(ctx, err); rpcErr != nil {
+			return rpcErr
+		}
+	}
+	return ErrorFor(ctx, err)
+}
+
+func errorResponse(id json.RawMessage, err *Error) Response {
+	return Response{JSONRPC: Version, ID: id, Error: err}
+}
+
+func encodeResponse(resp Response) []byte {
+	out, err := json.Marshal(resp)
+	if err != nil {
+		out, _ = json.Marshal(errorResponse(resp.ID, NewError(CodeInternalError, "internal error", nil)))
+	}
+	return out
+}
+
+// validID accepts a missing id, a string, a number, or null.
+func validID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	switch c := id[0]; {
+	case c == '"', c == '-', c >= '0' && c <= '9':
+		return true
+	default:
+		return string(id) == "null"
+	}
+}
+
+// validParams accepts missing params, an object, or an array.
+func validParams(params json.RawMessage) bool {
+	return params == nil || params[0] == '{' || params[0] == '['
+}
@@ -0,0 +1,199 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ServeStream reads newline-delimited JSON-RPC messages from r and writes
+// each response on its own line to w. Requests run concurrently, up to the
+// stream concurrency limit, so responses may arrive out of order and are
+// matched by id. It returns after r reaches EOF and in-flight requests
+// finish, or when a read or write fails. Lines longer than the maximum
+// message size end the stream with ErrMessageTooLarge.
+//
+// Example serving stdin and stdout:
+//
+//	err := srv.ServeStream(ctx, os.Stdin, os.Stdout)
+func (s *Server) ServeStream(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(s.maxMessageBytes))
+
+	var (
+		wg       sync.WaitGroup
+		writeMu  sync.Mutex
+		writeErr error
+		slots    = make(chan struct{}, s.streamConcurrency)
+	)
+	for ctx.Err() == nil && scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		payload := append([]byte(nil), line...)
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			resp := s.Serve(ctx, payload)
+			if resp == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			if writeErr == nil {
+				_, writeErr = w.Write(append(resp, '\n'))
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		err = fmt.Errorf("%w: limit is %d bytes", ErrMessageTooLarge, s.maxMessageBytes)
+	}
+	return errors.Join(err, writeErr)
+}
+
+// NewStreamClient returns a Client that exchanges newline-delimited messages
+// over conn, such as a TCP connection or a subprocess's pipes. Calls may be
+// in flight concurrently; responses are matched by id. An error with a null
+// id, which a server sends for a message it could not parse, answers every
+// pending call, and a line that is not a response fails the connection.
+// Close closes conn.
+func NewStreamClient(conn io.ReadWriteCloser, options ...ClientOption) *Client {
+	cfg := newClientConfig(options)
+	sc := &streamConn{
+		conn:    conn,
+		pending: make(map[string]chan Response),
+		done:    make(chan struct{}),
+	}
+	go sc.readLoop(cfg.maxMessageBytes)
+	return newClient(sc)
+}
+
+type streamConn struct {
+	conn    io.ReadWriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan Response
+	done    chan struct{}
+	err     error
+}
+
+func (c *streamConn) roundTrip(ctx context.Context, payload []byte, ids []string) ([]Response, error) {
+	waits := make([]chan Response, len(ids))
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	for i, id := range ids {
+		waits[i] = make(chan Response, 1)
+		c.pending[id] = waits[i]
+	}
+	c.mu.Unlock()
+	defer c.forget(ids)
+
+	c.writeMu.Lock()
+	_, err := c.conn.Write(append(payload, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("jsonrpc: write: %w", err)
+	}
+
+	responses := make([]Response, 0, len(ids))
+	for _, wait := range waits {
+		select {
+		case resp := <-wait:
+			responses = append(responses, resp)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, c.err
+		}
+	}
+	return responses, nil
+}
+
+func (c *streamConn) forget(ids []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.pending, id)
+	}
+}
+
+func (c *streamConn) readLoop(maxMessageBytes int64) {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 64<<10), int(maxMessageBytes))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		responses, err := decodeResponses(line)
+		if err != nil {
+			// The peer does not speak the protocol; nothing later on the
+			// stream can be trusted to answer the pending calls.
+			c.fail(fmt.Errorf("jsonrpc: invalid response: %w", err))
+			return
+		}
+		c.mu.Lock()
+		for _, resp := range responses {
+			if resp.ID == nil || string(resp.ID) == "null" {
+				c.deliverUnattributedLocked(resp)
+				continue
+			}
+			if wait, ok := c.pending[string(resp.ID)]; ok {
+				wait <- resp
+				delete(c.pending, string(resp.ID))
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	err := scanner.Err()
+	switch {
+	case errors.Is(err, bufio.ErrTooLong):
+		err = fmt.Errorf("%w: limit is %d bytes", ErrMessageTooLarge, maxMessageBytes)
+	case err == nil:
+		err = io.EOF
+	}
+	c.fail(fmt.Errorf("jsonrpc: connection closed: %w", err))
+}
+
+// deliverUnattributedLocked answers every pending call with a null-id error,
+// which a server sends when it cannot parse a message and so cannot tell
+// which request it belonged to. Null-id responses without an error are
+// ignored.
+func (c *streamConn) deliverUnattributedLocked(resp Response) {
+	if resp.Error == nil {
+		return
+	}
+	for id, wait := range c.pending {
+		resp.ID = json.RawMessage(id)
+		wait <- resp
+		delete(c.pending, id)
+	}
+}
+
+// fail records err as the connection error and releases all waiting calls.
+func (c *streamConn) fail(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *streamConn) close() error { return c.conn.Close() }
@@ -0,0 +1,42 @@
+package websocket
+
+import "encoding/json"
+
+// Codec marshals stream values into WebSocket messages and back.
+type Codec interface {
+	// MessageType is the frame type used for encoded messages.
+	MessageType() MessageType
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type funcCodec struct {
+	messageType MessageType
+	marshal     func(any) ([]byte, error)
+	unmarshal   func([]byte, any) error
+}
+
+func (c funcCodec) MessageType() MessageType           { return c.messageType }
+func (c funcCodec) Marshal(v any) ([]byte, error)      { return c.marshal(v) }
+func (c funcCodec) Unmarshal(data []byte, v any) error { return c.unmarshal(data, v) }
+
+// NewCodec returns a Codec that writes messages of messageType with marshal
+// and reads them with unmarshal. It panics when messageType is not a data
+// message type or a function is nil.
+//
+// Any transport/http/codec value adapts directly:
+//
+//	msgpack := websocket.NewCodec(websocket.BinaryMessage, codec.MessagePack.Marshal, codec.MessagePack.Unmarshal)
+func NewCodec(messageType MessageType, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) Codec {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		panic("websocket: codec message type must be TextMessage or BinaryMessage")
+	}
+	if marshal == nil || unmarshal == nil {
+		panic("websocket: codec marshal and unmarshal cannot be nil")
+	}
+	return funcCodec{messageType: messageType, marshal: marshal, unmarshal: unmarshal}
+}
+
+// JSON encodes values as text messages with encoding/json. It is the default
+// codec.
+var JSON = NewCodec(TextMessage, json.Marshal, json.Unmarshal)
@@ -0,0 +1,431 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType identifies the payload type of a data message.
+type MessageType int
+
+// Data message types defined by RFC 6455.
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Close codes defined by RFC 6455 section 7.4.1 and the IANA registry.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+	CloseTryAgainLater    = 1013
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	maxControlPayload = 125
+	maxCloseReason    = maxControlPayload - 2
+)
+
+var (
+	// ErrClosed is returned when writing to a connection whose close
+	// handshake has started, and by Stream methods after the stream ended.
+	ErrClosed = errors.New("websocket: connection closed")
+
+	// ErrProtocol is wrapped by errors for frames that violate RFC 6455.
+	ErrProtocol = errors.New("websocket: protocol error")
+
+	// ErrMessageTooBig is returned when a message exceeds the configured
+	// maximum size.
+	ErrMessageTooBig = errors.New("websocket: message too big")
+
+	// ErrInvalidMessage is wrapped by errors for text messages that are not
+	// valid UTF-8 and for messages the codec cannot decode.
+	ErrInvalidMessage = errors.New("websocket: invalid message")
+)
+
+// CloseError reports the close frame received from the peer. Stream
+// endpoints may also return a *CloseError to choose the close code and
+// reason sent to the client.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return "websocket: close " + strconv.Itoa(e.Code)
+	}
+	return "websocket: close " + strconv.Itoa(e.Code) + ": " + e.Reason
+}
+
+// Conn is a WebSocket connection created by Upgrade or Dial.
+//
+// ReadMessage must not be called concurrently with itself. Write methods are
+// safe for concurrent use and may run alongside ReadMessage.
+type Conn struct {
+	conn            net.Conn
+	br              *bufio.Reader
+	client          bool
+	subprotocol     string
+	maxMessageBytes int64
+	writeTimeout    time.Duration
+
+	// onPong runs on the reading goroutine for every pong received.
+	onPong func()
+
+	writeMu   sync.Mutex
+	closeSent bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool, cfg *config) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{
+		conn:            conn,
+		br:              br,
+		client:          client,
+		maxMessageBytes: cfg.maxMessageBytes,
+		writeTimeout:    cfg.writeTimeout,
+	}
+}
+
+// Subprotocol returns the negotiated Sec-WebSocket-Protocol, or "" when none
+// was agreed.
+func (c *Conn) Subprotocol() string { return c.subprotocol }
+
+// RemoteAddr returns the network address of the peer.
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetReadDeadline sets the deadline for reading the next frame. A zero value
+// disables the deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// Close closes the underlying network connection without a close handshake.
+// Call WriteClose first for a clean shutdown.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() { c.closeErr = c.conn.Close() })
+	return c.closeErr
+}
+
+// ReadMessage reads the next data message, reassembling fragments. Pings are
+// answered and pongs consumed while reading. When the peer closes the
+// connection, the close frame is echoed and a *CloseError is returned.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		messageType MessageType
+		message     []byte
+		fragmented  bool
+	)
+	for {
+		h, err := c.readHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if h.opcode >= opClose {
+			payload := make([]byte, h.length)
+			if err := c.readPayload(h, payload); err != nil {
+				return 0, nil, err
+			}
+			switch h.opcode {
+			case opPing:
+				if err := c.writeFrame(opPong, payload); err != nil && !errors.Is(err, ErrClosed) {
+					return 0, nil, err
+				}
+			case opPong:
+				if c.onPong != nil {
+					c.onPong()
+				}
+			case opClose:
+				return 0, nil, c.handleClose(payload)
+			default:
+				return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown control opcode %#x", h.opcode))
+			}
+			continue
+		}
+
+		switch h.opcode {
+		case opContinuation:
+			if !fragmented {
+				return 0, nil, c.fail(CloseProtocolError, "continuation frame without a message")
+			}
+		case opText, opBinary:
+			if fragmented {
+				return 0, nil, c.fail(CloseProtocolError, "data frame inside a fragmented message")
+			}
+			messageType = MessageType(h.opcode)
+			fragmented = true
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown data opcode %#x", h.opcode))
+		}
+
+		if c.maxMessageBytes > 0 && int64(len(message))+h.length > c.maxMessageBytes {
+			c.closeWith(CloseMessageTooBig, "message too big")
+			return 0, nil, fmt.Errorf("%w: limit is %d bytes", ErrMessageTooBig, c.maxMessageBytes)
+		}
+		start := len(message)
+		message = append(message, make([]byte, h.length)...)
+		if err := c.readPayload(h, message[start:]); err != nil {
+			return 0, nil, err
+		}
+
+		if h.fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				c.closeWith(CloseInvalidPayload, "invalid UTF-8")
+				return 0, nil, fmt.Errorf("%w: text message is not valid UTF-8", ErrInvalidMessage)
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// WriteMessage writes one unfragmented data message.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return c.writeFrame(byte(messageType), data)
+}
+
+// WritePing writes a ping control frame. The payload may be at most 125
+// bytes.
+func (c *Conn) WritePing(data []byte) error {
+	if len(data) > maxControlPayload {
+		return fmt.Errorf("websocket: ping payload exceeds %d bytes", maxControlPayload)
+	}
+	return c.writeFrame(opPing, data)
+}
+
+// WriteClose starts the close handshake by sending a close frame. The reason
+// is truncated to fit a control frame. Later calls are no-ops, and data
+// writes fail with ErrClosed afterwards.
+func (c *Conn) WriteClose(code int, reason string) error {
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		reason = truncateReason(reason)
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	return c.writeFrameLocked(opClose, payload)
+}
+
+type frameHeader struct {
+	fin    bool
+	opcode byte
+	length int64
+	masked bool
+	mask   [4]byte
+}
+
+func (c *Conn) readHeader() (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+	h.fin = b[0]&0x80 != 0
+	h.opcode = b[0] & 0x0f
+	h.masked = b[1]&0x80 != 0
+	if b[0]&0x70 != 0 {
+		return h, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if h.masked == c.client {
+		if c.client {
+			return h, c.fail(CloseProtocolError, "masked frame from server")
+		}
+		return h, c.fail(CloseProtocolError, "unmasked frame from client")
+	}
+
+	switch length := b[1] & 0x7f; length {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n>>63 != 0 {
+			return h, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		h.length = int64(n)
+	default:
+		h.length = int64(length)
+	}
+
+	if h.opcode >= opClose && (!h.fin || h.length > maxControlPayload) {
+		return h, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if h.masked {
+		if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+func (c *Conn) readPayload(h frameHeader, payload []byte) error {
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return err
+	}
+	if h.masked {
+		maskBytes(h.mask, payload)
+	}
+	return nil
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	code := CloseNoStatusReceived
+	reason := ""
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		reason = string(payload[2:])
+		if !validCloseCode(code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(reason) {
+			return c.fail(CloseInvalidPayload, "invalid close reason")
+		}
+	}
+	echo := code
+	if echo == CloseNoStatusReceived {
+		echo = CloseNormalClosure
+	}
+	_ = c.WriteClose(echo, "")
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// fail sends a close frame for a protocol violation, closes the connection,
+// and returns an error wrapping ErrProtocol or ErrInvalidMessage.
+func (c *Conn) fail(code int, reason string) error {
+	c.closeWith(code, reason)
+	if code == CloseInvalidPayload {
+		return fmt.Errorf("%w: %s", ErrInvalidMessage, reason)
+	}
+	return fmt.Errorf("%w: %s", ErrProtocol, reason)
+}
+
+func (c *Conn) closeWith(code int, reason string) {
+	_ = c.WriteClose(code, reason)
+	_ = c.Close()
+}
+
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	return c.writeFrameLocked(opcode, payload)
+}
+
+func (c *Conn) writeFrameLocked(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return fmt.Errorf("websocket: generate mask: %w", err)
+		}
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		maskBytes(mask, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	if c.writeTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i&3]
+	}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	default:
+		return false
+	}
+}
+
+func truncateReason(reason string) string {
+	if len(reason) <= maxCloseReason {
+		return reason
+	}
+	reason = reason[:maxCloseReason]
+	for len(reason) > 0 && !utf8.ValidString(reason) {
+		reason = reason[:len(reason)-1]
+	}
+	return reason
+}
@@ -0,0 +1,19 @@
+// Package websocket serves bidirectional message streams over RFC 6455
+// WebSocket connections.
+//
+// The protocol implementation uses only the standard library: Upgrade takes
+// over an HTTP/1.1 connection through http.ResponseController, and Dial
+// opens client connections. Conn exposes whole messages; fragmentation,
+// masking, control frames, and the close handshake are handled internally.
+//
+// Server adapts a StreamEndpoint to http.Handler. Each connection gets a
+// reader and a writer goroutine, a bounded send queue that disconnects slow
+// consumers instead of buffering without limit, and ping/pong keepalive that
+// detects dead peers. Messages are encoded by a Codec, chosen per connection
+// through subprotocol negotiation. FromEndpoint serves a plain
+// endpoint.Endpoint with one response message per request message.
+//
+// Errors returned by a StreamEndpoint close the connection with a code
+// derived from the error: apperror kinds, endpoint rejection errors, and
+// *CloseError values map as documented on CloseCodeForError.
+package websocket
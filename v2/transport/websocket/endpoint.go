@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// ErrorMessage is the message FromEndpoint sends in place of a response when
+// a request fails.
+type ErrorMessage struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes a failed request. Code is the application error code
+// or the apperror kind; Message is the public message, and internal errors
+// never expose their text.
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FromEndpoint adapts ep to a StreamEndpoint that decodes each incoming
+// message as a Req, calls ep, and sends the response as one message.
+// Requests are handled one at a time, so responses arrive in request order.
+//
+// Endpoint errors and undecodable messages are answered with an ErrorMessage
+// and the connection stays open. The stream ends when the client closes the
+// connection or a response cannot be sent.
+//
+// Example:
+//
+//	srv := websocket.NewServer(websocket.FromEndpoint[QuoteRequest](makeQuoteEndpoint(svc)))
+func FromEndpoint[Req any](ep endpoint.Endpoint) StreamEndpoint {
+	if ep == nil {
+		panic("websocket: endpoint cannot be nil")
+	}
+	return func(ctx context.Context, stream Stream) error {
+		for {
+			var req Req
+			if err := stream.Recv(&req); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if !errors.Is(err, ErrInvalidMessage) {
+					return err
+				}
+				if err := stream.Send(NewErrorMessage(ctx, err)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			response, err := ep(ctx, req)
+			if err != nil {
+				response = NewErrorMessage(ctx, err)
+			}
+			if err := stream.Send(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// NewErrorMessage builds the ErrorMessage FromEndpoint sends for err. Stream
+// endpoints that report per-message failures themselves can reuse it to keep
+// the same shape.
+func NewErrorMessage(ctx context.Context, err error) ErrorMessage {
+	code, reason := closeFor(err)
+	detail := ErrorDetail{Code: reason, RequestID: endpoint.RequestIDFromContext(ctx)}
+	if detail.Code == "" {
+		detail.Code = "internal"
+	}
+
+	var pm publicMessager
+	switch {
+	case errors.As(err, &pm) && pm.PublicMessage() != "":
+		detail.Message = pm.PublicMessage()
+	case code == CloseInternalError:
+		detail.Message = "internal error"
+	default:
+		detail.Message = err.Error()
+	}
+	return ErrorMessage{Error: detail}
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+)
+
+// CloseCodeForError returns the close code a Server sends when a stream
+// endpoint returns err:
+//
+//   - nil and io.EOF close with CloseNormalClosure, and context.Canceled
+//     with CloseGoingAway.
+//   - A *CloseError keeps its own code.
+//   - ErrProtocol, ErrInvalidMessage, and ErrMessageTooBig map to
+//     CloseProtocolError, CloseInvalidPayload, and CloseMessageTooBig;
+//     ErrSlowConsumer maps to ClosePolicyViolation.
+//   - The endpoint rejection errors (ErrBackpressure, ErrBulkheadFull,
+//     ErrCircuitOpen, ErrRateLimited) map to CloseTryAgainLater.
+//   - apperror kinds map as follows: invalid_argument to CloseInvalidPayload;
+//     unauthenticated, permission_denied, not_found, already_exists,
+//     conflict, and failed_precondition to ClosePolicyViolation;
+//     resource_exhausted and unavailable to CloseTryAgainLater.
+//   - Everything else closes with CloseInternalError.
+func CloseCodeForError(err error) int {
+	code, _ := closeFor(err)
+	return code
+}
+
+// closeFor returns the close code and a machine-readable reason safe to show
+// to clients: the application error code when there is one, otherwise a
+// fixed code. Error text is never exposed.
+func closeFor(err error) (int, string) {
+	if err == nil || errors.Is(err, io.EOF) {
+		return CloseNormalClosure, ""
+	}
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code, closeErr.Reason
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CloseGoingAway, "going_away"
+	case errors.Is(err, ErrProtocol):
+		return CloseProtocolError, "protocol_error"
+	case errors.Is(err, ErrInvalidMessage):
+		return CloseInvalidPayload, "invalid_message"
+	case errors.Is(err, ErrMessageTooBig):
+		return CloseMessageTooBig, "message_too_big"
+	case errors.Is(err, ErrSlowConsumer):
+		return ClosePolicyViolation, "slow_consumer"
+	case errors.Is(err, endpoint.ErrBackpressure), errors.Is(err, endpoint.ErrBulkheadFull),
+		errors.Is(err, endpoint.ErrCircuitOpen), errors.Is(err, endpoint.ErrRateLimited):
+		return CloseTryAgainLater, "try_again_later"
+	}
+
+	var kinder apperror.Kinder
+	if !errors.As(err, &kinder) {
+		return CloseInternalError, "internal"
+	}
+	kind := kinder.ErrorKind()
+	reason := string(kind)
+	var coder errorCoder
+	if errors.As(err, &coder) && coder.ErrorCode() != "" {
+		reason = coder.ErrorCode()
+	}
+	switch kind {
+	case apperror.KindInvalidArgument:
+		return CloseInvalidPayload, reason
+	case apperror.KindUnauthenticated, apperror.KindPermissionDenied, apperror.KindNotFound,
+		apperror.KindAlreadyExists, apperror.KindConflict, apperror.KindFailedPrecondition:
+		return ClosePolicyViolation, reason
+	case apperror.KindResourceExhausted, apperror.KindUnavailable:
+		return CloseTryAgainLater, reason
+	default:
+		return CloseInternalError, reason
+	}
+}
+
+type errorCoder interface {
+	ErrorCode() string
+}
+
+type publicMessager interface {
+	PublicMessage() string
+}
@@ -0,0 +1,287 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError reports a rejected opening handshake. On the server the
+// matching HTTP response has already been written when Upgrade returns it.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: handshake failed: " + e.Message
+}
+
+// StatusCode returns the HTTP status of the rejected handshake.
+func (e *HandshakeError) StatusCode() int { return e.Status }
+
+// Upgrade validates the opening handshake of r and takes over the underlying
+// HTTP/1.1 connection. Options that only apply to Server or Dial are
+// ignored.
+//
+// Cross-origin requests are rejected with 403 unless WithCheckOrigin allows
+// them; browsers do not apply the same-origin policy to WebSocket.
+func Upgrade(w http.ResponseWriter, r *http.Request, options ...Option) (*Conn, error) {
+	cfg := newConfig(options)
+	return upgrade(w, r, &cfg)
+}
+
+func upgrade(w http.ResponseWriter, r *http.Request, cfg *config) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, rejectHandshake(w, http.StatusMethodNotAllowed, "method must be GET")
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, rejectHandshake(w, http.StatusBadRequest, "missing upgrade headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, rejectHandshake(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, rejectHandshake(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := cfg.checkOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, rejectHandshake(w, http.StatusForbidden, "origin not allowed")
+	}
+	subprotocol := selectSubprotocol(cfg.subprotocols, headerTokens(r.Header, "Sec-WebSocket-Protocol"))
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, rejectHandshake(w, http.StatusInternalServerError, "connection does not support hijacking")
+	}
+	// Clear deadlines inherited from http.Server timeouts; the connection
+	// now lives as long as the WebSocket session.
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b.WriteString(acceptKey(key))
+	b.WriteString("\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+
+	conn := newConn(netConn, brw.Reader, false, cfg)
+	conn.subprotocol = subprotocol
+	return conn, nil
+}
+
+// Dial opens a client connection to a ws:// or wss:// URL. The subprotocols
+// registered with WithSubprotocol are offered in registration order; use
+// Conn.Subprotocol to see which one the server accepted.
+func Dial(ctx context.Context, rawURL string, options ...Option) (*Conn, error) {
+	cfg := newConfig(options)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: parse URL: %w", err)
+	}
+	var secure bool
+	switch u.Scheme {
+	case "ws":
+	case "wss":
+		secure = true
+	default:
+		return nil, fmt.Errorf("websocket: unsupported URL scheme %q", u.Scheme)
+	}
+	address := u.Host
+	if u.Port() == "" {
+		if secure {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var netConn net.Conn
+	if secure {
+		tlsConfig := cfg.tlsConfig.Clone()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		netConn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		netConn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("websocket: dial: %w", err)
+	}
+
+	conn, err := clientHandshake(ctx, netConn, u, &cfg)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func clientHandshake(ctx context.Context, netConn net.Conn, u *url.URL, cfg *config) (*Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := netConn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = netConn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("websocket: generate key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	httpURL := *u
+	httpURL.Scheme = strings.Replace(u.Scheme, "ws", "http", 1)
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &httpURL,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     cfg.header.Clone(),
+		Host:       u.Host,
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(cfg.subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(cfg.subprotocols, ", "))
+	}
+	if err := req.Write(netConn); err != nil {
+		return nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("websocket: read handshake: %w", ctxErr)
+		}
+		return nil, fmt.Errorf("websocket: read handshake: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		_ = resp.Body.Close()
+		return nil, &HandshakeError{Status: resp.StatusCode, Message: "unexpected status " + resp.Status}
+	}
+	if !headerContainsToken(resp.Header, "Upgrade", "websocket") || !headerContainsToken(resp.Header, "Connection", "upgrade") {
+		return nil, &HandshakeError{Status: resp.StatusCode, Message: "missing upgrade headers"}
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, &HandshakeError{Status: resp.StatusCode, Message: "invalid Sec-WebSocket-Accept"}
+	}
+	subprotocol := resp.Header.Get("Sec-WebSocket-Protocol")
+	if subprotocol != "" && !containsToken(cfg.subprotocols, subprotocol) {
+		return nil, &HandshakeError{Status: resp.StatusCode, Message: fmt.Sprintf("server selected unoffered subprotocol %q", subprotocol)}
+	}
+
+	if !stop() {
+		return nil, fmt.Errorf("websocket: read handshake: %w", ctx.Err())
+	}
+	if err := netConn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	conn := newConn(netConn, br, true, cfg)
+	conn.subprotocol = subprotocol
+	return conn, nil
+}
+
+func rejectHandshake(w http.ResponseWriter, status int, message string) error {
+	http.Error(w, http.StatusText(status), status)
+	return &HandshakeError{Status: status, Message: message}
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// sameOrigin accepts requests without an Origin header, which non-browser
+// clients usually omit, and requests whose Origin host matches the Host
+// header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(supported, offered []string) string {
+	for _, name := range supported {
+		if containsToken(offered, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func headerTokens(header http.Header, name string) []string {
+	var tokens []string
+	for _, value := range header.Values(name) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, candidate := range headerTokens(header, name) {
+		if strings.EqualFold(candidate, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsToken(tokens []string, token string) bool {
+	for _, candidate := range tokens {
+		if candidate == token {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package websocket
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/transport"
+)
+
+// Defaults applied by NewServer, Upgrade, and Dial.
+const (
+	DefaultMaxMessageBytes = 1 << 20
+	DefaultSendQueueSize   = 64
+	DefaultSendTimeout     = 5 * time.Second
+	DefaultWriteTimeout    = 10 * time.Second
+	DefaultPingInterval    = 30 * time.Second
+	DefaultPongTimeout     = 10 * time.Second
+)
+
+// Option configures a Server, Upgrade, or Dial. Options that do not apply to
+// the receiving function are ignored.
+type Option func(*config)
+
+type config struct {
+	codec           Codec
+	subprotocols    []string
+	codecs          map[string]Codec
+	checkOrigin     func(*http.Request) bool
+	maxMessageBytes int64
+	sendQueueSize   int
+	sendTimeout     time.Duration
+	writeTimeout    time.Duration
+	pingInterval    time.Duration
+	pongTimeout     time.Duration
+	errorHandler    transport.ErrorHandler
+	header          http.Header
+	tlsConfig       *tls.Config
+}
+
+func newConfig(options []Option) config {
+	cfg := config{
+		codec:           JSON,
+		maxMessageBytes: DefaultMaxMessageBytes,
+		sendQueueSize:   DefaultSendQueueSize,
+		sendTimeout:     DefaultSendTimeout,
+		writeTimeout:    DefaultWriteTimeout,
+		pingInterval:    DefaultPingInterval,
+		pongTimeout:     DefaultPongTimeout,
+		errorHandler:    transport.NopErrorHandler,
+	}
+	for _, option := range options {
+		if option != nil {
+			option(&cfg)
+		}
+	}
+	return cfg
+}
+
+// codecFor returns the codec registered for subprotocol, falling back to the
+// default codec.
+func (cfg *config) codecFor(subprotocol string) Codec {
+	if c, ok := cfg.codecs[subprotocol]; ok {
+		return c
+	}
+	return cfg.codec
+}
+
+// WithCodec sets the codec used when no registered subprotocol was
+// negotiated. The default is JSON.
+func WithCodec(c Codec) Option {
+	return func(cfg *config) {
+		if c != nil {
+			cfg.codec = c
+		}
+	}
+}
+
+// WithSubprotocol registers a Sec-WebSocket-Protocol name and the codec
+// used on connections that negotiate it; a nil codec means the default
+// codec. Servers pick the first registered name the client offers, so
+// register names in order of preference. Dial offers all registered names.
+func WithSubprotocol(name string, c Codec) Option {
+	return func(cfg *config) {
+		if name == "" || containsToken(cfg.subprotocols, name) {
+			return
+		}
+		cfg.subprotocols = append(cfg.subprotocols, name)
+		if c != nil {
+			if cfg.codecs == nil {
+				cfg.codecs = make(map[string]Codec)
+			}
+			cfg.codecs[name] = c
+		}
+	}
+}
+
+// WithCheckOrigin replaces the same-origin check applied to the Origin
+// header during the handshake. Return true to accept the request.
+func WithCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(cfg *config) { cfg.checkOrigin = check }
+}
+
+// WithMaxMessageBytes limits the size of a reassembled incoming message.
+// Larger messages close the connection with CloseMessageTooBig. The default
+// is DefaultMaxMessageBytes; non-positive values are ignored.
+func WithMaxMessageBytes(n int64) Option {
+	return func(cfg *config) {
+		if n > 0 {
+			cfg.maxMessageBytes = n
+		}
+	}
+}
+
+// WithSendQueue bounds the per-connection queue of outgoing messages. When
+// the queue is full, Stream.Send waits up to timeout for room; after that the
+// client is treated as a slow consumer and disconnected with
+// ClosePolicyViolation. A zero timeout disconnects as soon as the queue is
+// full. The defaults are DefaultSendQueueSize and DefaultSendTimeout.
+func WithSendQueue(size int, timeout time.Duration) Option {
+	return func(cfg *config) {
+		if size > 0 {
+			cfg.sendQueueSize = size
+		}
+		if timeout >= 0 {
+			cfg.sendTimeout = timeout
+		}
+	}
+}
+
+// WithWriteTimeout bounds each frame write. The default is
+// DefaultWriteTimeout; zero disables the deadline.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		if d >= 0 {
+			cfg.writeTimeout = d
+		}
+	}
+}
+
+// WithKeepalive sends a ping every interval and drops the connection when no
+// frame arrives within interval plus timeout. A non-positive interval
+// disables keepalive. The defaults are DefaultPingInterval and
+// DefaultPongTimeout.
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.pingInterval = interval
+		if timeout > 0 {
+			cfg.pongTimeout = timeout
+		}
+	}
+}
+
+// WithErrorHandler sets the handler for handshake failures and errors that
+// end a stream. The default discards them.
+func WithErrorHandler(errorHandler transport.ErrorHandler) Option {
+	return func(cfg *config) {
+		if errorHandler != nil {
+			cfg.errorHandler = errorHandler
+		}
+	}
+}
+
+// WithDialHeader adds request headers, such as Authorization or Origin, to
+// the opening handshake sent by Dial.
+func WithDialHeader(header http.Header) Option {
+	return func(cfg *config) {
+		if cfg.header == nil {
+			cfg.header = make(http.Header)
+		}
+		for k, values := range header {
+			for _, v := range values {
+				cfg.header.Add(k, v)
+			}
+		}
+	}
+}
+
+// WithTLSConfig sets the TLS configuration Dial uses for wss:// URLs.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(cfg *config) { cfg.tlsConfig = tlsConfig }
+}
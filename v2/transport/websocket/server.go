@@ -0,0 +1,343 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// closeGracePeriod bounds how long a closing session waits for the peer to
+// answer its close frame before dropping the connection.
+const closeGracePeriod = time.Second
+
+// ErrSlowConsumer is returned by Stream.Send when the send queue stayed full
+// for longer than the send timeout. The connection is closed with
+// ClosePolicyViolation.
+var ErrSlowConsumer = errors.New("websocket: slow consumer")
+
+// errSessionFinished is the cancellation cause of a session this side closed.
+var errSessionFinished = errors.New("websocket: session finished")
+
+// Stream is one WebSocket connection as seen by a StreamEndpoint.
+//
+// Send and Recv may be called from different goroutines. Send only queues
+// the message; it returns ErrSlowConsumer when the peer does not keep up.
+// Recv returns io.EOF after the peer closes the connection normally.
+type Stream interface {
+	Context() context.Context
+	Send(v any) error
+	Recv(v any) error
+}
+
+// StreamEndpoint handles one WebSocket connection. The context is cancelled
+// when the connection ends. Returning closes the connection with the code
+// CloseCodeForError reports for the returned error.
+type StreamEndpoint func(ctx context.Context, stream Stream) error
+
+// Server serves a StreamEndpoint to WebSocket clients. It implements
+// http.Handler; mount it on any mux or register it with kit.HandleWebSocket.
+//
+// Upgraded connections are hijacked from net/http, so http.Server.Shutdown
+// does not close them. Call Server.Shutdown for that.
+type Server struct {
+	stream StreamEndpoint
+	cfg    config
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	shutdown bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a Server for stream. It panics when stream is nil.
+func NewServer(stream StreamEndpoint, options ...Option) *Server {
+	if stream == nil {
+		panic("websocket: stream endpoint cannot be nil")
+	}
+	return &Server{
+		stream:   stream,
+		cfg:      newConfig(options),
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// ServeHTTP upgrades the request and runs the stream endpoint until the
+// connection ends.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	conn, err := upgrade(w, r, &s.cfg)
+	if err != nil {
+		s.cfg.errorHandler.Handle(r.Context(), err)
+		return
+	}
+
+	sess := newSession(r.Context(), conn, &s.cfg)
+	s.mu.Lock()
+	if s.shutdown {
+		s.mu.Unlock()
+		_ = conn.WriteClose(CloseGoingAway, "going_away")
+		_ = conn.Close()
+		return
+	}
+	s.sessions[sess] = struct{}{}
+	s.mu.Unlock()
+
+	err = sess.serve(s.stream)
+
+	s.mu.Lock()
+	delete(s.sessions, sess)
+	s.mu.Unlock()
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) {
+		s.cfg.errorHandler.Handle(r.Context(), err)
+	}
+}
+
+// Shutdown rejects new connections, closes open ones with CloseGoingAway,
+// and waits for their stream endpoints to return or for ctx to end.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.shutdown = true
+	for sess := range s.sessions {
+		sess.finish(CloseGoingAway, "going_away", true)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type outbound struct {
+	messageType MessageType
+	data        []byte
+}
+
+type session struct {
+	conn   *Conn
+	codec  Codec
+	cfg    *config
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	send      chan outbound
+	recv      chan []byte
+	readDone  chan struct{}
+	writeDone chan struct{}
+
+	finishOnce  sync.Once
+	finished    chan struct{}
+	closeCode   int
+	closeReason string
+	drain       bool
+}
+
+func newSession(parent context.Context, conn *Conn, cfg *config) *session {
+	ctx, cancel := context.WithCancelCause(parent)
+	return &session{
+		conn:      conn,
+		codec:     cfg.codecFor(conn.Subprotocol()),
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		send:      make(chan outbound, cfg.sendQueueSize),
+		recv:      make(chan []byte, 1),
+		readDone:  make(chan struct{}),
+		writeDone: make(chan struct{}),
+		finished:  make(chan struct{}),
+	}
+}
+
+func (s *session) Context() context.Context { return s.ctx }
+
+func (s *session) Send(v any) error {
+	if s.ctx.Err() != nil {
+		return s.err()
+	}
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	msg := outbound{messageType: s.codec.MessageType(), data: data}
+
+	select {
+	case s.send <- msg:
+		return nil
+	default:
+	}
+	if s.cfg.sendTimeout > 0 {
+		timer := time.NewTimer(s.cfg.sendTimeout)
+		defer timer.Stop()
+		select {
+		case s.send <- msg:
+			return nil
+		case <-s.ctx.Done():
+			return s.err()
+		case <-timer.C:
+		}
+	}
+	s.finish(ClosePolicyViolation, "slow_consumer", false)
+	return ErrSlowConsumer
+}
+
+func (s *session) Recv(v any) error {
+	select {
+	case data := <-s.recv:
+		return s.decode(data, v)
+	case <-s.ctx.Done():
+		select {
+		case data := <-s.recv:
+			return s.decode(data, v)
+		default:
+		}
+		return s.err()
+	}
+}
+
+func (s *session) decode(data []byte, v any) error {
+	if err := s.codec.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	return nil
+}
+
+// err reports why the session ended; normal closure by the peer is io.EOF.
+func (s *session) err() error {
+	cause := context.Cause(s.ctx)
+	var closeErr *CloseError
+	if errors.As(cause, &closeErr) {
+		switch closeErr.Code {
+		case CloseNormalClosure, CloseGoingAway, CloseNoStatusReceived:
+			return io.EOF
+		}
+	}
+	if cause == nil || errors.Is(cause, errSessionFinished) {
+		return ErrClosed
+	}
+	return cause
+}
+
+// finish asks the writer to flush the queue when drain is set, send a close
+// frame, and end the session. Only the first call has an effect.
+func (s *session) finish(code int, reason string, drain bool) {
+	s.finishOnce.Do(func() {
+		s.closeCode = code
+		s.closeReason = reason
+		s.drain = drain
+		close(s.finished)
+	})
+}
+
+func (s *session) serve(stream StreamEndpoint) error {
+	go s.readLoop()
+	go s.writeLoop()
+
+	err := stream(s.ctx, s)
+	code, reason := closeFor(err)
+	s.finish(code, reason, true)
+	<-s.writeDone
+
+	timer := time.NewTimer(closeGracePeriod)
+	select {
+	case <-s.readDone:
+	case <-timer.C:
+	}
+	timer.Stop()
+	_ = s.conn.Close()
+	<-s.readDone
+	s.cancel(errSessionFinished)
+	return err
+}
+
+func (s *session) readLoop() {
+	defer close(s.readDone)
+	if s.cfg.pingInterval > 0 {
+		s.conn.onPong = s.extendReadDeadline
+	}
+	for {
+		s.extendReadDeadline()
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.cancel(err)
+			return
+		}
+		select {
+		case s.recv <- data:
+		case <-s.ctx.Done():
+			// Keep reading so the peer's close frame completes the handshake.
+		}
+	}
+}
+
+func (s *session) extendReadDeadline() {
+	if s.cfg.pingInterval > 0 {
+		_ = s.conn.SetReadDeadline(time.Now().Add(s.cfg.pingInterval + s.cfg.pongTimeout))
+	}
+}
+
+func (s *session) writeLoop() {
+	defer close(s.writeDone)
+	var ping <-chan time.Time
+	if s.cfg.pingInterval > 0 {
+		ticker := time.NewTicker(s.cfg.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case msg := <-s.send:
+			if err := s.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				s.cancel(err)
+				return
+			}
+		case <-ping:
+			if err := s.conn.WritePing(nil); err != nil {
+				s.cancel(err)
+				return
+			}
+		case <-s.finished:
+			if s.drain && s.drainQueue() != nil {
+				s.cancel(errSessionFinished)
+				return
+			}
+			_ = s.conn.WriteClose(s.closeCode, s.closeReason)
+			s.cancel(errSessionFinished)
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *session) drainQueue() error {
+	for {
+		select {
+		case msg := <-s.send:
+			if err := s.conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
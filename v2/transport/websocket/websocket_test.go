@@ -0,0 +1,352 @@
+package websocket_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/go-kit/v2/apperror"
+	"github.com/dreamsxin/go-kit/v2/endpoint"
+	"github.com/dreamsxin/go-kit/v2/transport"
+	"github.com/dreamsxin/go-kit/v2/transport/websocket"
+)
+
+type chatMessage struct {
+	Text string `json:"text"`
+}
+
+func newWebSocketServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string, options ...websocket.Option) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := websocket.Dial(ctx, url, options...)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func writeJSON(t *testing.T, conn *websocket.Conn, v any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func readJSON(t *testing.T, conn *websocket.Conn, v any) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+}
+
+// readClose reads until the peer's close frame arrives.
+func readClose(t *testing.T, conn *websocket.Conn) *websocket.CloseError {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			t.Fatalf("read error = %v, want close frame", err)
+		}
+		return closeErr
+	}
+}
+
+func TestServer_EchoesMessagesAndClosesNormally(t *testing.T) {
+	ended := make(chan error, 1)
+	url := newWebSocketServer(t, websocket.NewServer(func(ctx context.Context, stream websocket.Stream) error {
+		for {
+			var msg chatMessage
+			if err := stream.Recv(&msg); err != nil {
+				ended <- err
+				return nil
+			}
+			if err := stream.Send(chatMessage{Text: "echo: " + msg.Text}); err != nil {
+				return err
+			}
+		}
+	}))
+
+	conn := dial(t, url)
+	for _, text := range []string{"hello", "world"} {
+		writeJSON(t, conn, chatMessage{Text: text})
+		var got chatMessage
+		readJSON(t, conn, &got)
+		if got.Text != "echo: "+text {
+			t.Fatalf("got %q, want echo of %q", got.Text, text)
+		}
+	}
+
+	if err := conn.WriteClose(websocket.CloseNormalClosure, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	if closeErr := readClose(t, conn); closeErr.Code != websocket.CloseNormalClosure {
+		t.Fatalf("close code = %d, want %d", closeErr.Code, websocket.CloseNormalClosure)
+	}
+	select {
+	case err := <-ended:
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("Recv error = %v, want io.EOF", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream endpoint did not observe the close")
+	}
+}
+
+func TestServer_MapsEndpointErrorsToCloseCodes(t *testing.T) {
+	url := newWebSocketServer(t, websocket.NewServer(func(context.Context, websocket.Stream) error {
+		return apperror.New(apperror.KindPermissionDenied, "dashboard_forbidden", "not allowed")
+	}))
+
+	closeErr := readClose(t, dial(t, url))
+	if closeErr.Code != websocket.ClosePolicyViolation || closeErr.Reason != "dashboard_forbidden" {
+		t.Fatalf("close = %d %q, want %d dashboard_forbidden", closeErr.Code, closeErr.Reason, websocket.ClosePolicyViolation)
+	}
+}
+
+func TestCloseCodeForError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, websocket.CloseNormalClosure},
+		{"eof", io.EOF, websocket.CloseNormalClosure},
+		{"canceled", context.Canceled, websocket.CloseGoingAway},
+		{"close error", &websocket.CloseError{Code: 4001, Reason: "custom"}, 4001},
+		{"too big", websocket.ErrMessageTooBig, websocket.CloseMessageTooBig},
+		{"invalid argument", apperror.New(apperror.KindInvalidArgument, "bad", "bad"), websocket.CloseInvalidPayload},
+		{"unauthenticated", apperror.New(apperror.KindUnauthenticated, "auth", "auth"), websocket.ClosePolicyViolation},
+		{"unavailable", apperror.New(apperror.KindUnavailable, "down", "down"), websocket.CloseTryAgainLater},
+		{"rate limited", endpoint.ErrRateLimited, websocket.CloseTryAgainLater},
+		{"internal", apperror.New(apperror.KindInternal, "boom", "boom"), websocket.CloseInternalError},
+		{"unclassified", errors.New("boom"), websocket.CloseInternalError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := websocket.CloseCodeForError(tc.err); got != tc.want {
+				t.Fatalf("CloseCodeForError(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromEndpoint_AnswersEachMessage(t *testing.T) {
+	ep := func(_ context.Context, request any) (any, error) {
+		msg := request.(chatMessage)
+		if msg.Text == "" {
+			return nil, apperror.New(apperror.KindInvalidArgument, "text_required", "text is required")
+		}
+		return chatMessage{Text: strings.ToUpper(msg.Text)}, nil
+	}
+	url := newWebSocketServer(t, websocket.NewServer(websocket.FromEndpoint[chatMessage](ep)))
+	conn := dial(t, url)
+
+	writeJSON(t, conn, chatMessage{Text: "hi"})
+	var got chatMessage
+	readJSON(t, conn, &got)
+	if got.Text != "HI" {
+		t.Fatalf("response = %q, want HI", got.Text)
+	}
+
+	writeJSON(t, conn, chatMessage{})
+	var failed websocket.ErrorMessage
+	readJSON(t, conn, &failed)
+	if failed.Error.Code != "text_required" || failed.Error.Message != "text is required" {
+		t.Fatalf("error message = %+v", failed.Error)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("{not json")); err != nil {
+		t.Fatal(err)
+	}
+	failed = websocket.ErrorMessage{}
+	readJSON(t, conn, &failed)
+	if failed.Error.Code != "invalid_message" {
+		t.Fatalf("error code = %q, want invalid_message", failed.Error.Code)
+	}
+
+	writeJSON(t, conn, chatMessage{Text: "still open"})
+	readJSON(t, conn, &got)
+	if got.Text != "STILL OPEN" {
+		t.Fatalf("response = %q, want STILL OPEN", got.Text)
+	}
+}
+
+func TestServer_NegotiatesSubprotocolCodec(t *testing.T) {
+	binaryJSON := websocket.NewCodec(websocket.BinaryMessage, json.Marshal, json.Unmarshal)
+	url := newWebSocketServer(t, websocket.NewServer(func(_ context.Context, stream websocket.Stream) error {
+		return stream.Send(chatMessage{Text: "hi"})
+	}, websocket.WithSubprotocol("chat.v2", binaryJSON), websocket.WithSubprotocol("chat.v1", nil)))
+
+	conn := dial(t, url, websocket.WithSubprotocol("chat.v1", nil), websocket.WithSubprotocol("chat.v2", nil))
+	if conn.Subprotocol() != "chat.v2" {
+		t.Fatalf("subprotocol = %q, want server preference chat.v2", conn.Subprotocol())
+	}
+	messageType, _, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageType != websocket.BinaryMessage {
+		t.Fatalf("message type = %d, want binary", messageType)
+	}
+}
+
+func TestUpgrade_RejectsCrossOriginRequests(t *testing.T) {
+	handled := make(chan error, 1)
+	url := newWebSocketServer(t, websocket.NewServer(func(context.Context, websocket.Stream) error {
+		return nil
+	}, websocket.WithErrorHandler(transport.ErrorHandlerFunc(func(_ context.Context, err error) { handled <- err }))))
+
+	_, err := websocket.Dial(context.Background(), url, websocket.WithDialHeader(http.Header{"Origin": {"https://evil.example"}}))
+	var handshakeErr *websocket.HandshakeError
+	if !errors.As(err, &handshakeErr) || handshakeErr.StatusCode() != http.StatusForbidden {
+		t.Fatalf("dial error = %v, want 403 handshake error", err)
+	}
+	select {
+	case err := <-handled:
+		if !errors.As(err, &handshakeErr) {
+			t.Fatalf("handled error = %v, want handshake error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handshake failure was not reported")
+	}
+}
+
+func TestUpgrade_RejectsPlainHTTPRequests(t *testing.T) {
+	h := websocket.NewServer(func(context.Context, websocket.Stream) error { return nil })
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestServer_ClosesOversizedMessages(t *testing.T) {
+	url := newWebSocketServer(t, websocket.NewServer(func(_ context.Context, stream websocket.Stream) error {
+		var msg chatMessage
+		return stream.Recv(&msg)
+	}, websocket.WithMaxMessageBytes(64)))
+
+	conn := dial(t, url)
+	writeJSON(t, conn, chatMessage{Text: strings.Repeat("x", 128)})
+	if closeErr := readClose(t, conn); closeErr.Code != websocket.CloseMessageTooBig {
+		t.Fatalf("close code = %d, want %d", closeErr.Code, websocket.CloseMessageTooBig)
+	}
+}
+
+func TestServer_DisconnectsSlowConsumers(t *testing.T) {
+	sendErr := make(chan error, 1)
+	url := newWebSocketServer(t, websocket.NewServer(func(_ context.Context, stream websocket.Stream) error {
+		payload := chatMessage{Text: strings.Repeat("x", 256<<10)}
+		for i := 0; i < 1000; i++ {
+			if err := stream.Send(payload); err != nil {
+				sendErr <- err
+				return err
+			}
+		}
+		sendErr <- nil
+		return nil
+	}, websocket.WithSendQueue(1, 0), websocket.WithWriteTimeout(200*time.Millisecond)))
+
+	// The client never reads, so socket buffers and then the queue fill up.
+	dial(t, url)
+	select {
+	case err := <-sendErr:
+		if !errors.Is(err, websocket.ErrSlowConsumer) {
+			t.Fatalf("Send error = %v, want ErrSlowConsumer", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Send never reported a slow consumer")
+	}
+}
+
+func TestServer_KeepaliveDropsUnresponsivePeers(t *testing.T) {
+	ended := make(chan struct{})
+	url := newWebSocketServer(t, websocket.NewServer(func(ctx context.Context, _ websocket.Stream) error {
+		<-ctx.Done()
+		close(ended)
+		return nil
+	}, websocket.WithKeepalive(20*time.Millisecond, 20*time.Millisecond)))
+
+	// A reading client answers pings and stays connected.
+	live := dial(t, url)
+	readErr := make(chan error, 1)
+	go func() {
+		_, _, err := live.ReadMessage()
+		readErr <- err
+	}()
+	select {
+	case <-ended:
+		t.Fatal("responsive peer was dropped")
+	case <-time.After(200 * time.Millisecond):
+	}
+	_ = live.Close()
+	<-readErr
+	<-ended
+
+	// A client that never reads never sees pings, so it never answers.
+	ended = make(chan struct{})
+	dial(t, url)
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("unresponsive peer was not dropped")
+	}
+}
+
+func TestServer_ShutdownClosesSessionsWithGoingAway(t *testing.T) {
+	started := make(chan struct{})
+	srv := websocket.NewServer(func(ctx context.Context, _ websocket.Stream) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	url := newWebSocketServer(t, srv)
+	conn := dial(t, url)
+	<-started
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(ctx)
+	}()
+	if closeErr := readClose(t, conn); closeErr.Code != websocket.CloseGoingAway {
+		t.Fatalf("close code = %d, want %d", closeErr.Code, websocket.CloseGoingAway)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if _, err := websocket.Dial(context.Background(), url); err == nil {
+		t.Fatal("dial after shutdown succeeded")
+	}
+}